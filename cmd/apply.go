@@ -67,6 +67,10 @@ func NewApplyCmd(globalCfg *config.GlobalImpl) *cobra.Command {
 	f.BoolVar(&applyOptions.ResetValues, "reset-values", false, `Override helmDefaults.reuseValues "helm upgrade --install --reset-values"`)
 	f.StringVar(&applyOptions.PostRenderer, "post-renderer", "", `pass --post-renderer to "helm template" or "helm upgrade --install"`)
 	f.StringVar(&applyOptions.Cascade, "cascade", "", "pass cascade to helm exec, default: background")
+	f.StringVar(&applyOptions.ReportFile, "report-file", "", "write the result of each release as JSON to the file. Useful for feeding dashboards and notifiers")
+	f.BoolVar(&applyOptions.RollbackOnFailure, "rollback-on-failure", false, "roll back the releases upgraded and delete the releases installed in this run, in the reverse order of needs, when any release fails to be applied")
+	f.StringVar(&applyOptions.PolicyFile, "policy-file", "", `path to the policy file the releases are checked against before being applied. Defaults to the ".helmfile-policy.yaml" next to the helmfile, if any`)
+	f.StringVar(&applyOptions.Plan, "plan", "", `apply exactly the changes recorded in the plan file written by "helmfile diff --out-plan". Fails if the helmfile state or the cluster has changed since the plan was written`)
 
 	return cmd
 }
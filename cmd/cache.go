@@ -29,6 +29,28 @@ func NewCacheInfoSubcommand(cacheImpl *config.CacheImpl) *cobra.Command {
 	return cmd
 }
 
+func NewCacheListSubcommand(cacheImpl *config.CacheImpl) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "list",
+		Short: "list cached remote files with their origin and age",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			err := config.NewCLIConfigImpl(cacheImpl.GlobalImpl)
+			if err != nil {
+				return err
+			}
+
+			if err := cacheImpl.ValidateConfig(); err != nil {
+				return err
+			}
+
+			a := app.New(cacheImpl)
+			return toCLIError(cacheImpl.GlobalImpl, a.ListCacheEntries(cacheImpl))
+		},
+	}
+
+	return cmd
+}
+
 func NewCacheCleanupSubcommand(cacheImpl *config.CacheImpl) *cobra.Command {
 	cmd := &cobra.Command{
 		Use:   "cleanup",
@@ -63,6 +85,7 @@ func NewCacheCmd(globalCfg *config.GlobalImpl) *cobra.Command {
 	cmd.AddCommand(
 		NewCacheCleanupSubcommand(cacheImpl),
 		NewCacheInfoSubcommand(cacheImpl),
+		NewCacheListSubcommand(cacheImpl),
 	)
 
 	return cmd
@@ -0,0 +1,39 @@
+package cmd
+
+import (
+	"github.com/spf13/cobra"
+
+	"github.com/helmfile/helmfile/pkg/app"
+	"github.com/helmfile/helmfile/pkg/config"
+)
+
+// NewCheckVersionsCmd returns check-versions subcmd
+func NewCheckVersionsCmd(globalCfg *config.GlobalImpl) *cobra.Command {
+	checkVersionsOptions := config.NewCheckVersionsOptions()
+
+	cmd := &cobra.Command{
+		Use:   "check-versions",
+		Short: "Check that the chart versions are pinned and locked, and report the newer chart versions available",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			checkVersionsImpl := config.NewCheckVersionsImpl(globalCfg, checkVersionsOptions)
+			err := config.NewCLIConfigImpl(checkVersionsImpl.GlobalImpl)
+			if err != nil {
+				return err
+			}
+
+			if err := checkVersionsImpl.ValidateConfig(); err != nil {
+				return err
+			}
+
+			a := app.New(checkVersionsImpl)
+			return toCLIError(checkVersionsImpl.GlobalImpl, a.CheckVersions(checkVersionsImpl))
+		},
+	}
+
+	f := cmd.Flags()
+	f.StringVar(&globalCfg.GlobalOptions.Args, "args", "", "pass args to helm exec")
+	f.BoolVar(&checkVersionsOptions.SkipRepos, "skip-repos", false, `skip running "helm repo update" before looking up the chart versions`)
+	f.StringVar(&checkVersionsOptions.Output, "output", "", "output format for the version report. Available options: table, json")
+
+	return cmd
+}
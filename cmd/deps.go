@@ -34,6 +34,9 @@ func NewDepsCmd(globalCfg *config.GlobalImpl) *cobra.Command {
 	f.StringVar(&globalCfg.GlobalOptions.Args, "args", "", "pass args to helm exec")
 	f.BoolVar(&depsOptions.SkipRepos, "skip-repos", false, `skip running "helm repo update" and "helm dependency build"`)
 	f.IntVar(&depsOptions.Concurrency, "concurrency", 0, "maximum number of concurrent helm processes to run, 0 is unlimited")
+	f.StringVar(&depsOptions.UpdateStrategy, "update-strategy", "", "how far to update the locked chart versions of the releases without updatePolicy. Available options: patch, minor, major, none")
+	f.BoolVar(&depsOptions.DryRun, "dry-run", false, "print the changes to the lock file instead of writing it")
+	f.BoolVar(&depsOptions.AllowDigestChanges, "allow-digest-changes", false, "record the new digests of the charts re-published with the locked versions instead of failing")
 
 	return cmd
 }
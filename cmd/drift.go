@@ -0,0 +1,39 @@
+package cmd
+
+import (
+	"github.com/spf13/cobra"
+
+	"github.com/helmfile/helmfile/pkg/app"
+	"github.com/helmfile/helmfile/pkg/config"
+)
+
+// NewDriftCmd returns drift subcmd
+func NewDriftCmd(globalCfg *config.GlobalImpl) *cobra.Command {
+	driftOptions := config.NewDriftOptions()
+
+	cmd := &cobra.Command{
+		Use:   "drift",
+		Short: "Detect releases whose live state has drifted from the desired state",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			driftImpl := config.NewDriftImpl(globalCfg, driftOptions)
+			err := config.NewCLIConfigImpl(driftImpl.GlobalImpl)
+			if err != nil {
+				return err
+			}
+
+			if err := driftImpl.ValidateConfig(); err != nil {
+				return err
+			}
+
+			a := app.New(driftImpl)
+			return toCLIError(driftImpl.GlobalImpl, a.Drift(driftImpl))
+		},
+	}
+
+	f := cmd.Flags()
+	f.StringVar(&globalCfg.GlobalOptions.Args, "args", "", "pass args to helm exec")
+	f.IntVar(&driftOptions.Concurrency, "concurrency", 0, "maximum number of concurrent helm processes to run, 0 is unlimited")
+	f.StringVar(&driftOptions.Output, "output", "", "output format for the drift report. Available options: table, json")
+
+	return cmd
+}
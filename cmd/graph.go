@@ -0,0 +1,44 @@
+package cmd
+
+import (
+	"github.com/spf13/cobra"
+
+	"github.com/helmfile/helmfile/pkg/app"
+	"github.com/helmfile/helmfile/pkg/config"
+)
+
+// NewGraphCmd returns graph subcmd
+func NewGraphCmd(globalCfg *config.GlobalImpl) *cobra.Command {
+	graphOptions := config.NewGraphOptions()
+
+	cmd := &cobra.Command{
+		Use:   "graph",
+		Short: "Print the dependency graph of the releases built from their needs",
+		Long: `Print the dependency graph of the releases built from their needs.
+
+Each release is shown along with the batch it is processed in, whether it is installed and whether its condition is enabled.
+Edges point from a release to the releases that need it, so that they follow the order of installation.`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			graphImpl := config.NewGraphImpl(globalCfg, graphOptions)
+			err := config.NewCLIConfigImpl(graphImpl.GlobalImpl)
+			if err != nil {
+				return err
+			}
+
+			if err := graphImpl.ValidateConfig(); err != nil {
+				return err
+			}
+
+			a := app.New(graphImpl)
+			return toCLIError(graphImpl.GlobalImpl, a.Graph(graphImpl))
+		},
+	}
+
+	f := cmd.Flags()
+	f.StringVar(&graphOptions.Output, "output", "dot", "output format of the graph. Available options: dot, mermaid, json")
+	f.BoolVar(&graphOptions.SkipNeeds, "skip-needs", true, `do not automatically include releases from the target release's "needs" when --selector/-l flag is provided. Does nothing when --selector/-l flag is not provided. Defaults to true when --include-needs or --include-transitive-needs is not provided`)
+	f.BoolVar(&graphOptions.IncludeNeeds, "include-needs", false, `automatically include releases from the target release's "needs" when --selector/-l flag is provided. Does nothing when --selector/-l flag is not provided`)
+	f.BoolVar(&graphOptions.IncludeTransitiveNeeds, "include-transitive-needs", false, `like --include-needs, but also includes transitive needs (needs of needs). Does nothing when --selector/-l flag is not provided. Overrides exclusions of other selectors and conditions.`)
+
+	return cmd
+}
@@ -33,7 +33,9 @@ func NewListCmd(globalCfg *config.GlobalImpl) *cobra.Command {
 	f := cmd.Flags()
 	f.BoolVar(&listOptions.KeepTempDir, "keep-temp-dir", false, "Keep temporary directory")
 	f.BoolVar(&listOptions.SkipCharts, "skip-charts", false, "don't prepare charts when listing releases")
-	f.StringVar(&listOptions.Output, "output", "", "output releases list as a json string")
+	f.StringVar(&listOptions.Output, "output", "", `output format of the releases list: table, json, yaml, csv, markdown or go-template. Defaults to table`)
+	f.StringVar(&listOptions.Template, "template", "", `Go template to print the releases list with when the output format is go-template, e.g. '{{range .}}{{.Name}}{{"\n"}}{{end}}'`)
+	f.BoolVar(&listOptions.WithStatus, "with-status", false, "add the deployed chart version, revision, status, updated time and whether the release is out of date, by running \"helm list\" once per kube context")
 
 	return cmd
 }
@@ -0,0 +1,50 @@
+package cmd
+
+import (
+	"github.com/spf13/cobra"
+
+	"github.com/helmfile/helmfile/pkg/app"
+	"github.com/helmfile/helmfile/pkg/config"
+)
+
+func NewPolicyCheckSubcommand(policyImpl *config.PolicyImpl) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "check",
+		Short: "Check the releases against the rules of the policy file",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			err := config.NewCLIConfigImpl(policyImpl.GlobalImpl)
+			if err != nil {
+				return err
+			}
+
+			if err := policyImpl.ValidateConfig(); err != nil {
+				return err
+			}
+
+			a := app.New(policyImpl)
+			return toCLIError(policyImpl.GlobalImpl, a.PolicyCheck(policyImpl))
+		},
+	}
+
+	f := cmd.Flags()
+	f.StringVar(&policyImpl.PolicyOptions.PolicyFile, "policy-file", "", `path to the policy file. Defaults to the ".helmfile-policy.yaml" next to the helmfile`)
+
+	return cmd
+}
+
+// NewPolicyCmd returns policy subcmd
+func NewPolicyCmd(globalCfg *config.GlobalImpl) *cobra.Command {
+	policyOptions := config.NewPolicyOptions()
+	policyImpl := config.NewPolicyImpl(globalCfg, policyOptions)
+
+	cmd := &cobra.Command{
+		Use:   "policy",
+		Short: "Policy management",
+	}
+
+	cmd.AddCommand(
+		NewPolicyCheckSubcommand(policyImpl),
+	)
+
+	return cmd
+}
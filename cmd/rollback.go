@@ -0,0 +1,39 @@
+package cmd
+
+import (
+	"github.com/spf13/cobra"
+
+	"github.com/helmfile/helmfile/pkg/app"
+	"github.com/helmfile/helmfile/pkg/config"
+)
+
+// NewRollbackCmd returns rollback subcmd
+func NewRollbackCmd(globalCfg *config.GlobalImpl) *cobra.Command {
+	rollbackOptions := config.NewRollbackOptions()
+
+	cmd := &cobra.Command{
+		Use:   "rollback",
+		Short: "Roll back releases to their previous revisions",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			rollbackImpl := config.NewRollbackImpl(globalCfg, rollbackOptions)
+			err := config.NewCLIConfigImpl(rollbackImpl.GlobalImpl)
+			if err != nil {
+				return err
+			}
+
+			if err := rollbackImpl.ValidateConfig(); err != nil {
+				return err
+			}
+
+			a := app.New(rollbackImpl)
+			return toCLIError(rollbackImpl.GlobalImpl, a.Rollback(rollbackImpl))
+		},
+	}
+
+	f := cmd.Flags()
+	f.StringVar(&globalCfg.GlobalOptions.Args, "args", "", "pass args to helm exec")
+	f.IntVar(&rollbackOptions.Concurrency, "concurrency", 0, "maximum number of concurrent helm processes to run, 0 is unlimited")
+	f.IntVar(&rollbackOptions.ToRevision, "to-revision", 0, "roll back to the revision instead of the previous revision of each release")
+
+	return cmd
+}
@@ -85,15 +85,22 @@ func NewRootCmd(globalConfig *config.GlobalOptions) (*cobra.Command, error) {
 		NewApplyCmd(globalImpl),
 		NewBuildCmd(globalImpl),
 		NewCacheCmd(globalImpl),
+		NewCheckVersionsCmd(globalImpl),
 		NewDepsCmd(globalImpl),
 		NewDestroyCmd(globalImpl),
+		NewRollbackCmd(globalImpl),
+		NewDriftCmd(globalImpl),
 		NewFetchCmd(globalImpl),
+		NewGraphCmd(globalImpl),
 		NewListCmd(globalImpl),
+		NewPolicyCmd(globalImpl),
 		NewReposCmd(globalImpl),
+		NewSchemaCmd(globalImpl),
 		NewLintCmd(globalImpl),
 		NewWriteValuesCmd(globalImpl),
 		NewTestCmd(globalImpl),
 		NewTemplateCmd(globalImpl),
+		NewVendorCmd(globalImpl),
 		NewSyncCmd(globalImpl),
 		NewDiffCmd(globalImpl),
 		NewStatusCmd(globalImpl),
@@ -131,14 +138,20 @@ func setGlobalOptionsForRootCmd(fs *pflag.FlagSet, globalOptions *config.GlobalO
 	fs.StringVar(&globalOptions.LogLevel, "log-level", "info", "Set log level, default info")
 	fs.StringVarP(&globalOptions.Namespace, "namespace", "n", "", "Set namespace. Uses the namespace set in the context by default, and is available in templates as {{ .Namespace }}")
 	fs.StringVarP(&globalOptions.Chart, "chart", "c", "", "Set chart. Uses the chart set in release by default, and is available in template as {{ .Chart }}")
-	fs.StringArrayVarP(&globalOptions.Selector, "selector", "l", nil, `Only run using the releases that match labels. Labels can take the form of foo=bar or foo!=bar.
+	fs.StringArrayVarP(&globalOptions.Selector, "selector", "l", nil, `Only run using the releases that match labels. Labels can take the form of foo=bar, foo!=bar,
+foo in (bar,baz), foo notin (bar,baz), foo, !foo, foo=~regex or foo!~regex.
 A release must match all labels in a group in order to be used. Multiple groups can be specified at once.
 "--selector tier=frontend,tier!=proxy --selector tier=backend" will match all frontend, non-proxy releases AND all backend releases.
-The name of a release can be used as a label: "--selector name=myrelease"`)
+The name, namespace, chart and kubeContext of a release can be used as labels: "--selector name=myrelease"`)
 	fs.BoolVar(&globalOptions.AllowNoMatchingRelease, "allow-no-matching-release", false, `Do not exit with an error code if the provided selector has no matching releases.`)
 	fs.BoolVar(&globalOptions.EnableLiveOutput, "enable-live-output", globalOptions.EnableLiveOutput, `Show live output from the Helm binary Stdout/Stderr into Helmfile own Stdout/Stderr.
 It only applies for the Helm CLI commands, Stdout/Stderr for Hooks are still displayed only when it's execution finishes.`)
 	fs.BoolVarP(&globalOptions.Interactive, "interactive", "i", false, "Request confirmation before attempting to modify clusters")
+	fs.BoolVar(&globalOptions.RefreshRemote, "refresh-remote", false, `Fetch remote helmfiles, values files and charts again ignoring the cache. Sources pinned to a tag or a commit are still served from the cache`)
+	fs.BoolVar(&globalOptions.Offline, "offline", false, `Fail instead of accessing the network to fetch remote helmfiles, values files and charts, or to add chart repositories. Use with a helmfile written by "helmfile vendor"`)
+	fs.BoolVar(&globalOptions.CrossHelmfileNeeds, "cross-helmfile-needs", false, `Load every helmfile, including sub-helmfiles, before processing any release, and order the releases of all the helmfiles with one DAG, so that "needs" can refer to releases defined in other helmfiles`)
+	fs.StringVar(&globalOptions.ChangedSince, "changed-since", "", `Only run using the releases whose helmfile, values files, secrets files or local chart changed since the git ref, as listed by "git diff --name-only <ref>", and the releases that need them`)
+	fs.DurationVar(&globalOptions.RemoteCacheTTL, "remote-cache-ttl", 0, `How long cached remote helmfiles, values files and charts are used before being fetched again, e.g. "1h". 0 means the cache never expires. Sources pinned to a tag or a commit never expire`)
 	// avoid 'pflag: help requested' error (#251)
 	fs.BoolP("help", "h", false, "help for helmfile")
 }
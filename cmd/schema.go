@@ -0,0 +1,34 @@
+package cmd
+
+import (
+	"github.com/spf13/cobra"
+
+	"github.com/helmfile/helmfile/pkg/app"
+	"github.com/helmfile/helmfile/pkg/config"
+)
+
+// NewSchemaCmd returns schema subcmd
+func NewSchemaCmd(globalCfg *config.GlobalImpl) *cobra.Command {
+	schemaOptions := config.NewSchemaOptions()
+
+	cmd := &cobra.Command{
+		Use:   "schema",
+		Short: "Print the JSON schema of helmfile.yaml, for editors to validate and complete helmfiles",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			schemaImpl := config.NewSchemaImpl(globalCfg, schemaOptions)
+			err := config.NewCLIConfigImpl(schemaImpl.GlobalImpl)
+			if err != nil {
+				return err
+			}
+
+			if err := schemaImpl.ValidateConfig(); err != nil {
+				return err
+			}
+
+			a := app.New(schemaImpl)
+			return toCLIError(schemaImpl.GlobalImpl, a.Schema(schemaImpl))
+		},
+	}
+
+	return cmd
+}
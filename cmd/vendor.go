@@ -0,0 +1,43 @@
+package cmd
+
+import (
+	"github.com/spf13/cobra"
+
+	"github.com/helmfile/helmfile/pkg/app"
+	"github.com/helmfile/helmfile/pkg/config"
+)
+
+// NewVendorCmd returns vendor subcmd
+func NewVendorCmd(globalCfg *config.GlobalImpl) *cobra.Command {
+	vendorOptions := config.NewVendorOptions()
+
+	cmd := &cobra.Command{
+		Use:   "vendor",
+		Short: "Copy charts, remote helmfiles and values into a directory or a tarball for offline use",
+		Long: `Copy charts, remote helmfiles and values into a directory or a tarball for offline use.
+
+The vendored directory contains a helmfile.yaml that refers to the copies instead of the original sources,
+so that it can be deployed with "helmfile --offline -f <dir> apply" without network access.`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			vendorImpl := config.NewVendorImpl(globalCfg, vendorOptions)
+			err := config.NewCLIConfigImpl(vendorImpl.GlobalImpl)
+			if err != nil {
+				return err
+			}
+
+			if err := vendorImpl.ValidateConfig(); err != nil {
+				return err
+			}
+
+			a := app.New(vendorImpl)
+			return toCLIError(vendorImpl.GlobalImpl, a.Vendor(vendorImpl))
+		},
+	}
+
+	f := cmd.Flags()
+	f.IntVar(&vendorOptions.Concurrency, "concurrency", 0, "maximum number of concurrent helm processes to run, 0 is unlimited")
+	f.StringVar(&vendorOptions.OutputDir, "output-dir", "vendor", "directory to write the vendored helmfile into")
+	f.StringVar(&vendorOptions.Tarball, "tarball", "", "write the vendored helmfile into this .tar.gz archive instead of the output directory")
+
+	return cmd
+}
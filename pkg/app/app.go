@@ -1,15 +1,21 @@
 package app
 
 import (
+	"archive/tar"
 	"bytes"
+	"compress/gzip"
 	goContext "context"
 	"fmt"
+	"io"
+	"io/fs"
 	"os"
 	"path/filepath"
 	"sort"
+	"strconv"
 	"strings"
 	"sync"
 	"text/tabwriter"
+	"time"
 
 	"github.com/helmfile/vals"
 	"go.uber.org/zap"
@@ -34,6 +40,11 @@ type App struct {
 	EnableLiveOutput           bool
 	StripArgsValuesOnExitError bool
 	DisableForceUpdate         bool
+	RefreshRemote              bool
+	Offline                    bool
+	RemoteCacheTTL             time.Duration
+	CrossHelmfileNeeds         bool
+	ChangedSince               string
 
 	Logger      *zap.SugaredLogger
 	Env         string
@@ -50,22 +61,45 @@ type App struct {
 
 	remote *remote.Remote
 
+	// changedFiles are the files changed since ChangedSince, or nil when ChangedSince is not set
+	changedFiles state.ChangedFiles
+	// listChangedFiles lists the files changed since the git ref. The files are listed with git when nil
+	listChangedFiles func(ref string) ([]string, error)
+
 	valsRuntime vals.Evaluator
 
 	helms      map[helmKey]helmexec.Interface
 	helmsMutex sync.Mutex
 
+	// crossHelmfile schedules the releases of all the helmfiles together with --cross-helmfile-needs
+	crossHelmfile *crossHelmfileScheduler
+
 	ctx goContext.Context
 }
 
 type HelmRelease struct {
-	Name      string `json:"name"`
-	Namespace string `json:"namespace"`
-	Enabled   bool   `json:"enabled"`
-	Installed bool   `json:"installed"`
-	Labels    string `json:"labels"`
-	Chart     string `json:"chart"`
-	Version   string `json:"version"`
+	Name      string `json:"name" yaml:"name"`
+	Namespace string `json:"namespace" yaml:"namespace"`
+	Enabled   bool   `json:"enabled" yaml:"enabled"`
+	Installed bool   `json:"installed" yaml:"installed"`
+	Labels    string `json:"labels" yaml:"labels"`
+	Chart     string `json:"chart" yaml:"chart"`
+	Version   string `json:"version" yaml:"version"`
+	// Status is the status of the release in the cluster, which is only set with --with-status
+	Status *HelmReleaseStatus `json:"status,omitempty" yaml:"status,omitempty"`
+}
+
+// HelmReleaseStatus is the status of a release in the cluster
+type HelmReleaseStatus struct {
+	// DeployedVersion is the version of the deployed chart. It is empty when the release is not deployed
+	DeployedVersion string `json:"deployedVersion" yaml:"deployedVersion"`
+	Revision        string `json:"revision" yaml:"revision"`
+	// Status is the status of the release as listed by helm, like deployed or failed. It is empty when the release is not deployed
+	Status  string `json:"status" yaml:"status"`
+	Updated string `json:"updated" yaml:"updated"`
+	// OutOfDate is true when the release is desired but not deployed, deployed but not desired,
+	// or deployed with a chart version that doesn't satisfy the desired version
+	OutOfDate bool `json:"outOfDate" yaml:"outOfDate"`
 }
 
 func New(conf ConfigProvider) *App {
@@ -79,6 +113,11 @@ func New(conf ConfigProvider) *App {
 		EnableLiveOutput:           conf.EnableLiveOutput(),
 		StripArgsValuesOnExitError: conf.StripArgsValuesOnExitError(),
 		DisableForceUpdate:         conf.DisableForceUpdate(),
+		RefreshRemote:              conf.RefreshRemote(),
+		Offline:                    conf.Offline(),
+		RemoteCacheTTL:             conf.RemoteCacheTTL(),
+		CrossHelmfileNeeds:         conf.CrossHelmfileNeeds(),
+		ChangedSince:               conf.ChangedSince(),
 		Logger:                     conf.Logger(),
 		Env:                        conf.Env(),
 		Namespace:                  conf.Namespace(),
@@ -172,6 +211,11 @@ func (a *App) Diff(c DiffConfigProvider) error {
 
 	var affectedAny bool
 
+	var plan *Plan
+	if c.OutPlan() != "" {
+		plan = &Plan{}
+	}
+
 	err := a.ForEachState(func(run *Run) (bool, []error) {
 		var criticalErrs []error
 
@@ -191,7 +235,7 @@ func (a *App) Diff(c DiffConfigProvider) error {
 			Concurrency:            c.Concurrency(),
 			IncludeTransitiveNeeds: c.IncludeNeeds(),
 		}, func() {
-			msg, matched, affected, errs = a.diff(run, c)
+			msg, matched, affected, errs = a.diff(run, c, plan)
 		})
 
 		if msg != nil {
@@ -220,12 +264,19 @@ func (a *App) Diff(c DiffConfigProvider) error {
 		}
 
 		return matched, criticalErrs
-	}, c.IncludeTransitiveNeeds())
+	}, c.IncludeTransitiveNeeds(), SetNeeds(c))
 
 	if err != nil {
 		return err
 	}
 
+	if plan != nil {
+		if err := plan.write(c.OutPlan()); err != nil {
+			return appError("writing plan", err)
+		}
+		a.Logger.Infof("Saved the plan to %s. Run `helmfile apply --plan %s` to apply it", c.OutPlan(), c.OutPlan())
+	}
+
 	if c.DetailedExitcode() && (len(allDiffDetectedErrs) > 0 || affectedAny) {
 		// We take the first release error w/ exit status 2 (although all the defered errs should have exit status 2)
 		// to just let helmfile itself to exit with 2
@@ -266,7 +317,7 @@ func (a *App) Template(c TemplateConfigProvider) error {
 		}
 
 		return
-	}, c.IncludeTransitiveNeeds())
+	}, c.IncludeTransitiveNeeds(), SetNeeds(c))
 }
 
 func (a *App) WriteValues(c WriteValuesConfigProvider) error {
@@ -341,7 +392,7 @@ func (a *App) Lint(c LintConfigProvider) error {
 		}
 
 		return
-	}, c.IncludeTransitiveNeeds())
+	}, c.IncludeTransitiveNeeds(), SetNeeds(c))
 
 	if err != nil {
 		return err
@@ -374,8 +425,187 @@ func (a *App) Fetch(c FetchConfigProvider) error {
 	}, false, SetFilter(true))
 }
 
+// Vendor copies the charts, remote helmfiles, values files and secrets files into a directory or a tarball,
+// along with a helmfile.yaml rewritten to refer to the copies, so that it can be deployed with --offline.
+func (a *App) Vendor(c VendorConfigProvider) error {
+	outputDir := c.OutputDir()
+	if c.Tarball() != "" {
+		tempDir, err := os.MkdirTemp("", "helmfile-vendor*")
+		if err != nil {
+			return appError("", err)
+		}
+		defer func() {
+			_ = os.RemoveAll(tempDir)
+		}()
+		outputDir = tempDir
+	}
+
+	// Each state is processed in its own directory
+	outputDir, err := filepath.Abs(outputDir)
+	if err != nil {
+		return appError("", err)
+	}
+
+	var (
+		envName   string
+		helmfiles []state.SubHelmfileSpec
+	)
+
+	err = a.ForEachState(func(run *Run) (ok bool, errs []error) {
+		if len(run.state.Releases) == 0 {
+			return true, nil
+		}
+
+		name := fmt.Sprintf("%03d-%s", len(helmfiles), vendoredStateName(run.state.FilePath))
+
+		prepErr := run.withPreparedCharts("vendor", state.ChartPrepareOptions{
+			ForceDownload: true,
+			SkipRepos:     c.SkipDeps(),
+			SkipDeps:      c.SkipDeps(),
+			OutputDir:     filepath.Join(outputDir, name, "charts"),
+			Concurrency:   c.Concurrency(),
+		}, func() {
+			vendored, err := run.state.Vendor(outputDir, name)
+			if err != nil {
+				errs = []error{err}
+				return
+			}
+
+			stateYaml, err := vendored.ToYaml()
+			if err != nil {
+				errs = []error{err}
+				return
+			}
+
+			if err := os.WriteFile(filepath.Join(outputDir, vendored.FilePath), []byte(stateYaml), 0644); err != nil {
+				errs = []error{err}
+				return
+			}
+
+			envName = run.state.Env.Name
+			helmfiles = append(helmfiles, state.SubHelmfileSpec{Path: vendored.FilePath})
+		})
+
+		if prepErr != nil {
+			errs = append(errs, prepErr)
+		}
+
+		return
+	}, false, SetFilter(true))
+	if err != nil {
+		return err
+	}
+
+	// The sub-helmfiles are loaded in the order they were vendored, with the environment they were vendored for
+	root := state.HelmState{
+		ReleaseSetSpec: state.ReleaseSetSpec{
+			Environments: map[string]state.EnvironmentSpec{envName: {}},
+			Helmfiles:    helmfiles,
+		},
+	}
+
+	rootYaml, err := root.ToYaml()
+	if err != nil {
+		return appError("", err)
+	}
+
+	if err := os.WriteFile(filepath.Join(outputDir, DefaultHelmfile), []byte(rootYaml), 0644); err != nil {
+		return appError("", err)
+	}
+
+	if c.Tarball() != "" {
+		if err := writeTarball(outputDir, c.Tarball()); err != nil {
+			return appError(fmt.Sprintf("writing %s", c.Tarball()), err)
+		}
+		a.Logger.Infof("Vendored %d helmfile(s) into %s", len(helmfiles), c.Tarball())
+		return nil
+	}
+
+	a.Logger.Infof("Vendored %d helmfile(s) into %s", len(helmfiles), outputDir)
+
+	return nil
+}
+
+// vendoredStateName returns the name of the state file without the extensions, e.g. `apps` for `apps.yaml.gotmpl`
+func vendoredStateName(file string) string {
+	name := filepath.Base(file)
+	for _, ext := range []string{".gotmpl", ".yaml", ".yml"} {
+		name = strings.TrimSuffix(name, ext)
+	}
+	return name
+}
+
+// writeTarball archives the files in dir into a gzipped tarball at path
+func writeTarball(dir, path string) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		_ = f.Close()
+	}()
+
+	gw := gzip.NewWriter(f)
+	tw := tar.NewWriter(gw)
+
+	err = filepath.WalkDir(dir, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+
+		rel, err := filepath.Rel(dir, p)
+		if err != nil || rel == "." {
+			return err
+		}
+
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+
+		hdr, err := tar.FileInfoHeader(info, "")
+		if err != nil {
+			return err
+		}
+		hdr.Name = filepath.ToSlash(rel)
+
+		if err := tw.WriteHeader(hdr); err != nil {
+			return err
+		}
+
+		if !info.Mode().IsRegular() {
+			return nil
+		}
+
+		src, err := os.Open(p)
+		if err != nil {
+			return err
+		}
+		defer func() {
+			_ = src.Close()
+		}()
+
+		_, err = io.Copy(tw, src)
+		return err
+	})
+	if err != nil {
+		return err
+	}
+
+	if err := tw.Close(); err != nil {
+		return err
+	}
+	if err := gw.Close(); err != nil {
+		return err
+	}
+
+	return f.Close()
+}
+
 func (a *App) Sync(c SyncConfigProvider) error {
-	return a.ForEachState(func(run *Run) (ok bool, errs []error) {
+	report := newReport(c.ReportFile())
+
+	err := a.ForEachState(func(run *Run) (ok bool, errs []error) {
 		includeCRDs := !c.SkipCRDs()
 
 		prepErr := run.withPreparedCharts("sync", state.ChartPrepareOptions{
@@ -388,7 +618,7 @@ func (a *App) Sync(c SyncConfigProvider) error {
 			Validate:               c.Validate(),
 			Concurrency:            c.Concurrency(),
 		}, func() {
-			ok, errs = a.sync(run, c)
+			ok, errs = a.sync(run, c, report)
 		})
 
 		if prepErr != nil {
@@ -396,7 +626,9 @@ func (a *App) Sync(c SyncConfigProvider) error {
 		}
 
 		return
-	}, c.IncludeTransitiveNeeds())
+	}, c.IncludeTransitiveNeeds(), SetNeeds(c))
+
+	return a.writeReport(report, c.ReportFile(), err)
 }
 
 func (a *App) Apply(c ApplyConfigProvider) error {
@@ -407,6 +639,22 @@ func (a *App) Apply(c ApplyConfigProvider) error {
 	var opts []LoadOption
 
 	opts = append(opts, SetRetainValuesFiles(c.RetainValuesFiles() || c.SkipCleanup()))
+	opts = append(opts, SetNeeds(c))
+
+	var plan *Plan
+	if c.Plan() != "" {
+		p, err := a.readPlan(c.Plan())
+		if err != nil {
+			return appError("", err)
+		}
+		plan = p
+	}
+
+	if err := a.enforcePolicy(c.PolicyFile()); err != nil {
+		return err
+	}
+
+	report := newReport(c.ReportFile())
 
 	err := a.ForEachState(func(run *Run) (ok bool, errs []error) {
 		includeCRDs := !c.SkipCRDs()
@@ -422,7 +670,7 @@ func (a *App) Apply(c ApplyConfigProvider) error {
 			Concurrency:            c.Concurrency(),
 			IncludeTransitiveNeeds: c.IncludeNeeds(),
 		}, func() {
-			matched, updated, es := a.apply(run, c)
+			matched, updated, es := a.apply(run, c, plan, report)
 
 			mut.Lock()
 			any = any || updated
@@ -438,10 +686,16 @@ func (a *App) Apply(c ApplyConfigProvider) error {
 		return
 	}, c.IncludeTransitiveNeeds(), opts...)
 
-	if err != nil {
+	if err := a.writeReport(report, c.ReportFile(), err); err != nil {
 		return err
 	}
 
+	if plan != nil {
+		if ids := plan.unapplied(); len(ids) > 0 {
+			return appError("", fmt.Errorf("the following releases in the plan were not found in the helmfile state: %s. Please re-run `helmfile diff --out-plan` with the same selectors", strings.Join(ids, ", ")))
+		}
+	}
+
 	if c.DetailedExitcode() && any {
 		code := 2
 
@@ -478,37 +732,196 @@ func (a *App) Delete(c DeleteConfigProvider) error {
 				SkipDeps:    c.SkipDeps(),
 				Concurrency: c.Concurrency(),
 			}, func() {
-				ok, errs = a.delete(run, c.Purge(), c)
+				ok, errs = a.delete(run, c.Purge(), c, nil)
 			})
 
 			if err != nil {
 				errs = append(errs, err)
 			}
 		} else {
-			ok, errs = a.delete(run, c.Purge(), c)
+			ok, errs = a.delete(run, c.Purge(), c, nil)
 		}
 		return
 	}, false, SetReverse(true))
 }
 
 func (a *App) Destroy(c DestroyConfigProvider) error {
-	return a.ForEachState(func(run *Run) (ok bool, errs []error) {
+	report := newReport(c.ReportFile())
+
+	err := a.ForEachState(func(run *Run) (ok bool, errs []error) {
 		if !c.SkipCharts() {
 			err := run.withPreparedCharts("destroy", state.ChartPrepareOptions{
 				SkipRepos:   c.SkipDeps(),
 				SkipDeps:    c.SkipDeps(),
 				Concurrency: c.Concurrency(),
 			}, func() {
-				ok, errs = a.delete(run, true, c)
+				ok, errs = a.delete(run, true, c, report)
 			})
 			if err != nil {
 				errs = append(errs, err)
 			}
 		} else {
-			ok, errs = a.delete(run, true, c)
+			ok, errs = a.delete(run, true, c, report)
 		}
 		return
 	}, false, SetReverse(true))
+
+	return a.writeReport(report, c.ReportFile(), err)
+}
+
+func (a *App) Rollback(c RollbackConfigProvider) error {
+	return a.ForEachState(func(run *Run) (ok bool, errs []error) {
+		return a.rollback(run, c)
+	}, false, SetReverse(true))
+}
+
+func (a *App) Drift(c DriftConfigProvider) error {
+	switch c.Output() {
+	case "", ListOutputTable, ListOutputJSON:
+	default:
+		return appError("", fmt.Errorf("unsupported output format %q: expected one of %s", c.Output(), strings.Join([]string{ListOutputTable, ListOutputJSON}, ", ")))
+	}
+
+	var drifts []state.ReleaseDrift
+
+	err := a.ForEachState(func(run *Run) (ok bool, errs []error) {
+		err := run.withPreparedCharts("drift", state.ChartPrepareOptions{
+			SkipRepos:   c.SkipDeps(),
+			SkipDeps:    c.SkipDeps(),
+			Concurrency: c.Concurrency(),
+		}, func() {
+			var stateDrifts []state.ReleaseDrift
+			ok, stateDrifts, errs = a.drift(run, c)
+			drifts = append(drifts, stateDrifts...)
+		})
+
+		if err != nil {
+			errs = append(errs, err)
+		}
+
+		return
+	}, false, SetFilter(true))
+
+	if err != nil {
+		return err
+	}
+
+	if c.Output() == ListOutputJSON {
+		err = FormatDriftsAsJson(drifts)
+	} else {
+		err = FormatDriftsAsTable(drifts)
+	}
+	if err != nil {
+		return appError("", err)
+	}
+
+	var drifted int
+	for _, d := range drifts {
+		if len(d.Drifts) > 0 {
+			drifted++
+		}
+	}
+
+	if drifted > 0 {
+		code := 2
+
+		return &Error{msg: fmt.Sprintf("Detected drift in %d release(s)", drifted), code: &code}
+	}
+
+	return nil
+}
+
+func (a *App) drift(r *Run, c DriftConfigProvider) (bool, []state.ReleaseDrift, []error) {
+	st := r.state
+	helm := r.helm
+
+	selectedReleases, _, err := a.getSelectedReleases(r, false)
+	if err != nil {
+		return false, nil, []error{err}
+	}
+	if len(selectedReleases) == 0 {
+		return false, nil, nil
+	}
+
+	args := GetArgs(c.Args(), st)
+
+	// Reset the extra args if already set, not to break `helm fetch` by adding the args intended for `lint`
+	helm.SetExtraArgs()
+
+	if len(args) > 0 {
+		helm.SetExtraArgs(args...)
+	}
+
+	drifts, errs := st.DetectDrifts(helm, selectedReleases, c.Concurrency())
+
+	return true, drifts, errs
+}
+
+func (a *App) CheckVersions(c CheckVersionsConfigProvider) error {
+	var checks []state.ChartVersionCheck
+
+	err := a.ForEachState(func(run *Run) (ok bool, errs []error) {
+		var stateChecks []state.ChartVersionCheck
+		ok, stateChecks, errs = a.checkVersions(run, c)
+		checks = append(checks, stateChecks...)
+
+		return
+	}, false, SetFilter(true))
+
+	if err != nil {
+		return err
+	}
+
+	if c.Output() == "json" {
+		err = FormatVersionChecksAsJson(checks)
+	} else {
+		err = FormatVersionChecksAsTable(checks)
+	}
+	if err != nil {
+		return appError("", err)
+	}
+
+	var failed int
+	for _, check := range checks {
+		if len(check.Issues) > 0 {
+			failed++
+		}
+	}
+
+	if failed > 0 {
+		return &Error{msg: fmt.Sprintf("Found chart version issues in %d release(s)", failed)}
+	}
+
+	return nil
+}
+
+func (a *App) checkVersions(r *Run, c CheckVersionsConfigProvider) (bool, []state.ChartVersionCheck, []error) {
+	st := r.state
+	helm := r.helm
+
+	selectedReleases, _, err := a.getSelectedReleases(r, false)
+	if err != nil {
+		return false, nil, []error{err}
+	}
+	if len(selectedReleases) == 0 {
+		return false, nil, nil
+	}
+
+	helm.SetExtraArgs(GetArgs(c.Args(), st)...)
+
+	// The versions are looked up in the local cache of the repositories, that `helm repo update` refreshes
+	if !c.SkipRepos() {
+		if err := r.ctx.SyncReposOnce(st, helm); err != nil {
+			return false, nil, []error{err}
+		}
+	}
+
+	checks, err := st.CheckChartVersions(helm, selectedReleases)
+	if err != nil {
+		return false, nil, []error{err}
+	}
+
+	return true, checks, nil
 }
 
 func (a *App) Test(c TestConfigProvider) error {
@@ -589,7 +1002,20 @@ func (a *App) PrintState(c StateConfigProvider) error {
 }
 
 func (a *App) ListReleases(c ListConfigProvider) error {
-	var releases []*HelmRelease
+	switch c.Output() {
+	case "", ListOutputTable, ListOutputJSON, ListOutputYAML, ListOutputCSV, ListOutputMarkdown:
+	case ListOutputGoTemplate:
+		if c.Template() == "" {
+			return appError("", fmt.Errorf("--template is required with --output %s", ListOutputGoTemplate))
+		}
+	default:
+		return appError("", fmt.Errorf("unsupported output format %q: expected one of %s", c.Output(), strings.Join([]string{ListOutputTable, ListOutputJSON, ListOutputYAML, ListOutputCSV, ListOutputMarkdown, ListOutputGoTemplate}, ", ")))
+	}
+
+	var (
+		releases []*HelmRelease
+		queries  []releaseStatusQuery
+	)
 
 	err := a.ForEachState(func(run *Run) (_ bool, errs []error) {
 		var stateReleases []*HelmRelease
@@ -613,10 +1039,24 @@ func (a *App) ListReleases(c ListConfigProvider) error {
 
 		if err != nil {
 			errs = append(errs, err)
+			return
 		}
 
 		releases = append(releases, stateReleases...)
 
+		if c.WithStatus() {
+			for i := range run.state.Releases {
+				r := run.state.Releases[i]
+				run.state.ApplyOverrides(&r)
+				queries = append(queries, releaseStatusQuery{
+					release:     stateReleases[i],
+					spec:        r,
+					kubeContext: run.state.KubeContext(&r),
+					helm:        run.helm,
+				})
+			}
+		}
+
 		return
 	}, false, SetFilter(true))
 
@@ -624,9 +1064,24 @@ func (a *App) ListReleases(c ListConfigProvider) error {
 		return err
 	}
 
-	if c.Output() == "json" {
+	if c.WithStatus() {
+		if err := addReleaseStatuses(queries); err != nil {
+			return appError("", err)
+		}
+	}
+
+	switch c.Output() {
+	case ListOutputJSON:
 		err = FormatAsJson(releases)
-	} else {
+	case ListOutputYAML:
+		err = FormatAsYaml(releases)
+	case ListOutputCSV:
+		err = FormatAsCSV(releases)
+	case ListOutputMarkdown:
+		err = FormatAsMarkdown(releases)
+	case ListOutputGoTemplate:
+		err = FormatAsGoTemplate(releases, c.Template())
+	default:
 		err = FormatAsTable(releases)
 	}
 
@@ -840,6 +1295,7 @@ func (a *App) visitStates(fileOrDir string, defOpts LoadOpts, converge func(*sta
 			}
 		}
 		st.Selectors = opts.Selectors
+		st.ChangedFiles = a.changedFiles
 
 		visitSubHelmfiles := func() error {
 			if len(st.Helmfiles) > 0 {
@@ -955,6 +1411,13 @@ var (
 			o.Filter = f
 		}
 	}
+
+	SetNeeds = func(c DAGConfig) func(o *LoadOpts) {
+		return func(o *LoadOpts) {
+			o.IncludeNeeds = c.IncludeNeeds() || c.IncludeTransitiveNeeds()
+			o.SkipNeeds = c.SkipNeeds()
+		}
+	}
 )
 
 func (a *App) ForEachState(do func(*Run) (bool, []error), includeTransitiveNeeds bool, o ...LoadOption) error {
@@ -1001,10 +1464,46 @@ func withDAG(templated *state.HelmState, helm helmexec.Interface, logger *zap.Su
 		return false, []error{err}
 	}
 
-	return withBatches(opts.Purpose, templated, batches, helm, logger, converge)
+	return withBatches(opts.Purpose, templated, batches, helm, logger, opts.Concurrency, converge)
+}
+
+// runDAG is withDAG, except that the releases are scheduled together with the releases of the other helmfiles
+// with --cross-helmfile-needs.
+func (a *App) runDAG(templated *state.HelmState, helm helmexec.Interface, opts state.PlanOptions, converge func(*state.HelmState, helmexec.Interface) (bool, []error)) (bool, []error) {
+	if a.crossHelmfile != nil {
+		return a.crossHelmfile.withDAG(templated, helm, opts, converge)
+	}
+
+	return withDAG(templated, helm, a.Logger, opts, converge)
 }
 
-func withBatches(purpose string, templated *state.HelmState, batches [][]state.Release, helm helmexec.Interface, logger *zap.SugaredLogger, converge func(*state.HelmState, helmexec.Interface) (bool, []error)) (bool, []error) {
+type dagResult struct {
+	index     int
+	processed bool
+	errs      []error
+}
+
+// withBatches calls converge for each release in the batches with a copy of templated that contains only the release.
+// See scheduleBatches for the order the releases are processed in.
+func withBatches(purpose string, templated *state.HelmState, batches [][]state.Release, helm helmexec.Interface, logger *zap.SugaredLogger, concurrency int, converge func(*state.HelmState, helmexec.Interface) (bool, []error)) (bool, []error) {
+	return scheduleBatches(purpose, templated.Releases, batches, logger, concurrency, func(release state.ReleaseSpec) (bool, []error) {
+		releaseSt := *templated
+		releaseSt.Releases = []state.ReleaseSpec{release}
+
+		return converge(&releaseSt, helm)
+	})
+}
+
+// scheduleBatches calls process for each release in the batches, starting every release as soon as all the releases it
+// needs in the preceding batches are processed, rather than waiting for the whole preceding batch to complete.
+// all is every release the needs are looked up in.
+// The releases being processed at once take up at most `concurrency` slots in total, where each release takes up as many
+// slots as its weight. 0 is unlimited.
+// Releases ready to be processed are started in the order of the batches, so that the result is the same as processing
+// the batches one by one when concurrency is 1. A release that doesn't fit into the remaining slots lets the lighter
+// releases after it start first.
+// Once any release fails, no more releases are started.
+func scheduleBatches(purpose string, all []state.ReleaseSpec, batches [][]state.Release, logger *zap.SugaredLogger, concurrency int, process func(state.ReleaseSpec) (bool, []error)) (bool, []error) {
 	numBatches := len(batches)
 
 	if purpose == "" {
@@ -1013,45 +1512,209 @@ func withBatches(purpose string, templated *state.HelmState, batches [][]state.R
 
 	logger.Debugf("%s %d groups of releases in this order:\n%s", purpose, numBatches, printBatches(batches))
 
-	any := false
+	var (
+		releases []state.ReleaseSpec
+		ids      []string
+		groups   []int
+	)
+
+	groupIDs := make([][]string, numBatches)
 
 	for i, batch := range batches {
-		var targets []state.ReleaseSpec
-
 		for _, marked := range batch {
-			targets = append(targets, marked.ReleaseSpec)
+			release := marked.ReleaseSpec
+			id := state.ReleaseToID(&release)
+
+			releases = append(releases, release)
+			ids = append(ids, id)
+			groups = append(groups, i)
+			groupIDs[i] = append(groupIDs[i], id)
 		}
+	}
 
-		var releaseIds []string
-		for _, r := range targets {
-			release := r
-			releaseIds = append(releaseIds, state.ReleaseToID(&release))
+	numReleases := len(releases)
+
+	// dependents[i] are the releases that must wait for releases[i], and numWaiting[i] is the number of releases
+	// that releases[i] still waits for.
+	dependents := make([][]int, numReleases)
+	numWaiting := make([]int, numReleases)
+
+	needs := transitiveNeeds(all, releases)
+
+	for i := range releases {
+		for j := range releases {
+			if groups[j] >= groups[i] {
+				continue
+			}
+			if needs[ids[i]][ids[j]] || needs[ids[j]][ids[i]] {
+				dependents[j] = append(dependents[j], i)
+				numWaiting[i]++
+			}
 		}
+	}
 
-		logger.Debugf("%s releases in group %d/%d: %s", purpose, i+1, numBatches, strings.Join(releaseIds, ", "))
+	var ready []int
+	for i := range releases {
+		if numWaiting[i] == 0 {
+			ready = append(ready, i)
+		}
+	}
 
-		batchSt := *templated
-		batchSt.Releases = targets
+	results := make(chan dagResult)
 
-		processed, errs := converge(&batchSt, helm)
+	groupStarted := make([]bool, numBatches)
 
-		if len(errs) > 0 {
-			return false, errs
+	var (
+		any     bool
+		errs    []error
+		running int
+	)
+
+	for {
+		for len(errs) == 0 {
+			// Take the first ready release that fits into the remaining concurrency budget.
+			// A release heavier than the whole budget is started alone.
+			k := -1
+			for j, i := range ready {
+				if concurrency < 1 || running == 0 || running+releases[i].ConcurrencyWeight() <= concurrency {
+					k = j
+					break
+				}
+			}
+			if k < 0 {
+				break
+			}
+
+			i := ready[k]
+			ready = append(ready[:k:k], ready[k+1:]...)
+
+			if g := groups[i]; !groupStarted[g] {
+				groupStarted[g] = true
+				logger.Debugf("%s releases in group %d/%d: %s", purpose, g+1, numBatches, strings.Join(groupIDs[g], ", "))
+			}
+
+			running += releases[i].ConcurrencyWeight()
+
+			go func(i int) {
+				processed, errs := process(releases[i])
+				results <- dagResult{index: i, processed: processed, errs: errs}
+			}(i)
+		}
+
+		if running == 0 {
+			break
+		}
+
+		r := <-results
+		running -= releases[r.index].ConcurrencyWeight()
+
+		if len(r.errs) > 0 {
+			errs = append(errs, r.errs...)
+			continue
 		}
 
-		any = any || processed
+		any = any || r.processed
+
+		for _, d := range dependents[r.index] {
+			numWaiting[d]--
+			if numWaiting[d] == 0 {
+				ready = append(ready, d)
+			}
+		}
+
+		sort.Ints(ready)
+	}
+
+	if len(errs) > 0 {
+		return false, errs
 	}
 
 	return any, nil
 }
 
+// transitiveNeeds returns the set of IDs of all the releases each of the releases needs directly or indirectly,
+// so that a release waits for another even when the release in between is not going to be processed.
+func transitiveNeeds(all []state.ReleaseSpec, releases []state.ReleaseSpec) map[string]map[string]bool {
+	direct := map[string][]string{}
+	for _, rs := range [][]state.ReleaseSpec{all, releases} {
+		for i := range rs {
+			id := state.ReleaseToID(&rs[i])
+			if _, ok := direct[id]; !ok {
+				direct[id] = rs[i].Needs
+			}
+		}
+	}
+
+	result := map[string]map[string]bool{}
+
+	var visit func(id string) map[string]bool
+	visit = func(id string) map[string]bool {
+		if needs, ok := result[id]; ok {
+			return needs
+		}
+
+		needs := map[string]bool{}
+		// Mark as visited before recursing so that we don't loop forever on cyclic needs
+		result[id] = needs
+
+		for _, n := range direct[id] {
+			needs[n] = true
+			for nn := range visit(n) {
+				needs[nn] = true
+			}
+		}
+
+		return needs
+	}
+
+	for id := range direct {
+		visit(id)
+	}
+
+	return result
+}
+
 type Opts struct {
 	DAGEnabled bool
 }
 
 func (a *App) visitStatesWithSelectorsAndRemoteSupport(fileOrDir string, converge func(*state.HelmState) (bool, []error), includeTransitiveNeeds bool, opt ...LoadOption) error {
+	opts := a.loadOpts(opt...)
+
+	if err := a.loadChangedFiles(); err != nil {
+		return err
+	}
+
+	f := converge
+	if opts.Filter {
+		f = func(st *state.HelmState) (bool, []error) {
+			return processFilteredReleases(st, func(st *state.HelmState) []error {
+				_, err := converge(st)
+				return err
+			},
+				includeTransitiveNeeds)
+		}
+	}
+
+	if a.CrossHelmfileNeeds {
+		return a.visitStatesAcrossHelmfiles(fileOrDir, opts, f, includeTransitiveNeeds)
+	}
+
+	// pre-overrides HelmState
+	fHelmStatsWithOverrides := func(st *state.HelmState) (bool, []error) {
+		st.Releases = st.GetReleasesWithOverrides()
+		return f(st)
+	}
+
+	return a.visitStates(fileOrDir, opts, fHelmStatsWithOverrides)
+}
+
+// loadOpts returns the options to load the helmfiles with, and sets up the remote to fetch remote helmfiles
+func (a *App) loadOpts(opt ...LoadOption) LoadOpts {
 	opts := LoadOpts{
 		Selectors: a.Selectors,
+		// Needs of unselected releases are skipped unless the command handles needs
+		SkipNeeds: true,
 	}
 
 	for _, o := range opt {
@@ -1073,29 +1736,15 @@ func (a *App) visitStatesWithSelectorsAndRemoteSupport(fileOrDir string, converg
 	}
 
 	a.remote = remote.NewRemote(a.Logger, "", a.fs)
+	a.remote.Refresh = a.RefreshRemote
+	a.remote.Offline = a.Offline
+	a.remote.CacheTTL = a.RemoteCacheTTL
 
-	f := converge
-	if opts.Filter {
-		f = func(st *state.HelmState) (bool, []error) {
-			return processFilteredReleases(st, func(st *state.HelmState) []error {
-				_, err := converge(st)
-				return err
-			},
-				includeTransitiveNeeds)
-		}
-	}
-
-	// pre-overrides HelmState
-	fHelmStatsWithOverrides := func(st *state.HelmState) (bool, []error) {
-		st.Releases = st.GetReleasesWithOverrides()
-		return f(st)
-	}
-
-	return a.visitStates(fileOrDir, opts, fHelmStatsWithOverrides)
+	return opts
 }
 
 func processFilteredReleases(st *state.HelmState, converge func(st *state.HelmState) []error, includeTransitiveNeeds bool) (bool, []error) {
-	if len(st.Selectors) > 0 {
+	if st.HasReleaseFilters() {
 		err := st.FilterReleases(includeTransitiveNeeds)
 		if err != nil {
 			return false, []error{err}
@@ -1315,10 +1964,18 @@ func (a *App) getSelectedReleases(r *Run, includeTransitiveNeeds bool) ([]state.
 
 	a.Logger.Debugf("%d release(s)%s found in %s\n", len(selected), extra, r.state.FilePath)
 
+	warnings, err := r.state.DisabledNeeds(selected)
+	if err != nil {
+		return nil, nil, err
+	}
+	for _, w := range warnings {
+		a.Logger.Warn(w)
+	}
+
 	return selected, deduplicated, nil
 }
 
-func (a *App) apply(r *Run, c ApplyConfigProvider) (bool, bool, []error) {
+func (a *App) apply(r *Run, c ApplyConfigProvider, savedPlan *Plan, report *Report) (bool, bool, []error) {
 	st := r.state
 	helm := r.helm
 
@@ -1372,7 +2029,17 @@ func (a *App) apply(r *Run, c ApplyConfigProvider) (bool, bool, []error) {
 		PostRenderer:      c.PostRenderer(),
 	}
 
-	infoMsg, releasesToBeUpdated, releasesToBeDeleted, errs := r.diff(false, detailedExitCode, c, diffOpts)
+	var (
+		infoMsg                                  *string
+		releasesToBeUpdated, releasesToBeDeleted map[string]state.ReleaseSpec
+		errs                                     []error
+	)
+	if savedPlan != nil {
+		// The plan has already been reviewed. We only verify that nothing has changed since then, instead of diffing again.
+		infoMsg, releasesToBeUpdated, releasesToBeDeleted, errs = r.planned(savedPlan)
+	} else {
+		infoMsg, releasesToBeUpdated, releasesToBeDeleted, errs = r.diff(false, detailedExitCode, c, diffOpts)
+	}
 	if len(errs) > 0 {
 		return false, false, errs
 	}
@@ -1422,7 +2089,7 @@ Do you really want to apply?
 	st.Releases = selectedAndNeededReleases
 
 	if !interactive || interactive && r.askForConfirmation(confMsg) {
-		if _, preapplyErrors := withDAG(st, helm, a.Logger, state.PlanOptions{Purpose: "invoking preapply hooks for", Reverse: true, SelectedReleases: toApplyWithNeeds, SkipNeeds: true}, a.WrapWithoutSelector(func(subst *state.HelmState, helm helmexec.Interface) []error {
+		if _, preapplyErrors := a.runDAG(st, helm, state.PlanOptions{Purpose: "invoking preapply hooks for", Reverse: true, SelectedReleases: toApplyWithNeeds, SkipNeeds: true, Concurrency: 1}, a.WrapWithoutSelector(func(subst *state.HelmState, helm helmexec.Interface) []error {
 			for _, r := range subst.Releases {
 				release := r
 				if _, err := st.TriggerPreapplyEvent(&release, "apply"); err != nil {
@@ -1437,7 +2104,7 @@ Do you really want to apply?
 
 		// We deleted releases by traversing the DAG in reverse order
 		if len(releasesToBeDeleted) > 0 {
-			_, deletionErrs := withDAG(st, helm, a.Logger, state.PlanOptions{Reverse: true, SelectedReleases: toDelete, SkipNeeds: true}, a.WrapWithoutSelector(func(subst *state.HelmState, helm helmexec.Interface) []error {
+			_, deletionErrs := a.runDAG(st, helm, state.PlanOptions{Reverse: true, SelectedReleases: toDelete, SkipNeeds: true, Concurrency: c.Concurrency()}, a.WrapWithoutSelector(func(subst *state.HelmState, helm helmexec.Interface) []error {
 				var rs []state.ReleaseSpec
 
 				for _, r := range subst.Releases {
@@ -1449,7 +2116,11 @@ Do you really want to apply?
 
 				subst.Releases = rs
 
-				return subst.DeleteReleasesForSync(&affectedReleases, helm, c.Concurrency(), c.Cascade())
+				var affected state.AffectedReleases
+				errs := subst.DeleteReleasesForSync(&affected, helm, c.Concurrency(), c.Cascade())
+				affectedReleases.Merge(&affected)
+
+				return errs
 			}))
 
 			if len(deletionErrs) > 0 {
@@ -1459,7 +2130,15 @@ Do you really want to apply?
 
 		// We upgrade releases by traversing the DAG
 		if len(releasesToBeUpdated) > 0 {
-			_, updateErrs := withDAG(st, helm, a.Logger, state.PlanOptions{SelectedReleases: toUpdate, Reverse: false, SkipNeeds: true, IncludeTransitiveNeeds: c.IncludeTransitiveNeeds()}, a.WrapWithoutSelector(func(subst *state.HelmState, helm helmexec.Interface) []error {
+			var revisions map[string]string
+			if c.RollbackOnFailure() {
+				revisions, err = deployedRevisions(st, helm, toUpdate)
+				if err != nil {
+					return true, false, []error{err}
+				}
+			}
+
+			_, updateErrs := a.runDAG(st, helm, state.PlanOptions{SelectedReleases: toUpdate, Reverse: false, SkipNeeds: true, IncludeTransitiveNeeds: c.IncludeTransitiveNeeds(), Concurrency: c.Concurrency()}, a.WrapWithoutSelector(func(subst *state.HelmState, helm helmexec.Interface) []error {
 				var rs []state.ReleaseSpec
 
 				for _, r := range subst.Releases {
@@ -1481,16 +2160,25 @@ Do you really want to apply?
 					ResetValues:  c.ResetValues(),
 					PostRenderer: c.PostRenderer(),
 				}
-				return subst.SyncReleases(&affectedReleases, helm, c.Values(), c.Concurrency(), syncOpts)
+				var affected state.AffectedReleases
+				errs := subst.SyncReleases(&affected, helm, c.Values(), c.Concurrency(), syncOpts)
+				affectedReleases.Merge(&affected)
+
+				return errs
 			}))
 
 			if len(updateErrs) > 0 {
 				applyErrs = append(applyErrs, updateErrs...)
+
+				if c.RollbackOnFailure() {
+					applyErrs = append(applyErrs, a.rollbackApplied(st, helm, c, toUpdate, revisions, &affectedReleases)...)
+				}
 			}
 		}
 	}
 
 	affectedReleases.DisplayAffectedReleases(c.Logger())
+	report.add(&affectedReleases, toApplyWithNeeds, releasesWithNoChange, applyErrs)
 
 	for id := range releasesWithNoChange {
 		r := releasesWithNoChange[id]
@@ -1505,7 +2193,85 @@ Do you really want to apply?
 	return true, true, applyErrs
 }
 
-func (a *App) delete(r *Run, purge bool, c DestroyConfigProvider) (bool, []error) {
+// deployedRevisions returns the deployed revisions of the releases keyed by their IDs.
+// A release that is not installed has an empty revision.
+func deployedRevisions(st *state.HelmState, helm helmexec.Interface, releases []state.ReleaseSpec) (map[string]string, error) {
+	revisions := map[string]string{}
+
+	for i := range releases {
+		release := releases[i]
+
+		revision, err := st.DeployedRevision(helm, &release)
+		if err != nil {
+			return nil, err
+		}
+
+		revisions[state.ReleaseToID(&release)] = revision
+	}
+
+	return revisions, nil
+}
+
+// rollbackApplied undoes the changes made by a failed apply.
+// Every release whose deployed revision differs from the one recorded before the upgrade is rolled back to the recorded revision,
+// or deleted if it has been freshly installed, in the reverse order of the DAG.
+func (a *App) rollbackApplied(st *state.HelmState, helm helmexec.Interface, c ApplyConfigProvider, releases []state.ReleaseSpec, revisions map[string]string, affectedReleases *state.AffectedReleases) []error {
+	current, err := deployedRevisions(st, helm, releases)
+	if err != nil {
+		return []error{err}
+	}
+
+	var toRollback []state.ReleaseSpec
+
+	for _, r := range releases {
+		release := r
+		id := state.ReleaseToID(&release)
+
+		// Untouched by this run, e.g. because it hasn't been processed before the failure
+		if current[id] == revisions[id] {
+			continue
+		}
+
+		toRollback = append(toRollback, release)
+	}
+
+	if len(toRollback) == 0 {
+		return nil
+	}
+
+	a.Logger.Infof("Rolling back %d release(s) applied before the failure", len(toRollback))
+
+	_, errs := a.runDAG(st, helm, state.PlanOptions{Purpose: "rolling back", Reverse: true, SelectedReleases: toRollback, SkipNeeds: true, Concurrency: c.Concurrency()}, a.WrapWithoutSelector(func(subst *state.HelmState, helm helmexec.Interface) []error {
+		var errs []error
+
+		for _, r := range subst.Releases {
+			release := r
+			id := state.ReleaseToID(&release)
+
+			releaseSt := *subst
+			releaseSt.Releases = []state.ReleaseSpec{release}
+
+			var affected state.AffectedReleases
+
+			if revisions[id] == "" {
+				// The release has been freshly installed in this run
+				errs = append(errs, releaseSt.DeleteReleases(&affected, helm, c.Concurrency(), true, c.Cascade())...)
+			} else if revision, err := strconv.Atoi(revisions[id]); err != nil {
+				errs = append(errs, fmt.Errorf("release %q: unexpected revision %q: %v", id, revisions[id], err))
+			} else {
+				errs = append(errs, releaseSt.RollbackReleases(&affected, helm, c.Concurrency(), revision)...)
+			}
+
+			affectedReleases.Merge(&affected)
+		}
+
+		return errs
+	}))
+
+	return errs
+}
+
+func (a *App) delete(r *Run, purge bool, c DestroyConfigProvider, report *Report) (bool, []error) {
 	st := r.state
 	helm := r.helm
 
@@ -1567,8 +2333,12 @@ Do you really want to delete?
 		r.helm.SetExtraArgs(GetArgs(c.Args(), r.state)...)
 
 		if len(releasesToDelete) > 0 {
-			_, deletionErrs := withDAG(st, helm, a.Logger, state.PlanOptions{SelectedReleases: toDelete, Reverse: true, SkipNeeds: true}, a.WrapWithoutSelector(func(subst *state.HelmState, helm helmexec.Interface) []error {
-				return subst.DeleteReleases(&affectedReleases, helm, c.Concurrency(), purge, c.Cascade())
+			_, deletionErrs := a.runDAG(st, helm, state.PlanOptions{SelectedReleases: toDelete, Reverse: true, SkipNeeds: true, Concurrency: c.Concurrency()}, a.WrapWithoutSelector(func(subst *state.HelmState, helm helmexec.Interface) []error {
+				var affected state.AffectedReleases
+				errs := subst.DeleteReleases(&affected, helm, c.Concurrency(), purge, c.Cascade())
+				affectedReleases.Merge(&affected)
+
+				return errs
 			}))
 
 			if len(deletionErrs) > 0 {
@@ -1577,10 +2347,74 @@ Do you really want to delete?
 		}
 	}
 	affectedReleases.DisplayAffectedReleases(c.Logger())
+	report.add(&affectedReleases, toSync, nil, errs)
 	return true, errs
 }
 
-func (a *App) diff(r *Run, c DiffConfigProvider) (*string, bool, bool, []error) {
+func (a *App) rollback(r *Run, c RollbackConfigProvider) (bool, []error) {
+	st := r.state
+	helm := r.helm
+
+	affectedReleases := state.AffectedReleases{}
+
+	selected, _, err := a.getSelectedReleases(r, false)
+	if err != nil {
+		return false, []error{err}
+	}
+	if len(selected) == 0 {
+		return false, nil
+	}
+
+	// Only the installed releases can be rolled back
+	toRollback, err := st.DetectReleasesToBeDeleted(helm, selected)
+	if err != nil {
+		return false, []error{err}
+	}
+	if len(toRollback) == 0 {
+		return true, nil
+	}
+
+	names := make([]string, len(toRollback))
+	for i, r := range toRollback {
+		names[i] = fmt.Sprintf("  %s (%s)", r.Name, r.Chart)
+	}
+
+	revision := "their previous revisions"
+	if c.ToRevision() > 0 {
+		revision = fmt.Sprintf("revision %d", c.ToRevision())
+	}
+
+	var errs []error
+
+	msg := fmt.Sprintf(`Affected releases are:
+%s
+
+Do you really want to roll back?
+  Helmfile will roll back the releases shown above to %s.
+
+`, strings.Join(names, "\n"), revision)
+	interactive := c.Interactive()
+	if !interactive || interactive && r.askForConfirmation(msg) {
+		r.helm.SetExtraArgs(GetArgs(c.Args(), r.state)...)
+
+		// We roll back releases by traversing the DAG in reverse order, so that a release is rolled back before the releases it needs
+		_, rollbackErrs := a.runDAG(st, helm, state.PlanOptions{Purpose: "rolling back", SelectedReleases: toRollback, Reverse: true, SkipNeeds: true, Concurrency: c.Concurrency()}, a.WrapWithoutSelector(func(subst *state.HelmState, helm helmexec.Interface) []error {
+			var affected state.AffectedReleases
+			errs := subst.RollbackReleases(&affected, helm, c.Concurrency(), c.ToRevision())
+			affectedReleases.Merge(&affected)
+
+			return errs
+		}))
+
+		if len(rollbackErrs) > 0 {
+			errs = append(errs, rollbackErrs...)
+		}
+	}
+	affectedReleases.DisplayAffectedReleases(c.Logger())
+	return true, errs
+}
+
+func (a *App) diff(r *Run, c DiffConfigProvider, plan *Plan) (*string, bool, bool, []error) {
 	var (
 		infoMsg          *string
 		updated, deleted map[string]state.ReleaseSpec
@@ -1610,7 +2444,14 @@ func (a *App) diff(r *Run, c DiffConfigProvider) (*string, bool, bool, []error)
 			ctx:   r.ctx,
 			Ask:   r.Ask,
 		}
-		infoMsg, updated, deleted, errs = filtered.diff(true, c.DetailedExitcode(), c, opts)
+		// The changes are detected only with the detailed exit code, which the plan needs regardless of --detailed-exitcode
+		infoMsg, updated, deleted, errs = filtered.diff(true, c.DetailedExitcode() || plan != nil, c, opts)
+
+		if plan != nil && len(errs) == 0 {
+			if err := plan.addReleases(st, helm, updated, deleted); err != nil {
+				errs = append(errs, err)
+			}
+		}
 
 		return errs
 	})
@@ -1695,7 +2536,7 @@ func (a *App) status(r *Run, c StatusesConfigProvider) (bool, []error) {
 	}
 
 	if len(toStatus) > 0 {
-		_, templateErrs := withDAG(st, helm, a.Logger, state.PlanOptions{SelectedReleases: toStatus, Reverse: false, SkipNeeds: true}, a.WrapWithoutSelector(func(subst *state.HelmState, helm helmexec.Interface) []error {
+		_, templateErrs := a.runDAG(st, helm, state.PlanOptions{SelectedReleases: toStatus, Reverse: false, SkipNeeds: true, Concurrency: c.Concurrency()}, a.WrapWithoutSelector(func(subst *state.HelmState, helm helmexec.Interface) []error {
 			return subst.ReleaseStatuses(helm, c.Concurrency())
 		}))
 
@@ -1706,7 +2547,7 @@ func (a *App) status(r *Run, c StatusesConfigProvider) (bool, []error) {
 	return true, errs
 }
 
-func (a *App) sync(r *Run, c SyncConfigProvider) (bool, []error) {
+func (a *App) sync(r *Run, c SyncConfigProvider, report *Report) (bool, []error) {
 	st := r.state
 	helm := r.helm
 
@@ -1827,7 +2668,7 @@ Do you really want to sync?
 
 	if !interactive || interactive && r.askForConfirmation(confMsg) {
 		if len(releasesToDelete) > 0 {
-			_, deletionErrs := withDAG(st, helm, a.Logger, state.PlanOptions{Reverse: true, SelectedReleases: toDelete, SkipNeeds: true}, a.WrapWithoutSelector(func(subst *state.HelmState, helm helmexec.Interface) []error {
+			_, deletionErrs := a.runDAG(st, helm, state.PlanOptions{Reverse: true, SelectedReleases: toDelete, SkipNeeds: true, Concurrency: c.Concurrency()}, a.WrapWithoutSelector(func(subst *state.HelmState, helm helmexec.Interface) []error {
 				var rs []state.ReleaseSpec
 
 				for _, r := range subst.Releases {
@@ -1839,7 +2680,11 @@ Do you really want to sync?
 
 				subst.Releases = rs
 
-				return subst.DeleteReleasesForSync(&affectedReleases, helm, c.Concurrency(), c.Cascade())
+				var affected state.AffectedReleases
+				errs := subst.DeleteReleasesForSync(&affected, helm, c.Concurrency(), c.Cascade())
+				affectedReleases.Merge(&affected)
+
+				return errs
 			}))
 
 			if len(deletionErrs) > 0 {
@@ -1848,7 +2693,7 @@ Do you really want to sync?
 		}
 
 		if len(releasesToUpdate) > 0 {
-			_, syncErrs := withDAG(st, helm, a.Logger, state.PlanOptions{SelectedReleases: toUpdate, SkipNeeds: true, IncludeTransitiveNeeds: c.IncludeTransitiveNeeds()}, a.WrapWithoutSelector(func(subst *state.HelmState, helm helmexec.Interface) []error {
+			_, syncErrs := a.runDAG(st, helm, state.PlanOptions{SelectedReleases: toUpdate, SkipNeeds: true, IncludeTransitiveNeeds: c.IncludeTransitiveNeeds(), Concurrency: c.Concurrency()}, a.WrapWithoutSelector(func(subst *state.HelmState, helm helmexec.Interface) []error {
 				var rs []state.ReleaseSpec
 
 				for _, r := range subst.Releases {
@@ -1869,7 +2714,11 @@ Do you really want to sync?
 					ResetValues:  c.ResetValues(),
 					PostRenderer: c.PostRenderer(),
 				}
-				return subst.SyncReleases(&affectedReleases, helm, c.Values(), c.Concurrency(), opts)
+				var affected state.AffectedReleases
+				errs := subst.SyncReleases(&affected, helm, c.Values(), c.Concurrency(), opts)
+				affectedReleases.Merge(&affected)
+
+				return errs
 			}))
 
 			if len(syncErrs) > 0 {
@@ -1878,6 +2727,7 @@ Do you really want to sync?
 		}
 	}
 	affectedReleases.DisplayAffectedReleases(c.Logger())
+	report.add(&affectedReleases, toSyncWithNeeds, releasesWithNoChange, errs)
 	return true, errs
 }
 
@@ -1962,7 +2812,7 @@ func (a *App) withNeeds(r *Run, c DAGConfig, includeDisabled bool, f func(*state
 		// That's why we don't pass in `IncludeNeeds: c.IncludeNeeds(), IncludeTransitiveNeeds: c.IncludeTransitiveNeeds()` here.
 		// Otherwise, in case include-needs=true, it will include the needs of needs, which results in unexpectedly introducing transitive needs,
 		// even if include-transitive-needs=true is unspecified.
-		if _, errs := withDAG(st, r.helm, a.Logger, state.PlanOptions{SelectedReleases: toRender, Reverse: false, SkipNeeds: c.SkipNeeds(), IncludeNeeds: includeNeeds}, a.WrapWithoutSelector(func(subst *state.HelmState, helm helmexec.Interface) []error {
+		if _, errs := withDAG(st, r.helm, a.Logger, state.PlanOptions{SelectedReleases: toRender, Reverse: false, SkipNeeds: c.SkipNeeds(), IncludeNeeds: includeNeeds, Concurrency: 1}, a.WrapWithoutSelector(func(subst *state.HelmState, helm helmexec.Interface) []error {
 			rels = append(rels, subst.Releases...)
 			return nil
 		})); len(errs) > 0 {
@@ -2177,6 +3027,18 @@ func (c context) wrapErrs(errs ...error) error {
 	return nil
 }
 
+// Schema prints the JSON schema of helmfile.yaml
+func (a *App) Schema(c SchemaConfigProvider) error {
+	schema, err := state.MarshalHelmfileJSONSchema()
+	if err != nil {
+		return appError("", err)
+	}
+
+	fmt.Println(string(schema))
+
+	return nil
+}
+
 func (a *App) ShowCacheDir(c CacheConfigProvider) error {
 	fmt.Printf("Cache directory: %s\n", remote.CacheDir())
 
@@ -2194,6 +3056,51 @@ func (a *App) ShowCacheDir(c CacheConfigProvider) error {
 	return nil
 }
 
+// ListCacheEntries shows the origin and the age of each entry in the cache directory.
+// Entries fetched by older versions of helmfile have no metadata, so their origin and age are unknown.
+func (a *App) ListCacheEntries(c CacheConfigProvider) error {
+	cacheDir := remote.CacheDir()
+
+	entries, err := remote.ListCacheEntries(cacheDir)
+	if err != nil {
+		return err
+	}
+
+	rows := make([]CacheEntryRow, 0, len(entries))
+	known := map[string]bool{}
+
+	for _, e := range entries {
+		rel, err := filepath.Rel(cacheDir, e.Path)
+		if err != nil {
+			return err
+		}
+		known[strings.SplitN(filepath.ToSlash(rel), "/", 2)[0]] = true
+
+		rows = append(rows, CacheEntryRow{
+			Path:      rel,
+			Source:    e.Source,
+			Revision:  e.Revision,
+			Age:       e.Age().Round(time.Second).String(),
+			Immutable: e.Immutable,
+		})
+	}
+
+	if a.fs.DirectoryExistsAt(cacheDir) {
+		dirs, err := a.fs.ReadDir(cacheDir)
+		if err != nil {
+			return err
+		}
+		for _, d := range dirs {
+			if known[d.Name()] || strings.HasSuffix(d.Name(), ".helmfile-cache.json") {
+				continue
+			}
+			rows = append(rows, CacheEntryRow{Path: d.Name(), Source: "unknown", Age: "unknown"})
+		}
+	}
+
+	return FormatCacheEntriesAsTable(rows)
+}
+
 func (a *App) CleanCacheDir(c CacheConfigProvider) error {
 	if !a.fs.DirectoryExistsAt(remote.CacheDir()) {
 		return nil
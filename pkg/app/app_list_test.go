@@ -10,6 +10,7 @@ import (
 	"github.com/stretchr/testify/assert"
 	"go.uber.org/zap"
 
+	"github.com/helmfile/helmfile/pkg/exectest"
 	ffs "github.com/helmfile/helmfile/pkg/filesystem"
 	"github.com/helmfile/helmfile/pkg/helmexec"
 	"github.com/helmfile/helmfile/pkg/testhelper"
@@ -297,3 +298,80 @@ func TestListWithJSONOutput(t *testing.T) {
 		testListWithJSONOutput(t, configImpl{skipCharts: true})
 	})
 }
+
+func TestListWithStatus(t *testing.T) {
+	files := map[string]string{
+		"/path/to/helmfile.yaml": `
+releases:
+- name: web
+  chart: bitnami/nginx
+  version: 15.1.0
+  namespace: web
+- name: db
+  chart: bitnami/postgresql
+  version: 12.2.0
+  namespace: backend
+- name: cache
+  chart: bitnami/redis
+  namespace: backend
+- name: queue
+  chart: bitnami/rabbitmq
+  version: 11.0.0
+- name: worker
+  chart: charts/worker
+`,
+	}
+
+	helm := &exectest.Helm{
+		FailOnUnexpectedList: true,
+		Helm3:                true,
+		Lists: map[exectest.ListKey]string{
+			{Filter: "", Flags: "--kube-context default --all-namespaces --all --max 0"}: "web\tweb    \t3\t2024-01-02 15:04:05 +0000 UTC\tdeployed\tnginx-15.1.0     \t1.25.3\n" +
+				"db \tbackend\t1\t2024-01-01 10:00:00 +0000 UTC\tfailed  \tpostgresql-12.1.0\t16.1.0\n" +
+				"queue\tapps\t2\t2024-01-03 12:00:00 +0000 UTC\tdeployed\trabbitmq-11.0.0\t3.11.0\n" +
+				"worker\tbackend\t1\t2024-01-03 12:00:00 +0000 UTC\tdeployed\tworker-0.1.0\t0.1.0\n",
+			// The releases without a namespace are looked up in the namespace of the kube context only
+			{Filter: "", Flags: "--kube-context default --all --max 0"}: "queue\tapps\t2\t2024-01-03 12:00:00 +0000 UTC\tdeployed\trabbitmq-11.0.0\t3.11.0\n",
+		},
+	}
+
+	var buffer bytes.Buffer
+	logger := helmexec.NewLogger(&buffer, "debug")
+
+	app := appWithFs(&App{
+		OverrideHelmBinary:  DefaultHelmBinary,
+		fs:                  ffs.DefaultFileSystem(),
+		OverrideKubeContext: "default",
+		Env:                 "default",
+		Logger:              logger,
+		helms: map[helmKey]helmexec.Interface{
+			createHelmKey("helm", "default"): helm,
+		},
+	}, files)
+
+	out, err := testutil.CaptureStdout(func() {
+		err := app.ListReleases(configImpl{skipCharts: true, withStatus: true, output: "json"})
+		assert.Nil(t, err)
+	})
+	assert.NoError(t, err)
+
+	expected := `[{"name":"web","namespace":"web","enabled":true,"installed":true,"labels":"","chart":"bitnami/nginx","version":"15.1.0","status":{"deployedVersion":"15.1.0","revision":"3","status":"deployed","updated":"2024-01-02 15:04:05 +0000 UTC","outOfDate":false}},{"name":"db","namespace":"backend","enabled":true,"installed":true,"labels":"","chart":"bitnami/postgresql","version":"12.2.0","status":{"deployedVersion":"12.1.0","revision":"1","status":"failed","updated":"2024-01-01 10:00:00 +0000 UTC","outOfDate":true}},{"name":"cache","namespace":"backend","enabled":true,"installed":true,"labels":"","chart":"bitnami/redis","version":"","status":{"deployedVersion":"","revision":"","status":"","updated":"","outOfDate":true}},{"name":"queue","namespace":"","enabled":true,"installed":true,"labels":"","chart":"bitnami/rabbitmq","version":"11.0.0","status":{"deployedVersion":"11.0.0","revision":"2","status":"deployed","updated":"2024-01-03 12:00:00 +0000 UTC","outOfDate":false}},{"name":"worker","namespace":"","enabled":true,"installed":true,"labels":"","chart":"charts/worker","version":"","status":{"deployedVersion":"","revision":"","status":"","updated":"","outOfDate":true}}]
+`
+	assert.Equal(t, expected, out)
+}
+
+func TestListWithUnsupportedOutput(t *testing.T) {
+	app := appWithFs(&App{
+		OverrideHelmBinary: DefaultHelmBinary,
+		fs:                 ffs.DefaultFileSystem(),
+		Env:                "default",
+		Logger:             newAppTestLogger(),
+	}, map[string]string{})
+
+	err := app.ListReleases(configImpl{output: "xml"})
+	assert.EqualError(t, err, `unsupported output format "xml": expected one of table, json, yaml, csv, markdown, go-template`)
+	assert.IsType(t, &Error{}, err)
+
+	err = app.ListReleases(configImpl{output: "go-template"})
+	assert.EqualError(t, err, `--template is required with --output go-template`)
+}
@@ -812,6 +812,7 @@ second-pass rendering result of "helmfile.yaml.part.0":
 merged environment: &{default  map[] map[]}
 2 release(s) matching app=test found in helmfile.yaml
 
+release "default/default/external-secrets" needs "default/kube-system/kubernetes-external-secrets" at helmfile.yaml:16, which is not installed as it has installed: false
 Affected releases are:
   external-secrets (incubator/raw) UPDATED
   kubernetes-external-secrets (incubator/raw) DELETED
@@ -960,6 +961,7 @@ second-pass rendering result of "helmfile.yaml.part.0":
 merged environment: &{default  map[] map[]}
 2 release(s) matching app=test found in helmfile.yaml
 
+release "default/default/external-secrets" needs "default/kube-system/kubernetes-external-secrets" at helmfile.yaml:16, which is not installed as it has installed: false
 Affected releases are:
   external-secrets (incubator/raw) UPDATED
   my-release (incubator/raw) UPDATED
@@ -311,9 +311,10 @@ releases:
 
 func TestTemplate_StrictParsing(t *testing.T) {
 	type testcase struct {
-		goccyGoYaml bool
-		ns          string
-		error       string
+		goccyGoYaml  bool
+		strictFields bool
+		ns           string
+		error        string
 	}
 
 	check := func(t *testing.T, tc testcase) {
@@ -321,8 +322,11 @@ func TestTemplate_StrictParsing(t *testing.T) {
 
 		v := runtime.GoccyGoYaml
 		runtime.GoccyGoYaml = tc.goccyGoYaml
+		strictFields := runtime.StrictFields
+		runtime.StrictFields = tc.strictFields
 		t.Cleanup(func() {
 			runtime.GoccyGoYaml = v
+			runtime.StrictFields = strictFields
 		})
 
 		var helm = &exectest.Helm{
@@ -403,6 +407,14 @@ releases:
   line 4: field foobar not found in type state.ReleaseSpec`,
 		})
 	})
+
+	t.Run("fail due to unknown field with strict fields", func(t *testing.T) {
+		check(t, testcase{
+			goccyGoYaml:  true,
+			strictFields: true,
+			error:        `in ./helmfile.yaml: helmfile.yaml:4: unknown field "foobar" in releases[0]`,
+		})
+	})
 }
 
 func TestTemplate_CyclicInheritance(t *testing.T) {
@@ -505,9 +505,13 @@ releases:
 		errMsg        string
 	}{
 		{label: "name=prometheus", expectedCount: 1, expectErr: false},
-		{label: "name=", expectedCount: 0, expectErr: true, errMsg: "in ./helmfile.yaml: in .helmfiles[0]: in /path/to/helmfile.d/a1.yaml: malformed label: name=. Expected label in form k=v or k!=v"},
-		{label: "name!=", expectedCount: 0, expectErr: true, errMsg: "in ./helmfile.yaml: in .helmfiles[0]: in /path/to/helmfile.d/a1.yaml: malformed label: name!=. Expected label in form k=v or k!=v"},
-		{label: "name", expectedCount: 0, expectErr: true, errMsg: "in ./helmfile.yaml: in .helmfiles[0]: in /path/to/helmfile.d/a1.yaml: malformed label: name. Expected label in form k=v or k!=v"},
+		{label: "name=", expectedCount: 0, expectErr: true, errMsg: "in ./helmfile.yaml: in .helmfiles[0]: in /path/to/helmfile.d/a1.yaml: malformed label: name=. Expected label in form k=v, k!=v, k=~regex, k!~regex, k in (v1,v2), k notin (v1,v2), k or !k"},
+		{label: "name!=", expectedCount: 0, expectErr: true, errMsg: "in ./helmfile.yaml: in .helmfiles[0]: in /path/to/helmfile.d/a1.yaml: malformed label: name!=. Expected label in form k=v, k!=v, k=~regex, k!~regex, k in (v1,v2), k notin (v1,v2), k or !k"},
+		{label: "name in prometheus", expectedCount: 0, expectErr: true, errMsg: "in ./helmfile.yaml: in .helmfiles[0]: in /path/to/helmfile.d/a1.yaml: malformed label: name in prometheus. Expected label in form k=v, k!=v, k=~regex, k!~regex, k in (v1,v2), k notin (v1,v2), k or !k"},
+		{label: "name in (prometheus,zipkin)", expectedCount: 2, expectErr: false},
+		{label: "name=~^(prom|zip)", expectedCount: 2, expectErr: false},
+		{label: "duplicatedOK", expectedCount: 2, expectErr: false},
+		{label: "duplicatedOK,namespace notin (bar1)", expectedCount: 1, expectErr: false},
 		// See https://github.com/roboll/helmfile/issues/193
 		{label: "duplicatedNs=yes", expectedCount: 0, expectErr: true, errMsg: "in ./helmfile.yaml: in .helmfiles[2]: in /path/to/helmfile.d/b.yaml: duplicate release \"foo\" found in kubecontext \"default\": there were 2 releases named \"foo\" matching specified selector"},
 		{label: "duplicatedCtx=yes", expectedCount: 0, expectErr: true, errMsg: "in ./helmfile.yaml: in .helmfiles[2]: in /path/to/helmfile.d/b.yaml: duplicate release \"foo\" found in kubecontext \"default\": there were 2 releases named \"foo\" matching specified selector"},
@@ -2099,6 +2103,8 @@ type configImpl struct {
 	includeTransitiveNeeds bool
 	skipCharts             bool
 	kubeVersion            string
+	withStatus             bool
+	template               string
 }
 
 func (c configImpl) Selectors() []string {
@@ -2177,6 +2183,14 @@ func (c configImpl) SkipCharts() bool {
 	return c.skipCharts
 }
 
+func (c configImpl) WithStatus() bool {
+	return c.withStatus
+}
+
+func (c configImpl) Template() string {
+	return c.template
+}
+
 func (c configImpl) PostRenderer() string {
 	return ""
 }
@@ -2223,6 +2237,10 @@ type applyConfig struct {
 	reuseValues            bool
 	postRenderer           string
 	kubeVersion            string
+	plan                   string
+	rollbackOnFailure      bool
+	reportFile             string
+	policyFile             string
 
 	// template-only options
 	includeCRDs, skipTests       bool
@@ -2354,6 +2372,26 @@ func (a applyConfig) DiffArgs() string {
 	return a.diffArgs
 }
 
+func (a applyConfig) Plan() string {
+	return a.plan
+}
+
+func (a applyConfig) OutPlan() string {
+	return ""
+}
+
+func (a applyConfig) RollbackOnFailure() bool {
+	return a.rollbackOnFailure
+}
+
+func (a applyConfig) PolicyFile() string {
+	return a.policyFile
+}
+
+func (a applyConfig) ReportFile() string {
+	return a.reportFile
+}
+
 // helmfile-template-only flags
 
 func (a applyConfig) IncludeCRDs() bool {
@@ -2390,6 +2428,9 @@ func (a applyConfig) KubeVersion() string {
 type depsConfig struct {
 	skipRepos              bool
 	includeTransitiveNeeds bool
+	updateStrategy         string
+	dryRun                 bool
+	allowDigestChanges     bool
 }
 
 func (d depsConfig) SkipRepos() bool {
@@ -2400,6 +2441,18 @@ func (d depsConfig) IncludeTransitiveNeeds() bool {
 	return d.includeTransitiveNeeds
 }
 
+func (d depsConfig) UpdateStrategy() string {
+	return d.updateStrategy
+}
+
+func (d depsConfig) DryRun() bool {
+	return d.dryRun
+}
+
+func (d depsConfig) AllowDigestChanges() bool {
+	return d.allowDigestChanges
+}
+
 func (d depsConfig) Args() string {
 	return ""
 }
@@ -2497,11 +2550,18 @@ func (helm *mockHelmExec) ReleaseStatus(context helmexec.HelmContext, release st
 func (helm *mockHelmExec) DeleteRelease(context helmexec.HelmContext, name string, flags ...string) error {
 	return nil
 }
+func (helm *mockHelmExec) RollbackRelease(context helmexec.HelmContext, name string, revision int, flags ...string) error {
+	return nil
+}
 
 func (helm *mockHelmExec) List(context helmexec.HelmContext, filter string, flags ...string) (string, error) {
 	return "", nil
 }
 
+func (helm *mockHelmExec) GetValues(context helmexec.HelmContext, name string, flags ...string) (string, error) {
+	return "", nil
+}
+
 func (helm *mockHelmExec) DecryptSecret(context helmexec.HelmContext, name string, flags ...string) (string, error) {
 	return "", nil
 }
@@ -2526,10 +2586,14 @@ func (helm *mockHelmExec) IsVersionAtLeast(versionStr string) bool {
 	return false
 }
 
-func (helm *mockHelmExec) ShowChart(chartPath string) (chart.Metadata, error) {
+func (helm *mockHelmExec) ShowChart(chartPath string, flags ...string) (chart.Metadata, error) {
 	return chart.Metadata{}, errors.New("tests logs rely on this error")
 }
 
+func (helm *mockHelmExec) SearchChartVersions(chart string, flags ...string) ([]string, error) {
+	return nil, nil
+}
+
 func TestTemplate_SingleStateFile(t *testing.T) {
 	files := map[string]string{
 		"/path/to/helmfile.yaml": `
@@ -0,0 +1,168 @@
+package app
+
+import (
+	"io"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/helmfile/helmfile/pkg/helmexec"
+	"github.com/helmfile/helmfile/pkg/state"
+)
+
+func TestWithBatches(t *testing.T) {
+	a := state.ReleaseSpec{Name: "a", Namespace: "default"}
+	b := state.ReleaseSpec{Name: "b", Namespace: "default"}
+	c := state.ReleaseSpec{Name: "c", Namespace: "default", Needs: []string{"default/a"}}
+
+	newState := func() *state.HelmState {
+		st := &state.HelmState{}
+		st.Releases = []state.ReleaseSpec{a, b, c}
+		return st
+	}
+
+	batches := [][]state.Release{
+		{{ReleaseSpec: a}, {ReleaseSpec: b}},
+		{{ReleaseSpec: c}},
+	}
+
+	logger := helmexec.NewLogger(io.Discard, "debug")
+
+	t.Run("starts a release as soon as its needs are processed", func(t *testing.T) {
+		cDone := make(chan struct{})
+
+		var (
+			mu    sync.Mutex
+			order []string
+		)
+
+		processed, errs := withBatches("processing", newState(), batches, nil, logger, 0, func(st *state.HelmState, _ helmexec.Interface) (bool, []error) {
+			require.Len(t, st.Releases, 1)
+
+			name := st.Releases[0].Name
+
+			switch name {
+			case "b":
+				// b is slow. c must not wait for it as c needs only a.
+				select {
+				case <-cDone:
+				case <-time.After(10 * time.Second):
+					t.Errorf("c has not been processed while b is being processed")
+				}
+			case "c":
+				close(cDone)
+			}
+
+			mu.Lock()
+			order = append(order, name)
+			mu.Unlock()
+
+			return true, nil
+		})
+
+		require.Empty(t, errs)
+		require.True(t, processed)
+		require.Equal(t, []string{"a", "c", "b"}, order)
+	})
+
+	t.Run("processes releases in the order of the batches with concurrency 1", func(t *testing.T) {
+		var order []string
+
+		_, errs := withBatches("processing", newState(), batches, nil, logger, 1, func(st *state.HelmState, _ helmexec.Interface) (bool, []error) {
+			order = append(order, st.Releases[0].Name)
+			return true, nil
+		})
+
+		require.Empty(t, errs)
+		require.Equal(t, []string{"a", "b", "c"}, order)
+	})
+
+	t.Run("stops starting releases after a failure", func(t *testing.T) {
+		var order []string
+
+		processed, errs := withBatches("processing", newState(), batches, nil, logger, 1, func(st *state.HelmState, _ helmexec.Interface) (bool, []error) {
+			name := st.Releases[0].Name
+			order = append(order, name)
+			if name == "a" {
+				return false, []error{io.EOF}
+			}
+			return true, nil
+		})
+
+		require.False(t, processed)
+		require.Equal(t, []error{io.EOF}, errs)
+		require.Equal(t, []string{"a"}, order)
+	})
+}
+
+func TestWithBatchesWeights(t *testing.T) {
+	var releases []state.ReleaseSpec
+	for _, name := range []string{"db1", "db2", "db3"} {
+		releases = append(releases, state.ReleaseSpec{Name: name, Namespace: "default", Weight: 2})
+	}
+	for _, name := range []string{"web1", "web2"} {
+		releases = append(releases, state.ReleaseSpec{Name: name, Namespace: "default"})
+	}
+	releases = append(releases, state.ReleaseSpec{Name: "huge", Namespace: "default", Weight: 10})
+
+	st := &state.HelmState{}
+	st.Releases = releases
+
+	var batch []state.Release
+	for _, r := range releases {
+		batch = append(batch, state.Release{ReleaseSpec: r})
+	}
+
+	logger := helmexec.NewLogger(io.Discard, "debug")
+
+	var (
+		mu      sync.Mutex
+		running int
+		peak    int
+		names   []string
+	)
+
+	processed, errs := withBatches("processing", st, [][]state.Release{batch}, nil, logger, 4, func(st *state.HelmState, _ helmexec.Interface) (bool, []error) {
+		r := st.Releases[0]
+
+		mu.Lock()
+		running += r.ConcurrencyWeight()
+		if r.Name != "huge" && running > peak {
+			peak = running
+		}
+		if r.Name == "huge" && running != r.Weight {
+			t.Errorf("huge is processed alongside other releases")
+		}
+		names = append(names, r.Name)
+		mu.Unlock()
+
+		time.Sleep(10 * time.Millisecond)
+
+		mu.Lock()
+		running -= r.ConcurrencyWeight()
+		mu.Unlock()
+
+		return true, nil
+	})
+
+	require.Empty(t, errs)
+	require.True(t, processed)
+	require.LessOrEqual(t, peak, 4)
+	require.ElementsMatch(t, []string{"db1", "db2", "db3", "web1", "web2", "huge"}, names)
+}
+
+func TestTransitiveNeeds(t *testing.T) {
+	all := []state.ReleaseSpec{
+		{Name: "a", Namespace: "default", Needs: []string{"default/b"}},
+		{Name: "b", Namespace: "default", Needs: []string{"default/c"}},
+		{Name: "c", Namespace: "default"},
+	}
+
+	needs := transitiveNeeds(all, nil)
+
+	require.Equal(t, map[string]bool{"default/b": true, "default/c": true}, needs["default/a"])
+	require.Equal(t, map[string]bool{"default/c": true}, needs["default/b"])
+	require.Empty(t, needs["default/c"])
+}
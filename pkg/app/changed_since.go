@@ -0,0 +1,75 @@
+package app
+
+import (
+	"errors"
+	"fmt"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	"github.com/helmfile/helmfile/pkg/state"
+)
+
+// loadChangedFiles lists the files changed since ChangedSince once, so that every helmfile selects its releases
+// against the same files
+func (a *App) loadChangedFiles() error {
+	if a.ChangedSince == "" || a.changedFiles != nil {
+		return nil
+	}
+
+	list := a.listChangedFiles
+	if list == nil {
+		list = gitChangedFiles
+	}
+
+	files, err := list(a.ChangedSince)
+	if err != nil {
+		return appError(fmt.Sprintf("listing the files changed since %s", a.ChangedSince), err)
+	}
+
+	a.Logger.Debugf("%d file(s) changed since %s:\n%s", len(files), a.ChangedSince, strings.Join(files, "\n"))
+
+	a.changedFiles = state.NewChangedFiles(files)
+
+	return nil
+}
+
+// gitChangedFiles returns the absolute paths to the files changed since the git ref, as listed by `git diff --name-only`.
+// The uncommitted changes in the working tree are included, but the untracked files are not.
+func gitChangedFiles(ref string) ([]string, error) {
+	top, err := git("rev-parse", "--show-toplevel")
+	if err != nil {
+		return nil, err
+	}
+
+	topLevel := strings.TrimSpace(top)
+
+	// Renames are listed as deletions and additions, so that releases referring to either path are selected
+	out, err := git("diff", "--name-only", "--no-renames", ref, "--")
+	if err != nil {
+		return nil, err
+	}
+
+	var files []string
+	for _, f := range strings.Split(out, "\n") {
+		if f == "" {
+			continue
+		}
+		files = append(files, filepath.Join(topLevel, filepath.FromSlash(f)))
+	}
+
+	return files, nil
+}
+
+func git(args ...string) (string, error) {
+	out, err := exec.Command("git", args...).Output()
+	if err != nil {
+		var exitErr *exec.ExitError
+		if errors.As(err, &exitErr) {
+			return "", fmt.Errorf("running git %s: %v: %s", strings.Join(args, " "), err, strings.TrimSpace(string(exitErr.Stderr)))
+		}
+		return "", fmt.Errorf("running git %s: %v", strings.Join(args, " "), err)
+	}
+
+	return string(out), nil
+}
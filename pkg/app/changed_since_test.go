@@ -0,0 +1,118 @@
+package app
+
+import (
+	"errors"
+	"sync"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/helmfile/vals"
+	"go.uber.org/zap"
+
+	"github.com/helmfile/helmfile/pkg/exectest"
+	ffs "github.com/helmfile/helmfile/pkg/filesystem"
+	"github.com/helmfile/helmfile/pkg/helmexec"
+)
+
+func TestSync_ChangedSince(t *testing.T) {
+	files := map[string]string{
+		"/path/to/helmfile.yaml": `
+releases:
+- name: db
+  chart: incubator/raw
+  namespace: default
+  values:
+  - values/db.yaml
+- name: app
+  chart: incubator/raw
+  namespace: default
+  needs:
+  - default/db
+- name: monitoring
+  chart: incubator/raw
+  namespace: default
+`,
+		"/path/to/values/db.yaml": `
+replicas: 1
+`,
+	}
+
+	helm := &exectest.Helm{
+		FailOnUnexpectedList: true,
+		FailOnUnexpectedDiff: true,
+		DiffMutex:            &sync.Mutex{},
+		ChartsMutex:          &sync.Mutex{},
+		ReleasesMutex:        &sync.Mutex{},
+		Helm3:                true,
+	}
+
+	var refs []string
+
+	_ = runWithLogCapture(t, "debug", func(t *testing.T, logger *zap.SugaredLogger) {
+		t.Helper()
+
+		valsRuntime, err := vals.New(vals.Options{CacheSize: 32})
+		if err != nil {
+			t.Errorf("unexpected error creating vals runtime: %v", err)
+		}
+
+		app := appWithFs(&App{
+			OverrideHelmBinary:  DefaultHelmBinary,
+			fs:                  ffs.DefaultFileSystem(),
+			OverrideKubeContext: "default",
+			Env:                 "default",
+			Logger:              logger,
+			ChangedSince:        "origin/main",
+			listChangedFiles: func(ref string) ([]string, error) {
+				refs = append(refs, ref)
+				return []string{"/path/to/values/db.yaml", "/path/to/README.md"}, nil
+			},
+			helms: map[helmKey]helmexec.Interface{
+				createHelmKey("helm", "default"): helm,
+			},
+			valsRuntime: valsRuntime,
+		}, files)
+
+		if err := app.Sync(applyConfig{
+			concurrency: 1,
+			logger:      logger,
+			skipNeeds:   true,
+		}); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	})
+
+	if d := cmp.Diff([]string{"origin/main"}, refs); d != "" {
+		t.Errorf("unexpected refs: want (-), got (+): %s", d)
+	}
+
+	var upgraded []string
+	for _, r := range helm.Releases {
+		upgraded = append(upgraded, r.Name)
+	}
+
+	if d := cmp.Diff([]string{"db", "app"}, upgraded); d != "" {
+		t.Errorf("unexpected upgrades: want (-), got (+): %s", d)
+	}
+}
+
+func TestLoadChangedFiles_Error(t *testing.T) {
+	app := &App{
+		ChangedSince: "origin/main",
+		listChangedFiles: func(ref string) ([]string, error) {
+			return nil, errors.New("running git diff --name-only --no-renames origin/main --: exit status 128: fatal: bad revision 'origin/main'")
+		},
+	}
+
+	err := app.loadChangedFiles()
+
+	var appErr *Error
+	if !errors.As(err, &appErr) {
+		t.Fatalf("unexpected error type %T: %v", err, err)
+	}
+
+	want := "listing the files changed since origin/main: running git diff --name-only --no-renames origin/main --: exit status 128: fatal: bad revision 'origin/main'"
+	if d := cmp.Diff(want, err.Error()); d != "" {
+		t.Errorf("unexpected error: want (-), got (+): %s", d)
+	}
+}
@@ -0,0 +1,110 @@
+package app
+
+import (
+	"io"
+	"testing"
+
+	"github.com/helmfile/vals"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+
+	"github.com/helmfile/helmfile/pkg/exectest"
+	ffs "github.com/helmfile/helmfile/pkg/filesystem"
+	"github.com/helmfile/helmfile/pkg/helmexec"
+	"github.com/helmfile/helmfile/pkg/testutil"
+)
+
+type checkVersionsConfig struct {
+	args      string
+	output    string
+	skipRepos bool
+	logger    *zap.SugaredLogger
+}
+
+func (c checkVersionsConfig) Args() string {
+	return c.args
+}
+
+func (c checkVersionsConfig) Output() string {
+	return c.output
+}
+
+func (c checkVersionsConfig) SkipRepos() bool {
+	return c.skipRepos
+}
+
+func (c checkVersionsConfig) Logger() *zap.SugaredLogger {
+	return c.logger
+}
+
+func TestCheckVersions(t *testing.T) {
+	files := map[string]string{
+		"/path/to/helmfile.yaml": `
+repositories:
+- name: bitnami
+  url: https://charts.bitnami.com/bitnami
+releases:
+- name: database
+  chart: bitnami/postgresql
+  version: 12.1.0
+- name: cache
+  chart: bitnami/redis
+  version: ~17.3
+- name: backend
+  chart: charts/backend
+`,
+		"/path/to/helmfile.lock": `
+dependencies:
+- name: postgresql
+  repository: https://charts.bitnami.com/bitnami
+  version: 12.1.0
+- name: redis
+  repository: https://charts.bitnami.com/bitnami
+  version: 17.3.1
+`,
+	}
+
+	helm := &exectest.Helm{
+		Helm3: true,
+		ChartVersions: map[string][]string{
+			"bitnami/postgresql": {"12.2.0", "12.1.0"},
+			"bitnami/redis":      {"18.0.0", "17.3.1"},
+		},
+	}
+
+	valsRuntime, err := vals.New(vals.Options{CacheSize: 32})
+	require.NoError(t, err)
+
+	logger := helmexec.NewLogger(io.Discard, "debug")
+
+	app := appWithFs(&App{
+		OverrideHelmBinary:  DefaultHelmBinary,
+		fs:                  ffs.DefaultFileSystem(),
+		OverrideKubeContext: "default",
+		Env:                 "default",
+		Logger:              logger,
+		helms: map[helmKey]helmexec.Interface{
+			createHelmKey("helm", "default"): helm,
+		},
+		valsRuntime: valsRuntime,
+	}, files)
+
+	var checkErr error
+
+	out, err := testutil.CaptureStdout(func() {
+		checkErr = app.CheckVersions(checkVersionsConfig{
+			output: "json",
+			logger: logger,
+		})
+	})
+	require.NoError(t, err)
+
+	require.EqualError(t, checkErr, "Found chart version issues in 1 release(s)")
+
+	require.Equal(t, "bitnami", helm.Repo[0])
+
+	expected := `[{"id":"default//database","chart":"bitnami/postgresql","version":"12.1.0","locked":"12.1.0","newest":"12.2.0","newestMinor":"12.2.0","issues":[]},` +
+		`{"id":"default//cache","chart":"bitnami/redis","version":"~17.3","locked":"17.3.1","newest":"18.0.0","newestMajor":"18.0.0","issues":["range"]}]
+`
+	require.Equal(t, expected, out)
+}
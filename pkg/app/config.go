@@ -1,6 +1,10 @@
 package app
 
-import "go.uber.org/zap"
+import (
+	"time"
+
+	"go.uber.org/zap"
+)
 
 type ConfigProvider interface {
 	Args() string
@@ -10,6 +14,11 @@ type ConfigProvider interface {
 	StripArgsValuesOnExitError() bool
 	DisableForceUpdate() bool
 	SkipDeps() bool
+	RefreshRemote() bool
+	Offline() bool
+	RemoteCacheTTL() time.Duration
+	CrossHelmfileNeeds() bool
+	ChangedSince() string
 
 	FileOrDir() string
 	KubeContext() string
@@ -36,6 +45,9 @@ type DepsConfigProvider interface {
 	Args() string
 	SkipRepos() bool
 	IncludeTransitiveNeeds() bool
+	UpdateStrategy() string
+	DryRun() bool
+	AllowDigestChanges() bool
 
 	concurrencyConfig
 }
@@ -47,6 +59,7 @@ type ReposConfigProvider interface {
 
 type ApplyConfigProvider interface {
 	Args() string
+	ReportFile() string
 	PostRenderer() string
 	Cascade() string
 
@@ -82,6 +95,13 @@ type ApplyConfigProvider interface {
 
 	DiffArgs() string
 
+	Plan() string
+	OutPlan() string
+
+	RollbackOnFailure() bool
+
+	PolicyFile() string
+
 	DAGConfig
 
 	concurrencyConfig
@@ -92,6 +112,7 @@ type ApplyConfigProvider interface {
 
 type SyncConfigProvider interface {
 	Args() string
+	ReportFile() string
 	PostRenderer() string
 	Cascade() string
 
@@ -144,6 +165,8 @@ type DiffConfigProvider interface {
 	Context() int
 	DiffOutput() string
 
+	OutPlan() string
+
 	concurrencyConfig
 	valuesControlMode
 }
@@ -151,6 +174,7 @@ type DiffConfigProvider interface {
 // TODO: Remove this function once Helmfile v0.x
 type DeleteConfigProvider interface {
 	Args() string
+	ReportFile() string
 	Cascade() string
 
 	Purge() bool
@@ -164,6 +188,7 @@ type DeleteConfigProvider interface {
 
 type DestroyConfigProvider interface {
 	Args() string
+	ReportFile() string
 	Cascade() string
 
 	SkipDeps() bool
@@ -174,6 +199,33 @@ type DestroyConfigProvider interface {
 	concurrencyConfig
 }
 
+type RollbackConfigProvider interface {
+	Args() string
+
+	ToRevision() int
+
+	interactive
+	loggingConfig
+	concurrencyConfig
+}
+
+type DriftConfigProvider interface {
+	Args() string
+	Output() string
+	SkipDeps() bool
+
+	loggingConfig
+	concurrencyConfig
+}
+
+type CheckVersionsConfigProvider interface {
+	Args() string
+	Output() string
+	SkipRepos() bool
+
+	loggingConfig
+}
+
 type TestConfigProvider interface {
 	Args() string
 
@@ -206,6 +258,14 @@ type FetchConfigProvider interface {
 	concurrencyConfig
 }
 
+type VendorConfigProvider interface {
+	SkipDeps() bool
+	OutputDir() string
+	Tarball() string
+
+	concurrencyConfig
+}
+
 type TemplateConfigProvider interface {
 	Args() string
 	PostRenderer() string
@@ -226,6 +286,12 @@ type TemplateConfigProvider interface {
 	concurrencyConfig
 }
 
+type GraphConfigProvider interface {
+	Output() string
+
+	DAGConfig
+}
+
 type DAGConfig interface {
 	SkipNeeds() bool
 	IncludeNeeds() bool
@@ -268,10 +334,18 @@ type interactive interface {
 type ListConfigProvider interface {
 	Output() string
 	SkipCharts() bool
+	WithStatus() bool
+	Template() string
 }
 
 type CacheConfigProvider any
 
+type SchemaConfigProvider any
+
+type PolicyConfigProvider interface {
+	PolicyFile() string
+}
+
 type InitConfigProvider interface {
 	Force() bool
 }
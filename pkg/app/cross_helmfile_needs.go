@@ -0,0 +1,557 @@
+package app
+
+import (
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/helmfile/helmfile/pkg/filesystem"
+	"github.com/helmfile/helmfile/pkg/helmexec"
+	"github.com/helmfile/helmfile/pkg/state"
+)
+
+// loadedHelmfile is a helmfile loaded before processing any release with --cross-helmfile-needs
+type loadedHelmfile struct {
+	state *state.HelmState
+	// dir is the absolute path to the directory the helmfile is processed in
+	dir string
+	// path is the path to the helmfile relative to the working directory, used in messages
+	path string
+}
+
+// helmfileGroup is the releases of a helmfile in a group of the DAG built from the releases of all the helmfiles
+type helmfileGroup struct {
+	helmfile loadedHelmfile
+	releases []state.ReleaseSpec
+}
+
+// visitStatesAcrossHelmfiles loads every helmfile, including sub-helmfiles, before processing any release,
+// and orders the releases of all the helmfiles with one DAG, so that releases can need releases defined in other helmfiles.
+// converge is called once per helmfile with the releases of the helmfile in the DAG, and the releases it processes with
+// runDAG are scheduled together with the releases of the other helmfiles. See crossHelmfileScheduler for details.
+// Once any helmfile fails, no more releases are processed.
+func (a *App) visitStatesAcrossHelmfiles(fileOrDir string, opts LoadOpts, converge func(*state.HelmState) (bool, []error), includeTransitiveNeeds bool) error {
+	helmfiles, err := a.loadStatesAcrossHelmfiles(fileOrDir, opts)
+	if err != nil {
+		return err
+	}
+
+	plan, all, _, err := a.planAcrossHelmfiles(helmfiles, opts, includeTransitiveNeeds)
+	if err != nil {
+		return appError("", err)
+	}
+
+	s := &crossHelmfileScheduler{app: a, all: all, defined: map[string]*helmfileWorker{}}
+	s.dirs.cond = sync.NewCond(&s.dirs.mu)
+	s.dirs.fs = a.fs
+
+	releases := map[*state.HelmState][]state.ReleaseSpec{}
+	for _, groups := range plan {
+		for _, g := range groups {
+			releases[g.helmfile.state] = append(releases[g.helmfile.state], g.releases...)
+		}
+	}
+
+	for i := range helmfiles {
+		h := helmfiles[i]
+		if opts.Reverse {
+			h = helmfiles[len(helmfiles)-1-i]
+		}
+
+		if len(releases[h.state]) == 0 {
+			continue
+		}
+
+		w := &helmfileWorker{helmfile: h, releases: releases[h.state], requests: make(chan *dagRequest), results: make(chan dagResult)}
+		for _, r := range w.releases {
+			release := r
+			s.defined[state.ReleaseToID(&release)] = w
+		}
+
+		s.workers = append(s.workers, w)
+	}
+
+	a.crossHelmfile = s
+	defer func() { a.crossHelmfile = nil }()
+
+	CleanWaitGroup.Add(1)
+	defer CleanWaitGroup.Done()
+
+	return s.run(func(st *state.HelmState) (bool, error) {
+		ok, errs := converge(st)
+
+		return ok, context{app: a, st: st, retainValues: opts.RetainValuesFiles}.clean(errs)
+	})
+}
+
+// crossHelmfileScheduler runs the converge function of every helmfile once, each in its own goroutine, and processes
+// the releases they pass to runDAG together with one DAG of the releases of all the helmfiles, so that chart preparation,
+// the global hooks, diffs and confirmation happen once per helmfile while every release waits for the releases it needs
+// in the other helmfiles.
+//
+// Only one helmfile runs its converge function at a time, within the directory of the helmfile, until it either completes
+// or waits for the releases it passed to runDAG to be processed. Once every helmfile does so, the releases waiting to be
+// processed in reverse order, like the releases to be deleted, are processed first in a round of their own, followed by
+// the releases to be processed in order, like the releases to be upgraded.
+type crossHelmfileScheduler struct {
+	app *App
+
+	// all is the releases of all the helmfiles, with their needs
+	all []state.ReleaseSpec
+	// defined is the helmfile each release is defined in
+	defined map[string]*helmfileWorker
+
+	workers []*helmfileWorker
+	// active is the helmfile running its converge function
+	active *helmfileWorker
+
+	dirs dirLock
+}
+
+// helmfileWorker runs the converge function of a helmfile in its own goroutine
+type helmfileWorker struct {
+	helmfile loadedHelmfile
+	// releases is the releases of the helmfile in the DAG, without their needs
+	releases []state.ReleaseSpec
+
+	// requests receives the releases the converge function waits to be processed, and is closed once it completes
+	requests chan *dagRequest
+	results  chan dagResult
+
+	started bool
+	// pending is the request waiting to be processed in the next round
+	pending *dagRequest
+
+	processed bool
+	err       error
+}
+
+// dagRequest is the releases passed to runDAG by the converge function of a helmfile
+type dagRequest struct {
+	templated *state.HelmState
+	helm      helmexec.Interface
+	opts      state.PlanOptions
+	converge  func(*state.HelmState, helmexec.Interface) (bool, []error)
+}
+
+func (s *crossHelmfileScheduler) run(converge func(*state.HelmState) (bool, error)) error {
+	var failed *helmfileWorker
+
+	for _, w := range s.workers {
+		if err := s.resume(w, converge, dagResult{}); err != nil {
+			return err
+		}
+
+		if w.err != nil {
+			failed = w
+			break
+		}
+	}
+
+	for failed == nil {
+		reverse := true
+
+		round := s.pending(reverse)
+		if len(round) == 0 {
+			reverse = false
+			round = s.pending(reverse)
+		}
+		if len(round) == 0 {
+			break
+		}
+
+		results := s.runRound(round, reverse)
+
+		for _, w := range round {
+			if err := s.resume(w, converge, results[w]); err != nil {
+				return err
+			}
+
+			if w.err != nil && failed == nil {
+				failed = w
+			}
+		}
+	}
+
+	if failed != nil {
+		// Let the helmfiles waiting for their releases to be processed complete, so that they clean up
+		for _, w := range s.workers {
+			for w.pending != nil {
+				skipped := fmt.Errorf("releases in %s were not processed because of the failure in %s", w.helmfile.path, failed.helmfile.path)
+				if err := s.resume(w, converge, dagResult{errs: []error{skipped}}); err != nil {
+					return err
+				}
+			}
+		}
+
+		return appError(fmt.Sprintf("in %s", failed.helmfile.path), failed.err)
+	}
+
+	for _, w := range s.workers {
+		if w.processed {
+			return nil
+		}
+	}
+
+	return &NoMatchingHelmfileError{selectors: s.app.Selectors, env: s.app.Env}
+}
+
+// resume starts or resumes the converge function of the helmfile within the directory of the helmfile, passing the result
+// of the request it waits for, until it either completes or waits for another request to be processed.
+func (s *crossHelmfileScheduler) resume(w *helmfileWorker, converge func(*state.HelmState) (bool, error), result dagResult) error {
+	return s.app.within(w.helmfile.dir, func() error {
+		s.active = w
+
+		if !w.started {
+			w.started = true
+
+			st := *w.helmfile.state
+			// The releases are already selected, and their needs are satisfied by the scheduler
+			st.Selectors = nil
+			st.ChangedFiles = nil
+			st.Releases = withoutNeeds(w.releases)
+
+			go func() {
+				w.processed, w.err = converge(&st)
+
+				close(w.requests)
+			}()
+		} else {
+			w.results <- result
+		}
+
+		w.pending = <-w.requests
+
+		return nil
+	})
+}
+
+// pending returns the helmfiles waiting for releases to be processed in reverse order, or in order
+func (s *crossHelmfileScheduler) pending(reverse bool) []*helmfileWorker {
+	var ws []*helmfileWorker
+	for _, w := range s.workers {
+		if w.pending != nil && w.pending.opts.Reverse == reverse {
+			ws = append(ws, w)
+		}
+	}
+
+	return ws
+}
+
+// withDAG is called by the converge function of the active helmfile through runDAG.
+// It waits for the releases to be processed in a round, and returns the result.
+func (s *crossHelmfileScheduler) withDAG(templated *state.HelmState, helm helmexec.Interface, opts state.PlanOptions, converge func(*state.HelmState, helmexec.Interface) (bool, []error)) (bool, []error) {
+	w := s.active
+
+	w.requests <- &dagRequest{templated: templated, helm: helm, opts: opts, converge: converge}
+
+	r := <-w.results
+
+	return r.processed, r.errs
+}
+
+// runRound processes the releases of the requests of the helmfiles with one DAG, and returns the result of each request.
+// Releases of a helmfile that are not processed because releases of other helmfiles failed make the request fail.
+func (s *crossHelmfileScheduler) runRound(ws []*helmfileWorker, reverse bool) map[*helmfileWorker]dagResult {
+	results := map[*helmfileWorker]dagResult{}
+
+	fail := func(err error) map[*helmfileWorker]dagResult {
+		for _, w := range ws {
+			r := results[w]
+			r.errs = append(r.errs, err)
+			results[w] = r
+		}
+		return results
+	}
+
+	var (
+		selected    []state.ReleaseSpec
+		concurrency int
+	)
+
+	specs := map[string]state.ReleaseSpec{}
+
+	for _, w := range ws {
+		for _, r := range w.pending.opts.SelectedReleases {
+			release := r
+			specs[state.ReleaseToID(&release)] = release
+			selected = append(selected, release)
+		}
+
+		if c := w.pending.opts.Concurrency; c > 0 && (concurrency == 0 || c < concurrency) {
+			concurrency = c
+		}
+	}
+
+	releases := make([]state.Release, 0, len(s.all))
+	for _, r := range s.all {
+		releases = append(releases, state.Release{ReleaseSpec: r})
+	}
+
+	batches, err := state.SortedReleaseGroups(releases, state.PlanOptions{Reverse: reverse, SelectedReleases: selected, SkipNeeds: true})
+	if err != nil {
+		return fail(err)
+	}
+
+	wd, err := s.app.fs.Getwd()
+	if err != nil {
+		return fail(err)
+	}
+
+	var mu sync.Mutex
+
+	started := map[string]bool{}
+
+	_, errs := scheduleBatches(ws[0].pending.opts.Purpose, s.all, batches, s.app.Logger, concurrency, func(release state.ReleaseSpec) (bool, []error) {
+		id := state.ReleaseToID(&release)
+		w := s.defined[id]
+		req := w.pending
+
+		mu.Lock()
+		started[id] = true
+		mu.Unlock()
+
+		if err := s.dirs.enter(w.helmfile.dir); err != nil {
+			return false, []error{err}
+		}
+		defer s.dirs.leave()
+
+		releaseSt := *req.templated
+		releaseSt.Releases = []state.ReleaseSpec{specs[id]}
+
+		processed, errs := req.converge(&releaseSt, req.helm)
+
+		mu.Lock()
+		defer mu.Unlock()
+
+		r := results[w]
+		r.processed = r.processed || processed
+		r.errs = append(r.errs, errs...)
+		results[w] = r
+
+		return processed, errs
+	})
+
+	s.dirs.dir = ""
+	if err := s.app.fs.Chdir(wd); err != nil {
+		return fail(fmt.Errorf("failed changing working directory back to \"%s\": %v", wd, err))
+	}
+
+	if len(errs) > 0 {
+		for _, w := range ws {
+			var skipped []string
+			for _, r := range w.pending.opts.SelectedReleases {
+				release := r
+				if id := state.ReleaseToID(&release); !started[id] {
+					skipped = append(skipped, id)
+				}
+			}
+
+			if r := results[w]; len(r.errs) == 0 && len(skipped) > 0 {
+				r.errs = []error{fmt.Errorf("release(s) %s were not processed because releases in other helmfiles failed", strings.Join(skipped, ", "))}
+				results[w] = r
+			}
+		}
+	}
+
+	return results
+}
+
+// dirLock lets the releases of the same helmfile be processed at once within the directory of the helmfile, while the
+// releases of the other helmfiles wait for them to complete.
+type dirLock struct {
+	fs *filesystem.FileSystem
+
+	mu   sync.Mutex
+	cond *sync.Cond
+
+	dir     string
+	holders int
+}
+
+func (l *dirLock) enter(dir string) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	for l.holders > 0 && l.dir != dir {
+		l.cond.Wait()
+	}
+
+	if l.dir != dir {
+		if err := l.fs.Chdir(dir); err != nil {
+			return fmt.Errorf("failed changing working directory to \"%s\": %v", dir, err)
+		}
+		l.dir = dir
+	}
+
+	l.holders++
+
+	return nil
+}
+
+func (l *dirLock) leave() {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.holders--
+	if l.holders == 0 {
+		l.cond.Broadcast()
+	}
+}
+
+// loadStatesAcrossHelmfiles loads every helmfile, including sub-helmfiles, in the order they would be processed
+func (a *App) loadStatesAcrossHelmfiles(fileOrDir string, opts LoadOpts) ([]loadedHelmfile, error) {
+	wd, err := a.fs.Getwd()
+	if err != nil {
+		return nil, err
+	}
+
+	var helmfiles []loadedHelmfile
+
+	err = a.visitStates(fileOrDir, opts, func(st *state.HelmState) (bool, []error) {
+		// visitStates runs this function within the directory of the helmfile
+		dir, err := a.fs.Getwd()
+		if err != nil {
+			return false, []error{err}
+		}
+
+		path, err := filepath.Rel(wd, filepath.Join(dir, st.FilePath))
+		if err != nil {
+			path = filepath.Join(dir, st.FilePath)
+		}
+
+		st.Releases = st.GetReleasesWithOverrides()
+		helmfiles = append(helmfiles, loadedHelmfile{state: st, dir: dir, path: path})
+
+		return true, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return helmfiles, nil
+}
+
+// planAcrossHelmfiles orders the releases of all the states with one DAG.
+// It returns the releases of each state in each group, in the order they are processed, along with all the releases
+// and the selected releases.
+func (a *App) planAcrossHelmfiles(helmfiles []loadedHelmfile, opts LoadOpts, includeTransitiveNeeds bool) ([][]helmfileGroup, []state.ReleaseSpec, []state.ReleaseSpec, error) {
+	var (
+		releases []state.Release
+		all      []state.ReleaseSpec
+		selected []state.ReleaseSpec
+	)
+
+	// defined is the index of the helmfile each release is defined in
+	defined := map[string]int{}
+
+	for i, h := range helmfiles {
+		selectedInState, deduplicated, err := a.getSelectedReleases(&Run{state: h.state}, includeTransitiveNeeds)
+		if err != nil {
+			return nil, nil, nil, fmt.Errorf("in %s: %v", h.path, err)
+		}
+
+		for _, r := range deduplicated {
+			id := state.ReleaseToID(&r)
+			if j, ok := defined[id]; ok && j != i {
+				return nil, nil, nil, fmt.Errorf("release %q is defined in both %s and %s. Releases must be defined only once to be needed across helmfiles", id, helmfiles[j].path, h.path)
+			}
+
+			defined[id] = i
+			releases = append(releases, state.Release{ReleaseSpec: r})
+			all = append(all, r)
+		}
+
+		selected = append(selected, selectedInState...)
+	}
+
+	// Needs of releases in the same state are warned about by getSelectedReleases
+	for _, r := range selected {
+		for k, n := range r.Needs {
+			j, ok := defined[n]
+			if !ok || j == defined[state.ReleaseToID(&r)] {
+				continue
+			}
+
+			need, ok := releaseByID(helmfiles[j].state.Releases, n)
+			if !ok {
+				continue
+			}
+
+			w, err := state.DisabledNeedWarning(r, k, need, helmfiles[j].state.Values())
+			if err != nil {
+				return nil, nil, nil, err
+			}
+			if w != "" {
+				a.Logger.Warn(w)
+			}
+		}
+	}
+
+	batches, err := state.GroupReleasesByDependency(releases, state.PlanOptions{
+		SelectedReleases: selected,
+		IncludeNeeds:     opts.IncludeNeeds,
+		SkipNeeds:        opts.SkipNeeds,
+	})
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	a.Logger.Debugf("processing %d groups of releases of all the helmfiles in this order:\n%s", len(batches), printBatches(batches))
+
+	plan := make([][]helmfileGroup, 0, len(batches))
+
+	for _, batch := range batches {
+		byState := map[int][]state.ReleaseSpec{}
+		for _, r := range batch {
+			i := defined[state.ReleaseToID(&r.ReleaseSpec)]
+			byState[i] = append(byState[i], r.ReleaseSpec)
+		}
+
+		// Keep the order the helmfiles are loaded in
+		indices := make([]int, 0, len(byState))
+		for i := range byState {
+			indices = append(indices, i)
+		}
+		sort.Ints(indices)
+
+		groups := make([]helmfileGroup, 0, len(indices))
+		for _, i := range indices {
+			groups = append(groups, helmfileGroup{helmfile: helmfiles[i], releases: byState[i]})
+		}
+
+		plan = append(plan, groups)
+	}
+
+	if opts.Reverse {
+		for i, j := 0, len(plan)-1; i < j; i, j = i+1, j-1 {
+			plan[i], plan[j] = plan[j], plan[i]
+		}
+	}
+
+	return plan, all, selected, nil
+}
+
+func releaseByID(releases []state.ReleaseSpec, id string) (state.ReleaseSpec, bool) {
+	for i := range releases {
+		if state.ReleaseToID(&releases[i]) == id {
+			return releases[i], true
+		}
+	}
+
+	return state.ReleaseSpec{}, false
+}
+
+// withoutNeeds returns copies of the releases without needs, so that they can be processed without the releases they
+// need, which may be defined in other helmfiles
+func withoutNeeds(releases []state.ReleaseSpec) []state.ReleaseSpec {
+	rs := make([]state.ReleaseSpec, 0, len(releases))
+	for _, r := range releases {
+		r.Needs = nil
+		r.NeedsPositions = nil
+		rs = append(rs, r)
+	}
+	return rs
+}
@@ -0,0 +1,225 @@
+package app
+
+import (
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/helmfile/vals"
+	"go.uber.org/zap"
+
+	"github.com/helmfile/helmfile/pkg/exectest"
+	ffs "github.com/helmfile/helmfile/pkg/filesystem"
+	"github.com/helmfile/helmfile/pkg/helmexec"
+)
+
+func TestSync_CrossHelmfileNeeds(t *testing.T) {
+	type testcase struct {
+		files        map[string]string
+		selectors    []string
+		includeNeeds bool
+		upgraded     []string
+		deleted      []string
+		// logged is the logs expected exactly once
+		logged []string
+		error  string
+	}
+
+	files := map[string]string{
+		"/path/to/helmfile.yaml": `
+helmfiles:
+- apps/helmfile.yaml
+- infra/helmfile.yaml
+`,
+		"/path/to/apps/helmfile.yaml": `
+releases:
+- name: app
+  chart: incubator/raw
+  namespace: default
+  labels:
+    tier: frontend
+  needs:
+  - default/db
+`,
+		"/path/to/infra/helmfile.yaml": `
+releases:
+- name: db
+  chart: incubator/raw
+  namespace: default
+- name: monitoring
+  chart: incubator/raw
+  namespace: default
+  needs:
+  - default/app
+`,
+	}
+
+	check := func(t *testing.T, tc testcase) {
+		t.Helper()
+
+		helm := &exectest.Helm{
+			FailOnUnexpectedList: true,
+			FailOnUnexpectedDiff: true,
+			DiffMutex:            &sync.Mutex{},
+			ChartsMutex:          &sync.Mutex{},
+			ReleasesMutex:        &sync.Mutex{},
+			Helm3:                true,
+		}
+
+		bs := runWithLogCapture(t, "debug", func(t *testing.T, logger *zap.SugaredLogger) {
+			t.Helper()
+
+			valsRuntime, err := vals.New(vals.Options{CacheSize: 32})
+			if err != nil {
+				t.Errorf("unexpected error creating vals runtime: %v", err)
+			}
+
+			app := appWithFs(&App{
+				OverrideHelmBinary:  DefaultHelmBinary,
+				fs:                  ffs.DefaultFileSystem(),
+				OverrideKubeContext: "default",
+				Env:                 "default",
+				Logger:              logger,
+				CrossHelmfileNeeds:  true,
+				Selectors:           tc.selectors,
+				helms: map[helmKey]helmexec.Interface{
+					createHelmKey("helm", "default"): helm,
+				},
+				valsRuntime: valsRuntime,
+			}, tc.files)
+
+			syncErr := app.Sync(applyConfig{
+				concurrency:  1,
+				logger:       logger,
+				skipNeeds:    !tc.includeNeeds,
+				includeNeeds: tc.includeNeeds,
+			})
+
+			var gotErr string
+			if syncErr != nil {
+				gotErr = syncErr.Error()
+			}
+
+			if d := cmp.Diff(tc.error, gotErr); d != "" {
+				t.Fatalf("unexpected error: want (-), got (+): %s", d)
+			}
+		})
+
+		var upgraded []string
+		for _, r := range helm.Releases {
+			upgraded = append(upgraded, r.Name)
+		}
+
+		if d := cmp.Diff(tc.upgraded, upgraded); d != "" {
+			t.Errorf("unexpected upgrades: want (-), got (+): %s", d)
+		}
+
+		var deleted []string
+		for _, r := range helm.Deleted {
+			deleted = append(deleted, r.Name)
+		}
+
+		if d := cmp.Diff(tc.deleted, deleted); d != "" {
+			t.Errorf("unexpected deletions: want (-), got (+): %s", d)
+		}
+
+		for _, l := range tc.logged {
+			if n := strings.Count(bs.String(), l); n != 1 {
+				t.Errorf("%q is logged %d times, want once", l, n)
+			}
+		}
+	}
+
+	t.Run("releases are ordered across helmfiles", func(t *testing.T) {
+		check(t, testcase{
+			files:    files,
+			upgraded: []string{"db", "app", "monitoring"},
+		})
+	})
+
+	t.Run("include needs defined in another helmfile", func(t *testing.T) {
+		check(t, testcase{
+			files:        files,
+			selectors:    []string{"tier=frontend"},
+			includeNeeds: true,
+			upgraded:     []string{"db", "app"},
+		})
+	})
+
+	t.Run("skip needs defined in another helmfile", func(t *testing.T) {
+		check(t, testcase{
+			files:     files,
+			selectors: []string{"tier=frontend"},
+			upgraded:  []string{"app"},
+		})
+	})
+
+	t.Run("releases are deleted in reverse order across helmfiles", func(t *testing.T) {
+		check(t, testcase{
+			files: map[string]string{
+				"/path/to/helmfile.yaml": files["/path/to/helmfile.yaml"],
+				"/path/to/apps/helmfile.yaml": `
+releases:
+- name: app
+  chart: incubator/raw
+  namespace: default
+  installed: false
+  needs:
+  - default/db
+`,
+				"/path/to/infra/helmfile.yaml": `
+releases:
+- name: db
+  chart: incubator/raw
+  namespace: default
+  installed: false
+`,
+			},
+			deleted: []string{"app", "db"},
+		})
+	})
+
+	t.Run("each helmfile is prepared and cleaned up once", func(t *testing.T) {
+		check(t, testcase{
+			files: map[string]string{
+				"/path/to/helmfile.yaml":      files["/path/to/helmfile.yaml"],
+				"/path/to/apps/helmfile.yaml": files["/path/to/apps/helmfile.yaml"],
+				"/path/to/infra/helmfile.yaml": files["/path/to/infra/helmfile.yaml"] + `
+hooks:
+- events: ["prepare"]
+  command: echo
+  showlogs: true
+  args: ["prepared infra"]
+- events: ["cleanup"]
+  command: echo
+  showlogs: true
+  args: ["cleaned up infra"]
+`,
+			},
+			upgraded: []string{"db", "app", "monitoring"},
+			logged:   []string{"logs | prepared infra", "logs | cleaned up infra"},
+		})
+	})
+
+	t.Run("release defined in multiple helmfiles", func(t *testing.T) {
+		check(t, testcase{
+			files: map[string]string{
+				"/path/to/helmfile.yaml": files["/path/to/helmfile.yaml"],
+				"/path/to/apps/helmfile.yaml": `
+releases:
+- name: db
+  chart: incubator/raw
+  namespace: default
+`,
+				"/path/to/infra/helmfile.yaml": `
+releases:
+- name: db
+  chart: incubator/raw
+  namespace: default
+`,
+			},
+			error: `release "default/default/db" is defined in both apps/helmfile.yaml and infra/helmfile.yaml. Releases must be defined only once to be needed across helmfiles`,
+		})
+	})
+}
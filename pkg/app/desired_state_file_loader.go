@@ -202,6 +202,9 @@ func (ld *desiredStateLoader) load(env, overrodeEnv *environment.Environment, ba
 	hasEnv := env != nil || overrodeEnv != nil
 	var finalState *state.HelmState
 
+	// The line in the helmfile where the part starts, minus one
+	var lineOffset int
+
 	for i, part := range parts {
 		id := fmt.Sprintf("%s.part.%d", filename, i)
 
@@ -227,6 +230,15 @@ func (ld *desiredStateLoader) load(env, overrodeEnv *environment.Environment, ba
 			rawContent = part
 		}
 
+		if unknown := state.FindUnknownFields(rawContent, filename, lineOffset); len(unknown) > 0 {
+			if runtime.StrictFields {
+				return nil, &state.UnknownFieldsError{Fields: unknown}
+			}
+			for _, f := range unknown {
+				ld.logger.Warnf("WARNING: %s", f)
+			}
+		}
+
 		currentState, err := ld.rawLoad(
 			rawContent,
 			baseDir,
@@ -239,6 +251,9 @@ func (ld *desiredStateLoader) load(env, overrodeEnv *environment.Environment, ba
 			return nil, err
 		}
 
+		currentState.ShiftNeedsPositions(filename, lineOffset)
+		lineOffset += bytes.Count(part, []byte("\n")) + 2
+
 		if finalState == nil {
 			finalState = currentState
 		} else {
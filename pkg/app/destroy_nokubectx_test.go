@@ -655,6 +655,7 @@ second-pass rendering result of "helmfile.yaml.part.0":
 merged environment: &{default  map[] map[]}
 2 release(s) found in helmfile.yaml
 
+release "frontend-v1" needs "backend-v1" at helmfile.yaml:9, which is not installed as it has installed: false
 processing 2 groups of releases in this order:
 GROUP RELEASES
 1     frontend-v1
@@ -727,6 +728,7 @@ second-pass rendering result of "helmfile.yaml.part.0":
 merged environment: &{default  map[] map[]}
 2 release(s) found in helmfile.yaml
 
+release "frontend-v1" needs "backend-v1" at helmfile.yaml:9, which is not installed as it has installed: false
 processing 2 groups of releases in this order:
 GROUP RELEASES
 1     frontend-v1
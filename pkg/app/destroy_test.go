@@ -41,6 +41,7 @@ type destroyConfig struct {
 	logger                 *zap.SugaredLogger
 	includeTransitiveNeeds bool
 	skipCharts             bool
+	reportFile             string
 }
 
 func (d destroyConfig) Args() string {
@@ -75,6 +76,10 @@ func (d destroyConfig) IncludeTransitiveNeeds() bool {
 	return d.includeTransitiveNeeds
 }
 
+func (d destroyConfig) ReportFile() string {
+	return d.reportFile
+}
+
 func TestDestroy(t *testing.T) {
 	type testcase struct {
 		ns          string
@@ -716,6 +721,7 @@ second-pass rendering result of "helmfile.yaml.part.0":
 merged environment: &{default  map[] map[]}
 2 release(s) found in helmfile.yaml
 
+release "default//frontend-v1" needs "default//backend-v1" at helmfile.yaml:9, which is not installed as it has installed: false
 processing 2 groups of releases in this order:
 GROUP RELEASES
 1     default//frontend-v1
@@ -788,6 +794,7 @@ second-pass rendering result of "helmfile.yaml.part.0":
 merged environment: &{default  map[] map[]}
 2 release(s) found in helmfile.yaml
 
+release "default//frontend-v1" needs "default//backend-v1" at helmfile.yaml:9, which is not installed as it has installed: false
 processing 2 groups of releases in this order:
 GROUP RELEASES
 1     default//frontend-v1
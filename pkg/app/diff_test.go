@@ -41,6 +41,7 @@ type diffConfig struct {
 	interactive            bool
 	skipDiffOnInstall      bool
 	reuseValues            bool
+	outPlan                string
 	logger                 *zap.SugaredLogger
 }
 
@@ -52,6 +53,10 @@ func (a diffConfig) DiffArgs() string {
 	return a.diffArgs
 }
 
+func (a diffConfig) OutPlan() string {
+	return a.outPlan
+}
+
 func (a diffConfig) Values() []string {
 	return a.values
 }
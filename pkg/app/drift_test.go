@@ -0,0 +1,162 @@
+package app
+
+import (
+	"io"
+	"sync"
+	"testing"
+
+	"github.com/helmfile/vals"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+
+	"github.com/helmfile/helmfile/pkg/exectest"
+	ffs "github.com/helmfile/helmfile/pkg/filesystem"
+	"github.com/helmfile/helmfile/pkg/helmexec"
+	"github.com/helmfile/helmfile/pkg/testutil"
+)
+
+type driftConfig struct {
+	args        string
+	output      string
+	skipDeps    bool
+	concurrency int
+	logger      *zap.SugaredLogger
+}
+
+func (d driftConfig) Args() string {
+	return d.args
+}
+
+func (d driftConfig) Output() string {
+	return d.output
+}
+
+func (d driftConfig) SkipDeps() bool {
+	return d.skipDeps
+}
+
+func (d driftConfig) Logger() *zap.SugaredLogger {
+	return d.logger
+}
+
+func (d driftConfig) Concurrency() int {
+	return d.concurrency
+}
+
+func TestDrift(t *testing.T) {
+	files := map[string]string{
+		"/path/to/helmfile.yaml": `
+releases:
+- name: database
+  chart: charts/mysql
+  version: 3.1.0
+  values:
+  - values/database.yaml
+- name: backend
+  chart: charts/backend
+  version: ">=4.0.0"
+  set:
+  - name: image.tag
+    value: v2
+- name: frontend
+  chart: charts/frontend
+- name: cache
+  chart: charts/redis
+  version: 17.3.1
+- name: logging
+  chart: charts/fluent-bit
+  installed: false
+`,
+		"/path/to/values/database.yaml": `
+replicas: 2
+`,
+	}
+
+	lists := map[exectest.ListKey]string{
+		{Filter: "^database$", Flags: listFlags("", "default")}: `NAME	REVISION	UPDATED                 	STATUS  	CHART        	APP VERSION	NAMESPACE
+database 	4       	Fri Nov  1 08:40:07 2019	DEPLOYED	mysql-3.1.0	3.1.0      	default
+`,
+		{Filter: "^backend$", Flags: listFlags("", "default")}: `NAME	REVISION	UPDATED                 	STATUS  	CHART        	APP VERSION	NAMESPACE
+backend 	4       	Fri Nov  1 08:40:07 2019	DEPLOYED	backend-3.1.0	3.1.0      	default
+`,
+		{Filter: "^frontend$", Flags: listFlags("", "default")}: `NAME	REVISION	UPDATED                 	STATUS  	CHART        	APP VERSION	NAMESPACE
+`,
+		// The chart of the deployed release was renamed, so that its version can't be told
+		{Filter: "^cache$", Flags: listFlags("", "default")}: `NAME	REVISION	UPDATED                 	STATUS  	CHART        	APP VERSION	NAMESPACE
+cache 	2       	Fri Nov  1 08:40:07 2019	DEPLOYED	valkey-8.0.0	8.0.0      	default
+`,
+		{Filter: "^logging$", Flags: listFlags("", "default")}: `NAME	REVISION	UPDATED                 	STATUS  	CHART        	APP VERSION	NAMESPACE
+logging 	1       	Fri Nov  1 08:40:07 2019	DEPLOYED	fluent-bit-3.1.0	3.1.0      	default
+`,
+	}
+
+	helm := &exectest.Helm{
+		Helm3:                true,
+		FailOnUnexpectedList: true,
+		FailOnUnexpectedDiff: true,
+		Lists:                lists,
+		DeployedValues: map[string]string{
+			"database": "replicas: 2\n",
+			"backend":  "image:\n  tag: v1\n",
+		},
+		DiffMutex:     &sync.Mutex{},
+		ChartsMutex:   &sync.Mutex{},
+		ReleasesMutex: &sync.Mutex{},
+	}
+
+	valsRuntime, err := vals.New(vals.Options{CacheSize: 32})
+	require.NoError(t, err)
+
+	logger := helmexec.NewLogger(io.Discard, "debug")
+
+	app := appWithFs(&App{
+		OverrideHelmBinary:  DefaultHelmBinary,
+		fs:                  ffs.DefaultFileSystem(),
+		OverrideKubeContext: "default",
+		Env:                 "default",
+		Logger:              logger,
+		helms: map[helmKey]helmexec.Interface{
+			createHelmKey("helm", "default"): helm,
+		},
+		valsRuntime: valsRuntime,
+	}, files)
+
+	var driftErr error
+
+	out, err := testutil.CaptureStdout(func() {
+		driftErr = app.Drift(driftConfig{
+			output:      "json",
+			skipDeps:    true,
+			concurrency: 1,
+			logger:      logger,
+		})
+	})
+	require.NoError(t, err)
+
+	require.EqualError(t, driftErr, "Detected drift in 3 release(s)")
+
+	appErr, ok := driftErr.(*Error)
+	require.True(t, ok)
+	require.Equal(t, 2, appErr.Code())
+
+	expected := `[{"id":"default//database","desired":true,"installed":true,"desiredVersion":"3.1.0","deployedVersion":"3.1.0","drifts":[]},` +
+		`{"id":"default//backend","desired":true,"installed":true,"desiredVersion":">=4.0.0","deployedVersion":"3.1.0","changedValues":["image.tag"],"drifts":["version","values"]},` +
+		`{"id":"default//frontend","desired":true,"installed":false,"drifts":["missing"]},` +
+		`{"id":"default//cache","desired":true,"installed":true,"desiredVersion":"17.3.1","drifts":[]},` +
+		`{"id":"default//logging","desired":false,"installed":true,"drifts":["unwanted"]}]
+`
+	require.Equal(t, expected, out)
+}
+
+func TestDrift_UnsupportedOutput(t *testing.T) {
+	app := appWithFs(&App{
+		OverrideHelmBinary: DefaultHelmBinary,
+		fs:                 ffs.DefaultFileSystem(),
+		Env:                "default",
+		Logger:             newAppTestLogger(),
+	}, map[string]string{})
+
+	err := app.Drift(driftConfig{output: "yaml"})
+	require.EqualError(t, err, `unsupported output format "yaml": expected one of table, json`)
+	require.IsType(t, &Error{}, err)
+}
@@ -1,18 +1,69 @@
 package app
 
 import (
+	"encoding/csv"
 	"encoding/json"
 	"fmt"
+	"os"
+	"strings"
+	"text/template"
 
 	"github.com/gosuri/uitable"
+
+	"github.com/helmfile/helmfile/pkg/state"
+	"github.com/helmfile/helmfile/pkg/yaml"
+)
+
+const (
+	ListOutputTable      = "table"
+	ListOutputJSON       = "json"
+	ListOutputYAML       = "yaml"
+	ListOutputCSV        = "csv"
+	ListOutputMarkdown   = "markdown"
+	ListOutputGoTemplate = "go-template"
 )
 
+// releaseRows returns the header and the rows of the releases listed as a table, csv or markdown.
+// The status columns are added when the releases are listed with their status.
+func releaseRows(releases []*HelmRelease) ([]string, [][]string) {
+	var withStatus bool
+	for _, r := range releases {
+		if r.Status != nil {
+			withStatus = true
+			break
+		}
+	}
+
+	header := []string{"NAME", "NAMESPACE", "ENABLED", "INSTALLED", "LABELS", "CHART", "VERSION"}
+	if withStatus {
+		header = append(header, "DEPLOYED VERSION", "REVISION", "STATUS", "UPDATED", "OUT OF DATE")
+	}
+
+	var rows [][]string
+
+	for _, r := range releases {
+		row := []string{r.Name, r.Namespace, fmt.Sprintf("%t", r.Enabled), fmt.Sprintf("%t", r.Installed), r.Labels, r.Chart, r.Version}
+		if withStatus {
+			s := r.Status
+			if s == nil {
+				s = &HelmReleaseStatus{}
+			}
+			row = append(row, s.DeployedVersion, s.Revision, s.Status, s.Updated, fmt.Sprintf("%t", s.OutOfDate))
+		}
+		rows = append(rows, row)
+	}
+
+	return header, rows
+}
+
 func FormatAsTable(releases []*HelmRelease) error {
 	table := uitable.New()
-	table.AddRow("NAME", "NAMESPACE", "ENABLED", "INSTALLED", "LABELS", "CHART", "VERSION")
 
-	for _, r := range releases {
-		table.AddRow(r.Name, r.Namespace, fmt.Sprintf("%t", r.Enabled), fmt.Sprintf("%t", r.Installed), r.Labels, r.Chart, r.Version)
+	header, rows := releaseRows(releases)
+
+	table.AddRow(toCells(header)...)
+	for _, row := range rows {
+		table.AddRow(toCells(row)...)
 	}
 
 	fmt.Println(table.String())
@@ -20,6 +71,92 @@ func FormatAsTable(releases []*HelmRelease) error {
 	return nil
 }
 
+func toCells(row []string) []any {
+	cells := make([]any, 0, len(row))
+	for _, c := range row {
+		cells = append(cells, c)
+	}
+	return cells
+}
+
+func FormatAsYaml(releases []*HelmRelease) error {
+	if releases == nil {
+		releases = []*HelmRelease{}
+	}
+
+	output, err := yaml.Marshal(releases)
+	if err != nil {
+		return fmt.Errorf("error generating yaml: %v", err)
+	}
+
+	fmt.Print(string(output))
+
+	return nil
+}
+
+func FormatAsCSV(releases []*HelmRelease) error {
+	w := csv.NewWriter(os.Stdout)
+
+	header, rows := releaseRows(releases)
+
+	if err := w.Write(header); err != nil {
+		return fmt.Errorf("error generating csv: %v", err)
+	}
+	if err := w.WriteAll(rows); err != nil {
+		return fmt.Errorf("error generating csv: %v", err)
+	}
+
+	return nil
+}
+
+func FormatAsMarkdown(releases []*HelmRelease) error {
+	header, rows := releaseRows(releases)
+
+	var b strings.Builder
+
+	writeRow := func(row []string) {
+		b.WriteString("|")
+		for _, c := range row {
+			fmt.Fprintf(&b, " %s |", strings.ReplaceAll(c, "|", "\\|"))
+		}
+		b.WriteString("\n")
+	}
+
+	writeRow(header)
+
+	b.WriteString("|")
+	for range header {
+		b.WriteString(" --- |")
+	}
+	b.WriteString("\n")
+
+	for _, row := range rows {
+		writeRow(row)
+	}
+
+	fmt.Print(b.String())
+
+	return nil
+}
+
+// FormatAsGoTemplate prints the releases with the Go template, which is executed with the list of the releases
+func FormatAsGoTemplate(releases []*HelmRelease, text string) error {
+	tmpl, err := template.New("list").Parse(text)
+	if err != nil {
+		return fmt.Errorf("error parsing template: %v", err)
+	}
+
+	if releases == nil {
+		releases = []*HelmRelease{}
+	}
+
+	if err := tmpl.Execute(os.Stdout, releases); err != nil {
+		return fmt.Errorf("error executing template: %v", err)
+	}
+
+	return nil
+}
+
 func FormatAsJson(releases []*HelmRelease) error {
 	output, err := json.Marshal(releases)
 
@@ -31,3 +168,83 @@ func FormatAsJson(releases []*HelmRelease) error {
 
 	return nil
 }
+
+// CacheEntryRow is a row of `helmfile cache list`
+type CacheEntryRow struct {
+	Path      string
+	Source    string
+	Revision  string
+	Age       string
+	Immutable bool
+}
+
+func FormatCacheEntriesAsTable(entries []CacheEntryRow) error {
+	table := uitable.New()
+	table.AddRow("PATH", "SOURCE", "REVISION", "AGE", "IMMUTABLE")
+
+	for _, e := range entries {
+		table.AddRow(e.Path, e.Source, e.Revision, e.Age, fmt.Sprintf("%t", e.Immutable))
+	}
+
+	fmt.Println(table.String())
+
+	return nil
+}
+
+func FormatDriftsAsTable(drifts []state.ReleaseDrift) error {
+	table := uitable.New()
+	table.AddRow("ID", "DRIFTS", "DESIRED VERSION", "DEPLOYED VERSION", "CHANGED VALUES")
+
+	for _, d := range drifts {
+		table.AddRow(d.ID, strings.Join(d.Drifts, ","), d.DesiredVersion, d.DeployedVersion, strings.Join(d.ChangedValues, ","))
+	}
+
+	fmt.Println(table.String())
+
+	return nil
+}
+
+func FormatDriftsAsJson(drifts []state.ReleaseDrift) error {
+	if drifts == nil {
+		drifts = []state.ReleaseDrift{}
+	}
+
+	// Version constraints like `>=4.0.0` are printed as they are, instead of being escaped for HTML
+	enc := json.NewEncoder(os.Stdout)
+	enc.SetEscapeHTML(false)
+
+	if err := enc.Encode(drifts); err != nil {
+		return fmt.Errorf("error generating json: %v", err)
+	}
+
+	return nil
+}
+
+func FormatVersionChecksAsTable(checks []state.ChartVersionCheck) error {
+	table := uitable.New()
+	table.AddRow("ID", "CHART", "VERSION", "LOCKED", "NEWEST", "PATCH", "MINOR", "MAJOR", "ISSUES")
+
+	for _, c := range checks {
+		table.AddRow(c.ID, c.Chart, c.Version, c.Locked, c.Newest, c.NewestPatch, c.NewestMinor, c.NewestMajor, strings.Join(c.Issues, ","))
+	}
+
+	fmt.Println(table.String())
+
+	return nil
+}
+
+func FormatVersionChecksAsJson(checks []state.ChartVersionCheck) error {
+	if checks == nil {
+		checks = []state.ChartVersionCheck{}
+	}
+
+	output, err := json.Marshal(checks)
+
+	if err != nil {
+		return fmt.Errorf("error generating json: %v", err)
+	}
+
+	fmt.Println(string(output))
+
+	return nil
+}
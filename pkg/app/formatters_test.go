@@ -85,3 +85,104 @@ func TestFormatAsJson(t *testing.T) {
 		t.Errorf("FormatAsJson() = %v, want %v", result, string(expectd))
 	}
 }
+
+func TestFormatAsCSV(t *testing.T) {
+	h := []*HelmRelease{
+		{
+			Name:      "test",
+			Namespace: "test",
+			Enabled:   true,
+			Installed: true,
+			Labels:    "app:test,tier:web",
+			Chart:     "test",
+			Version:   "test",
+			Status: &HelmReleaseStatus{
+				DeployedVersion: "test",
+				Revision:        "2",
+				Status:          "deployed",
+				Updated:         "2024-01-02 15:04:05 +0000 UTC",
+			},
+		},
+		{
+			Name:      "test1",
+			Namespace: "test2",
+			Enabled:   false,
+			Installed: false,
+			Labels:    "test1",
+			Chart:     "test1",
+			Version:   "test1",
+			Status: &HelmReleaseStatus{
+				OutOfDate: true,
+			},
+		},
+	}
+
+	output := "testdata/formatters/csvoutput"
+	expectd, err := os.ReadFile(output)
+	if err != nil {
+		t.Errorf("error reading %s: %v", output, err)
+	}
+
+	result, err := testutil.CaptureStdout(func() {
+		assert.NoError(t, FormatAsCSV(h))
+	})
+
+	assert.NoError(t, err)
+
+	if result != string(expectd) {
+		t.Errorf("FormatAsCSV() = %v, want %v", result, string(expectd))
+	}
+}
+
+func TestFormatAsMarkdown(t *testing.T) {
+	h := []*HelmRelease{
+		{
+			Name:      "test",
+			Namespace: "test",
+			Enabled:   true,
+			Installed: true,
+			Labels:    "app:test,tier:web",
+			Chart:     "test",
+			Version:   "test",
+		},
+		{
+			Name:      "test1",
+			Namespace: "test2",
+			Enabled:   false,
+			Installed: false,
+			Labels:    "a|b",
+			Chart:     "test1",
+			Version:   "test1",
+		},
+	}
+
+	output := "testdata/formatters/markdownoutput"
+	expectd, err := os.ReadFile(output)
+	if err != nil {
+		t.Errorf("error reading %s: %v", output, err)
+	}
+
+	result, err := testutil.CaptureStdout(func() {
+		assert.NoError(t, FormatAsMarkdown(h))
+	})
+
+	assert.NoError(t, err)
+
+	if result != string(expectd) {
+		t.Errorf("FormatAsMarkdown() = %v, want %v", result, string(expectd))
+	}
+}
+
+func TestFormatAsGoTemplate(t *testing.T) {
+	h := []*HelmRelease{
+		{Name: "test", Namespace: "test", Status: &HelmReleaseStatus{OutOfDate: true}},
+		{Name: "test1", Namespace: "test2", Status: &HelmReleaseStatus{}},
+	}
+
+	result, err := testutil.CaptureStdout(func() {
+		assert.NoError(t, FormatAsGoTemplate(h, `{{range .}}{{if .Status.OutOfDate}}{{.Namespace}}/{{.Name}}{{"\n"}}{{end}}{{end}}`))
+	})
+
+	assert.NoError(t, err)
+	assert.Equal(t, "test/test\n", result)
+}
@@ -0,0 +1,476 @@
+package app
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/helmfile/helmfile/pkg/state"
+)
+
+const (
+	GraphOutputDOT     = "dot"
+	GraphOutputMermaid = "mermaid"
+	GraphOutputJSON    = "json"
+)
+
+// ReleaseGraph is the dependency graph of the releases printed by `helmfile graph`.
+// It is built from the same plan as the one used to process the releases, so that it shows what helmfile would do.
+type ReleaseGraph struct {
+	// Selectors are the selectors the releases were selected with
+	Selectors []string `json:"selectors,omitempty"`
+
+	Releases []GraphRelease `json:"releases"`
+	Needs    []GraphNeed    `json:"needs"`
+}
+
+// GraphRelease is a release in the dependency graph
+type GraphRelease struct {
+	ID          string            `json:"id"`
+	Name        string            `json:"name"`
+	Namespace   string            `json:"namespace,omitempty"`
+	KubeContext string            `json:"kubeContext,omitempty"`
+	Chart       string            `json:"chart"`
+	Labels      map[string]string `json:"labels,omitempty"`
+
+	// Helmfile is the path to the helmfile the release is defined in
+	Helmfile string `json:"helmfile"`
+	// Batch is the 1-based index of the batch the release is processed in.
+	// Batches are numbered per helmfile, or across all the helmfiles with --cross-helmfile-needs.
+	Batch int `json:"batch"`
+
+	Installed bool   `json:"installed"`
+	Condition string `json:"condition,omitempty"`
+	// Enabled is false when the condition of the release is disabled
+	Enabled bool `json:"enabled"`
+	// Selected is false when the release doesn't match the selectors but is included as a need of a selected release
+	Selected bool `json:"selected"`
+}
+
+// GraphNeed is an edge of the dependency graph, from a release to a release it needs
+type GraphNeed struct {
+	Helmfile string `json:"helmfile"`
+	// Release is the ID of the release that needs the other one
+	Release string `json:"release"`
+	// Needs is the ID of the release that is needed
+	Needs string `json:"needs"`
+	// NeedsHelmfile is the path to the helmfile the needed release is defined in, when it differs from Helmfile
+	NeedsHelmfile string `json:"needsHelmfile,omitempty"`
+	// CrossContext is true when the releases are deployed to different kube contexts
+	CrossContext bool `json:"crossContext,omitempty"`
+}
+
+func (a *App) Graph(c GraphConfigProvider) error {
+	switch c.Output() {
+	case GraphOutputDOT, GraphOutputMermaid, GraphOutputJSON:
+	default:
+		return appError("", fmt.Errorf("unsupported output format %q: expected one of %s, %s or %s", c.Output(), GraphOutputDOT, GraphOutputMermaid, GraphOutputJSON))
+	}
+
+	graph := &ReleaseGraph{
+		Selectors: a.Selectors,
+	}
+
+	var err error
+	if a.CrossHelmfileNeeds {
+		err = a.graphAcrossHelmfiles(c, graph)
+	} else {
+		err = a.ForEachState(func(run *Run) (ok bool, errs []error) {
+			ok, err := a.graph(run, c, graph)
+			if err != nil {
+				return false, []error{err}
+			}
+
+			return ok, nil
+		}, c.IncludeTransitiveNeeds())
+	}
+	if err != nil {
+		return err
+	}
+
+	switch c.Output() {
+	case GraphOutputMermaid:
+		fmt.Print(graph.Mermaid())
+	case GraphOutputJSON:
+		bs, err := graph.JSON()
+		if err != nil {
+			return appError("", err)
+		}
+		fmt.Println(string(bs))
+	default:
+		fmt.Print(graph.DOT())
+	}
+
+	return nil
+}
+
+func (a *App) graph(r *Run, c DAGConfig, graph *ReleaseGraph) (bool, error) {
+	st := r.state
+
+	selectedReleases, deduplicated, err := a.getSelectedReleases(r, false)
+	if err != nil {
+		return false, err
+	}
+	if len(selectedReleases) == 0 {
+		return false, nil
+	}
+
+	// See withNeeds for why the releases need to be deduplicated before planning
+	st.Releases = deduplicated
+
+	batches, err := st.PlanReleases(state.PlanOptions{
+		SelectedReleases:       selectedReleases,
+		IncludeNeeds:           c.IncludeNeeds() || c.IncludeTransitiveNeeds(),
+		IncludeTransitiveNeeds: c.IncludeTransitiveNeeds(),
+		SkipNeeds:              c.SkipNeeds(),
+	})
+	if err != nil {
+		return false, err
+	}
+
+	if err := graph.addBatches(st.FilePath, batches, selectedReleases, st.Values()); err != nil {
+		return false, err
+	}
+
+	return true, nil
+}
+
+// graphAcrossHelmfiles adds the releases of all the helmfiles ordered with one DAG, as done with --cross-helmfile-needs
+func (a *App) graphAcrossHelmfiles(c GraphConfigProvider, graph *ReleaseGraph) error {
+	opts := a.loadOpts(SetNeeds(c))
+
+	if err := a.loadChangedFiles(); err != nil {
+		return err
+	}
+
+	helmfiles, err := a.loadStatesAcrossHelmfiles(a.FileOrDir, opts)
+	if err != nil {
+		return err
+	}
+
+	plan, _, selected, err := a.planAcrossHelmfiles(helmfiles, opts, c.IncludeTransitiveNeeds())
+	if err != nil {
+		return appError("", err)
+	}
+	if len(plan) == 0 {
+		return &NoMatchingHelmfileError{selectors: a.Selectors, env: a.Env}
+	}
+
+	batches := make([][]graphReleases, 0, len(plan))
+	for _, groups := range plan {
+		var batch []graphReleases
+		for _, g := range groups {
+			batch = append(batch, graphReleases{helmfile: g.helmfile.path, values: g.helmfile.state.Values(), releases: g.releases})
+		}
+		batches = append(batches, batch)
+	}
+
+	return graph.add(batches, selected)
+}
+
+// graphReleases is the releases of a helmfile processed in a batch
+type graphReleases struct {
+	helmfile string
+	// values are the values of the helmfile, used to evaluate the conditions of the releases
+	values   map[string]any
+	releases []state.ReleaseSpec
+}
+
+// addBatches adds the releases in the batches planned for a helmfile, along with the needs between them
+func (g *ReleaseGraph) addBatches(helmfile string, batches [][]state.Release, selected []state.ReleaseSpec, values map[string]any) error {
+	rs := make([][]graphReleases, 0, len(batches))
+	for _, batch := range batches {
+		releases := make([]state.ReleaseSpec, 0, len(batch))
+		for _, marked := range batch {
+			releases = append(releases, marked.ReleaseSpec)
+		}
+		rs = append(rs, []graphReleases{{helmfile: helmfile, values: values, releases: releases}})
+	}
+
+	return g.add(rs, selected)
+}
+
+// add adds the releases in the batches, which may be defined in multiple helmfiles, along with the needs between them
+func (g *ReleaseGraph) add(batches [][]graphReleases, selected []state.ReleaseSpec) error {
+	selectedIDs := map[string]bool{}
+	for i := range selected {
+		selectedIDs[state.ReleaseToID(&selected[i])] = true
+	}
+
+	type added struct {
+		helmfile string
+		release  state.ReleaseSpec
+	}
+
+	var releases []added
+
+	byID := map[string]added{}
+
+	for i, batch := range batches {
+		for _, b := range batch {
+			for _, r := range b.releases {
+				id := state.ReleaseToID(&r)
+
+				enabled, err := state.ConditionEnabled(r, b.values)
+				if err != nil {
+					return err
+				}
+
+				g.Releases = append(g.Releases, GraphRelease{
+					ID:          id,
+					Name:        r.Name,
+					Namespace:   r.Namespace,
+					KubeContext: r.KubeContext,
+					Chart:       r.Chart,
+					Labels:      r.Labels,
+					Helmfile:    b.helmfile,
+					Batch:       i + 1,
+					Installed:   r.Desired(),
+					Condition:   r.Condition,
+					Enabled:     enabled,
+					Selected:    selectedIDs[id],
+				})
+
+				byID[id] = added{helmfile: b.helmfile, release: r}
+				releases = append(releases, added{helmfile: b.helmfile, release: r})
+			}
+		}
+	}
+
+	for _, r := range releases {
+		id := state.ReleaseToID(&r.release)
+
+		for _, n := range r.release.Needs {
+			need, ok := byID[n]
+			if !ok {
+				// The needed release was skipped with --skip-needs
+				continue
+			}
+
+			var needsHelmfile string
+			if need.helmfile != r.helmfile {
+				needsHelmfile = need.helmfile
+			}
+
+			g.Needs = append(g.Needs, GraphNeed{
+				Helmfile:      r.helmfile,
+				Release:       id,
+				Needs:         n,
+				NeedsHelmfile: needsHelmfile,
+				CrossContext:  need.release.KubeContext != r.release.KubeContext,
+			})
+		}
+	}
+
+	return nil
+}
+
+// neededHelmfile returns the path to the helmfile the needed release is defined in
+func (n GraphNeed) neededHelmfile() string {
+	if n.NeedsHelmfile != "" {
+		return n.NeedsHelmfile
+	}
+	return n.Helmfile
+}
+
+// nodes returns the names of the nodes of the releases in the DOT and Mermaid outputs, as the same release ID can
+// appear in multiple helmfiles
+func (g *ReleaseGraph) nodes() map[string]string {
+	nodes := map[string]string{}
+	for i, r := range g.Releases {
+		nodes[r.Helmfile+"\x00"+r.ID] = fmt.Sprintf("n%d", i)
+	}
+	return nodes
+}
+
+// helmfiles returns the helmfiles in the order their releases appear in the graph
+func (g *ReleaseGraph) helmfiles() []string {
+	var helmfiles []string
+
+	seen := map[string]bool{}
+	for _, r := range g.Releases {
+		if !seen[r.Helmfile] {
+			seen[r.Helmfile] = true
+			helmfiles = append(helmfiles, r.Helmfile)
+		}
+	}
+
+	return helmfiles
+}
+
+// details returns the lines describing the release in the DOT and Mermaid outputs
+func (r GraphRelease) details() []string {
+	lines := []string{r.ID, "chart: " + r.Chart, fmt.Sprintf("batch %d", r.Batch)}
+
+	if !r.Installed {
+		lines = append(lines, "installed: false")
+	}
+
+	if r.Condition != "" {
+		status := "enabled"
+		if !r.Enabled {
+			status = "disabled"
+		}
+		lines = append(lines, fmt.Sprintf("condition: %s (%s)", r.Condition, status))
+	}
+
+	if !r.Selected {
+		lines = append(lines, "included as a need")
+	}
+
+	return lines
+}
+
+// DOT returns the graph in the Graphviz DOT language.
+// Edges point from the needed releases to the releases that need them, following the order of installation.
+func (g *ReleaseGraph) DOT() string {
+	var b strings.Builder
+
+	nodes := g.nodes()
+
+	b.WriteString("digraph helmfile {\n")
+	b.WriteString("  rankdir=LR;\n")
+	b.WriteString("  node [shape=box];\n")
+
+	if len(g.Selectors) > 0 {
+		fmt.Fprintf(&b, "  labelloc=t;\n  label=%s;\n", dotQuote("selectors: "+strings.Join(g.Selectors, " ")))
+	}
+
+	for i, helmfile := range g.helmfiles() {
+		fmt.Fprintf(&b, "  subgraph cluster_%d {\n", i)
+		fmt.Fprintf(&b, "    label=%s;\n", dotQuote(helmfile))
+
+		for _, r := range g.Releases {
+			if r.Helmfile != helmfile {
+				continue
+			}
+
+			attrs := []string{"label=" + dotQuote(strings.Join(r.details(), "\n"))}
+
+			var styles []string
+			if !r.Installed {
+				styles = append(styles, "dashed")
+			}
+			if !r.Selected {
+				styles = append(styles, "dotted")
+			}
+			if len(styles) > 0 {
+				attrs = append(attrs, "style="+dotQuote(strings.Join(styles, ",")))
+			}
+			if !r.Enabled {
+				attrs = append(attrs, "color=gray", "fontcolor=gray")
+			}
+
+			fmt.Fprintf(&b, "    %s [%s];\n", nodes[r.Helmfile+"\x00"+r.ID], strings.Join(attrs, ", "))
+		}
+
+		b.WriteString("  }\n")
+	}
+
+	for _, n := range g.Needs {
+		from, to := nodes[n.neededHelmfile()+"\x00"+n.Needs], nodes[n.Helmfile+"\x00"+n.Release]
+		if n.CrossContext {
+			fmt.Fprintf(&b, "  %s -> %s [style=dashed, label=\"cross-context\"];\n", from, to)
+		} else {
+			fmt.Fprintf(&b, "  %s -> %s;\n", from, to)
+		}
+	}
+
+	b.WriteString("}\n")
+
+	return b.String()
+}
+
+// Mermaid returns the graph as a Mermaid flowchart.
+// Edges point from the needed releases to the releases that need them, following the order of installation.
+func (g *ReleaseGraph) Mermaid() string {
+	var b strings.Builder
+
+	nodes := g.nodes()
+
+	b.WriteString("flowchart LR\n")
+
+	if len(g.Selectors) > 0 {
+		fmt.Fprintf(&b, "  %%%% selectors: %s\n", strings.Join(g.Selectors, " "))
+	}
+
+	classes := map[string][]string{}
+
+	for i, helmfile := range g.helmfiles() {
+		fmt.Fprintf(&b, "  subgraph h%d[%s]\n", i, mermaidQuote(helmfile))
+
+		for _, r := range g.Releases {
+			if r.Helmfile != helmfile {
+				continue
+			}
+
+			node := nodes[r.Helmfile+"\x00"+r.ID]
+
+			fmt.Fprintf(&b, "    %s[%s]\n", node, mermaidQuote(strings.Join(r.details(), "<br/>")))
+
+			if !r.Installed {
+				classes["notInstalled"] = append(classes["notInstalled"], node)
+			}
+			if !r.Enabled {
+				classes["disabled"] = append(classes["disabled"], node)
+			}
+			if !r.Selected {
+				classes["need"] = append(classes["need"], node)
+			}
+		}
+
+		b.WriteString("  end\n")
+	}
+
+	for _, n := range g.Needs {
+		from, to := nodes[n.neededHelmfile()+"\x00"+n.Needs], nodes[n.Helmfile+"\x00"+n.Release]
+		if n.CrossContext {
+			fmt.Fprintf(&b, "  %s -. cross-context .-> %s\n", from, to)
+		} else {
+			fmt.Fprintf(&b, "  %s --> %s\n", from, to)
+		}
+	}
+
+	for _, c := range []struct{ name, style string }{
+		{"notInstalled", "stroke-dasharray: 5 5"},
+		{"disabled", "fill:#eee,color:#999"},
+		{"need", "stroke-dasharray: 2 2"},
+	} {
+		if len(classes[c.name]) == 0 {
+			continue
+		}
+		fmt.Fprintf(&b, "  classDef %s %s\n", c.name, c.style)
+		fmt.Fprintf(&b, "  class %s %s\n", strings.Join(classes[c.name], ","), c.name)
+	}
+
+	return b.String()
+}
+
+// JSON returns the graph as indented JSON
+func (g *ReleaseGraph) JSON() ([]byte, error) {
+	out := *g
+	if out.Releases == nil {
+		out.Releases = []GraphRelease{}
+	}
+	if out.Needs == nil {
+		out.Needs = []GraphNeed{}
+	}
+
+	bs, err := json.MarshalIndent(out, "", "  ")
+	if err != nil {
+		return nil, fmt.Errorf("error generating json: %v", err)
+	}
+
+	return bs, nil
+}
+
+func dotQuote(s string) string {
+	s = strings.ReplaceAll(s, `\`, `\\`)
+	s = strings.ReplaceAll(s, `"`, `\"`)
+	s = strings.ReplaceAll(s, "\n", `\n`)
+	return `"` + s + `"`
+}
+
+func mermaidQuote(s string) string {
+	return `"` + strings.ReplaceAll(s, `"`, "#quot;") + `"`
+}
@@ -0,0 +1,157 @@
+package app
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+
+	"github.com/helmfile/helmfile/pkg/state"
+)
+
+func boolPtr(b bool) *bool {
+	return &b
+}
+
+func TestReleaseGraph_addBatches(t *testing.T) {
+	db := state.ReleaseSpec{Name: "db", Namespace: "default", Chart: "bitnami/postgresql"}
+	cache := state.ReleaseSpec{Name: "cache", Namespace: "default", KubeContext: "other", Chart: "bitnami/redis", Installed: boolPtr(false)}
+	app := state.ReleaseSpec{
+		Name:      "app",
+		Namespace: "default",
+		Chart:     "./app",
+		Condition: "app.enabled",
+		Labels:    map[string]string{"tier": "frontend"},
+		Needs:     []string{"default/db", "other/default/cache", "default/skipped"},
+	}
+
+	g := &ReleaseGraph{Selectors: []string{"tier=frontend"}}
+
+	batches := [][]state.Release{
+		{{ReleaseSpec: db}, {ReleaseSpec: cache}},
+		{{ReleaseSpec: app}},
+	}
+	values := map[string]any{"app": map[string]any{"enabled": false}}
+
+	if err := g.addBatches("helmfile.yaml", batches, []state.ReleaseSpec{app}, values); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	want := &ReleaseGraph{
+		Selectors: []string{"tier=frontend"},
+		Releases: []GraphRelease{
+			{ID: "default/db", Name: "db", Namespace: "default", Chart: "bitnami/postgresql", Helmfile: "helmfile.yaml", Batch: 1, Installed: true, Enabled: true},
+			{ID: "other/default/cache", Name: "cache", Namespace: "default", KubeContext: "other", Chart: "bitnami/redis", Helmfile: "helmfile.yaml", Batch: 1, Enabled: true},
+			{ID: "default/app", Name: "app", Namespace: "default", Chart: "./app", Labels: map[string]string{"tier": "frontend"}, Helmfile: "helmfile.yaml", Batch: 2, Installed: true, Condition: "app.enabled", Selected: true},
+		},
+		Needs: []GraphNeed{
+			{Helmfile: "helmfile.yaml", Release: "default/app", Needs: "default/db"},
+			{Helmfile: "helmfile.yaml", Release: "default/app", Needs: "other/default/cache", CrossContext: true},
+		},
+	}
+
+	if d := cmp.Diff(want, g); d != "" {
+		t.Errorf("unexpected graph: want (-), got (+):\n%s", d)
+	}
+}
+
+func testReleaseGraph() *ReleaseGraph {
+	return &ReleaseGraph{
+		Selectors: []string{"tier=frontend"},
+		Releases: []GraphRelease{
+			{ID: "default/db", Name: "db", Namespace: "default", Chart: "bitnami/postgresql", Helmfile: "helmfile.yaml", Batch: 1, Installed: true, Enabled: true},
+			{ID: "other/default/cache", Name: "cache", Namespace: "default", KubeContext: "other", Chart: "bitnami/redis", Helmfile: "helmfile.yaml", Batch: 1, Enabled: true},
+			{ID: "default/app", Name: "app", Namespace: "default", Chart: "./app", Helmfile: "helmfile.yaml", Batch: 2, Installed: true, Condition: "app.enabled", Selected: true},
+			{ID: "default/db", Name: "db", Namespace: "default", Chart: "./db", Helmfile: "helmfiles/\"quoted\".yaml", Batch: 1, Installed: true, Enabled: true, Selected: true},
+		},
+		Needs: []GraphNeed{
+			{Helmfile: "helmfile.yaml", Release: "default/app", Needs: "default/db"},
+			{Helmfile: "helmfile.yaml", Release: "default/app", Needs: "other/default/cache", CrossContext: true},
+		},
+	}
+}
+
+func TestReleaseGraph_DOT(t *testing.T) {
+	want := `digraph helmfile {
+  rankdir=LR;
+  node [shape=box];
+  labelloc=t;
+  label="selectors: tier=frontend";
+  subgraph cluster_0 {
+    label="helmfile.yaml";
+    n0 [label="default/db\nchart: bitnami/postgresql\nbatch 1\nincluded as a need", style="dotted"];
+    n1 [label="other/default/cache\nchart: bitnami/redis\nbatch 1\ninstalled: false\nincluded as a need", style="dashed,dotted"];
+    n2 [label="default/app\nchart: ./app\nbatch 2\ncondition: app.enabled (disabled)", color=gray, fontcolor=gray];
+  }
+  subgraph cluster_1 {
+    label="helmfiles/\"quoted\".yaml";
+    n3 [label="default/db\nchart: ./db\nbatch 1"];
+  }
+  n0 -> n2;
+  n1 -> n2 [style=dashed, label="cross-context"];
+}
+`
+
+	if d := cmp.Diff(want, testReleaseGraph().DOT()); d != "" {
+		t.Errorf("unexpected dot: want (-), got (+):\n%s", d)
+	}
+}
+
+func TestReleaseGraph_Mermaid(t *testing.T) {
+	want := `flowchart LR
+  %% selectors: tier=frontend
+  subgraph h0["helmfile.yaml"]
+    n0["default/db<br/>chart: bitnami/postgresql<br/>batch 1<br/>included as a need"]
+    n1["other/default/cache<br/>chart: bitnami/redis<br/>batch 1<br/>installed: false<br/>included as a need"]
+    n2["default/app<br/>chart: ./app<br/>batch 2<br/>condition: app.enabled (disabled)"]
+  end
+  subgraph h1["helmfiles/#quot;quoted#quot;.yaml"]
+    n3["default/db<br/>chart: ./db<br/>batch 1"]
+  end
+  n0 --> n2
+  n1 -. cross-context .-> n2
+  classDef notInstalled stroke-dasharray: 5 5
+  class n1 notInstalled
+  classDef disabled fill:#eee,color:#999
+  class n2 disabled
+  classDef need stroke-dasharray: 2 2
+  class n0,n1 need
+`
+
+	if d := cmp.Diff(want, testReleaseGraph().Mermaid()); d != "" {
+		t.Errorf("unexpected mermaid: want (-), got (+):\n%s", d)
+	}
+}
+
+func TestReleaseGraph_JSON(t *testing.T) {
+	g := &ReleaseGraph{
+		Releases: []GraphRelease{
+			{ID: "default/db", Name: "db", Namespace: "default", Chart: "./db", Helmfile: "helmfile.yaml", Batch: 1, Installed: true, Enabled: true, Selected: true},
+		},
+	}
+
+	want := `{
+  "releases": [
+    {
+      "id": "default/db",
+      "name": "db",
+      "namespace": "default",
+      "chart": "./db",
+      "helmfile": "helmfile.yaml",
+      "batch": 1,
+      "installed": true,
+      "enabled": true,
+      "selected": true
+    }
+  ],
+  "needs": []
+}`
+
+	bs, err := g.JSON()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if d := cmp.Diff(want, string(bs)); d != "" {
+		t.Errorf("unexpected json: want (-), got (+):\n%s", d)
+	}
+}
@@ -0,0 +1,77 @@
+package app
+
+import (
+	"fmt"
+	"sync"
+
+	"github.com/helmfile/helmfile/pkg/helmexec"
+	"github.com/helmfile/helmfile/pkg/state"
+)
+
+// releaseStatusQuery is a release listed with --with-status
+type releaseStatusQuery struct {
+	release     *HelmRelease
+	spec        state.ReleaseSpec
+	kubeContext string
+	helm        helmexec.Interface
+}
+
+// addReleaseStatuses lists the deployed releases once per kube context, concurrently,
+// and sets the status of each listed release
+func addReleaseStatuses(queries []releaseStatusQuery) error {
+	var kubeContexts []string
+
+	helms := map[string]helmexec.Interface{}
+	withDefault := map[string]bool{}
+	for _, q := range queries {
+		if _, ok := helms[q.kubeContext]; !ok {
+			helms[q.kubeContext] = q.helm
+			kubeContexts = append(kubeContexts, q.kubeContext)
+		}
+		if q.spec.Namespace == "" {
+			withDefault[q.kubeContext] = true
+		}
+	}
+
+	deployed := make([]*state.DeployedReleases, len(kubeContexts))
+	errs := make([]error, len(kubeContexts))
+
+	var wg sync.WaitGroup
+	for i := range kubeContexts {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			deployed[i], errs[i] = state.ListDeployedReleases(helms[kubeContexts[i]], kubeContexts[i], withDefault[kubeContexts[i]])
+		}(i)
+	}
+	wg.Wait()
+
+	deployedByKubeContext := map[string]*state.DeployedReleases{}
+	for i, kubeContext := range kubeContexts {
+		if errs[i] != nil {
+			if kubeContext == "" {
+				return fmt.Errorf("listing releases in the current kube context: %v", errs[i])
+			}
+			return fmt.Errorf("listing releases in kube context %q: %v", kubeContext, errs[i])
+		}
+		deployedByKubeContext[kubeContext] = deployed[i]
+	}
+
+	for _, q := range queries {
+		d, _ := state.FindDeployedRelease(&q.spec, deployedByKubeContext[q.kubeContext])
+
+		status := &HelmReleaseStatus{
+			OutOfDate: state.OutOfDate(&q.spec, d),
+		}
+		if d != nil {
+			status.DeployedVersion = d.ChartVersion(q.spec.Chart)
+			status.Revision = d.Revision
+			status.Status = d.Status
+			status.Updated = d.Updated
+		}
+
+		q.release.Status = status
+	}
+
+	return nil
+}
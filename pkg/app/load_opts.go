@@ -17,6 +17,11 @@ type LoadOpts struct {
 	Reverse bool
 
 	Filter bool
+
+	// IncludeNeeds and SkipNeeds are how the needs of the selected releases are handled
+	// when the releases of all the helmfiles are ordered with one DAG
+	IncludeNeeds bool
+	SkipNeeds    bool
 }
 
 func (o LoadOpts) DeepCopy() LoadOpts {
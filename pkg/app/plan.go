@@ -0,0 +1,182 @@
+package app
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/helmfile/helmfile/pkg/helmexec"
+	"github.com/helmfile/helmfile/pkg/state"
+)
+
+const (
+	PlanActionUpgrade = "upgrade"
+	PlanActionDelete  = "delete"
+	PlanActionNone    = "none"
+)
+
+// Plan is the set of changes computed by `helmfile diff --out-plan`.
+// `helmfile apply --plan` runs exactly this set of changes, as long as neither the helmfile state nor
+// the cluster has changed since the plan was written.
+type Plan struct {
+	Releases []PlannedRelease `json:"releases"`
+
+	mu      sync.Mutex
+	applied map[string]bool
+}
+
+// PlannedRelease is a release that was diffed while writing a plan, along with the action decided for it.
+type PlannedRelease struct {
+	state.ReleaseFingerprint
+
+	// Action is one of "upgrade", "delete" or "none"
+	Action string `json:"action"`
+}
+
+// addReleases records the fingerprints of all the releases in the state along with the action decided by Run.diff.
+func (p *Plan) addReleases(st *state.HelmState, helm helmexec.Interface, updated, deleted map[string]state.ReleaseSpec) error {
+	for i := range st.Releases {
+		release := st.Releases[i]
+
+		fp, err := st.FingerprintRelease(helm, &release)
+		if err != nil {
+			return fmt.Errorf("planning release %q: %w", release.Name, err)
+		}
+
+		action := PlanActionNone
+		if _, ok := updated[fp.ID]; ok {
+			action = PlanActionUpgrade
+		} else if _, ok := deleted[fp.ID]; ok {
+			action = PlanActionDelete
+		}
+
+		p.mu.Lock()
+		p.Releases = append(p.Releases, PlannedRelease{ReleaseFingerprint: *fp, Action: action})
+		p.mu.Unlock()
+	}
+
+	return nil
+}
+
+func (p *Plan) get(id string) *PlannedRelease {
+	for i := range p.Releases {
+		if p.Releases[i].ID == id {
+			return &p.Releases[i]
+		}
+	}
+	return nil
+}
+
+func (p *Plan) markApplied(id string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.applied == nil {
+		p.applied = map[string]bool{}
+	}
+	p.applied[id] = true
+}
+
+// unapplied returns the IDs of the planned releases that were not found in any of the processed helmfiles.
+func (p *Plan) unapplied() []string {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	var ids []string
+	for _, r := range p.Releases {
+		if !p.applied[r.ID] {
+			ids = append(ids, r.ID)
+		}
+	}
+	sort.Strings(ids)
+
+	return ids
+}
+
+func (p *Plan) write(path string) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	sort.SliceStable(p.Releases, func(i, j int) bool {
+		return p.Releases[i].ID < p.Releases[j].ID
+	})
+
+	bs, err := json.MarshalIndent(p, "", "  ")
+	if err != nil {
+		return fmt.Errorf("error generating plan: %v", err)
+	}
+
+	return os.WriteFile(path, append(bs, '\n'), 0644)
+}
+
+func (a *App) readPlan(path string) (*Plan, error) {
+	bs, err := a.fs.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("reading plan %s: %v", path, err)
+	}
+
+	var p Plan
+	if err := json.Unmarshal(bs, &p); err != nil {
+		return nil, fmt.Errorf("parsing plan %s: %v", path, err)
+	}
+
+	return &p, nil
+}
+
+// planned is the counterpart of diff that reads the releases to be updated and deleted from the plan instead of
+// running helm-diff. It fails when any release in the state differs from the time the plan was written.
+func (r *Run) planned(plan *Plan) (*string, map[string]state.ReleaseSpec, map[string]state.ReleaseSpec, []error) {
+	st := r.state
+	helm := r.helm
+
+	releasesToBeUpdated := map[string]state.ReleaseSpec{}
+	releasesToBeDeleted := map[string]state.ReleaseSpec{}
+
+	var errs []error
+
+	for i := range st.Releases {
+		release := st.Releases[i]
+		id := state.ReleaseToID(&release)
+
+		planned := plan.get(id)
+		if planned == nil {
+			errs = append(errs, fmt.Errorf("release %q is not part of the plan. Please re-run `helmfile diff --out-plan` with the same selectors", id))
+			continue
+		}
+
+		fp, err := st.FingerprintRelease(helm, &release)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+
+		if diffs := planned.ReleaseFingerprint.Diff(*fp); len(diffs) > 0 {
+			errs = append(errs, fmt.Errorf("release %q has changed since the plan was written: %s", id, strings.Join(diffs, ", ")))
+			continue
+		}
+
+		plan.markApplied(id)
+
+		switch planned.Action {
+		case PlanActionUpgrade:
+			releasesToBeUpdated[id] = release
+		case PlanActionDelete:
+			releasesToBeDeleted[id] = release
+		}
+	}
+
+	if len(errs) > 0 {
+		return nil, nil, nil, errs
+	}
+
+	if len(releasesToBeUpdated) == 0 && len(releasesToBeDeleted) == 0 {
+		return nil, nil, nil, nil
+	}
+
+	infoMsg := affectedReleasesMessage(releasesToBeUpdated, releasesToBeDeleted)
+
+	return &infoMsg, releasesToBeUpdated, releasesToBeDeleted, nil
+}
@@ -0,0 +1,214 @@
+package app
+
+import (
+	"encoding/json"
+	"io"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/helmfile/vals"
+	"github.com/stretchr/testify/require"
+	"helm.sh/helm/v3/pkg/chart"
+
+	"github.com/helmfile/helmfile/pkg/exectest"
+	"github.com/helmfile/helmfile/pkg/filesystem"
+	"github.com/helmfile/helmfile/pkg/helmexec"
+)
+
+func TestDiffOutPlanAndApplyPlan(t *testing.T) {
+	helmfile := `
+releases:
+- name: foo
+  chart: incubator/raw
+  namespace: default
+  set:
+  - name: replicas
+    value: 2
+- name: bar
+  chart: incubator/raw
+  namespace: default
+- name: baz
+  chart: incubator/raw
+  namespace: default
+  installed: false
+`
+
+	diffs := map[exectest.DiffKey]error{
+		{Name: "foo", Chart: "incubator/raw", Flags: "--kube-context default --namespace default --set replicas=2 --detailed-exitcode --reset-values"}: helmexec.ExitError{Code: 2},
+		{Name: "bar", Chart: "incubator/raw", Flags: "--kube-context default --namespace default --detailed-exitcode --reset-values"}:                  nil,
+	}
+
+	listsAt := func(fooRevision string) map[exectest.ListKey]string {
+		return map[exectest.ListKey]string{
+			{Filter: "^foo$", Flags: listFlags("default", "default")}: `NAME	REVISION	UPDATED                 	STATUS  	CHART        	APP VERSION	NAMESPACE
+foo 	` + fooRevision + `       	Fri Nov  1 08:40:07 2019	DEPLOYED	raw-3.1.0	3.1.0      	default
+`,
+			{Filter: "^bar$", Flags: listFlags("default", "default")}: `NAME	REVISION	UPDATED                 	STATUS  	CHART        	APP VERSION	NAMESPACE
+bar 	2       	Fri Nov  1 08:40:07 2019	DEPLOYED	raw-3.1.0	3.1.0      	default
+`,
+			{Filter: "^baz$", Flags: listFlags("default", "default")}: `NAME	REVISION	UPDATED                 	STATUS  	CHART        	APP VERSION	NAMESPACE
+baz 	7       	Fri Nov  1 08:40:07 2019	DEPLOYED	raw-3.1.0	3.1.0      	default
+`,
+		}
+	}
+
+	newApp := func(t *testing.T, helm *exectest.Helm, files map[string]string) *App {
+		t.Helper()
+
+		valsRuntime, err := vals.New(vals.Options{CacheSize: 32})
+		require.NoError(t, err)
+
+		return appWithFs(&App{
+			OverrideHelmBinary:  DefaultHelmBinary,
+			fs:                  filesystem.DefaultFileSystem(),
+			OverrideKubeContext: "default",
+			Env:                 "default",
+			Logger:              helmexec.NewLogger(io.Discard, "debug"),
+			helms: map[helmKey]helmexec.Interface{
+				createHelmKey("helm", "default"): helm,
+			},
+			valsRuntime: valsRuntime,
+		}, files)
+	}
+
+	// rawVersion is the newest version of incubator/raw, which the releases without versions are installed with
+	newHelm := func(lists map[exectest.ListKey]string, diffs map[exectest.DiffKey]error, rawVersion string) *exectest.Helm {
+		return &exectest.Helm{
+			FailOnUnexpectedList: true,
+			FailOnUnexpectedDiff: true,
+			Lists:                lists,
+			Diffs:                diffs,
+			ChartMetadata: map[string]chart.Metadata{
+				"incubator/raw": {Name: "raw", Version: rawVersion},
+			},
+			DiffMutex:     &sync.Mutex{},
+			ChartsMutex:   &sync.Mutex{},
+			ReleasesMutex: &sync.Mutex{},
+			Helm3:         true,
+		}
+	}
+
+	planFile := filepath.Join(t.TempDir(), "plan.json")
+
+	err := newApp(t, newHelm(listsAt("4"), diffs, "0.2.5"), map[string]string{
+		"/path/to/helmfile.yaml": helmfile,
+	}).Diff(diffConfig{
+		concurrency: 1,
+		outPlan:     planFile,
+		logger:      helmexec.NewLogger(io.Discard, "debug"),
+	})
+	require.NoError(t, err)
+
+	planData, err := os.ReadFile(planFile)
+	require.NoError(t, err)
+
+	var plan Plan
+	require.NoError(t, json.Unmarshal(planData, &plan))
+
+	actions := map[string]string{}
+	revisions := map[string]string{}
+	for _, r := range plan.Releases {
+		actions[r.ID] = r.Action
+		revisions[r.ID] = r.Revision
+		require.NotEmpty(t, r.ValuesHash, "values hash of %s", r.ID)
+		require.Equal(t, "0.2.5", r.Version, "chart version of %s", r.ID)
+	}
+
+	if d := cmp.Diff(map[string]string{
+		"default/default/foo": PlanActionUpgrade,
+		"default/default/bar": PlanActionNone,
+		"default/default/baz": PlanActionDelete,
+	}, actions); d != "" {
+		t.Fatalf("unexpected actions: want (-), got (+): %s", d)
+	}
+
+	if d := cmp.Diff(map[string]string{
+		"default/default/foo": "4",
+		"default/default/bar": "2",
+		"default/default/baz": "7",
+	}, revisions); d != "" {
+		t.Fatalf("unexpected revisions: want (-), got (+): %s", d)
+	}
+
+	t.Run("apply runs exactly the plan without diffing", func(t *testing.T) {
+		// Any call to helm-diff fails as there is no expected diff
+		helm := newHelm(listsAt("4"), map[exectest.DiffKey]error{}, "0.2.5")
+
+		err := newApp(t, helm, map[string]string{
+			"/path/to/helmfile.yaml": helmfile,
+			planFile:                 string(planData),
+		}).Apply(applyConfig{
+			concurrency: 1,
+			plan:        planFile,
+			logger:      helmexec.NewLogger(io.Discard, "debug"),
+		})
+		require.NoError(t, err)
+
+		require.Empty(t, helm.Diffed)
+		require.Len(t, helm.Releases, 1)
+		require.Equal(t, "foo", helm.Releases[0].Name)
+		require.Len(t, helm.Deleted, 1)
+		require.Equal(t, "baz", helm.Deleted[0].Name)
+	})
+
+	t.Run("apply refuses to run when the cluster has changed", func(t *testing.T) {
+		helm := newHelm(listsAt("5"), map[exectest.DiffKey]error{}, "0.2.5")
+
+		err := newApp(t, helm, map[string]string{
+			"/path/to/helmfile.yaml": helmfile,
+			planFile:                 string(planData),
+		}).Apply(applyConfig{
+			concurrency: 1,
+			plan:        planFile,
+			logger:      helmexec.NewLogger(io.Discard, "debug"),
+		})
+		require.Error(t, err)
+		require.Contains(t, err.Error(), `release "default/default/foo" has changed since the plan was written: deployed revision changed from "4" to "5"`)
+
+		require.Empty(t, helm.Releases)
+		require.Empty(t, helm.Deleted)
+	})
+
+	t.Run("apply refuses to run when a newer chart was published", func(t *testing.T) {
+		helm := newHelm(listsAt("4"), map[exectest.DiffKey]error{}, "0.2.6")
+
+		err := newApp(t, helm, map[string]string{
+			"/path/to/helmfile.yaml": helmfile,
+			planFile:                 string(planData),
+		}).Apply(applyConfig{
+			concurrency: 1,
+			plan:        planFile,
+			logger:      helmexec.NewLogger(io.Discard, "debug"),
+		})
+		require.Error(t, err)
+		require.Contains(t, err.Error(), `release "default/default/foo" has changed since the plan was written: chart version changed from "0.2.5" to "0.2.6"`)
+
+		require.Empty(t, helm.Releases)
+		require.Empty(t, helm.Deleted)
+	})
+
+	t.Run("apply refuses to run when the state has changed", func(t *testing.T) {
+		helm := newHelm(listsAt("4"), map[exectest.DiffKey]error{}, "0.2.5")
+
+		err := newApp(t, helm, map[string]string{
+			"/path/to/helmfile.yaml": helmfile + `
+- name: qux
+  chart: incubator/raw
+  namespace: default
+`,
+			planFile: string(planData),
+		}).Apply(applyConfig{
+			concurrency: 1,
+			plan:        planFile,
+			logger:      helmexec.NewLogger(io.Discard, "debug"),
+		})
+		require.Error(t, err)
+		require.Contains(t, err.Error(), `release "default/default/qux" is not part of the plan`)
+
+		require.Empty(t, helm.Releases)
+		require.Empty(t, helm.Deleted)
+	})
+}
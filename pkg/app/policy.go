@@ -0,0 +1,105 @@
+package app
+
+import (
+	"fmt"
+	"path/filepath"
+
+	"github.com/helmfile/helmfile/pkg/policy"
+)
+
+// defaultPolicyFile returns the policy file next to the helmfile, or in the helmfile.d directory
+func (a *App) defaultPolicyFile() string {
+	switch {
+	case a.FileOrDir == "" || a.FileOrDir == "-":
+		return policy.DefaultFile
+	case a.fs.DirectoryExistsAt(a.FileOrDir):
+		return filepath.Join(a.FileOrDir, policy.DefaultFile)
+	}
+
+	return filepath.Join(filepath.Dir(a.FileOrDir), policy.DefaultFile)
+}
+
+// loadPolicy loads the policy file. It returns nil when no file is given and the default one doesn't exist
+func (a *App) loadPolicy(file string) (*policy.Policy, error) {
+	if file == "" {
+		file = a.defaultPolicyFile()
+		if !a.fs.FileExistsAt(file) {
+			return nil, nil
+		}
+	}
+
+	return policy.Load(a.fs, file)
+}
+
+// checkPolicy returns the violations of the policy by the selected releases of all the helmfiles
+func (a *App) checkPolicy(p *policy.Policy) ([]policy.Violation, error) {
+	var violations []policy.Violation
+
+	err := a.ForEachState(func(run *Run) (bool, []error) {
+		vs, err := p.Check(run.state)
+		if err != nil {
+			return false, []error{err}
+		}
+
+		violations = append(violations, vs...)
+
+		return true, nil
+	}, false, SetFilter(true))
+
+	return violations, err
+}
+
+// enforcePolicy fails when the selected releases violate the rules of the policy with the error severity,
+// and warns about the other violations. It does nothing when there's no policy file.
+func (a *App) enforcePolicy(file string) error {
+	p, err := a.loadPolicy(file)
+	if err != nil {
+		return appError("", err)
+	}
+	if p == nil {
+		return nil
+	}
+
+	violations, err := a.checkPolicy(p)
+	if err != nil {
+		return err
+	}
+
+	for _, v := range violations {
+		if v.Severity != policy.SeverityError {
+			a.Logger.Warnf("%s", v)
+		}
+	}
+
+	if errs := policy.Errors(violations); len(errs) > 0 {
+		return appError("", &policy.ViolationsError{Violations: errs})
+	}
+
+	return nil
+}
+
+// PolicyCheck prints the violations of the policy by the selected releases, and fails when any of them has the error severity
+func (a *App) PolicyCheck(c PolicyConfigProvider) error {
+	p, err := a.loadPolicy(c.PolicyFile())
+	if err != nil {
+		return appError("", err)
+	}
+	if p == nil {
+		return appError("", fmt.Errorf("no policy file found: %s doesn't exist", a.defaultPolicyFile()))
+	}
+
+	violations, err := a.checkPolicy(p)
+	if err != nil {
+		return err
+	}
+
+	for _, v := range violations {
+		fmt.Println(v)
+	}
+
+	if errs := policy.Errors(violations); len(errs) > 0 {
+		return appError("", fmt.Errorf("%d of %d policy violation(s) have the error severity", len(errs), len(violations)))
+	}
+
+	return nil
+}
@@ -0,0 +1,97 @@
+package app
+
+import (
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/helmfile/vals"
+	"go.uber.org/zap"
+
+	"github.com/helmfile/helmfile/pkg/exectest"
+	ffs "github.com/helmfile/helmfile/pkg/filesystem"
+	"github.com/helmfile/helmfile/pkg/helmexec"
+)
+
+func TestApply_PolicyViolations(t *testing.T) {
+	files := map[string]string{
+		"/path/to/helmfile.yaml": `
+releases:
+- name: app
+  chart: incubator/raw
+  namespace: default
+- name: db
+  chart: incubator/raw
+  namespace: default
+  version: 0.2.5
+  labels:
+    team: data
+`,
+		"/path/to/.helmfile-policy.yaml": `
+rules:
+- name: pinned-version
+  builtin: pinned-version
+- name: team-label
+  builtin: required-labels
+  severity: warning
+  labels: [team]
+`,
+	}
+
+	helm := &exectest.Helm{
+		FailOnUnexpectedList: true,
+		FailOnUnexpectedDiff: true,
+		DiffMutex:            &sync.Mutex{},
+		ChartsMutex:          &sync.Mutex{},
+		ReleasesMutex:        &sync.Mutex{},
+		Helm3:                true,
+	}
+
+	var applyErr error
+
+	logs := runWithLogCapture(t, "debug", func(t *testing.T, logger *zap.SugaredLogger) {
+		t.Helper()
+
+		valsRuntime, err := vals.New(vals.Options{CacheSize: 32})
+		if err != nil {
+			t.Errorf("unexpected error creating vals runtime: %v", err)
+		}
+
+		app := appWithFs(&App{
+			OverrideHelmBinary:  DefaultHelmBinary,
+			fs:                  ffs.DefaultFileSystem(),
+			OverrideKubeContext: "default",
+			Env:                 "default",
+			Logger:              logger,
+			helms: map[helmKey]helmexec.Interface{
+				createHelmKey("helm", "default"): helm,
+			},
+			valsRuntime: valsRuntime,
+		}, files)
+
+		applyErr = app.Apply(applyConfig{
+			concurrency: 1,
+			logger:      logger,
+		})
+	})
+
+	if applyErr == nil {
+		t.Fatal("expected an error due to the policy violations")
+	}
+
+	if !strings.Contains(applyErr.Error(), `error: pinned-version: release "default/default/app"`) {
+		t.Errorf("unexpected error: %v", applyErr)
+	}
+
+	if strings.Contains(applyErr.Error(), "team-label") {
+		t.Errorf("unexpected warning in the error: %v", applyErr)
+	}
+
+	if !strings.Contains(logs.String(), `warning: team-label: release "default/default/app"`) {
+		t.Errorf("missing warning in the logs: %s", logs.String())
+	}
+
+	if len(helm.Releases) > 0 || len(helm.Diffed) > 0 {
+		t.Errorf("unexpected releases applied: %v", helm.Releases)
+	}
+}
@@ -0,0 +1,72 @@
+package app
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"sync"
+
+	"github.com/helmfile/helmfile/pkg/state"
+)
+
+// Report is the machine-readable result of sync, apply or destroy, written to the file given by `--report-file`.
+type Report struct {
+	Releases []state.ReleaseReport `json:"releases"`
+
+	mu sync.Mutex
+}
+
+// newReport returns a Report to be written to path, or nil when path is empty.
+func newReport(path string) *Report {
+	if path == "" {
+		return nil
+	}
+
+	return &Report{Releases: []state.ReleaseReport{}}
+}
+
+// add records the releases processed in a helmfile. It does nothing on a nil Report.
+func (r *Report) add(affected *state.AffectedReleases, releases []state.ReleaseSpec, unchanged map[string]state.ReleaseSpec, errs []error) {
+	if r == nil {
+		return
+	}
+
+	reports := affected.Report(releases, unchanged, errs)
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.Releases = append(r.Releases, reports...)
+}
+
+func (r *Report) write(path string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	bs, err := json.MarshalIndent(r, "", "  ")
+	if err != nil {
+		return fmt.Errorf("error generating report: %v", err)
+	}
+
+	return os.WriteFile(path, append(bs, '\n'), 0644)
+}
+
+// writeReport writes the report even when the command has failed, as the report is most useful on failures.
+// err is the error returned by the command, which takes precedence over the error on writing the report.
+func (a *App) writeReport(report *Report, path string, err error) error {
+	if report == nil {
+		return err
+	}
+
+	if werr := report.write(path); werr != nil {
+		if err != nil {
+			a.Logger.Warnf("failed to write report to %s: %v", path, werr)
+			return err
+		}
+		return appError("writing report", werr)
+	}
+
+	a.Logger.Infof("Report written to %s", path)
+
+	return err
+}
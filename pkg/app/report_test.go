@@ -0,0 +1,97 @@
+package app
+
+import (
+	"encoding/json"
+	"io"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+
+	"github.com/helmfile/vals"
+	"github.com/stretchr/testify/require"
+
+	"github.com/helmfile/helmfile/pkg/exectest"
+	ffs "github.com/helmfile/helmfile/pkg/filesystem"
+	"github.com/helmfile/helmfile/pkg/helmexec"
+	"github.com/helmfile/helmfile/pkg/state"
+)
+
+func TestSyncReportFile(t *testing.T) {
+	files := map[string]string{
+		"/path/to/helmfile.yaml": `
+releases:
+- name: database
+  chart: charts/mysql
+  version: 1.2.3
+- name: error-backend
+  chart: charts/backend
+  needs:
+  - database
+- name: frontend
+  chart: charts/frontend
+  needs:
+  - error-backend
+`,
+	}
+
+	helm := &exectest.Helm{
+		Helm3:         true,
+		DiffMutex:     &sync.Mutex{},
+		ChartsMutex:   &sync.Mutex{},
+		ReleasesMutex: &sync.Mutex{},
+	}
+
+	valsRuntime, err := vals.New(vals.Options{CacheSize: 32})
+	require.NoError(t, err)
+
+	logger := helmexec.NewLogger(io.Discard, "debug")
+
+	app := appWithFs(&App{
+		OverrideHelmBinary:  DefaultHelmBinary,
+		fs:                  ffs.DefaultFileSystem(),
+		OverrideKubeContext: "default",
+		Env:                 "default",
+		Logger:              logger,
+		helms: map[helmKey]helmexec.Interface{
+			createHelmKey("helm", "default"): helm,
+		},
+		valsRuntime: valsRuntime,
+	}, files)
+
+	reportFile := filepath.Join(t.TempDir(), "report.json")
+
+	err = app.Sync(applyConfig{
+		concurrency: 1,
+		reportFile:  reportFile,
+		logger:      logger,
+	})
+	require.Error(t, err)
+
+	bs, err := os.ReadFile(reportFile)
+	require.NoError(t, err)
+
+	var report Report
+	require.NoError(t, json.Unmarshal(bs, &report))
+
+	outcomes := map[string]string{}
+	for _, r := range report.Releases {
+		outcomes[r.ID] = r.Outcome
+	}
+
+	require.Equal(t, map[string]string{
+		"default//database":      state.ReleaseOutcomeUpgraded,
+		"default//error-backend": state.ReleaseOutcomeFailed,
+		"default//frontend":      state.ReleaseOutcomeSkipped,
+	}, outcomes)
+
+	for _, r := range report.Releases {
+		switch r.ID {
+		case "default//database":
+			require.Equal(t, "1.2.3", r.RequestedVersion)
+		case "default//error-backend":
+			require.Equal(t, "failed processing release error-backend: error", r.Error)
+			require.Equal(t, 1, r.ExitCode)
+		}
+	}
+}
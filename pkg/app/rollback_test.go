@@ -0,0 +1,243 @@
+package app
+
+import (
+	"fmt"
+	"io"
+	"sync"
+	"testing"
+
+	"github.com/helmfile/vals"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+
+	"github.com/helmfile/helmfile/pkg/exectest"
+	ffs "github.com/helmfile/helmfile/pkg/filesystem"
+	"github.com/helmfile/helmfile/pkg/helmexec"
+)
+
+type rollbackConfig struct {
+	args        string
+	toRevision  int
+	concurrency int
+	interactive bool
+	logger      *zap.SugaredLogger
+}
+
+func (r rollbackConfig) Args() string {
+	return r.args
+}
+
+func (r rollbackConfig) ToRevision() int {
+	return r.toRevision
+}
+
+func (r rollbackConfig) Interactive() bool {
+	return r.interactive
+}
+
+func (r rollbackConfig) Logger() *zap.SugaredLogger {
+	return r.logger
+}
+
+func (r rollbackConfig) Concurrency() int {
+	return r.concurrency
+}
+
+func TestRollback(t *testing.T) {
+	files := map[string]string{
+		"/path/to/helmfile.yaml": `
+releases:
+- name: frontend
+  chart: charts/frontend
+  needs:
+  - backend
+- name: backend
+  chart: charts/backend
+  needs:
+  - database
+- name: database
+  chart: charts/mysql
+- name: logging
+  chart: charts/fluent-bit
+`,
+	}
+
+	lists := map[exectest.ListKey]string{
+		{Filter: "^frontend$", Flags: listFlags("", "default")}: `NAME	REVISION	UPDATED                 	STATUS  	CHART        	APP VERSION	NAMESPACE
+frontend 	4       	Fri Nov  1 08:40:07 2019	DEPLOYED	frontend-3.1.0	3.1.0      	default
+`,
+		{Filter: "^backend$", Flags: listFlags("", "default")}: `NAME	REVISION	UPDATED                 	STATUS  	CHART        	APP VERSION	NAMESPACE
+backend 	4       	Fri Nov  1 08:40:07 2019	DEPLOYED	backend-3.1.0	3.1.0      	default
+`,
+		{Filter: "^database$", Flags: listFlags("", "default")}: `NAME	REVISION	UPDATED                 	STATUS  	CHART        	APP VERSION	NAMESPACE
+database 	4       	Fri Nov  1 08:40:07 2019	DEPLOYED	mysql-3.1.0	3.1.0      	default
+`,
+		// helm-list prints nothing for the release that is not installed, as helmexec strips the header
+		{Filter: "^logging$", Flags: listFlags("", "default")}: ``,
+	}
+
+	run := func(t *testing.T, selectors []string, c rollbackConfig) *exectest.Helm {
+		t.Helper()
+
+		helm := &exectest.Helm{
+			Helm3:                true,
+			FailOnUnexpectedList: true,
+			FailOnUnexpectedDiff: true,
+			Lists:                lists,
+			DiffMutex:            &sync.Mutex{},
+			ChartsMutex:          &sync.Mutex{},
+			ReleasesMutex:        &sync.Mutex{},
+		}
+
+		valsRuntime, err := vals.New(vals.Options{CacheSize: 32})
+		require.NoError(t, err)
+
+		logger := helmexec.NewLogger(io.Discard, "debug")
+
+		app := appWithFs(&App{
+			OverrideHelmBinary:  DefaultHelmBinary,
+			fs:                  ffs.DefaultFileSystem(),
+			OverrideKubeContext: "default",
+			Env:                 "default",
+			Logger:              logger,
+			Selectors:           selectors,
+			helms: map[helmKey]helmexec.Interface{
+				createHelmKey("helm", "default"): helm,
+			},
+			valsRuntime: valsRuntime,
+		}, files)
+
+		c.logger = logger
+
+		require.NoError(t, app.Rollback(c))
+
+		return helm
+	}
+
+	t.Run("rolls back installed releases in the reverse order of needs", func(t *testing.T) {
+		helm := run(t, nil, rollbackConfig{concurrency: 1})
+
+		require.Equal(t, []exectest.Release{
+			{Name: "frontend", Flags: []string{"--kube-context", "default"}},
+			{Name: "backend", Flags: []string{"--kube-context", "default"}},
+			{Name: "database", Flags: []string{"--kube-context", "default"}},
+		}, helm.RolledBack)
+	})
+
+	t.Run("rolls back to the revision", func(t *testing.T) {
+		helm := run(t, []string{"name=backend"}, rollbackConfig{concurrency: 1, toRevision: 2})
+
+		require.Equal(t, []exectest.Release{
+			{Name: "backend", Flags: []string{"2", "--kube-context", "default"}},
+		}, helm.RolledBack)
+	})
+}
+
+// upgradingHelm bumps the deployed revision of each release it syncs, so that
+// the revisions listed before and after an apply differ like they do in a real cluster.
+type upgradingHelm struct {
+	*exectest.Helm
+
+	mu        sync.Mutex
+	revisions map[string]int
+}
+
+func (helm *upgradingHelm) SyncRelease(context helmexec.HelmContext, name, chart string, flags ...string) error {
+	if err := helm.Helm.SyncRelease(context, name, chart, flags...); err != nil {
+		return err
+	}
+
+	helm.mu.Lock()
+	defer helm.mu.Unlock()
+
+	helm.revisions[name]++
+
+	return nil
+}
+
+func (helm *upgradingHelm) List(context helmexec.HelmContext, filter string, flags ...string) (string, error) {
+	helm.mu.Lock()
+	defer helm.mu.Unlock()
+
+	for name, revision := range helm.revisions {
+		if filter == "^"+name+"$" && revision > 0 {
+			return fmt.Sprintf("%s\tdefault\t%d\t2019-11-01 08:40:07.000 +0000 UTC\tdeployed\t%s-3.1.0\t3.1.0\n", name, revision, name), nil
+		}
+	}
+
+	return "", nil
+}
+
+func TestApplyRollbackOnFailure(t *testing.T) {
+	files := map[string]string{
+		"/path/to/helmfile.yaml": `
+releases:
+- name: database
+  chart: charts/mysql
+- name: backend
+  chart: charts/backend
+  needs:
+  - database
+- name: error-frontend
+  chart: charts/frontend
+  needs:
+  - backend
+- name: logging
+  chart: charts/fluent-bit
+`,
+	}
+
+	helm := &upgradingHelm{
+		Helm: &exectest.Helm{
+			Helm3:                true,
+			FailOnUnexpectedDiff: true,
+			Diffs: map[exectest.DiffKey]error{
+				{Name: "database", Chart: "charts/mysql", Flags: "--kube-context default --detailed-exitcode --reset-values"}:          helmexec.ExitError{Code: 2},
+				{Name: "backend", Chart: "charts/backend", Flags: "--kube-context default --detailed-exitcode --reset-values"}:         helmexec.ExitError{Code: 2},
+				{Name: "error-frontend", Chart: "charts/frontend", Flags: "--kube-context default --detailed-exitcode --reset-values"}: helmexec.ExitError{Code: 2},
+				{Name: "logging", Chart: "charts/fluent-bit", Flags: "--kube-context default --detailed-exitcode --reset-values"}:      nil,
+			},
+			DiffMutex:     &sync.Mutex{},
+			ChartsMutex:   &sync.Mutex{},
+			ReleasesMutex: &sync.Mutex{},
+		},
+		// backend is going to be freshly installed
+		revisions: map[string]int{
+			"database":       3,
+			"error-frontend": 5,
+			"logging":        1,
+		},
+	}
+
+	valsRuntime, err := vals.New(vals.Options{CacheSize: 32})
+	require.NoError(t, err)
+
+	logger := helmexec.NewLogger(io.Discard, "debug")
+
+	app := appWithFs(&App{
+		OverrideHelmBinary:  DefaultHelmBinary,
+		fs:                  ffs.DefaultFileSystem(),
+		OverrideKubeContext: "default",
+		Env:                 "default",
+		Logger:              logger,
+		helms: map[helmKey]helmexec.Interface{
+			createHelmKey("helm", "default"): helm,
+		},
+		valsRuntime: valsRuntime,
+	}, files)
+
+	err = app.Apply(applyConfig{
+		concurrency:       1,
+		rollbackOnFailure: true,
+		logger:            logger,
+	})
+	require.Error(t, err)
+
+	// error-frontend failed without creating a new revision, so it is left as is
+	require.Equal(t, []exectest.Release{
+		{Name: "database", Flags: []string{"3", "--kube-context", "default"}},
+	}, helm.RolledBack)
+	require.Equal(t, []exectest.Release{
+		{Name: "backend", Flags: []string{"--kube-context", "default"}},
+	}, helm.Deleted)
+}
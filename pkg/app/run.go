@@ -106,7 +106,13 @@ func (r *Run) withPreparedCharts(helmfileCommand string, opts state.ChartPrepare
 func (r *Run) Deps(c DepsConfigProvider) []error {
 	r.helm.SetExtraArgs(GetArgs(c.Args(), r.state)...)
 
-	return r.state.UpdateDeps(r.helm, c.IncludeTransitiveNeeds())
+	opts := &state.UpdateDepsOpts{
+		UpdatePolicy:       c.UpdateStrategy(),
+		DryRun:             c.DryRun(),
+		AllowDigestChanges: c.AllowDigestChanges(),
+	}
+
+	return r.state.UpdateDeps(r.helm, c.IncludeTransitiveNeeds(), opts)
 }
 
 func (r *Run) Repos(c ReposConfigProvider) error {
@@ -191,6 +197,12 @@ func (r *Run) diff(triggerCleanupEvent bool, detailedExitCode bool, c DiffConfig
 		return msg, nil, nil, nil
 	}
 
+	infoMsg := affectedReleasesMessage(releasesToBeUpdated, releasesToBeDeleted)
+
+	return &infoMsg, releasesToBeUpdated, releasesToBeDeleted, nil
+}
+
+func affectedReleasesMessage(releasesToBeUpdated, releasesToBeDeleted map[string]state.ReleaseSpec) string {
 	names := []string{}
 	for _, r := range releasesToBeUpdated {
 		names = append(names, fmt.Sprintf("  %s (%s) UPDATED", r.Name, r.Chart))
@@ -201,9 +213,7 @@ func (r *Run) diff(triggerCleanupEvent bool, detailedExitCode bool, c DiffConfig
 	// Make the output deterministic for testing purpose
 	sort.Strings(names)
 
-	infoMsg := fmt.Sprintf(`Affected releases are:
+	return fmt.Sprintf(`Affected releases are:
 %s
 `, strings.Join(names, "\n"))
-
-	return &infoMsg, releasesToBeUpdated, releasesToBeDeleted, nil
 }
@@ -60,6 +60,14 @@ type ApplyOptions struct {
 	PostRenderer string
 	// Cascade '--cascade' to helmv3 delete, available values: background, foreground, or orphan, default: background
 	Cascade string
+	// Plan is the path to the plan file written by `helmfile diff --out-plan`
+	Plan string
+	// RollbackOnFailure is true if the releases upgraded or installed in this run should be rolled back or deleted when any release fails
+	RollbackOnFailure bool
+	// ReportFile is the path to write the JSON report of the processed releases to
+	ReportFile string
+	// PolicyFile is the path to the policy file the releases are checked against before being applied
+	PolicyFile string
 }
 
 // NewApply creates a new Apply
@@ -226,3 +234,28 @@ func (a *ApplyImpl) PostRenderer() string {
 func (a *ApplyImpl) Cascade() string {
 	return a.ApplyOptions.Cascade
 }
+
+// Plan returns the path to the plan file to be applied.
+func (a *ApplyImpl) Plan() string {
+	return a.ApplyOptions.Plan
+}
+
+// OutPlan returns an empty string as apply never writes a plan
+func (a *ApplyImpl) OutPlan() string {
+	return ""
+}
+
+// RollbackOnFailure returns the rollback on failure flag.
+func (a *ApplyImpl) RollbackOnFailure() bool {
+	return a.ApplyOptions.RollbackOnFailure
+}
+
+// ReportFile returns the path to write the report to.
+func (a *ApplyImpl) ReportFile() string {
+	return a.ApplyOptions.ReportFile
+}
+
+// PolicyFile returns the path to the policy file.
+func (a *ApplyImpl) PolicyFile() string {
+	return a.ApplyOptions.PolicyFile
+}
@@ -0,0 +1,38 @@
+package config
+
+// CheckVersionsOptions is the options for the check-versions command
+type CheckVersionsOptions struct {
+	// SkipRepos is true to look up the chart versions without updating the repositories
+	SkipRepos bool
+	// Output is the output format of the version report, table or json
+	Output string
+}
+
+// NewCheckVersionsOptions creates a new CheckVersionsOptions
+func NewCheckVersionsOptions() *CheckVersionsOptions {
+	return &CheckVersionsOptions{}
+}
+
+// CheckVersionsImpl is impl for CheckVersionsOptions
+type CheckVersionsImpl struct {
+	*GlobalImpl
+	*CheckVersionsOptions
+}
+
+// NewCheckVersionsImpl creates a new CheckVersionsImpl
+func NewCheckVersionsImpl(g *GlobalImpl, b *CheckVersionsOptions) *CheckVersionsImpl {
+	return &CheckVersionsImpl{
+		GlobalImpl:           g,
+		CheckVersionsOptions: b,
+	}
+}
+
+// SkipRepos returns the skip repos
+func (c *CheckVersionsImpl) SkipRepos() bool {
+	return c.CheckVersionsOptions.SkipRepos
+}
+
+// Output returns the output format
+func (c *CheckVersionsImpl) Output() string {
+	return c.CheckVersionsOptions.Output
+}
@@ -51,3 +51,8 @@ func (c *DeleteImpl) SkipCharts() bool {
 func (c *DeleteImpl) Cascade() string {
 	return c.DeleteOptions.Cascade
 }
+
+// ReportFile returns an empty string as delete never writes a report
+func (c *DeleteImpl) ReportFile() string {
+	return ""
+}
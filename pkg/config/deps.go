@@ -6,6 +6,12 @@ type DepsOptions struct {
 	SkipRepos bool
 	// Concurrency is the maximum number of concurrent helm processes to run
 	Concurrency int
+	// UpdateStrategy is the update policy of the releases that don't set their own updatePolicy
+	UpdateStrategy string
+	// DryRun is true to print the changes to the lock file instead of writing it
+	DryRun bool
+	// AllowDigestChanges is true to record the new digests of the charts re-published with the locked versions instead of failing
+	AllowDigestChanges bool
 }
 
 // NewDepsOptions creates a new Apply
@@ -41,3 +47,18 @@ func (d *DepsImpl) IncludeTransitiveNeeds() bool {
 func (c *DepsImpl) Concurrency() int {
 	return c.DepsOptions.Concurrency
 }
+
+// UpdateStrategy returns the update strategy
+func (c *DepsImpl) UpdateStrategy() string {
+	return c.DepsOptions.UpdateStrategy
+}
+
+// DryRun returns the dry run
+func (c *DepsImpl) DryRun() bool {
+	return c.DepsOptions.DryRun
+}
+
+// AllowDigestChanges returns the allow digest changes flag
+func (c *DepsImpl) AllowDigestChanges() bool {
+	return c.DepsOptions.AllowDigestChanges
+}
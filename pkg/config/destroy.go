@@ -8,6 +8,8 @@ type DestroyOptions struct {
 	SkipCharts bool
 	// Cascade '--cascade' to helmv3 delete, available values: background, foreground, or orphan, default: background
 	Cascade string
+	// ReportFile is the path to write the JSON report of the processed releases to
+	ReportFile string
 }
 
 // NewDestroyOptions creates a new Apply
@@ -43,3 +45,8 @@ func (c *DestroyImpl) SkipCharts() bool {
 func (c *DestroyImpl) Cascade() string {
 	return c.DestroyOptions.Cascade
 }
+
+// ReportFile returns the path to write the report to.
+func (c *DestroyImpl) ReportFile() string {
+	return c.DestroyOptions.ReportFile
+}
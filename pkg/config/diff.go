@@ -44,6 +44,8 @@ type DiffOptions struct {
 	PostRenderer string
 	// DiffArgs is the list of arguments to pass to helm-diff.
 	DiffArgs string
+	// OutPlan is the path to the plan file to be written for `helmfile apply --plan`
+	OutPlan string
 }
 
 // NewDiffOptions creates a new Apply
@@ -185,3 +187,8 @@ func (t *DiffImpl) ResetValues() bool {
 func (t *DiffImpl) PostRenderer() string {
 	return t.DiffOptions.PostRenderer
 }
+
+// OutPlan returns the path to the plan file to be written.
+func (t *DiffImpl) OutPlan() string {
+	return t.DiffOptions.OutPlan
+}
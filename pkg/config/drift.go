@@ -0,0 +1,38 @@
+package config
+
+// DriftOptions is the options for the drift command
+type DriftOptions struct {
+	// Concurrency is the maximum number of concurrent helm processes to run, 0 is unlimited
+	Concurrency int
+	// Output is the output format of the drift report, table or json
+	Output string
+}
+
+// NewDriftOptions creates a new DriftOptions
+func NewDriftOptions() *DriftOptions {
+	return &DriftOptions{}
+}
+
+// DriftImpl is impl for DriftOptions
+type DriftImpl struct {
+	*GlobalImpl
+	*DriftOptions
+}
+
+// NewDriftImpl creates a new DriftImpl
+func NewDriftImpl(g *GlobalImpl, b *DriftOptions) *DriftImpl {
+	return &DriftImpl{
+		GlobalImpl:   g,
+		DriftOptions: b,
+	}
+}
+
+// Concurrency returns the concurrency
+func (c *DriftImpl) Concurrency() int {
+	return c.DriftOptions.Concurrency
+}
+
+// Output returns the output format
+func (c *DriftImpl) Output() string {
+	return c.DriftOptions.Output
+}
@@ -4,6 +4,7 @@ import (
 	"errors"
 	"fmt"
 	"os"
+	"time"
 
 	"go.uber.org/zap"
 	"golang.org/x/term"
@@ -57,6 +58,16 @@ type GlobalOptions struct {
 	EnableLiveOutput bool
 	// Interactive is true if the user should be prompted for input.
 	Interactive bool
+	// RefreshRemote is true if remote helmfiles, values files and charts should be fetched again ignoring the cache
+	RefreshRemote bool
+	// Offline is true if helmfile should fail instead of accessing the network
+	Offline bool
+	// RemoteCacheTTL is how long cached remote files are used before being fetched again. Zero means forever.
+	RemoteCacheTTL time.Duration
+	// CrossHelmfileNeeds is true if the releases of all the helmfiles should be ordered with one DAG
+	CrossHelmfileNeeds bool
+	// ChangedSince is the git ref to select the releases whose inputs changed since
+	ChangedSince string
 	// Args is the list of arguments to pass to the Helm binary.
 	Args string
 }
@@ -150,6 +161,31 @@ func (g *GlobalImpl) SkipDeps() bool {
 	return g.GlobalOptions.SkipDeps
 }
 
+// RefreshRemote returns true if remote files should be fetched again ignoring the cache
+func (g *GlobalImpl) RefreshRemote() bool {
+	return g.GlobalOptions.RefreshRemote
+}
+
+// Offline returns true if helmfile should fail instead of accessing the network
+func (g *GlobalImpl) Offline() bool {
+	return g.GlobalOptions.Offline
+}
+
+// CrossHelmfileNeeds returns true if the releases of all the helmfiles should be ordered with one DAG
+func (g *GlobalImpl) CrossHelmfileNeeds() bool {
+	return g.GlobalOptions.CrossHelmfileNeeds
+}
+
+// ChangedSince returns the git ref to select the releases whose inputs changed since
+func (g *GlobalImpl) ChangedSince() string {
+	return g.GlobalOptions.ChangedSince
+}
+
+// RemoteCacheTTL returns how long cached remote files are used before being fetched again
+func (g *GlobalImpl) RemoteCacheTTL() time.Duration {
+	return g.GlobalOptions.RemoteCacheTTL
+}
+
 // StripArgsValuesOnExitError return if the ARGS output on exit error should be suppressed
 func (g *GlobalImpl) StripArgsValuesOnExitError() bool {
 	return g.GlobalOptions.StripArgsValuesOnExitError
@@ -0,0 +1,56 @@
+package config
+
+// GraphOptions is the options for the graph command
+type GraphOptions struct {
+	// Output is the output format of the graph, dot, mermaid or json
+	Output string
+	// SkipNeeds is the skip needs flag
+	SkipNeeds bool
+	// IncludeNeeds is the include needs flag
+	IncludeNeeds bool
+	// IncludeTransitiveNeeds is the include transitive needs flag
+	IncludeTransitiveNeeds bool
+}
+
+// NewGraphOptions creates a new GraphOptions
+func NewGraphOptions() *GraphOptions {
+	return &GraphOptions{}
+}
+
+// GraphImpl is impl for GraphOptions
+type GraphImpl struct {
+	*GlobalImpl
+	*GraphOptions
+}
+
+// NewGraphImpl creates a new GraphImpl
+func NewGraphImpl(g *GlobalImpl, b *GraphOptions) *GraphImpl {
+	return &GraphImpl{
+		GlobalImpl:   g,
+		GraphOptions: b,
+	}
+}
+
+// Output returns the output format
+func (c *GraphImpl) Output() string {
+	return c.GraphOptions.Output
+}
+
+// IncludeNeeds returns the include needs
+func (c *GraphImpl) IncludeNeeds() bool {
+	return c.GraphOptions.IncludeNeeds || c.IncludeTransitiveNeeds()
+}
+
+// IncludeTransitiveNeeds returns the include transitive needs
+func (c *GraphImpl) IncludeTransitiveNeeds() bool {
+	return c.GraphOptions.IncludeTransitiveNeeds
+}
+
+// SkipNeeds returns the skip needs
+func (c *GraphImpl) SkipNeeds() bool {
+	if !c.IncludeNeeds() {
+		return c.GraphOptions.SkipNeeds
+	}
+
+	return false
+}
@@ -8,6 +8,10 @@ type ListOptions struct {
 	KeepTempDir bool
 	// SkipCharts makes List skip `withPreparedCharts`
 	SkipCharts bool
+	// WithStatus adds the status of the releases in the cluster
+	WithStatus bool
+	// Template is the Go template to print the releases with when the output format is go-template
+	Template string
 }
 
 // NewListOptions creates a new Apply
@@ -38,3 +42,13 @@ func (c *ListImpl) Output() string {
 func (c *ListImpl) SkipCharts() bool {
 	return c.ListOptions.SkipCharts
 }
+
+// WithStatus returns the with-status flag
+func (c *ListImpl) WithStatus() bool {
+	return c.ListOptions.WithStatus
+}
+
+// Template returns the Go template to print the releases with
+func (c *ListImpl) Template() string {
+	return c.ListOptions.Template
+}
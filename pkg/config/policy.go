@@ -0,0 +1,31 @@
+package config
+
+// PolicyOptions is the options for the policy command
+type PolicyOptions struct {
+	// PolicyFile is the path to the policy file
+	PolicyFile string
+}
+
+// NewPolicyOptions creates a new PolicyOptions
+func NewPolicyOptions() *PolicyOptions {
+	return &PolicyOptions{}
+}
+
+// PolicyImpl is impl for PolicyOptions
+type PolicyImpl struct {
+	*GlobalImpl
+	*PolicyOptions
+}
+
+// NewPolicyImpl creates a new PolicyImpl
+func NewPolicyImpl(g *GlobalImpl, b *PolicyOptions) *PolicyImpl {
+	return &PolicyImpl{
+		GlobalImpl:    g,
+		PolicyOptions: b,
+	}
+}
+
+// PolicyFile returns the path to the policy file.
+func (p *PolicyImpl) PolicyFile() string {
+	return p.PolicyOptions.PolicyFile
+}
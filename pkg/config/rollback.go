@@ -0,0 +1,38 @@
+package config
+
+// RollbackOptions is the options for the rollback command
+type RollbackOptions struct {
+	// Concurrency is the maximum number of concurrent helm processes to run, 0 is unlimited
+	Concurrency int
+	// ToRevision is the revision to roll back to. 0 rolls back each release to its previous revision
+	ToRevision int
+}
+
+// NewRollbackOptions creates a new RollbackOptions
+func NewRollbackOptions() *RollbackOptions {
+	return &RollbackOptions{}
+}
+
+// RollbackImpl is impl for RollbackOptions
+type RollbackImpl struct {
+	*GlobalImpl
+	*RollbackOptions
+}
+
+// NewRollbackImpl creates a new RollbackImpl
+func NewRollbackImpl(g *GlobalImpl, b *RollbackOptions) *RollbackImpl {
+	return &RollbackImpl{
+		GlobalImpl:      g,
+		RollbackOptions: b,
+	}
+}
+
+// Concurrency returns the concurrency
+func (c *RollbackImpl) Concurrency() int {
+	return c.RollbackOptions.Concurrency
+}
+
+// ToRevision returns the revision to roll back to
+func (c *RollbackImpl) ToRevision() int {
+	return c.RollbackOptions.ToRevision
+}
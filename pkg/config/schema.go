@@ -0,0 +1,23 @@
+package config
+
+// SchemaOptions is the options for the schema command
+type SchemaOptions struct{}
+
+// NewSchemaOptions creates a new SchemaOptions
+func NewSchemaOptions() *SchemaOptions {
+	return &SchemaOptions{}
+}
+
+// SchemaImpl is impl for SchemaOptions
+type SchemaImpl struct {
+	*GlobalImpl
+	*SchemaOptions
+}
+
+// NewSchemaImpl creates a new SchemaImpl
+func NewSchemaImpl(g *GlobalImpl, b *SchemaOptions) *SchemaImpl {
+	return &SchemaImpl{
+		GlobalImpl:    g,
+		SchemaOptions: b,
+	}
+}
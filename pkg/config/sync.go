@@ -30,6 +30,8 @@ type SyncOptions struct {
 	PostRenderer string
 	// Cascade '--cascade' to helmv3 delete, available values: background, foreground, or orphan, default: background
 	Cascade string
+	// ReportFile is the path to write the JSON report of the processed releases to
+	ReportFile string
 }
 
 // NewSyncOptions creates a new Apply
@@ -125,3 +127,8 @@ func (t *SyncImpl) PostRenderer() string {
 func (t *SyncImpl) Cascade() string {
 	return t.SyncOptions.Cascade
 }
+
+// ReportFile returns the path to write the report to.
+func (t *SyncImpl) ReportFile() string {
+	return t.SyncOptions.ReportFile
+}
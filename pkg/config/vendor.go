@@ -0,0 +1,45 @@
+package config
+
+// VendorOptions is the options for the vendor command
+type VendorOptions struct {
+	// Concurrency is the maximum number of concurrent helm processes to run, 0 is unlimited
+	Concurrency int
+	// OutputDir is the directory to write the vendored helmfile into
+	OutputDir string
+	// Tarball is the path to the .tar.gz archive to write the vendored helmfile into, instead of the output directory
+	Tarball string
+}
+
+// NewVendorOptions creates a new VendorOptions
+func NewVendorOptions() *VendorOptions {
+	return &VendorOptions{}
+}
+
+// VendorImpl is impl for VendorOptions
+type VendorImpl struct {
+	*GlobalImpl
+	*VendorOptions
+}
+
+// NewVendorImpl creates a new VendorImpl
+func NewVendorImpl(g *GlobalImpl, b *VendorOptions) *VendorImpl {
+	return &VendorImpl{
+		GlobalImpl:    g,
+		VendorOptions: b,
+	}
+}
+
+// Concurrency returns the concurrency
+func (c *VendorImpl) Concurrency() int {
+	return c.VendorOptions.Concurrency
+}
+
+// OutputDir returns the output directory
+func (c *VendorImpl) OutputDir() string {
+	return c.VendorOptions.OutputDir
+}
+
+// Tarball returns the path to the archive
+func (c *VendorImpl) Tarball() string {
+	return c.VendorOptions.Tarball
+}
@@ -10,5 +10,7 @@ const (
 	UpgradeNoticeDisabled         = "HELMFILE_UPGRADE_NOTICE_DISABLED"
 	V1Mode                        = "HELMFILE_V1MODE"
 	GoccyGoYaml                   = "HELMFILE_GOCCY_GOYAML"
+	StrictFields                  = "HELMFILE_STRICT_FIELDS"
 	CacheHome                     = "HELMFILE_CACHE_HOME"
+	RemoteMirrors                 = "HELMFILE_REMOTE_MIRRORS"
 )
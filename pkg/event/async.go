@@ -0,0 +1,68 @@
+package event
+
+import (
+	"sync"
+
+	"go.uber.org/multierr"
+)
+
+// AsyncHooks tracks the hooks running in the background, so that they are joined at a later event.
+// The hooks are grouped, for example by release, so that each group is joined independently.
+type AsyncHooks struct {
+	mu      sync.Mutex
+	pending map[string][]chan error
+}
+
+func (a *AsyncHooks) start(group string, f func() error) {
+	ch := make(chan error, 1)
+
+	a.mu.Lock()
+	if a.pending == nil {
+		a.pending = map[string][]chan error{}
+	}
+	a.pending[group] = append(a.pending[group], ch)
+	a.mu.Unlock()
+
+	go func() {
+		ch <- f()
+	}()
+}
+
+// Wait waits for the hooks in the group to complete and returns their combined errors.
+func (a *AsyncHooks) Wait(group string) error {
+	if a == nil {
+		return nil
+	}
+
+	a.mu.Lock()
+	chs := a.pending[group]
+	delete(a.pending, group)
+	a.mu.Unlock()
+
+	return wait(chs)
+}
+
+// WaitAll waits for all the hooks to complete and returns their combined errors.
+func (a *AsyncHooks) WaitAll() error {
+	if a == nil {
+		return nil
+	}
+
+	a.mu.Lock()
+	var chs []chan error
+	for _, c := range a.pending {
+		chs = append(chs, c...)
+	}
+	a.pending = nil
+	a.mu.Unlock()
+
+	return wait(chs)
+}
+
+func wait(chs []chan error) error {
+	var errs error
+	for _, ch := range chs {
+		errs = multierr.Append(errs, <-ch)
+	}
+	return errs
+}
@@ -2,9 +2,14 @@ package event
 
 import (
 	goContext "context"
+	"errors"
 	"fmt"
+	"strconv"
 	"strings"
+	"sync"
+	"time"
 
+	"go.uber.org/multierr"
 	"go.uber.org/zap"
 
 	"github.com/helmfile/helmfile/pkg/environment"
@@ -13,13 +18,39 @@ import (
 	"github.com/helmfile/helmfile/pkg/tmpl"
 )
 
+const (
+	// HookOnFailureFail aborts the operation when the hook fails. This is the default
+	HookOnFailureFail = "fail"
+	// HookOnFailureWarn logs a warning and continues the operation when the hook fails
+	HookOnFailureWarn = "warn"
+	// HookOnFailureIgnore continues the operation silently when the hook fails
+	HookOnFailureIgnore = "ignore"
+)
+
+// hookRetryInterval is the interval before the first retry of a failed hook. It doubles on each retry.
+var hookRetryInterval = time.Second
+
 type Hook struct {
 	Name     string            `yaml:"name"`
 	Events   []string          `yaml:"events"`
 	Command  string            `yaml:"command"`
 	Kubectl  map[string]string `yaml:"kubectlApply,omitempty"`
+	HTTP     *HTTPHook         `yaml:"http,omitempty"`
 	Args     []string          `yaml:"args"`
 	ShowLogs bool              `yaml:"showlogs"`
+	// If is a template rendered to either true or false to decide whether to run the hook. The hook always runs when empty
+	If string `yaml:"if,omitempty"`
+	// OnFailure is either fail, warn or ignore
+	OnFailure string `yaml:"onFailure,omitempty"`
+	// Timeout is the time in seconds to wait for each run of the hook. 0 is unlimited, except for HTTP hooks which default to 30.
+	// The command is interrupted on timeout when it is run by a ShellRunner
+	Timeout int `yaml:"timeout,omitempty"`
+	// Retries is the number of times the hook is retried on failure, with exponential backoff
+	Retries int `yaml:"retries,omitempty"`
+	// Parallel runs the hook concurrently with the other hooks for the same event
+	Parallel bool `yaml:"parallel,omitempty"`
+	// Async runs the hook in the background, and joins it at the cleanup event
+	Async bool `yaml:"async,omitempty"`
 }
 
 type event struct {
@@ -40,6 +71,11 @@ type Bus struct {
 	Fs  *filesystem.FileSystem
 
 	Logger *zap.SugaredLogger
+
+	// Async tracks the async hooks. Async hooks run synchronously when it is nil
+	Async *AsyncHooks
+	// AsyncGroup is the group of the async hooks started by this bus, which are joined at the cleanup event of the same group
+	AsyncGroup string
 }
 
 func (bus *Bus) Trigger(evt string, evtErr error, context map[string]any) (bool, error) {
@@ -53,8 +89,19 @@ func (bus *Bus) Trigger(evt string, evtErr error, context map[string]any) (bool,
 		}
 	}
 
-	executed := false
+	var (
+		executed bool
+		errs     error
+		mu       sync.Mutex
+		wg       sync.WaitGroup
+	)
+
+	// Join the async hooks before running the cleanup hooks, which may clean up what the async hooks have done
+	if evt == "cleanup" {
+		errs = multierr.Append(errs, bus.Async.Wait(bus.AsyncGroup))
+	}
 
+hooks:
 	for _, hook := range bus.Hooks {
 		contained := false
 		for _, e := range hook.Events {
@@ -64,77 +111,270 @@ func (bus *Bus) Trigger(evt string, evtErr error, context map[string]any) (bool,
 			continue
 		}
 
-		var err error
+		switch {
+		case hook.Async && bus.Async != nil && evt != "cleanup":
+			hook := hook
+			bus.Async.start(bus.AsyncGroup, func() error {
+				_, err := bus.triggerHook(hook, evt, evtErr, context)
+				return err
+			})
+			executed = true
+		case hook.Parallel:
+			wg.Add(1)
+			go func(hook Hook) {
+				defer wg.Done()
 
-		name := hook.Name
-		if name == "" {
-			if hook.Kubectl != nil {
-				name = "kubectlApply"
-			} else {
-				name = hook.Command
+				ok, err := bus.triggerHook(hook, evt, evtErr, context)
+
+				mu.Lock()
+				defer mu.Unlock()
+
+				executed = executed || ok
+				errs = multierr.Append(errs, err)
+			}(hook)
+		default:
+			ok, err := bus.triggerHook(hook, evt, evtErr, context)
+
+			mu.Lock()
+			executed = executed || ok
+			errs = multierr.Append(errs, err)
+			mu.Unlock()
+
+			if err != nil {
+				break hooks
 			}
 		}
+	}
+
+	wg.Wait()
+
+	if errs != nil {
+		return false, errs
+	}
+
+	return executed, nil
+}
+
+// triggerHook runs the hook triggered by the event, returning false without an error when the hook is skipped or
+// its failure is ignored by onFailure.
+func (bus *Bus) triggerHook(hook Hook, evt string, evtErr error, context map[string]any) (bool, error) {
+	name := hook.Name
+	if name == "" {
+		if hook.HTTP != nil {
+			name = "http"
+		} else if hook.Kubectl != nil {
+			name = "kubectlApply"
+		} else {
+			name = hook.Command
+		}
+	}
 
-		if hook.Kubectl != nil {
-			if hook.Command != "" {
-				bus.Logger.Warnf("warn: ignoring command '%s' given within a kubectlApply hook", hook.Command)
+	switch hook.OnFailure {
+	case "", HookOnFailureFail, HookOnFailureWarn, HookOnFailureIgnore:
+	default:
+		return false, fmt.Errorf("hook[%s]: onFailure must be one of %s, %s and %s, but got %q", name, HookOnFailureFail, HookOnFailureWarn, HookOnFailureIgnore, hook.OnFailure)
+	}
+
+	if hook.HTTP != nil && (hook.Kubectl != nil || hook.Command != "") {
+		return false, fmt.Errorf("hook[%s]: http cannot be used together with command or kubectlApply", name)
+	}
+
+	// manifests is the inline manifests passed to `kubectl apply -f -` via stdin
+	var manifests *string
+
+	if hook.Kubectl != nil {
+		if hook.Command != "" {
+			bus.Logger.Warnf("warn: ignoring command '%s' given within a kubectlApply hook", hook.Command)
+		}
+		hook.Command = "kubectl"
+		_, hasFilename := hook.Kubectl["filename"]
+		_, hasKustomize := hook.Kubectl["kustomize"]
+		if val, found := hook.Kubectl["manifests"]; found {
+			if hasFilename || hasKustomize {
+				return false, fmt.Errorf("hook[%s]: manifests cannot be used together with kustomize or filename", name)
 			}
-			hook.Command = "kubectl"
-			if val, found := hook.Kubectl["filename"]; found {
-				if _, found := hook.Kubectl["kustomize"]; found {
-					return false, fmt.Errorf("hook[%s]: kustomize & filename cannot be used together", name)
-				}
-				hook.Args = append([]string{"apply", "-f"}, val)
-			} else if val, found := hook.Kubectl["kustomize"]; found {
-				hook.Args = append([]string{"apply", "-k"}, val)
-			} else {
-				return false, fmt.Errorf("hook[%s]: either kustomize or filename must be given", name)
+			manifests = &val
+			hook.Args = []string{"apply", "-f", "-"}
+		} else if val, found := hook.Kubectl["filename"]; found {
+			if hasKustomize {
+				return false, fmt.Errorf("hook[%s]: kustomize & filename cannot be used together", name)
 			}
+			hook.Args = append([]string{"apply", "-f"}, val)
+		} else if val, found := hook.Kubectl["kustomize"]; found {
+			hook.Args = append([]string{"apply", "-k"}, val)
+		} else {
+			return false, fmt.Errorf("hook[%s]: either kustomize, filename or manifests must be given", name)
 		}
+	}
+
+	bus.Logger.Debugf("hook[%s]: stateFilePath=%s, basePath=%s\n", name, bus.StateFilePath, bus.BasePath)
 
-		bus.Logger.Debugf("hook[%s]: stateFilePath=%s, basePath=%s\n", name, bus.StateFilePath, bus.BasePath)
+	data := map[string]any{
+		"Environment": bus.Env,
+		"Namespace":   bus.Namespace,
+		"Event": event{
+			Name:  evt,
+			Error: evtErr,
+		},
+	}
+	for k, v := range context {
+		data[k] = v
+	}
+	render := tmpl.NewTextRenderer(bus.Fs, bus.BasePath, data)
 
-		data := map[string]any{
-			"Environment": bus.Env,
-			"Namespace":   bus.Namespace,
-			"Event": event{
-				Name:  evt,
-				Error: evtErr,
-			},
+	if hook.If != "" {
+		run, err := renderCondition(render, hook.If)
+		if err != nil {
+			return false, fmt.Errorf("hook[%s]: if: %v", name, err)
+		}
+		if !run {
+			bus.Logger.Debugf("hook[%s]: skipped as the condition %q is false\n", name, hook.If)
+			return false, nil
 		}
-		for k, v := range context {
-			data[k] = v
+	}
+
+	bus.Logger.Debugf("hook[%s]: triggered by event \"%s\"\n", name, evt)
+
+	if err := bus.runWithRetries(hook, name, evt, render, manifests); err != nil {
+		switch hook.OnFailure {
+		case HookOnFailureWarn:
+			bus.Logger.Warnf("warn: %v", err)
+			return false, nil
+		case HookOnFailureIgnore:
+			bus.Logger.Debugf("ignoring the failure: %v\n", err)
+			return false, nil
 		}
-		render := tmpl.NewTextRenderer(bus.Fs, bus.BasePath, data)
+		return false, err
+	}
 
-		bus.Logger.Debugf("hook[%s]: triggered by event \"%s\"\n", name, evt)
+	return true, nil
+}
 
-		command, err := render.RenderTemplateText(hook.Command)
+// renderCondition renders the `if` condition of a hook. An empty result is false.
+func renderCondition(render tmpl.TextRenderer, cond string) (bool, error) {
+	rendered, err := render.RenderTemplateText(cond)
+	if err != nil {
+		return false, err
+	}
+
+	rendered = strings.TrimSpace(rendered)
+	if rendered == "" {
+		return false, nil
+	}
+
+	b, err := strconv.ParseBool(rendered)
+	if err != nil {
+		return false, fmt.Errorf("%q must be rendered to either true or false, but got %q", cond, rendered)
+	}
+
+	return b, nil
+}
+
+// runWithRetries runs the hook up to 1+hook.Retries times until it succeeds, doubling the interval between the runs.
+// Unretryable errors fail the hook immediately.
+func (bus *Bus) runWithRetries(hook Hook, name, evt string, render tmpl.TextRenderer, manifests *string) error {
+	interval := hookRetryInterval
+
+	for attempt := 0; ; attempt++ {
+		bytes, err := bus.runWithTimeout(hook, name, render, manifests)
+		bus.logHookOutput(hook, name, evt, bytes)
+
+		if err == nil || attempt >= hook.Retries || errors.As(err, &unretryableError{}) {
+			return err
+		}
+
+		bus.Logger.Warnf("hook[%s]: retrying in %s (%d/%d): %v", name, interval, attempt+1, hook.Retries, err)
+
+		time.Sleep(interval)
+		interval *= 2
+	}
+}
+
+// runWithTimeout runs the hook once, cancelling the HTTP request or the command run by a ShellRunner after hook.Timeout seconds.
+func (bus *Bus) runWithTimeout(hook Hook, name string, render tmpl.TextRenderer, manifests *string) ([]byte, error) {
+	runner := bus.Runner
+
+	ctx := goContext.Background()
+	if sr, ok := runner.(helmexec.ShellRunner); ok && sr.Ctx != nil {
+		ctx = sr.Ctx
+	}
+
+	timeout := hook.Timeout
+	if timeout <= 0 && hook.HTTP != nil {
+		timeout = defaultHTTPHookTimeout
+	}
+
+	if timeout > 0 {
+		var cancel goContext.CancelFunc
+		ctx, cancel = goContext.WithTimeout(ctx, time.Duration(timeout)*time.Second)
+		defer cancel()
+	}
+
+	if sr, ok := runner.(helmexec.ShellRunner); ok {
+		sr.Ctx = ctx
+		runner = sr
+	}
+
+	bytes, err := bus.run(ctx, runner, hook, name, render, manifests)
+	if err != nil && errors.Is(ctx.Err(), goContext.DeadlineExceeded) {
+		return bytes, fmt.Errorf("hook[%s]: timed out after %ds", name, timeout)
+	}
+
+	return bytes, err
+}
+
+// run runs the hook once.
+func (bus *Bus) run(ctx goContext.Context, runner helmexec.Runner, hook Hook, name string, render tmpl.TextRenderer, manifests *string) ([]byte, error) {
+	if hook.HTTP != nil {
+		req, err := hook.HTTP.render(render)
 		if err != nil {
-			return false, fmt.Errorf("hook[%s]: %v", name, err)
+			return nil, fmt.Errorf("hook[%s]: %v", name, err)
 		}
 
-		args := make([]string, len(hook.Args))
-		for i, raw := range hook.Args {
-			args[i], err = render.RenderTemplateText(raw)
-			if err != nil {
-				return false, fmt.Errorf("hook[%s]: %v", name, err)
-			}
+		bytes, err := hook.HTTP.send(ctx, req)
+		if err != nil {
+			return bytes, fmt.Errorf("hook[%s]: %s %s failed: %w", name, req.method, req.url, err)
 		}
 
-		bytes, err := bus.Runner.Execute(command, args, map[string]string{}, false)
-		bus.Logger.Debugf("hook[%s]: %s\n", name, string(bytes))
-		if hook.ShowLogs {
-			prefix := fmt.Sprintf("\nhook[%s] logs | ", evt)
-			bus.Logger.Infow(prefix + strings.ReplaceAll(string(bytes), "\n", prefix))
+		return bytes, nil
+	}
+
+	command, err := render.RenderTemplateText(hook.Command)
+	if err != nil {
+		return nil, fmt.Errorf("hook[%s]: %v", name, err)
+	}
+
+	args := make([]string, len(hook.Args))
+	for i, raw := range hook.Args {
+		args[i], err = render.RenderTemplateText(raw)
+		if err != nil {
+			return nil, fmt.Errorf("hook[%s]: %v", name, err)
 		}
+	}
 
+	var bytes []byte
+	if manifests != nil {
+		var stdin string
+		stdin, err = render.RenderTemplateText(*manifests)
 		if err != nil {
-			return false, fmt.Errorf("hook[%s]: command `%s` failed: %v", name, command, err)
+			return nil, fmt.Errorf("hook[%s]: %v", name, err)
 		}
+		bytes, err = runner.ExecuteStdIn(command, args, map[string]string{}, strings.NewReader(stdin))
+	} else {
+		bytes, err = runner.Execute(command, args, map[string]string{}, false)
+	}
 
-		executed = true
+	if err != nil {
+		return bytes, fmt.Errorf("hook[%s]: command `%s` failed: %v", name, command, err)
 	}
 
-	return executed, nil
+	return bytes, nil
+}
+
+func (bus *Bus) logHookOutput(hook Hook, name, evt string, bytes []byte) {
+	bus.Logger.Debugf("hook[%s]: %s\n", name, string(bytes))
+	if hook.ShowLogs {
+		prefix := fmt.Sprintf("\nhook[%s] logs | ", evt)
+		bus.Logger.Infow(prefix + strings.ReplaceAll(string(bytes), "\n", prefix))
+	}
 }
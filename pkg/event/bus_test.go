@@ -1,15 +1,23 @@
 package event
 
 import (
+	"context"
 	"fmt"
 	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
 	"testing"
+	"time"
 
+	"github.com/stretchr/testify/require"
 	"go.uber.org/zap"
 	"go.uber.org/zap/zaptest/observer"
 
 	"github.com/helmfile/helmfile/pkg/environment"
 	ffs "github.com/helmfile/helmfile/pkg/filesystem"
+	"github.com/helmfile/helmfile/pkg/helmexec"
 )
 
 type runner struct {
@@ -41,21 +49,21 @@ func TestTrigger(t *testing.T) {
 	}{
 		{
 			"okhook1",
-			&Hook{"okhook1", []string{"foo"}, "ok", nil, []string{}, true},
+			&Hook{Name: "okhook1", Events: []string{"foo"}, Command: "ok", Args: []string{}, ShowLogs: true},
 			"foo",
 			true,
 			"",
 		},
 		{
 			"okhooké",
-			&Hook{"okhook2", []string{"foo"}, "ok", nil, []string{}, false},
+			&Hook{Name: "okhook2", Events: []string{"foo"}, Command: "ok", Args: []string{}, ShowLogs: false},
 			"foo",
 			true,
 			"",
 		},
 		{
 			"missinghook1",
-			&Hook{"okhook1", []string{"foo"}, "ok", nil, []string{}, false},
+			&Hook{Name: "okhook1", Events: []string{"foo"}, Command: "ok", Args: []string{}, ShowLogs: false},
 			"bar",
 			false,
 			"",
@@ -69,70 +77,91 @@ func TestTrigger(t *testing.T) {
 		},
 		{
 			"nghook1",
-			&Hook{"nghook1", []string{"foo"}, "ng", nil, []string{}, false},
+			&Hook{Name: "nghook1", Events: []string{"foo"}, Command: "ng", Args: []string{}, ShowLogs: false},
 			"foo",
 			false,
 			"hook[nghook1]: command `ng` failed: cmd failed due to invalid cmd: ng",
 		},
 		{
 			"nghook2",
-			&Hook{"nghook2", []string{"foo"}, "ok", nil, []string{"ng"}, false},
+			&Hook{Name: "nghook2", Events: []string{"foo"}, Command: "ok", Args: []string{"ng"}, ShowLogs: false},
 			"foo",
 			false,
 			"hook[nghook2]: command `ok` failed: cmd failed due to invalid arg: ng",
 		},
 		{
 			"okkubeapply1",
-			&Hook{"okkubeapply1", []string{"foo"}, "", map[string]string{"kustomize": "kustodir"}, []string{}, false},
+			&Hook{Name: "okkubeapply1", Events: []string{"foo"}, Command: "", Kubectl: map[string]string{"kustomize": "kustodir"}, Args: []string{}, ShowLogs: false},
 			"foo",
 			true,
 			"",
 		},
 		{
 			"okkubeapply2",
-			&Hook{"okkubeapply2", []string{"foo"}, "", map[string]string{"filename": "resource.yaml"}, []string{}, false},
+			&Hook{Name: "okkubeapply2", Events: []string{"foo"}, Command: "", Kubectl: map[string]string{"filename": "resource.yaml"}, Args: []string{}, ShowLogs: false},
 			"foo",
 			true,
 			"",
 		},
 		{
 			"kokubeapply",
-			&Hook{"kokubeapply", []string{"foo"}, "", map[string]string{"kustomize": "kustodir", "filename": "resource.yaml"}, []string{}, true},
+			&Hook{Name: "kokubeapply", Events: []string{"foo"}, Command: "", Kubectl: map[string]string{"kustomize": "kustodir", "filename": "resource.yaml"}, Args: []string{}, ShowLogs: true},
 			"foo",
 			false,
 			"hook[kokubeapply]: kustomize & filename cannot be used together",
 		},
 		{
 			"kokubeapply2",
-			&Hook{"kokubeapply2", []string{"foo"}, "", map[string]string{}, []string{}, true},
+			&Hook{Name: "kokubeapply2", Events: []string{"foo"}, Command: "", Kubectl: map[string]string{}, Args: []string{}, ShowLogs: true},
 			"foo",
 			false,
-			"hook[kokubeapply2]: either kustomize or filename must be given",
+			"hook[kokubeapply2]: either kustomize, filename or manifests must be given",
 		},
 		{
 			"kokubeapply3",
-			&Hook{"", []string{"foo"}, "", map[string]string{}, []string{}, true},
+			&Hook{Name: "", Events: []string{"foo"}, Command: "", Kubectl: map[string]string{}, Args: []string{}, ShowLogs: true},
 			"foo",
 			false,
-			"hook[kubectlApply]: either kustomize or filename must be given",
+			"hook[kubectlApply]: either kustomize, filename or manifests must be given",
+		},
+		{
+			"okkubeapply3",
+			&Hook{Name: "okkubeapply3", Events: []string{"foo"}, Kubectl: map[string]string{"manifests": "kind: Namespace"}, Args: []string{}, ShowLogs: false},
+			"foo",
+			true,
+			"",
+		},
+		{
+			"kokubeapply4",
+			&Hook{Name: "kokubeapply4", Events: []string{"foo"}, Kubectl: map[string]string{"manifests": "kind: Namespace", "filename": "resource.yaml"}, Args: []string{}, ShowLogs: true},
+			"foo",
+			false,
+			"hook[kokubeapply4]: manifests cannot be used together with kustomize or filename",
+		},
+		{
+			"kohttp1",
+			&Hook{Name: "kohttp1", Events: []string{"foo"}, Command: "ok", HTTP: &HTTPHook{URL: "http://localhost"}, Args: []string{}, ShowLogs: true},
+			"foo",
+			false,
+			"hook[kohttp1]: http cannot be used together with command or kubectlApply",
 		},
 		{
 			"warnkubeapply1",
-			&Hook{"warnkubeapply1", []string{"foo"}, "ok", map[string]string{"filename": "resource.yaml"}, []string{}, true},
+			&Hook{Name: "warnkubeapply1", Events: []string{"foo"}, Command: "ok", Kubectl: map[string]string{"filename": "resource.yaml"}, Args: []string{}, ShowLogs: true},
 			"foo",
 			true,
 			"",
 		},
 		{
 			"warnkubeapply2",
-			&Hook{"warnkubeapply2", []string{"foo"}, "", map[string]string{"filename": "resource.yaml"}, []string{"ng"}, true},
+			&Hook{Name: "warnkubeapply2", Events: []string{"foo"}, Command: "", Kubectl: map[string]string{"filename": "resource.yaml"}, Args: []string{"ng"}, ShowLogs: true},
 			"foo",
 			true,
 			"",
 		},
 		{
 			"warnkubeapply3",
-			&Hook{"warnkubeapply3", []string{"foo"}, "ok", map[string]string{"filename": "resource.yaml"}, []string{"ng"}, true},
+			&Hook{Name: "warnkubeapply3", Events: []string{"foo"}, Command: "ok", Kubectl: map[string]string{"filename": "resource.yaml"}, Args: []string{"ng"}, ShowLogs: true},
 			"foo",
 			true,
 			"",
@@ -185,3 +214,441 @@ func TestTrigger(t *testing.T) {
 		}
 	}
 }
+
+type stdinRecorder struct {
+	runner
+
+	cmd   string
+	args  []string
+	stdin string
+}
+
+func (r *stdinRecorder) ExecuteStdIn(cmd string, args []string, env map[string]string, stdin io.Reader) ([]byte, error) {
+	bs, err := io.ReadAll(stdin)
+	if err != nil {
+		return nil, err
+	}
+	r.cmd = cmd
+	r.args = args
+	r.stdin = string(bs)
+	return []byte(""), nil
+}
+
+func newTestBus(hooks []Hook) *Bus {
+	return &Bus{
+		Hooks:         hooks,
+		StateFilePath: "path/to/helmfile.yaml",
+		BasePath:      "path/to",
+		Namespace:     "myns",
+		Env:           environment.Environment{Name: "prod"},
+		Logger:        zap.NewNop().Sugar(),
+		Fs: &ffs.FileSystem{ReadFile: func(filename string) ([]byte, error) {
+			return nil, fmt.Errorf("unexpected call to readFile: %s", filename)
+		}},
+	}
+}
+
+func TestTrigger_KubectlApplyManifests(t *testing.T) {
+	bus := newTestBus([]Hook{
+		{
+			Name:   "namespace",
+			Events: []string{"presync"},
+			Kubectl: map[string]string{
+				"manifests": "apiVersion: v1\nkind: Namespace\nmetadata:\n  name: {{ .Release.Name }}-{{ .Environment.Name }}\n",
+			},
+		},
+	})
+
+	r := &stdinRecorder{}
+	bus.Runner = r
+
+	ok, err := bus.Trigger("presync", nil, map[string]any{"Release": map[string]any{"Name": "myrel"}})
+	require.NoError(t, err)
+	require.True(t, ok)
+
+	require.Equal(t, "kubectl", r.cmd)
+	require.Equal(t, []string{"apply", "-f", "-"}, r.args)
+	require.Equal(t, "apiVersion: v1\nkind: Namespace\nmetadata:\n  name: myrel-prod\n", r.stdin)
+}
+
+func TestTrigger_HTTP(t *testing.T) {
+	retryInterval := hookRetryInterval
+	hookRetryInterval = time.Millisecond
+	t.Cleanup(func() { hookRetryInterval = retryInterval })
+
+	type request struct {
+		method string
+		path   string
+		token  string
+		body   string
+	}
+
+	var (
+		mu       sync.Mutex
+		requests []request
+		failures int
+	)
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		bs, err := io.ReadAll(r.Body)
+		require.NoError(t, err)
+
+		mu.Lock()
+		defer mu.Unlock()
+
+		requests = append(requests, request{method: r.Method, path: r.URL.Path, token: r.Header.Get("Authorization"), body: string(bs)})
+
+		switch {
+		case strings.HasPrefix(r.URL.Path, "/flaky") && failures < 2:
+			failures++
+			w.WriteHeader(http.StatusServiceUnavailable)
+		case strings.HasPrefix(r.URL.Path, "/notfound"):
+			w.WriteHeader(http.StatusNotFound)
+		case strings.HasPrefix(r.URL.Path, "/slow"):
+			mu.Unlock()
+			<-r.Context().Done()
+			mu.Lock()
+		default:
+			_, _ = w.Write([]byte("ok"))
+		}
+	}))
+	t.Cleanup(server.Close)
+
+	data := map[string]any{
+		"Release": map[string]any{"Name": "myrel"},
+	}
+
+	t.Run("sends the templated request", func(t *testing.T) {
+		requests = nil
+
+		bus := newTestBus([]Hook{
+			{
+				Events: []string{"postsync"},
+				HTTP: &HTTPHook{
+					URL:     server.URL + "/deploys/{{ .Release.Name }}",
+					Headers: map[string]string{"Authorization": "Bearer {{ .Environment.Name }}"},
+					Body:    `{"release":"{{ .Release.Name }}","event":"{{ .Event.Name }}","failed":{{ if .Event.Error }}true{{ else }}false{{ end }}}`,
+				},
+			},
+		})
+
+		ok, err := bus.Trigger("postsync", nil, data)
+		require.NoError(t, err)
+		require.True(t, ok)
+
+		require.Equal(t, []request{
+			{method: "POST", path: "/deploys/myrel", token: "Bearer prod", body: `{"release":"myrel","event":"postsync","failed":false}`},
+		}, requests)
+	})
+
+	t.Run("retries on server errors", func(t *testing.T) {
+		requests = nil
+
+		bus := newTestBus([]Hook{
+			{
+				Name:    "notify",
+				Events:  []string{"postsync"},
+				HTTP:    &HTTPHook{Method: "put", URL: server.URL + "/flaky"},
+				Retries: 2,
+			},
+		})
+
+		ok, err := bus.Trigger("postsync", nil, data)
+		require.NoError(t, err)
+		require.True(t, ok)
+
+		require.Len(t, requests, 3)
+		require.Equal(t, "PUT", requests[2].method)
+	})
+
+	t.Run("doesn't retry on client errors", func(t *testing.T) {
+		requests = nil
+
+		bus := newTestBus([]Hook{
+			{
+				Name:    "notify",
+				Events:  []string{"postsync"},
+				HTTP:    &HTTPHook{Method: "GET", URL: server.URL + "/notfound"},
+				Retries: 2,
+			},
+		})
+
+		ok, err := bus.Trigger("postsync", nil, data)
+		require.EqualError(t, err, fmt.Sprintf("hook[notify]: GET %s/notfound failed: unexpected status 404 Not Found", server.URL))
+		require.False(t, ok)
+
+		require.Len(t, requests, 1)
+	})
+
+	t.Run("cancels the request on timeout", func(t *testing.T) {
+		requests = nil
+
+		bus := newTestBus([]Hook{
+			{
+				Name:    "notify",
+				Events:  []string{"postsync"},
+				HTTP:    &HTTPHook{URL: server.URL + "/slow"},
+				Timeout: 1,
+			},
+		})
+
+		start := time.Now()
+
+		ok, err := bus.Trigger("postsync", nil, data)
+		require.EqualError(t, err, "hook[notify]: timed out after 1s")
+		require.False(t, ok)
+		require.Less(t, time.Since(start), 3*time.Second)
+	})
+}
+
+// countingRunner fails the first `failures` runs of each command.
+type countingRunner struct {
+	failures int
+
+	mu   sync.Mutex
+	runs map[string]int
+}
+
+func (r *countingRunner) ExecuteStdIn(cmd string, args []string, env map[string]string, stdin io.Reader) ([]byte, error) {
+	return r.Execute(cmd, args, env, false)
+}
+
+func (r *countingRunner) Execute(cmd string, args []string, env map[string]string, enableLiveOutput bool) ([]byte, error) {
+	r.mu.Lock()
+	if r.runs == nil {
+		r.runs = map[string]int{}
+	}
+	r.runs[cmd]++
+	n := r.runs[cmd]
+	r.mu.Unlock()
+
+	if n <= r.failures {
+		return nil, fmt.Errorf("run %d of %s failed", n, cmd)
+	}
+
+	return []byte(""), nil
+}
+
+func (r *countingRunner) count(cmd string) int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.runs[cmd]
+}
+
+func TestTrigger_OnFailure(t *testing.T) {
+	cases := []struct {
+		onFailure   string
+		executed    bool
+		expectedErr string
+		warnings    int
+	}{
+		{onFailure: "", expectedErr: "hook[notify]: command `notify` failed: run 1 of notify failed"},
+		{onFailure: "fail", expectedErr: "hook[notify]: command `notify` failed: run 1 of notify failed"},
+		{onFailure: "warn", warnings: 1},
+		{onFailure: "ignore"},
+		{onFailure: "retry", expectedErr: `hook[notify]: onFailure must be one of fail, warn and ignore, but got "retry"`},
+	}
+
+	for _, c := range cases {
+		t.Run(c.onFailure, func(t *testing.T) {
+			observer, observedLogs := observer.New(zap.WarnLevel)
+
+			bus := newTestBus([]Hook{
+				{Name: "notify", Events: []string{"postsync"}, Command: "notify", OnFailure: c.onFailure},
+			})
+			bus.Logger = zap.New(observer).Sugar()
+			bus.Runner = &countingRunner{failures: 1}
+
+			ok, err := bus.Trigger("postsync", nil, nil)
+			if c.expectedErr != "" {
+				require.EqualError(t, err, c.expectedErr)
+			} else {
+				require.NoError(t, err)
+			}
+			require.Equal(t, c.executed, ok)
+			require.Equal(t, c.warnings, observedLogs.Len())
+		})
+	}
+}
+
+func TestTrigger_Retries(t *testing.T) {
+	retryInterval := hookRetryInterval
+	hookRetryInterval = time.Millisecond
+	t.Cleanup(func() { hookRetryInterval = retryInterval })
+
+	t.Run("succeeds after retries", func(t *testing.T) {
+		r := &countingRunner{failures: 2}
+
+		bus := newTestBus([]Hook{
+			{Name: "notify", Events: []string{"postsync"}, Command: "notify", Retries: 2},
+		})
+		bus.Runner = r
+
+		ok, err := bus.Trigger("postsync", nil, nil)
+		require.NoError(t, err)
+		require.True(t, ok)
+		require.Equal(t, 3, r.count("notify"))
+	})
+
+	t.Run("fails after running out of retries", func(t *testing.T) {
+		r := &countingRunner{failures: 3}
+
+		bus := newTestBus([]Hook{
+			{Name: "notify", Events: []string{"postsync"}, Command: "notify", Retries: 2},
+		})
+		bus.Runner = r
+
+		ok, err := bus.Trigger("postsync", nil, nil)
+		require.EqualError(t, err, "hook[notify]: command `notify` failed: run 3 of notify failed")
+		require.False(t, ok)
+		require.Equal(t, 3, r.count("notify"))
+	})
+}
+
+func TestTrigger_Timeout(t *testing.T) {
+	bus := newTestBus([]Hook{
+		{Name: "slow", Events: []string{"prepare"}, Command: "sleep", Args: []string{"3"}, Timeout: 1},
+	})
+	bus.Runner = helmexec.ShellRunner{Logger: bus.Logger, Ctx: context.Background()}
+
+	start := time.Now()
+
+	ok, err := bus.Trigger("prepare", nil, nil)
+	require.EqualError(t, err, "hook[slow]: timed out after 1s")
+	require.False(t, ok)
+	require.Less(t, time.Since(start), 3*time.Second)
+}
+
+func TestTrigger_If(t *testing.T) {
+	cases := []struct {
+		name        string
+		cond        string
+		evtErr      error
+		executed    bool
+		expectedErr string
+	}{
+		{name: "true", cond: `{{ eq .Environment.Name "prod" }}`, executed: true},
+		{name: "false", cond: `{{ eq .Environment.Name "dev" }}`, executed: false},
+		{name: "empty", cond: `{{ if .Event.Error }}true{{ end }}`, executed: false},
+		{name: "event error", cond: `{{ if .Event.Error }}true{{ end }}`, evtErr: fmt.Errorf("failed"), executed: true},
+		{name: "release", cond: `{{ eq .Release.Name "myrel" }}`, executed: true},
+		{name: "not a bool", cond: `maybe`, expectedErr: `hook[notify]: if: "maybe" must be rendered to either true or false, but got "maybe"`},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			r := &countingRunner{}
+
+			bus := newTestBus([]Hook{
+				{Name: "notify", Events: []string{"postsync"}, Command: "notify", If: c.cond},
+			})
+			bus.Runner = r
+
+			ok, err := bus.Trigger("postsync", c.evtErr, map[string]any{"Release": map[string]any{"Name": "myrel"}})
+			if c.expectedErr != "" {
+				require.EqualError(t, err, c.expectedErr)
+			} else {
+				require.NoError(t, err)
+			}
+			require.Equal(t, c.executed, ok)
+
+			expectedRuns := 0
+			if c.executed {
+				expectedRuns = 1
+			}
+			require.Equal(t, expectedRuns, r.count("notify"))
+		})
+	}
+}
+
+// barrierRunner blocks each command until all the `n` commands have started, so that it deadlocks unless they run concurrently.
+type barrierRunner struct {
+	runner
+
+	wg      sync.WaitGroup
+	release chan struct{}
+}
+
+func newBarrierRunner(n int) *barrierRunner {
+	r := &barrierRunner{release: make(chan struct{})}
+	r.wg.Add(n)
+	go func() {
+		r.wg.Wait()
+		close(r.release)
+	}()
+	return r
+}
+
+func (r *barrierRunner) Execute(cmd string, args []string, env map[string]string, enableLiveOutput bool) ([]byte, error) {
+	r.wg.Done()
+
+	select {
+	case <-r.release:
+	case <-time.After(10 * time.Second):
+		return nil, fmt.Errorf("%s: timed out waiting for the other hooks", cmd)
+	}
+
+	return r.runner.Execute(cmd, args, env, enableLiveOutput)
+}
+
+func TestTrigger_Parallel(t *testing.T) {
+	t.Run("runs hooks concurrently", func(t *testing.T) {
+		bus := newTestBus([]Hook{
+			{Name: "cache1", Events: []string{"prepare"}, Command: "ok", Parallel: true},
+			{Name: "cache2", Events: []string{"prepare"}, Command: "ok", Parallel: true},
+			{Name: "cache3", Events: []string{"prepare"}, Command: "ok", Parallel: true},
+		})
+		bus.Runner = newBarrierRunner(3)
+
+		ok, err := bus.Trigger("prepare", nil, nil)
+		require.NoError(t, err)
+		require.True(t, ok)
+	})
+
+	t.Run("combines errors", func(t *testing.T) {
+		bus := newTestBus([]Hook{
+			{Name: "cache1", Events: []string{"prepare"}, Command: "ng", Parallel: true},
+			{Name: "cache2", Events: []string{"prepare"}, Command: "ng", Parallel: true},
+		})
+		bus.Runner = newBarrierRunner(2)
+
+		ok, err := bus.Trigger("prepare", nil, nil)
+		require.False(t, ok)
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "hook[cache1]: command `ng` failed")
+		require.Contains(t, err.Error(), "hook[cache2]: command `ng` failed")
+	})
+}
+
+func TestTrigger_Async(t *testing.T) {
+	async := &AsyncHooks{}
+
+	r := newBarrierRunner(2)
+
+	newBus := func() *Bus {
+		bus := newTestBus([]Hook{
+			{Name: "warmup", Events: []string{"prepare"}, Command: "ng", Async: true},
+			{Name: "wait", Events: []string{"presync"}, Command: "ok"},
+		})
+		bus.Runner = r
+		bus.Async = async
+		bus.AsyncGroup = "default/myrel"
+		return bus
+	}
+
+	// The async hook doesn't block the prepare event
+	ok, err := newBus().Trigger("prepare", nil, nil)
+	require.NoError(t, err)
+	require.True(t, ok)
+
+	// The presync hook runs while the async hook is still running
+	ok, err = newBus().Trigger("presync", nil, nil)
+	require.NoError(t, err)
+	require.True(t, ok)
+
+	ok, err = newBus().Trigger("cleanup", nil, nil)
+	require.EqualError(t, err, "hook[warmup]: command `ng` failed: cmd failed due to invalid cmd: ng")
+	require.False(t, ok)
+
+	require.NoError(t, async.WaitAll())
+}
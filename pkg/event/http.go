@@ -0,0 +1,106 @@
+package event
+
+import (
+	goContext "context"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/helmfile/helmfile/pkg/tmpl"
+)
+
+// defaultHTTPHookTimeout is the timeout in seconds of HTTP hooks without a timeout
+const defaultHTTPHookTimeout = 30
+
+// HTTPHook sends an HTTP request when the hook is triggered.
+// URL, Headers and Body are rendered with the same template context as the command and args of command hooks.
+// The request is retried and timed out by the retries and timeout of the hook.
+type HTTPHook struct {
+	// Method is the HTTP method of the request (default POST)
+	Method  string            `yaml:"method,omitempty"`
+	URL     string            `yaml:"url"`
+	Headers map[string]string `yaml:"headers,omitempty"`
+	Body    string            `yaml:"body,omitempty"`
+}
+
+// unretryableError fails the hook without retrying it, like a 4xx response to the request of an HTTP hook.
+type unretryableError struct {
+	error
+}
+
+func (e unretryableError) Unwrap() error {
+	return e.error
+}
+
+type httpRequest struct {
+	method  string
+	url     string
+	headers map[string]string
+	body    string
+}
+
+func (h *HTTPHook) render(render tmpl.TextRenderer) (*httpRequest, error) {
+	req := &httpRequest{
+		method:  strings.ToUpper(h.Method),
+		headers: map[string]string{},
+	}
+	if req.method == "" {
+		req.method = http.MethodPost
+	}
+
+	var err error
+
+	if req.url, err = render.RenderTemplateText(h.URL); err != nil {
+		return nil, err
+	}
+	if req.url == "" {
+		return nil, fmt.Errorf("url must be given")
+	}
+
+	for k, v := range h.Headers {
+		if req.headers[k], err = render.RenderTemplateText(v); err != nil {
+			return nil, err
+		}
+	}
+
+	if req.body, err = render.RenderTemplateText(h.Body); err != nil {
+		return nil, err
+	}
+
+	return req, nil
+}
+
+// send sends the request, cancelling it when ctx is done, and returns the body of the response.
+// Errors other than network errors and 5xx responses are unretryable.
+func (h *HTTPHook) send(ctx goContext.Context, req *httpRequest) ([]byte, error) {
+	r, err := http.NewRequestWithContext(ctx, req.method, req.url, strings.NewReader(req.body))
+	if err != nil {
+		return nil, unretryableError{err}
+	}
+
+	for k, v := range req.headers {
+		r.Header.Set(k, v)
+	}
+
+	res, err := http.DefaultClient.Do(r)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = res.Body.Close() }()
+
+	body, err := io.ReadAll(res.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	if res.StatusCode < 200 || res.StatusCode >= 300 {
+		err := fmt.Errorf("unexpected status %s", res.Status)
+		if res.StatusCode < 500 {
+			return body, unretryableError{err}
+		}
+		return body, err
+	}
+
+	return body, nil
+}
@@ -3,6 +3,7 @@ package exectest
 import (
 	"errors"
 	"fmt"
+	"strconv"
 	"strings"
 	"sync"
 
@@ -32,9 +33,13 @@ type Helm struct {
 	Repo                 []string
 	Releases             []Release
 	Deleted              []Release
+	RolledBack           []Release
 	Linted               []Release
 	Templated            []Release
 	Lists                map[ListKey]string
+	DeployedValues       map[string]string
+	ChartVersions        map[string][]string
+	ChartMetadata        map[string]chart.Metadata
 	Diffs                map[DiffKey]error
 	Diffed               []Release
 	FailOnUnexpectedDiff bool
@@ -151,6 +156,18 @@ func (helm *Helm) DeleteRelease(context helmexec.HelmContext, name string, flags
 	helm.Deleted = append(helm.Deleted, Release{Name: name, Flags: flags})
 	return nil
 }
+func (helm *Helm) RollbackRelease(context helmexec.HelmContext, name string, revision int, flags ...string) error {
+	if strings.Contains(name, "error") {
+		return errors.New("error")
+	}
+	if revision > 0 {
+		flags = append([]string{strconv.Itoa(revision)}, flags...)
+	}
+	helm.sync(helm.ReleasesMutex, func() {
+		helm.RolledBack = append(helm.RolledBack, Release{Name: name, Flags: flags})
+	})
+	return nil
+}
 func (helm *Helm) List(context helmexec.HelmContext, filter string, flags ...string) (string, error) {
 	key := ListKey{Filter: filter, Flags: strings.Join(flags, " ")}
 
@@ -168,6 +185,12 @@ func (helm *Helm) List(context helmexec.HelmContext, filter string, flags ...str
 	}
 	return res, nil
 }
+func (helm *Helm) GetValues(context helmexec.HelmContext, name string, flags ...string) (string, error) {
+	if strings.Contains(name, "error") {
+		return "", errors.New("error")
+	}
+	return helm.DeployedValues[name], nil
+}
 func (helm *Helm) DecryptSecret(context helmexec.HelmContext, name string, flags ...string) (string, error) {
 	return "", nil
 }
@@ -234,7 +257,11 @@ func (helm *Helm) sync(m *sync.Mutex, f func()) {
 	f()
 }
 
-func (helm *Helm) ShowChart(chartPath string) (chart.Metadata, error) {
+func (helm *Helm) ShowChart(chartPath string, flags ...string) (chart.Metadata, error) {
+	if metadata, ok := helm.ChartMetadata[chartPath]; ok {
+		return metadata, nil
+	}
+
 	switch chartPath {
 	case "../../foo-bar":
 		return chart.Metadata{Version: "3.2.0"}, nil
@@ -242,3 +269,11 @@ func (helm *Helm) ShowChart(chartPath string) (chart.Metadata, error) {
 		return chart.Metadata{}, errors.New("fake test error")
 	}
 }
+
+func (helm *Helm) SearchChartVersions(chart string, flags ...string) ([]string, error) {
+	versions, ok := helm.ChartVersions[chart]
+	if !ok {
+		return nil, fmt.Errorf("no versions found for chart %q", chart)
+	}
+	return versions, nil
+}
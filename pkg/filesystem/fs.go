@@ -25,6 +25,7 @@ func (fs fileStat) Sys() any           { return nil }
 type FileSystem struct {
 	ReadFile          func(string) ([]byte, error)
 	ReadDir           func(string) ([]fs.DirEntry, error)
+	WriteFile         func(string, []byte, fs.FileMode) error
 	DeleteFile        func(string) error
 	FileExists        func(string) (bool, error)
 	Glob              func(string) ([]string, error)
@@ -40,6 +41,7 @@ type FileSystem struct {
 func DefaultFileSystem() *FileSystem {
 	dfs := FileSystem{
 		ReadDir:      os.ReadDir,
+		WriteFile:    os.WriteFile,
 		DeleteFile:   os.Remove,
 		Stat:         os.Stat,
 		Glob:         filepath.Glob,
@@ -66,6 +68,9 @@ func FromFileSystem(params FileSystem) *FileSystem {
 	if params.ReadDir != nil {
 		dfs.ReadDir = params.ReadDir
 	}
+	if params.WriteFile != nil {
+		dfs.WriteFile = params.WriteFile
+	}
 	if params.DeleteFile != nil {
 		dfs.DeleteFile = params.DeleteFile
 	}
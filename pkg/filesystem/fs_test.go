@@ -158,6 +158,7 @@ func TestFs_DefaultBuilder(t *testing.T) {
 	ffs := DefaultFileSystem()
 	if ffs.ReadFile == nil ||
 		ffs.ReadDir == nil ||
+		ffs.WriteFile == nil ||
 		ffs.DeleteFile == nil ||
 		ffs.FileExists == nil ||
 		ffs.Glob == nil ||
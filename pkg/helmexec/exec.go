@@ -2,6 +2,7 @@ package helmexec
 
 import (
 	"bytes"
+	"encoding/json"
 	"fmt"
 	"io"
 	"net/url"
@@ -305,6 +306,18 @@ func (helm *execer) List(context HelmContext, filter string, flags ...string) (s
 	return string(out), err
 }
 
+func (helm *execer) GetValues(context HelmContext, name string, flags ...string) (string, error) {
+	helm.logger.Infof("Getting values of %v", name)
+	preArgs := make([]string, 0)
+	env := make(map[string]string)
+	args := []string{"get", "values", name, "--output", "yaml"}
+
+	// Never print the values to the console, as they may contain secrets
+	enableLiveOutput := false
+	out, err := helm.exec(append(append(preArgs, args...), flags...), env, &enableLiveOutput)
+	return string(out), err
+}
+
 func (helm *execer) DecryptSecret(context HelmContext, name string, flags ...string) (string, error) {
 	absPath, err := filepath.Abs(name)
 	if err != nil {
@@ -521,6 +534,19 @@ func (helm *execer) DeleteRelease(context HelmContext, name string, flags ...str
 	return err
 }
 
+func (helm *execer) RollbackRelease(context HelmContext, name string, revision int, flags ...string) error {
+	helm.logger.Infof("Rolling back %v", name)
+	preArgs := make([]string, 0)
+	env := make(map[string]string)
+	args := []string{"rollback", name}
+	if revision > 0 {
+		args = append(args, strconv.Itoa(revision))
+	}
+	out, err := helm.exec(append(append(preArgs, args...), flags...), env, nil)
+	helm.write(nil, out)
+	return err
+}
+
 func (helm *execer) TestRelease(context HelmContext, name string, flags ...string) error {
 	helm.logger.Infof("Testing %v", name)
 	preArgs := make([]string, 0)
@@ -636,8 +662,9 @@ func resolveOciChart(ociChart string) (ociChartURL, ociChartTag string) {
 	return ociChartURL, ociChartTag
 }
 
-func (helm *execer) ShowChart(chartPath string) (chart.Metadata, error) {
+func (helm *execer) ShowChart(chartPath string, flags ...string) (chart.Metadata, error) {
 	var helmArgs = []string{"show", "chart", chartPath}
+	helmArgs = append(helmArgs, flags...)
 	out, error := helm.exec(helmArgs, map[string]string{}, nil)
 	if error != nil {
 		return chart.Metadata{}, error
@@ -649,3 +676,34 @@ func (helm *execer) ShowChart(chartPath string) (chart.Metadata, error) {
 	}
 	return metadata, nil
 }
+
+// SearchChartVersions returns the versions of the chart, like `repo/chart`, found in the local cache of the repositories.
+// The versions are sorted from the newest to the oldest, as printed by `helm search repo`.
+func (helm *execer) SearchChartVersions(chart string, flags ...string) ([]string, error) {
+	helm.logger.Infof("Searching versions of %v", chart)
+	args := []string{"search", "repo", chart, "--versions", "--output", "json"}
+
+	enableLiveOutput := false
+	out, err := helm.exec(append(args, flags...), map[string]string{}, &enableLiveOutput)
+	if err != nil {
+		return nil, err
+	}
+
+	var results []struct {
+		Name    string `json:"name"`
+		Version string `json:"version"`
+	}
+	if err := json.Unmarshal(out, &results); err != nil {
+		return nil, fmt.Errorf("unable to parse the output of helm search repo: %v", err)
+	}
+
+	// helm search repo matches the keyword against the names of all the charts, so that searching `repo/chart` also finds `repo/chart-foo`
+	var versions []string
+	for _, r := range results {
+		if r.Name == chart {
+			versions = append(versions, r.Version)
+		}
+	}
+
+	return versions, nil
+}
@@ -574,6 +574,54 @@ exec: helm --kube-context dev delete release --purge
 	}
 }
 
+func Test_GetValues(t *testing.T) {
+	var buffer bytes.Buffer
+	logger := NewLogger(&buffer, "debug")
+	helm := MockExecer(logger, "dev")
+	_, err := helm.GetValues(HelmContext{}, "release", "--namespace", "ns")
+	expected := `Getting values of release
+exec: helm --kube-context dev get values release --output yaml --namespace ns
+`
+	if err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+	if buffer.String() != expected {
+		t.Errorf("helmexec.GetValues()\nactual = %v\nexpect = %v", buffer.String(), expected)
+	}
+}
+
+func Test_RollbackRelease(t *testing.T) {
+	var buffer bytes.Buffer
+	logger := NewLogger(&buffer, "debug")
+	helm := MockExecer(logger, "dev")
+	err := helm.RollbackRelease(HelmContext{}, "release", 0, "--namespace", "ns")
+	expected := `Rolling back release
+exec: helm --kube-context dev rollback release --namespace ns
+`
+	if err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+	if buffer.String() != expected {
+		t.Errorf("helmexec.RollbackRelease()\nactual = %v\nexpect = %v", buffer.String(), expected)
+	}
+}
+
+func Test_RollbackRelease_ToRevision(t *testing.T) {
+	var buffer bytes.Buffer
+	logger := NewLogger(&buffer, "debug")
+	helm := MockExecer(logger, "dev")
+	err := helm.RollbackRelease(HelmContext{}, "release", 3)
+	expected := `Rolling back release
+exec: helm --kube-context dev rollback release 3
+`
+	if err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+	if buffer.String() != expected {
+		t.Errorf("helmexec.RollbackRelease()\nactual = %v\nexpect = %v", buffer.String(), expected)
+	}
+}
+
 func Test_TestRelease(t *testing.T) {
 	var buffer bytes.Buffer
 	logger := NewLogger(&buffer, "debug")
@@ -1030,6 +1078,35 @@ func Test_ShowChart(t *testing.T) {
 	}
 }
 
+func Test_SearchChartVersions(t *testing.T) {
+	var buffer bytes.Buffer
+	searchRunner := mockRunner{output: []byte(`[{"name":"bitnami/redis","version":"17.3.1","app_version":"7.0.5","description":""},` +
+		`{"name":"bitnami/redis-cluster","version":"8.2.7","app_version":"7.0.5","description":""},` +
+		`{"name":"bitnami/redis","version":"17.3.0","app_version":"7.0.5","description":""}]`)}
+	helm := &execer{
+		helmBinary:  "helm",
+		version:     semver.MustParse("3.3.2"),
+		logger:      NewLogger(&buffer, "debug"),
+		kubeContext: "dev",
+		runner:      &searchRunner,
+	}
+
+	versions, err := helm.SearchChartVersions("bitnami/redis", "--devel")
+	if err != nil {
+		t.Errorf("helmexec.SearchChartVersions() - unexpected error: %v", err)
+	}
+	if !reflect.DeepEqual(versions, []string{"17.3.1", "17.3.0"}) {
+		t.Errorf("helmexec.SearchChartVersions() - unexpected versions: %v", versions)
+	}
+
+	expected := `Searching versions of bitnami/redis
+exec: helm --kube-context dev search repo bitnami/redis --versions --output json --devel
+`
+	if buffer.String() != expected {
+		t.Errorf("helmexec.SearchChartVersions()\nactual = %v\nexpect = %v", buffer.String(), expected)
+	}
+}
+
 func TestParseHelmVersion(t *testing.T) {
 	tests := []struct {
 		name    string
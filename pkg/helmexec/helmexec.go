@@ -30,13 +30,16 @@ type Interface interface {
 	Lint(name, chart string, flags ...string) error
 	ReleaseStatus(context HelmContext, name string, flags ...string) error
 	DeleteRelease(context HelmContext, name string, flags ...string) error
+	RollbackRelease(context HelmContext, name string, revision int, flags ...string) error
 	TestRelease(context HelmContext, name string, flags ...string) error
 	List(context HelmContext, filter string, flags ...string) (string, error)
+	GetValues(context HelmContext, name string, flags ...string) (string, error)
 	DecryptSecret(context HelmContext, name string, flags ...string) (string, error)
 	IsHelm3() bool
 	GetVersion() Version
 	IsVersionAtLeast(versionStr string) bool
-	ShowChart(chart string) (chart.Metadata, error)
+	ShowChart(chart string, flags ...string) (chart.Metadata, error)
+	SearchChartVersions(chart string, flags ...string) ([]string, error)
 }
 
 type DependencyUpdater interface {
@@ -189,3 +189,22 @@ func Set(m map[string]any, key []string, value string) {
 
 	getCursor(key[0]).set(m, value)
 }
+
+// MergeMaps deep-merges b into a copy of a the same way Helm merges values files.
+// Nested maps are merged recursively, and any other value in b overrides the one in a.
+func MergeMaps(a, b map[string]any) map[string]any {
+	out := make(map[string]any, len(a))
+	for k, v := range a {
+		out[k] = v
+	}
+	for k, v := range b {
+		if v, ok := v.(map[string]any); ok {
+			if bv, ok := out[k].(map[string]any); ok {
+				out[k] = MergeMaps(bv, v)
+				continue
+			}
+		}
+		out[k] = v
+	}
+	return out
+}
@@ -174,3 +174,37 @@ func TestMapUtil_ParseKey(t *testing.T) {
 		}
 	}
 }
+
+func TestMapUtil_MergeMaps(t *testing.T) {
+	a := map[string]any{
+		"replicas": 1,
+		"image": map[string]any{
+			"repository": "nginx",
+			"tag":        "1.0",
+		},
+		"args": []any{"a", "b"},
+	}
+	b := map[string]any{
+		"image": map[string]any{
+			"tag": "2.0",
+		},
+		"args": []any{"c"},
+	}
+
+	expected := map[string]any{
+		"replicas": 1,
+		"image": map[string]any{
+			"repository": "nginx",
+			"tag":        "2.0",
+		},
+		"args": []any{"c"},
+	}
+
+	if actual := MergeMaps(a, b); !reflect.DeepEqual(expected, actual) {
+		t.Errorf("unexpected result: expected=%v, actual=%v", expected, actual)
+	}
+
+	if a["image"].(map[string]any)["tag"] != "1.0" {
+		t.Errorf("MergeMaps must not modify its arguments")
+	}
+}
@@ -0,0 +1,338 @@
+package policy
+
+import (
+	"fmt"
+	"io"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/Masterminds/semver/v3"
+
+	"github.com/helmfile/helmfile/pkg/environment"
+	"github.com/helmfile/helmfile/pkg/filesystem"
+	"github.com/helmfile/helmfile/pkg/state"
+	"github.com/helmfile/helmfile/pkg/tmpl"
+	"github.com/helmfile/helmfile/pkg/yaml"
+)
+
+// DefaultFile is the name of the file of the policy rules, looked up next to the helmfile
+const DefaultFile = ".helmfile-policy.yaml"
+
+// Severity is the severity of the violations of a rule
+type Severity string
+
+const (
+	// SeverityError fails the checks and prevents helmfile apply from running
+	SeverityError Severity = "error"
+	// SeverityWarning is only reported
+	SeverityWarning Severity = "warning"
+)
+
+// The built-in rules
+const (
+	// RulePinnedVersion requires the releases of remote charts to set the exact version of their chart
+	RulePinnedVersion = "pinned-version"
+	// RuleNoForce forbids the releases from being forced to update with force: true
+	RuleNoForce = "no-force"
+	// RuleRequiredLabels requires the releases to have the labels listed in the labels of the rule
+	RuleRequiredLabels = "required-labels"
+	// RuleApprovedRepositories requires the charts of the releases to come from the repositories listed in the
+	// repositories of the rule, given by their names or URLs
+	RuleApprovedRepositories = "approved-repositories"
+)
+
+var builtinRules = []string{RulePinnedVersion, RuleNoForce, RuleRequiredLabels, RuleApprovedRepositories}
+
+// Policy is the set of rules defined in a policy file
+type Policy struct {
+	Rules []Rule `yaml:"rules"`
+
+	fs       *filesystem.FileSystem
+	basePath string
+}
+
+// Rule is a rule that every release has to comply with.
+// It is either one of the built-in rules, or a Go template rendered for each release to true when the release complies.
+type Rule struct {
+	Name        string `yaml:"name"`
+	Description string `yaml:"description,omitempty"`
+	// Severity is either error, which is the default, or warning
+	Severity Severity `yaml:"severity,omitempty"`
+	// Environments are the environments the rule applies to. It applies to all environments when empty
+	Environments []string `yaml:"environments,omitempty"`
+
+	// Builtin is the name of the built-in rule
+	Builtin string `yaml:"builtin,omitempty"`
+	// Labels are the labels required by the required-labels rule
+	Labels []string `yaml:"labels,omitempty"`
+	// Repositories are the names or the URLs of the repositories approved by the approved-repositories rule
+	Repositories []string `yaml:"repositories,omitempty"`
+
+	// Template is rendered with .Release, .Environment and .Values, and must render to either true or false
+	Template string `yaml:"template,omitempty"`
+	// Message describes the violations of the template rule. It defaults to the description of the rule
+	Message string `yaml:"message,omitempty"`
+}
+
+// Violation is a release that doesn't comply with a rule
+type Violation struct {
+	Rule     string
+	Severity Severity
+	// File is the helmfile that defines the release
+	File    string
+	Release string
+	Message string
+}
+
+func (v Violation) String() string {
+	return fmt.Sprintf("%s: %s: release %q in %s: %s", v.Severity, v.Rule, v.Release, v.File, v.Message)
+}
+
+// ViolationsError is returned when releases violate rules with the error severity
+type ViolationsError struct {
+	Violations []Violation
+}
+
+func (e *ViolationsError) Error() string {
+	lines := []string{fmt.Sprintf("%d policy violation(s) found:", len(e.Violations))}
+	for _, v := range e.Violations {
+		lines = append(lines, "  "+v.String())
+	}
+
+	return strings.Join(lines, "\n")
+}
+
+// Errors returns the violations with the error severity
+func Errors(violations []Violation) []Violation {
+	var errs []Violation
+	for _, v := range violations {
+		if v.Severity == SeverityError {
+			errs = append(errs, v)
+		}
+	}
+
+	return errs
+}
+
+// Load reads the policy file and validates its rules.
+// Files read by the templates of the rules are relative to the policy file.
+func Load(fs *filesystem.FileSystem, path string) (*Policy, error) {
+	content, err := fs.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	p := &Policy{
+		fs:       fs,
+		basePath: filepath.Dir(path),
+	}
+
+	if err := yaml.NewDecoder(content, true)(p); err != nil && err != io.EOF {
+		return nil, fmt.Errorf("failed to read %s: %v", path, err)
+	}
+
+	if err := p.validate(); err != nil {
+		return nil, fmt.Errorf("invalid policy %s: %v", path, err)
+	}
+
+	return p, nil
+}
+
+func (p *Policy) validate() error {
+	names := map[string]bool{}
+
+	for i := range p.Rules {
+		r := &p.Rules[i]
+
+		if r.Name == "" {
+			return fmt.Errorf("rules[%d]: name is required", i)
+		}
+		if names[r.Name] {
+			return fmt.Errorf("rules[%d]: rule %q is defined more than once", i, r.Name)
+		}
+		names[r.Name] = true
+
+		switch r.Severity {
+		case "":
+			r.Severity = SeverityError
+		case SeverityError, SeverityWarning:
+		default:
+			return fmt.Errorf("rule %q: unsupported severity %q: expected one of %s, %s", r.Name, r.Severity, SeverityError, SeverityWarning)
+		}
+
+		if (r.Builtin == "") == (r.Template == "") {
+			return fmt.Errorf("rule %q: either builtin or template is required", r.Name)
+		}
+
+		switch r.Builtin {
+		case "", RulePinnedVersion, RuleNoForce:
+		case RuleRequiredLabels:
+			if len(r.Labels) == 0 {
+				return fmt.Errorf("rule %q: labels are required by the %s rule", r.Name, RuleRequiredLabels)
+			}
+		case RuleApprovedRepositories:
+			if len(r.Repositories) == 0 {
+				return fmt.Errorf("rule %q: repositories are required by the %s rule", r.Name, RuleApprovedRepositories)
+			}
+		default:
+			return fmt.Errorf("rule %q: unknown builtin rule %q: expected one of %s", r.Name, r.Builtin, strings.Join(builtinRules, ", "))
+		}
+	}
+
+	return nil
+}
+
+// Check returns the violations of the rules by the releases of the helmfile, in the order of the releases and the rules.
+// Releases that are not going to be installed, as they are disabled by installed: false or their condition, are skipped.
+func (p *Policy) Check(st *state.HelmState) ([]Violation, error) {
+	var violations []Violation
+
+	for i := range st.Releases {
+		r := &st.Releases[i]
+
+		if !r.Desired() {
+			continue
+		}
+
+		enabled, err := state.ConditionEnabled(*r, st.Values())
+		if err != nil {
+			return nil, err
+		}
+		if !enabled {
+			continue
+		}
+
+		for j := range p.Rules {
+			rule := &p.Rules[j]
+
+			if len(rule.Environments) > 0 && !slices.Contains(rule.Environments, st.Env.Name) {
+				continue
+			}
+
+			msg, err := p.check(rule, st, r)
+			if err != nil {
+				return nil, fmt.Errorf("checking rule %q on release %q in %s: %v", rule.Name, state.ReleaseToID(r), st.FilePath, err)
+			}
+			if msg == "" {
+				continue
+			}
+
+			violations = append(violations, Violation{
+				Rule:     rule.Name,
+				Severity: rule.Severity,
+				File:     st.FilePath,
+				Release:  state.ReleaseToID(r),
+				Message:  msg,
+			})
+		}
+	}
+
+	return violations, nil
+}
+
+// check returns the message of the violation of the rule by the release, or an empty string if the release complies
+func (p *Policy) check(rule *Rule, st *state.HelmState, r *state.ReleaseSpec) (string, error) {
+	switch rule.Builtin {
+	case RulePinnedVersion:
+		if _, _, remote := st.ChartRepository(r); !remote {
+			return "", nil
+		}
+		if r.Version == "" {
+			return "the version of the chart is not set", nil
+		}
+		if _, err := semver.StrictNewVersion(strings.TrimPrefix(r.Version, "v")); err != nil {
+			return fmt.Sprintf("the version of the chart %q is not an exact version", r.Version), nil
+		}
+	case RuleNoForce:
+		force := st.HelmDefaults.Force
+		if r.Force != nil {
+			force = *r.Force
+		}
+		if force {
+			return "force is enabled", nil
+		}
+	case RuleRequiredLabels:
+		var missing []string
+		for _, l := range rule.Labels {
+			if _, ok := r.Labels[l]; ok {
+				continue
+			}
+			if _, ok := st.CommonLabels[l]; ok {
+				continue
+			}
+			missing = append(missing, l)
+		}
+		if len(missing) > 0 {
+			sort.Strings(missing)
+			return fmt.Sprintf("missing label(s) %s", strings.Join(missing, ", ")), nil
+		}
+	case RuleApprovedRepositories:
+		name, url, remote := st.ChartRepository(r)
+		if !remote {
+			return "", nil
+		}
+		for _, approved := range rule.Repositories {
+			if (name != "" && approved == name) || (url != "" && urlApproved(url, approved)) {
+				return "", nil
+			}
+		}
+		repo := name
+		if repo == "" {
+			repo = url
+		}
+		return fmt.Sprintf("the chart %q comes from the repository %q that is not approved", r.Chart, repo), nil
+	default:
+		return p.checkTemplate(rule, st, r)
+	}
+
+	return "", nil
+}
+
+// urlApproved returns true when the url is the approved URL or a path under it.
+// `https://charts.example.com` approves `https://charts.example.com/stable` but not `https://charts.example.com.evil.io`.
+func urlApproved(url, approved string) bool {
+	url = strings.TrimSuffix(url, "/")
+	approved = strings.TrimSuffix(approved, "/")
+
+	return url == approved || strings.HasPrefix(url, approved+"/")
+}
+
+// ruleTemplateData is the data the templates of the rules are rendered with
+type ruleTemplateData struct {
+	Release     state.ReleaseSpec
+	Environment environment.Environment
+	Values      map[string]any
+}
+
+func (p *Policy) checkTemplate(rule *Rule, st *state.HelmState, r *state.ReleaseSpec) (string, error) {
+	data := ruleTemplateData{
+		Release:     *r,
+		Environment: st.Env,
+		Values:      st.Values(),
+	}
+
+	out, err := tmpl.NewTextRenderer(p.fs, p.basePath, data).RenderTemplateText(rule.Template)
+	if err != nil {
+		return "", err
+	}
+
+	ok, err := strconv.ParseBool(strings.TrimSpace(out))
+	if err != nil {
+		return "", fmt.Errorf("the template must render to either true or false, but rendered %q", out)
+	}
+	if ok {
+		return "", nil
+	}
+
+	switch {
+	case rule.Message != "":
+		return rule.Message, nil
+	case rule.Description != "":
+		return rule.Description, nil
+	}
+
+	return "the template rendered to false", nil
+}
@@ -0,0 +1,184 @@
+package policy
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/helmfile/helmfile/pkg/environment"
+	"github.com/helmfile/helmfile/pkg/state"
+	"github.com/helmfile/helmfile/pkg/testhelper"
+)
+
+func TestCheck(t *testing.T) {
+	force := true
+	notInstalled := false
+
+	policyFile := `
+rules:
+- name: pinned-version
+  builtin: pinned-version
+- name: no-force-in-prod
+  builtin: no-force
+  environments: [prod]
+- name: team-label
+  builtin: required-labels
+  severity: warning
+  labels: [team]
+- name: approved-repositories
+  builtin: approved-repositories
+  repositories: [bitnami, oci://registry.example.com/]
+- name: namespaced
+  template: '{{ ne .Release.Namespace "" }}'
+  message: the namespace must be set
+`
+
+	st := &state.HelmState{
+		FilePath: "helmfile.yaml",
+		ReleaseSetSpec: state.ReleaseSetSpec{
+			Repositories: []state.RepositorySpec{
+				{Name: "bitnami", URL: "https://charts.bitnami.com/bitnami"},
+				{Name: "incubator", URL: "https://charts.helm.sh/incubator"},
+			},
+			CommonLabels: map[string]string{"tier": "backend"},
+			Releases: []state.ReleaseSpec{
+				{Name: "db", Namespace: "data", Chart: "bitnami/postgresql", Version: "12.1.0", Labels: map[string]string{"team": "data"}},
+				{Name: "cache", Namespace: "data", Chart: "bitnami/redis", Version: "~17.3", Force: &force, Labels: map[string]string{"team": "data"}},
+				{Name: "raw", Chart: "incubator/raw", Version: "0.2.5", Labels: map[string]string{"team": "platform"}},
+				{Name: "app", Namespace: "web", Chart: "oci://registry.example.com/charts/app", Version: "v1.0.0"},
+				{Name: "local", Namespace: "web", Chart: "./charts/local", Labels: map[string]string{"team": "web"}},
+				{Name: "removed", Namespace: "web", Chart: "incubator/raw", Installed: &notInstalled},
+			},
+			Env: environment.Environment{Name: "prod"},
+		},
+		RenderedValues: map[string]any{},
+	}
+
+	fs := testhelper.NewTestFs(map[string]string{"/path/to/.helmfile-policy.yaml": policyFile}).ToFileSystem()
+
+	p, err := Load(fs, "/path/to/.helmfile-policy.yaml")
+	require.NoError(t, err)
+
+	violations, err := p.Check(st)
+	require.NoError(t, err)
+
+	require.Equal(t, []Violation{
+		{Rule: "pinned-version", Severity: SeverityError, File: "helmfile.yaml", Release: "data/cache", Message: `the version of the chart "~17.3" is not an exact version`},
+		{Rule: "no-force-in-prod", Severity: SeverityError, File: "helmfile.yaml", Release: "data/cache", Message: "force is enabled"},
+		{Rule: "approved-repositories", Severity: SeverityError, File: "helmfile.yaml", Release: "raw", Message: `the chart "incubator/raw" comes from the repository "incubator" that is not approved`},
+		{Rule: "namespaced", Severity: SeverityError, File: "helmfile.yaml", Release: "raw", Message: "the namespace must be set"},
+		{Rule: "team-label", Severity: SeverityWarning, File: "helmfile.yaml", Release: "web/app", Message: "missing label(s) team"},
+	}, violations)
+
+	require.Len(t, Errors(violations), 4)
+
+	st.Env.Name = "staging"
+
+	violations, err = p.Check(st)
+	require.NoError(t, err)
+
+	for _, v := range violations {
+		require.NotEqual(t, "no-force-in-prod", v.Rule)
+	}
+}
+
+func TestCheck_TemplateError(t *testing.T) {
+	fs := testhelper.NewTestFs(map[string]string{
+		"/path/to/.helmfile-policy.yaml": `
+rules:
+- name: not-a-bool
+  template: '{{ .Release.Name }}'
+`,
+	}).ToFileSystem()
+
+	p, err := Load(fs, "/path/to/.helmfile-policy.yaml")
+	require.NoError(t, err)
+
+	st := &state.HelmState{
+		FilePath: "helmfile.yaml",
+		ReleaseSetSpec: state.ReleaseSetSpec{
+			Releases: []state.ReleaseSpec{{Name: "app", Chart: "incubator/raw"}},
+		},
+		RenderedValues: map[string]any{},
+	}
+
+	_, err = p.Check(st)
+	require.EqualError(t, err, `checking rule "not-a-bool" on release "app" in helmfile.yaml: the template must render to either true or false, but rendered "app"`)
+}
+
+func TestLoad_Invalid(t *testing.T) {
+	testCases := []struct {
+		name    string
+		content string
+		err     string
+	}{
+		{
+			name:    "missing name",
+			content: "rules:\n- builtin: no-force\n",
+			err:     "invalid policy /path/to/.helmfile-policy.yaml: rules[0]: name is required",
+		},
+		{
+			name:    "duplicate name",
+			content: "rules:\n- name: a\n  builtin: no-force\n- name: a\n  builtin: pinned-version\n",
+			err:     `invalid policy /path/to/.helmfile-policy.yaml: rules[1]: rule "a" is defined more than once`,
+		},
+		{
+			name:    "both builtin and template",
+			content: "rules:\n- name: a\n  builtin: no-force\n  template: 'true'\n",
+			err:     `invalid policy /path/to/.helmfile-policy.yaml: rule "a": either builtin or template is required`,
+		},
+		{
+			name:    "unknown builtin",
+			content: "rules:\n- name: a\n  builtin: no-latest\n",
+			err:     `invalid policy /path/to/.helmfile-policy.yaml: rule "a": unknown builtin rule "no-latest": expected one of pinned-version, no-force, required-labels, approved-repositories`,
+		},
+		{
+			name:    "required labels without labels",
+			content: "rules:\n- name: a\n  builtin: required-labels\n",
+			err:     `invalid policy /path/to/.helmfile-policy.yaml: rule "a": labels are required by the required-labels rule`,
+		},
+		{
+			name:    "unsupported severity",
+			content: "rules:\n- name: a\n  builtin: no-force\n  severity: fatal\n",
+			err:     `invalid policy /path/to/.helmfile-policy.yaml: rule "a": unsupported severity "fatal": expected one of error, warning`,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			fs := testhelper.NewTestFs(map[string]string{"/path/to/.helmfile-policy.yaml": tc.content}).ToFileSystem()
+
+			_, err := Load(fs, "/path/to/.helmfile-policy.yaml")
+			require.EqualError(t, err, tc.err)
+		})
+	}
+}
+
+func TestViolationsError(t *testing.T) {
+	err := &ViolationsError{Violations: []Violation{
+		{Rule: "pinned-version", Severity: SeverityError, File: "helmfile.yaml", Release: "default/app", Message: "the version of the chart is not set"},
+	}}
+
+	require.EqualError(t, err, `1 policy violation(s) found:
+  error: pinned-version: release "default/app" in helmfile.yaml: the version of the chart is not set`)
+}
+
+func TestURLApproved(t *testing.T) {
+	testCases := []struct {
+		url      string
+		approved string
+		want     bool
+	}{
+		{url: "https://charts.example.com", approved: "https://charts.example.com", want: true},
+		{url: "https://charts.example.com/", approved: "https://charts.example.com", want: true},
+		{url: "https://charts.example.com/stable", approved: "https://charts.example.com", want: true},
+		{url: "oci://registry.example.com/charts/app", approved: "oci://registry.example.com/", want: true},
+		{url: "https://charts.example.com.evil.io", approved: "https://charts.example.com", want: false},
+		{url: "https://charts.example.com-attacker/stable", approved: "https://charts.example.com", want: false},
+		{url: "https://charts.example.com/stable-evil", approved: "https://charts.example.com/stable", want: false},
+	}
+
+	for _, tc := range testCases {
+		require.Equal(t, tc.want, urlApproved(tc.url, tc.approved), "%s approved by %s", tc.url, tc.approved)
+	}
+}
@@ -0,0 +1,242 @@
+package remote
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/fs"
+	neturl "net/url"
+	"os"
+	"os/exec"
+	"path"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+)
+
+// cacheEntrySuffix is appended to the path of a cache entry to get the path of its metadata file
+const cacheEntrySuffix = ".helmfile-cache.json"
+
+var (
+	// now is replaced in tests
+	now = time.Now
+
+	commitRefPattern  = regexp.MustCompile(`^[0-9a-f]{7,40}$`)
+	versionRefPattern = regexp.MustCompile(`^v?[0-9]+\.[0-9]+\.[0-9]+([-+][0-9A-Za-z.+-]*)?$`)
+)
+
+// CacheEntry is the metadata of a remote file or directory in the cache directory
+type CacheEntry struct {
+	// Source is the URL the entry was fetched from, with secrets like the ssh key redacted
+	Source string `json:"source"`
+	// Path is the path to the cached file or directory
+	Path string `json:"path"`
+	// FetchedAt is the time the entry was fetched
+	FetchedAt time.Time `json:"fetchedAt"`
+	// Revision is the resolved commit for git sources or the ETag for http sources, if known
+	Revision string `json:"revision,omitempty"`
+	// Digest is the SHA-256 of the fetched file, or of the whole tree for a fetched directory
+	Digest string `json:"digest,omitempty"`
+	// Immutable is true when the source points to a tag or a commit, so that the entry never expires
+	Immutable bool `json:"immutable,omitempty"`
+}
+
+// Age returns how long ago the entry was fetched
+func (e CacheEntry) Age() time.Duration {
+	return now().Sub(e.FetchedAt)
+}
+
+// revisionGetter is implemented by getters that can tell which revision of the source they fetched
+type revisionGetter interface {
+	Revision(src, dst string) (string, error)
+}
+
+// isImmutableRef returns true when the `ref` of the source is a commit SHA or a full semver tag like `v1.2.3`.
+// Branches like `main`, and refs like `v2` that are commonly moving major version branches, are never considered immutable.
+func isImmutableRef(u *Source) bool {
+	q, err := neturl.ParseQuery(u.RawQuery)
+	if err != nil {
+		return false
+	}
+
+	ref := q.Get("ref")
+
+	return commitRefPattern.MatchString(ref) || versionRefPattern.MatchString(ref)
+}
+
+// redactSource removes secrets from the source so that it can be written to the cache metadata
+func redactSource(src string) string {
+	i := strings.LastIndex(src, "?")
+	if i < 0 {
+		return src
+	}
+
+	q, err := neturl.ParseQuery(src[i+1:])
+	if err != nil || !q.Has("sshkey") {
+		return src
+	}
+	q.Set("sshkey", "redacted")
+
+	return src[:i+1] + q.Encode()
+}
+
+func (r *Remote) readCacheEntry(path string) (*CacheEntry, error) {
+	bs, err := r.fs.ReadFile(path + cacheEntrySuffix)
+	if err != nil {
+		return nil, err
+	}
+
+	var entry CacheEntry
+	if err := json.Unmarshal(bs, &entry); err != nil {
+		return nil, fmt.Errorf("parsing cache metadata of %s: %v", path, err)
+	}
+
+	return &entry, nil
+}
+
+func (r *Remote) writeCacheEntry(entry CacheEntry) error {
+	bs, err := json.MarshalIndent(entry, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	return r.fs.WriteFile(entry.Path+cacheEntrySuffix, bs, 0644)
+}
+
+// isStale returns true when the cached entry must be fetched again,
+// either because refreshing was requested or because the entry is older than the TTL.
+func (r *Remote) isStale(path string, immutable bool) bool {
+	if immutable {
+		return false
+	}
+
+	if r.Refresh {
+		return !r.fetchedInThisRun(path)
+	}
+
+	if r.CacheTTL <= 0 {
+		return false
+	}
+
+	entry, err := r.readCacheEntry(path)
+	if err != nil {
+		// The entry was fetched by an older helmfile or its metadata is broken, so we can't tell its age
+		r.Logger.Debugf("remote> unable to read cache metadata of %s: %v", path, err)
+		return true
+	}
+
+	return entry.Age() > r.CacheTTL
+}
+
+func (r *Remote) fetchedInThisRun(path string) bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return r.fetched[path]
+}
+
+func (r *Remote) markFetched(path string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.fetched == nil {
+		r.fetched = map[string]bool{}
+	}
+	r.fetched[path] = true
+}
+
+// evict removes the cached file or directory along with its metadata
+func (r *Remote) evict(path string, isDir bool) error {
+	var err error
+	if isDir {
+		err = os.RemoveAll(path)
+	} else {
+		err = r.fs.DeleteFile(path)
+	}
+	if err != nil && !os.IsNotExist(err) {
+		return err
+	}
+
+	if err := r.fs.DeleteFile(path + cacheEntrySuffix); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+
+	return nil
+}
+
+// ListCacheEntries returns the metadata of all the entries in the cache directory, sorted by path.
+func ListCacheEntries(home string) ([]CacheEntry, error) {
+	var entries []CacheEntry
+
+	err := filepath.WalkDir(home, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+
+		if d.IsDir() || !strings.HasSuffix(path, cacheEntrySuffix) {
+			return nil
+		}
+
+		bs, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+
+		var entry CacheEntry
+		if err := json.Unmarshal(bs, &entry); err != nil {
+			return fmt.Errorf("parsing cache metadata %s: %v", path, err)
+		}
+		entry.Path = strings.TrimSuffix(path, cacheEntrySuffix)
+
+		entries = append(entries, entry)
+
+		return nil
+	})
+	if err != nil && !os.IsNotExist(err) {
+		return nil, err
+	}
+
+	sort.Slice(entries, func(i, j int) bool {
+		return entries[i].Path < entries[j].Path
+	})
+
+	return entries, nil
+}
+
+// Revision returns the commit checked out in dst, if the source was a git repository
+func (g *GoGetter) Revision(src, dst string) (string, error) {
+	if _, err := os.Stat(filepath.Join(dst, ".git")); err != nil {
+		return "", nil
+	}
+
+	out, err := exec.Command("git", "-C", dst, "rev-parse", "HEAD").Output()
+	if err != nil {
+		return "", fmt.Errorf("resolving the commit of %s: %v", src, err)
+	}
+
+	return strings.TrimSpace(string(out)), nil
+}
+
+// Revision returns the ETag of the response the remote file was fetched with, if the server sent one
+func (g *HttpGetter) Revision(src, dst string) (string, error) {
+	u, err := neturl.Parse(src)
+	if err != nil {
+		return "", err
+	}
+
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	return g.etags[filepath.Join(dst, path.Base(u.Path))], nil
+}
+
+func (g *HttpGetter) setETag(file, etag string) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	if g.etags == nil {
+		g.etags = map[string]string{}
+	}
+	g.etags[file] = etag
+}
@@ -0,0 +1,305 @@
+package remote
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+
+	"github.com/helmfile/helmfile/pkg/helmexec"
+	"github.com/helmfile/helmfile/pkg/testhelper"
+)
+
+func TestRemote_Fetch_CacheFreshness(t *testing.T) {
+	fetchedAt := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	origNow := now
+	defer func() { now = origNow }()
+
+	metadata := func(t *testing.T, path string) string {
+		t.Helper()
+
+		bs, err := json.Marshal(CacheEntry{Source: "git::https://github.com/helmfile/helmfile.git@README.md", Path: path, FetchedAt: fetchedAt})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		return string(bs)
+	}
+
+	testcases := []struct {
+		name           string
+		ref            string
+		age            time.Duration
+		ttl            time.Duration
+		refresh        bool
+		noMetadata     bool
+		expectCacheHit bool
+	}{
+		{name: "no ttl", ref: "main", age: 240 * time.Hour, expectCacheHit: true},
+		{name: "fresh", ref: "main", age: 30 * time.Minute, ttl: time.Hour, expectCacheHit: true},
+		{name: "expired", ref: "main", age: 2 * time.Hour, ttl: time.Hour, expectCacheHit: false},
+		{name: "no metadata", ref: "main", ttl: time.Hour, noMetadata: true, expectCacheHit: false},
+		{name: "expired tag", ref: "v0.151.0", age: 2 * time.Hour, ttl: time.Hour, expectCacheHit: true},
+		{name: "expired commit", ref: "8a5d2e3", age: 2 * time.Hour, ttl: time.Hour, expectCacheHit: true},
+		{name: "refresh", ref: "main", refresh: true, expectCacheHit: false},
+		{name: "refresh tag", ref: "v0.151.0", refresh: true, expectCacheHit: true},
+	}
+
+	for _, tt := range testcases {
+		t.Run(tt.name, func(t *testing.T) {
+			home := t.TempDir()
+			entry := filepath.Join(home, "https_github_com_helmfile_helmfile_git.ref="+tt.ref)
+
+			files := map[string]string{
+				filepath.Join(entry, "README.md"): "foo: bar",
+			}
+			if !tt.noMetadata {
+				files[entry+cacheEntrySuffix] = metadata(t, entry)
+			}
+			testfs := testhelper.NewTestFs(files)
+
+			now = func() time.Time { return fetchedAt.Add(tt.age) }
+
+			fetches := 0
+			remote := &Remote{
+				Logger: helmexec.NewLogger(io.Discard, "debug"),
+				Home:   home,
+				Getter: &testGetter{
+					get: func(wd, src, dst string) error {
+						fetches++
+						return nil
+					},
+				},
+				CacheTTL: tt.ttl,
+				Refresh:  tt.refresh,
+				fs:       testfs.ToFileSystem(),
+			}
+
+			url := "git::https://github.com/helmfile/helmfile.git@README.md?ref=" + tt.ref
+			if _, err := remote.Fetch(url); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			if tt.expectCacheHit && fetches != 0 {
+				t.Errorf("unexpected cache miss")
+			}
+			if !tt.expectCacheHit && fetches != 1 {
+				t.Errorf("unexpected cache hit")
+			}
+
+			// The entry must be fetched only once per run, even when refreshing
+			if _, err := remote.Fetch(url); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if fetches > 1 {
+				t.Errorf("unexpected number of fetches: want at most 1, got %d", fetches)
+			}
+		})
+	}
+}
+
+func TestRemote_Fetch_WritesCacheMetadata(t *testing.T) {
+	fetchedAt := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	origNow := now
+	defer func() { now = origNow }()
+	now = func() time.Time { return fetchedAt }
+
+	home := t.TempDir()
+	testfs := testhelper.NewTestFs(map[string]string{})
+
+	remote := &Remote{
+		Logger: helmexec.NewLogger(io.Discard, "debug"),
+		Home:   home,
+		Getter: &testGetter{
+			get: func(wd, src, dst string) error { return nil },
+		},
+		fs: testfs.ToFileSystem(),
+	}
+
+	if _, err := remote.Fetch("git::ssh://git@github.com/helmfile/helmfile.git@README.md?ref=v0.151.0&sshkey=secret"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	path := filepath.Join(home, "ssh_github_com_helmfile_helmfile_git.ref=v0.151.0_sshkey=redacted")
+
+	bs, err := testfs.ReadFile(path + cacheEntrySuffix)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	var got CacheEntry
+	if err := json.Unmarshal(bs, &got); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	want := CacheEntry{
+		Source:    "git::ssh://git@github.com/helmfile/helmfile.git@README.md?ref=v0.151.0&sshkey=redacted",
+		Path:      path,
+		FetchedAt: fetchedAt,
+		Immutable: true,
+	}
+
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("unexpected cache metadata: %s", diff)
+	}
+}
+
+func TestListCacheEntries(t *testing.T) {
+	home := t.TempDir()
+
+	entries := []CacheEntry{
+		{
+			Source:    "https://example.com/values/values.yaml",
+			Path:      filepath.Join(home, "https_example_com", "values", "values.yaml"),
+			FetchedAt: time.Date(2024, 1, 2, 0, 0, 0, 0, time.UTC),
+			Revision:  `"abc"`,
+		},
+		{
+			Source:    "git::https://github.com/helmfile/helmfile.git@README.md?ref=v0.151.0",
+			Path:      filepath.Join(home, "https_github_com_helmfile_helmfile_git.ref=v0.151.0"),
+			FetchedAt: time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC),
+			Revision:  "8a5d2e3",
+			Immutable: true,
+		},
+	}
+
+	for _, e := range entries {
+		if err := os.MkdirAll(filepath.Dir(e.Path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		bs, err := json.Marshal(e)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(e.Path+cacheEntrySuffix, bs, 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	got, err := ListCacheEntries(home)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if diff := cmp.Diff(entries, got); diff != "" {
+		t.Errorf("unexpected cache entries: %s", diff)
+	}
+
+	got, err = ListCacheEntries(filepath.Join(home, "nonexistent"))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(got) != 0 {
+		t.Errorf("unexpected cache entries: %v", got)
+	}
+}
+
+func TestHttpGetter_Revision(t *testing.T) {
+	var requests int
+	var getETag string
+
+	// The ETag changes on every request, as if the file was updated between the requests
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		etag := fmt.Sprintf(`"%d"`, requests)
+		if r.Method == http.MethodGet {
+			getETag = etag
+		}
+		w.Header().Set("ETag", etag)
+		_, _ = w.Write([]byte("foo: bar"))
+	}))
+	defer server.Close()
+
+	g := &HttpGetter{Logger: helmexec.NewLogger(io.Discard, "debug")}
+
+	dst := t.TempDir()
+	src := server.URL + "/values/values.yaml"
+
+	if err := g.Get(dst, src, dst); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	rev, err := g.Revision(src, dst)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if getETag == "" || rev != getETag {
+		t.Errorf("unexpected revision: want the ETag %s of the GET response, got %s", getETag, rev)
+	}
+}
+
+func TestIsImmutableRef(t *testing.T) {
+	testcases := []struct {
+		query string
+		want  bool
+	}{
+		{query: "", want: false},
+		{query: "ref=main", want: false},
+		{query: "ref=release-1.0", want: false},
+		{query: "ref=v0.151.0", want: true},
+		{query: "ref=1.2.3-rc.1", want: true},
+		{query: "ref=8a5d2e3", want: true},
+		{query: "ref=8a5d2e3c9b0f1e2d3c4b5a69788a5d2e3c9b0f1e", want: true},
+		{query: "depth=1&ref=v1.0.0", want: true},
+		{query: "ref=v1", want: false},
+		{query: "ref=2", want: false},
+		{query: "ref=v1.2", want: false},
+	}
+
+	for _, tt := range testcases {
+		t.Run(tt.query, func(t *testing.T) {
+			if got := isImmutableRef(&Source{RawQuery: tt.query}); got != tt.want {
+				t.Errorf("unexpected result for %q: want %v, got %v", tt.query, tt.want, got)
+			}
+		})
+	}
+}
+
+func TestRemote_Fetch_Offline(t *testing.T) {
+	home := t.TempDir()
+	entry := filepath.Join(home, "https_github_com_helmfile_helmfile_git.ref=main")
+
+	testfs := testhelper.NewTestFs(map[string]string{
+		filepath.Join(entry, "README.md"): "foo: bar",
+	})
+
+	remote := &Remote{
+		Logger: helmexec.NewLogger(io.Discard, "debug"),
+		Home:   home,
+		Getter: &testGetter{
+			get: func(wd, src, dst string) error {
+				t.Errorf("unexpected fetch of %s", src)
+				return nil
+			},
+		},
+		// Cached entries must be used even when they are stale
+		Refresh: true,
+		Offline: true,
+		fs:      testfs.ToFileSystem(),
+	}
+
+	file, err := remote.Fetch("git::https://github.com/helmfile/helmfile.git@README.md?ref=main")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if want := filepath.Join(entry, "README.md"); file != want {
+		t.Errorf("unexpected file: want %s, got %s", want, file)
+	}
+
+	_, err = remote.Fetch("git::ssh://git@github.com/helmfile/helmfile.git@README.md?ref=main&sshkey=secret")
+
+	want := "git::ssh://git@github.com/helmfile/helmfile.git@README.md?ref=main&sshkey=redacted is not in the cache, and fetching it is not allowed in offline mode"
+	if err == nil || err.Error() != want {
+		t.Errorf("unexpected error: want %q, got %v", want, err)
+	}
+}
@@ -0,0 +1,172 @@
+package remote
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io/fs"
+	neturl "net/url"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// checksumParam is the query parameter used to pin the SHA-256 of a remote file or directory,
+// e.g. `git::https://github.com/cloudposse/helmfiles.git@releases/kiam.yaml?ref=main&checksum=sha256:...`
+const checksumParam = "checksum"
+
+var sha256Pattern = regexp.MustCompile(`^[0-9a-f]{64}$`)
+
+// ChecksumMismatchError is returned when the fetched file or directory doesn't match the pinned checksum
+type ChecksumMismatchError struct {
+	Source   string
+	Expected string
+	Actual   string
+}
+
+func (e *ChecksumMismatchError) Error() string {
+	return fmt.Sprintf("checksum mismatch for %s: expected %s, got %s", e.Source, e.Expected, e.Actual)
+}
+
+// parseChecksum normalizes the value of the checksum parameter, which is either `sha256:<hex>` or just `<hex>`.
+func parseChecksum(v string) (string, error) {
+	algo, sum, found := strings.Cut(v, ":")
+	if !found {
+		algo, sum = "sha256", v
+	}
+
+	if algo != "sha256" {
+		return "", fmt.Errorf("unsupported checksum type %q: only sha256 is supported", algo)
+	}
+
+	sum = strings.ToLower(sum)
+	if !sha256Pattern.MatchString(sum) {
+		return "", fmt.Errorf("invalid sha256 checksum %q: it must be 64 hexadecimal characters", sum)
+	}
+
+	return "sha256:" + sum, nil
+}
+
+// splitChecksum removes the checksum parameter from the query so that it isn't sent to the remote,
+// and returns the remaining query along with the normalized checksum.
+func splitChecksum(rawQuery string) (string, string, error) {
+	if !strings.Contains(rawQuery, checksumParam+"=") {
+		return rawQuery, "", nil
+	}
+
+	q, err := neturl.ParseQuery(rawQuery)
+	if err != nil {
+		return "", "", err
+	}
+
+	checksum, err := parseChecksum(q.Get(checksumParam))
+	if err != nil {
+		return "", "", err
+	}
+	q.Del(checksumParam)
+
+	return q.Encode(), checksum, nil
+}
+
+// withQuery replaces the query of the go-getter source
+func withQuery(src, rawQuery string) string {
+	if i := strings.LastIndex(src, "?"); i >= 0 {
+		src = src[:i]
+	}
+
+	if rawQuery == "" {
+		return src
+	}
+
+	return src + "?" + rawQuery
+}
+
+// verify returns an error when the cached entry doesn't match the checksum
+func (r *Remote) verify(src, path string, isDir bool, checksum string) error {
+	digest, err := r.digest(path, isDir)
+	if err != nil {
+		return fmt.Errorf("computing checksum of %s: %v", path, err)
+	}
+
+	if digest != checksum {
+		return &ChecksumMismatchError{Source: redactSource(src), Expected: checksum, Actual: digest}
+	}
+
+	return nil
+}
+
+// digest returns the SHA-256 of the cached file, or of the whole tree for a cached directory.
+// The digest of a tree is computed over the sorted relative paths and the digests of the files in it,
+// ignoring the .git directory so that it doesn't depend on how the repository was cloned.
+// A symlink is digested as a file containing the path it points to, the same as git stores it,
+// so that replacing a file with a symlink to any other file never passes the verification.
+func (r *Remote) digest(path string, isDir bool) (string, error) {
+	if !isDir {
+		bs, err := r.fs.ReadFile(path)
+		if err != nil {
+			return "", err
+		}
+		return fmt.Sprintf("sha256:%x", sha256.Sum256(bs)), nil
+	}
+
+	sums := map[string][sha256.Size]byte{}
+
+	err := filepath.WalkDir(path, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+
+		if d.IsDir() {
+			if d.Name() == ".git" {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+
+		var bs []byte
+
+		switch {
+		case d.Type()&fs.ModeSymlink != 0:
+			target, err := os.Readlink(p)
+			if err != nil {
+				return err
+			}
+			bs = []byte(target)
+		case d.Type().IsRegular():
+			bs, err = r.fs.ReadFile(p)
+			if err != nil {
+				return err
+			}
+		default:
+			return nil
+		}
+
+		rel, err := filepath.Rel(path, p)
+		if err != nil {
+			return err
+		}
+
+		sums[filepath.ToSlash(rel)] = sha256.Sum256(bs)
+
+		return nil
+	})
+	if err != nil {
+		return "", err
+	}
+
+	// WalkDir sorts by file name in each directory, which differs from sorting by path, e.g. for `a/b` and `a.txt`
+	paths := make([]string, 0, len(sums))
+	for p := range sums {
+		paths = append(paths, p)
+	}
+	sort.Strings(paths)
+
+	h := sha256.New()
+	for _, p := range paths {
+		fmt.Fprintf(h, "%x  %s\n", sums[p], p)
+	}
+
+	return "sha256:" + hex.EncodeToString(h.Sum(nil)), nil
+}
@@ -0,0 +1,222 @@
+package remote
+
+import (
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/helmfile/helmfile/pkg/filesystem"
+	"github.com/helmfile/helmfile/pkg/helmexec"
+)
+
+func TestParseChecksum(t *testing.T) {
+	sum := fmt.Sprintf("%x", sha256.Sum256([]byte("foo")))
+
+	testcases := []struct {
+		value string
+		want  string
+		err   string
+	}{
+		{value: "sha256:" + sum, want: "sha256:" + sum},
+		{value: sum, want: "sha256:" + sum},
+		{value: "SHA256:" + sum, err: `unsupported checksum type "SHA256": only sha256 is supported`},
+		{value: "md5:acbd18db4cc2f85cedef654fccc4a4d8", err: `unsupported checksum type "md5": only sha256 is supported`},
+		{value: "sha256:abc", err: `invalid sha256 checksum "abc": it must be 64 hexadecimal characters`},
+	}
+
+	for _, tt := range testcases {
+		t.Run(tt.value, func(t *testing.T) {
+			got, err := parseChecksum(tt.value)
+			if tt.err != "" {
+				if err == nil || err.Error() != tt.err {
+					t.Fatalf("unexpected error: want %q, got %v", tt.err, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("unexpected checksum: want %s, got %s", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestRemote_Fetch_Checksum(t *testing.T) {
+	content := "foo: bar\n"
+
+	// The digest of a tree containing only README.md
+	fileSum := sha256.Sum256([]byte(content))
+	treeSum := sha256.Sum256([]byte(fmt.Sprintf("%x  README.md\n", fileSum)))
+	checksum := fmt.Sprintf("sha256:%x", treeSum)
+
+	testcases := []struct {
+		name     string
+		checksum string
+		cached   string
+		fetched  string
+		err      string
+	}{
+		{name: "match", checksum: checksum, fetched: content},
+		{name: "mismatch", checksum: checksum, fetched: "foo: baz\n", err: "checksum mismatch"},
+		{name: "cached match", checksum: checksum, cached: content},
+		{name: "cached tampered", checksum: checksum, cached: "foo: baz\n", fetched: content},
+		{name: "cached tampered and mismatch", checksum: checksum, cached: "foo: baz\n", fetched: "foo: qux\n", err: "checksum mismatch"},
+	}
+
+	for _, tt := range testcases {
+		t.Run(tt.name, func(t *testing.T) {
+			home := t.TempDir()
+			dir := filepath.Join(home, "https_github_com_helmfile_helmfile_git.checksum="+tt.checksum[:6]+"%3A"+tt.checksum[7:]+"_ref=main")
+
+			if tt.cached != "" {
+				if err := os.MkdirAll(dir, 0755); err != nil {
+					t.Fatal(err)
+				}
+				if err := os.WriteFile(filepath.Join(dir, "README.md"), []byte(tt.cached), 0644); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			fetched := false
+			remote := &Remote{
+				Logger: helmexec.NewLogger(io.Discard, "debug"),
+				Home:   home,
+				Getter: &testGetter{
+					get: func(wd, src, dst string) error {
+						if src != "git::https://github.com/helmfile/helmfile.git?ref=main" {
+							return fmt.Errorf("unexpected src: %s", src)
+						}
+						fetched = true
+						if err := os.MkdirAll(dst, 0755); err != nil {
+							return err
+						}
+						return os.WriteFile(filepath.Join(dst, "README.md"), []byte(tt.fetched), 0644)
+					},
+				},
+				fs: filesystem.DefaultFileSystem(),
+			}
+
+			file, err := remote.Fetch("git::https://github.com/helmfile/helmfile.git@README.md?ref=main&checksum=" + tt.checksum)
+
+			if tt.err != "" {
+				var mismatch *ChecksumMismatchError
+				if !errors.As(err, &mismatch) {
+					t.Fatalf("unexpected error: want checksum mismatch, got %v", err)
+				}
+				if _, err := os.Stat(dir); !os.IsNotExist(err) {
+					t.Errorf("the fetched directory must be removed on checksum mismatch")
+				}
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			if fetched != (tt.fetched != "") {
+				t.Errorf("unexpected fetch: want %v, got %v", tt.fetched != "", fetched)
+			}
+
+			bs, err := os.ReadFile(file)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(bs) != content {
+				t.Errorf("unexpected content: %s", string(bs))
+			}
+		})
+	}
+}
+
+func TestRemote_Fetch_InvalidChecksum(t *testing.T) {
+	remote := &Remote{
+		Logger: helmexec.NewLogger(io.Discard, "debug"),
+		Home:   t.TempDir(),
+		Getter: &testGetter{
+			get: func(wd, src, dst string) error {
+				return fmt.Errorf("unexpected fetch of %s", src)
+			},
+		},
+		fs: filesystem.DefaultFileSystem(),
+	}
+
+	_, err := remote.Fetch("git::https://github.com/helmfile/helmfile.git@README.md?ref=main&checksum=md5:acbd18db4cc2f85cedef654fccc4a4d8")
+
+	want := `parsing checksum of git::https://github.com/helmfile/helmfile.git@README.md?ref=main&checksum=md5:acbd18db4cc2f85cedef654fccc4a4d8: unsupported checksum type "md5": only sha256 is supported`
+	if err == nil || err.Error() != want {
+		t.Errorf("unexpected error: want %q, got %v", want, err)
+	}
+}
+
+func TestRemote_digest_Tree(t *testing.T) {
+	dir := t.TempDir()
+
+	files := map[string]string{
+		"a.txt":       "a",
+		"a/b":         "b",
+		".git/HEAD":   "ref: refs/heads/main",
+		"dir/c.yaml":  "c: d",
+		"dir/.hidden": "e",
+	}
+	for p, content := range files {
+		if err := os.MkdirAll(filepath.Dir(filepath.Join(dir, p)), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(filepath.Join(dir, p), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// Sorted by path like `LC_ALL=C sort` does, without the .git directory
+	var sums string
+	for _, p := range []string{"a.txt", "a/b", "dir/.hidden", "dir/c.yaml"} {
+		sums += fmt.Sprintf("%x  %s\n", sha256.Sum256([]byte(files[p])), p)
+	}
+	want := fmt.Sprintf("sha256:%x", sha256.Sum256([]byte(sums)))
+
+	remote := &Remote{fs: filesystem.DefaultFileSystem()}
+
+	got, err := remote.digest(dir, true)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got != want {
+		t.Errorf("unexpected digest: want %s, got %s", want, got)
+	}
+
+	// A file replaced with a symlink to another file of the same content is digested as the path it points to
+	outside := filepath.Join(t.TempDir(), "c.yaml")
+	if err := os.WriteFile(outside, []byte(files["dir/c.yaml"]), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Remove(filepath.Join(dir, "dir/c.yaml")); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Symlink(outside, filepath.Join(dir, "dir/c.yaml")); err != nil {
+		t.Fatal(err)
+	}
+
+	sums = ""
+	for _, p := range []string{"a.txt", "a/b", "dir/.hidden"} {
+		sums += fmt.Sprintf("%x  %s\n", sha256.Sum256([]byte(files[p])), p)
+	}
+	sums += fmt.Sprintf("%x  %s\n", sha256.Sum256([]byte(outside)), "dir/c.yaml")
+	symlinked := fmt.Sprintf("sha256:%x", sha256.Sum256([]byte(sums)))
+
+	got, err = remote.digest(dir, true)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got == want {
+		t.Errorf("the digest must change when a file is replaced with a symlink")
+	}
+	if got != symlinked {
+		t.Errorf("unexpected digest: want %s, got %s", symlinked, got)
+	}
+}
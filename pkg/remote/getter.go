@@ -0,0 +1,205 @@
+package remote
+
+import (
+	"context"
+	"fmt"
+	"io"
+	neturl "net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/azidentity"
+	"github.com/Azure/azure-sdk-for-go/sdk/storage/azblob"
+	"github.com/hashicorp/go-getter"
+	"github.com/hashicorp/go-getter/helper/url"
+	"go.uber.org/zap"
+)
+
+// GetterFactory creates the getter used by a remote to fetch files
+type GetterFactory func(logger *zap.SugaredLogger) Getter
+
+var (
+	gettersMu sync.RWMutex
+
+	// getters are the factories of the getters used for fetching single files, keyed by URL scheme.
+	// Sources with a forced getter like `git::` are fetched by go-getter instead.
+	getters = map[string]GetterFactory{
+		"s3":    func(logger *zap.SugaredLogger) Getter { return &S3Getter{Logger: logger} },
+		"http":  func(logger *zap.SugaredLogger) Getter { return &HttpGetter{Logger: logger} },
+		"https": func(logger *zap.SugaredLogger) Getter { return &HttpGetter{Logger: logger} },
+		"gs":    func(logger *zap.SugaredLogger) Getter { return &GCSGetter{Logger: logger} },
+		"az":    func(logger *zap.SugaredLogger) Getter { return &AzureBlobGetter{Logger: logger} },
+		"oci":   func(logger *zap.SugaredLogger) Getter { return &OCIGetter{Logger: logger} },
+		"file":  func(logger *zap.SugaredLogger) Getter { return &FileGetter{Logger: logger} },
+	}
+)
+
+// RegisterGetter makes remotes fetch the files with URLs like `<scheme>://host/path/to/file` with the getter created by newGetter.
+// It replaces the getter already registered for the scheme, if any.
+// Remotes created before the call are not affected.
+func RegisterGetter(scheme string, newGetter GetterFactory) {
+	gettersMu.Lock()
+	defer gettersMu.Unlock()
+
+	getters[strings.ToLower(scheme)] = newGetter
+}
+
+func isRegisteredScheme(scheme string) bool {
+	gettersMu.RLock()
+	defer gettersMu.RUnlock()
+
+	_, ok := getters[scheme]
+
+	return ok
+}
+
+func newGetters(logger *zap.SugaredLogger) map[string]Getter {
+	gettersMu.RLock()
+	defer gettersMu.RUnlock()
+
+	gs := make(map[string]Getter, len(getters))
+	for scheme, newGetter := range getters {
+		gs[scheme] = newGetter(logger)
+	}
+
+	return gs
+}
+
+// GCSGetter fetches files from Google Cloud Storage with URLs like `gs://bucket/path/to/file`.
+// It uses the application default credentials, or the token in GOOGLE_OAUTH_ACCESS_TOKEN.
+type GCSGetter struct {
+	Logger *zap.SugaredLogger
+}
+
+func (g *GCSGetter) Get(wd, src, dst string) error {
+	u, err := url.Parse(src)
+	if err != nil {
+		return err
+	}
+
+	// go-getter only understands the URLs of the JSON API
+	api, err := url.Parse(fmt.Sprintf("https://www.googleapis.com/storage/v1/%s%s", u.Host, u.Path))
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(dst, os.FileMode(0700)); err != nil {
+		return err
+	}
+
+	g.Logger.Debugf("Fetching %s from %s", src, api)
+
+	gcs := &getter.GCSGetter{}
+
+	return gcs.GetFile(filepath.Join(dst, path.Base(u.Path)), api)
+}
+
+// AzureBlobGetter fetches files from Azure Blob Storage with URLs like `az://account/container/path/to/file`.
+// It uses the default Azure credential chain, i.e. environment variables, workload identity, managed identity or the Azure CLI.
+type AzureBlobGetter struct {
+	Logger *zap.SugaredLogger
+}
+
+func (g *AzureBlobGetter) Get(wd, src, dst string) error {
+	account, container, blob, err := ParseAzureBlobURL(src)
+	if err != nil {
+		return err
+	}
+
+	cred, err := azidentity.NewDefaultAzureCredential(nil)
+	if err != nil {
+		return fmt.Errorf("creating azure credential: %v", err)
+	}
+
+	client, err := azblob.NewClient(fmt.Sprintf("https://%s.blob.core.windows.net/", account), cred, nil)
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(dst, os.FileMode(0700)); err != nil {
+		return err
+	}
+
+	localFile, err := os.Create(filepath.Join(dst, path.Base(blob)))
+	if err != nil {
+		return err
+	}
+	defer func(localFile *os.File) {
+		err := localFile.Close()
+		if err != nil {
+			g.Logger.Errorf("Error writing file \n%v", err)
+		}
+	}(localFile)
+
+	g.Logger.Debugf("Downloading blob %s from container %s of account %s", blob, container, account)
+
+	_, err = client.DownloadFile(context.Background(), container, blob, localFile, nil)
+
+	return err
+}
+
+// ParseAzureBlobURL returns the storage account, the container and the blob name of an `az://account/container/blob` URL
+func ParseAzureBlobURL(azURL string) (string, string, string, error) {
+	u, err := url.Parse(azURL)
+	if err != nil {
+		return "", "", "", fmt.Errorf("failed to parse Azure Blob URL: %w", err)
+	}
+
+	if u.Scheme != "az" {
+		return "", "", "", fmt.Errorf("invalid URL scheme (expected 'az')")
+	}
+
+	container, blob, _ := strings.Cut(strings.TrimPrefix(u.Path, "/"), "/")
+	if u.Host == "" || container == "" || blob == "" {
+		return "", "", "", fmt.Errorf("invalid Azure Blob URL %s: expected az://<account>/<container>/<blob>", azURL)
+	}
+
+	return u.Host, container, blob, nil
+}
+
+// FileGetter copies files with URLs like `file:///path/to/file`, which is useful for mirrors on a shared filesystem
+type FileGetter struct {
+	Logger *zap.SugaredLogger
+}
+
+func (g *FileGetter) Get(wd, src, dst string) error {
+	u, err := neturl.Parse(src)
+	if err != nil {
+		return err
+	}
+
+	if u.Host != "" && u.Host != "localhost" {
+		return fmt.Errorf("unsupported file URL %s: expected file:///absolute/path/to/file", src)
+	}
+	file := filepath.FromSlash(u.Path)
+
+	in, err := os.Open(file)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		_ = in.Close()
+	}()
+
+	if err := os.MkdirAll(dst, os.FileMode(0700)); err != nil {
+		return err
+	}
+
+	localFile, err := os.Create(filepath.Join(dst, filepath.Base(file)))
+	if err != nil {
+		return err
+	}
+	defer func(localFile *os.File) {
+		err := localFile.Close()
+		if err != nil {
+			g.Logger.Errorf("Error writing file \n%v", err)
+		}
+	}(localFile)
+
+	_, err = io.Copy(localFile, in)
+
+	return err
+}
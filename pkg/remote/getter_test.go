@@ -0,0 +1,183 @@
+package remote
+
+import (
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"go.uber.org/zap"
+
+	"github.com/helmfile/helmfile/pkg/filesystem"
+	"github.com/helmfile/helmfile/pkg/helmexec"
+	"github.com/helmfile/helmfile/pkg/testhelper"
+)
+
+func TestRemote_Fetch_Getters(t *testing.T) {
+	testcases := []struct {
+		url  string
+		src  string
+		file string
+	}{
+		{
+			url:  "gs://helmfile-values/subdir/values.yaml",
+			src:  "gs://helmfile-values/subdir/values.yaml",
+			file: "gs_helmfile-values/subdir/values.yaml",
+		},
+		{
+			url:  "az://helmfile/values/subdir/values.yaml",
+			src:  "az://helmfile/values/subdir/values.yaml",
+			file: "az_helmfile/values/subdir/values.yaml",
+		},
+		{
+			url:  "oci://ghcr.io/helmfile/values:1.0.0/values.yaml",
+			src:  "oci://ghcr.io/helmfile/values:1.0.0/values.yaml",
+			file: "oci_ghcr_io/helmfile/values:1.0.0/values.yaml",
+		},
+		{
+			url:  "file:///srv/mirror/values.yaml",
+			src:  "file:///srv/mirror/values.yaml",
+			file: "file_/srv/mirror/values.yaml",
+		},
+	}
+
+	for _, tt := range testcases {
+		t.Run(tt.url, func(t *testing.T) {
+			home := t.TempDir()
+
+			getter := testhelper.NewTestGetter(map[string]map[string]string{
+				tt.src: {"values.yaml": "foo: bar"},
+			})
+
+			remote := NewRemote(helmexec.NewLogger(io.Discard, "debug"), home, filesystem.DefaultFileSystem())
+			remote.Getters = map[string]Getter{"gs": getter, "az": getter, "oci": getter, "file": getter}
+
+			file, err := remote.Fetch(tt.url)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			if want := filepath.Join(home, tt.file); file != want {
+				t.Errorf("unexpected file: want %s, got %s", want, file)
+			}
+
+			if diff := cmp.Diff([]string{tt.src}, getter.Fetched()); diff != "" {
+				t.Errorf("unexpected fetches: %s", diff)
+			}
+		})
+	}
+}
+
+func TestRegisterGetter(t *testing.T) {
+	defer func() {
+		gettersMu.Lock()
+		delete(getters, "test")
+		gettersMu.Unlock()
+	}()
+
+	if IsRemote("test://example.com/values.yaml") {
+		t.Fatalf("unexpected remote source for an unregistered scheme")
+	}
+
+	getter := testhelper.NewTestGetter(map[string]map[string]string{
+		"test://example.com/values.yaml": {"values.yaml": "foo: bar"},
+	})
+
+	RegisterGetter("test", func(logger *zap.SugaredLogger) Getter { return getter })
+
+	if !IsRemote("test://example.com/values.yaml") {
+		t.Fatalf("expected a remote source for a registered scheme")
+	}
+
+	home := t.TempDir()
+	remote := NewRemote(helmexec.NewLogger(io.Discard, "debug"), home, filesystem.DefaultFileSystem())
+
+	file, err := remote.Fetch("test://example.com/values.yaml")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	bs, err := os.ReadFile(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(bs) != "foo: bar" {
+		t.Errorf("unexpected content: %s", string(bs))
+	}
+}
+
+func TestRemote_Fetch_MissingGetter(t *testing.T) {
+	remote := &Remote{
+		Logger:  helmexec.NewLogger(io.Discard, "debug"),
+		Home:    t.TempDir(),
+		Getters: map[string]Getter{},
+		fs:      filesystem.DefaultFileSystem(),
+	}
+
+	_, err := remote.Fetch("gs://helmfile-values/values.yaml")
+
+	want := `no getter registered for the "gs" scheme of gs://helmfile-values/values.yaml`
+	if err == nil || err.Error() != want {
+		t.Errorf("unexpected error: want %q, got %v", want, err)
+	}
+}
+
+func TestFileGetter(t *testing.T) {
+	src := filepath.Join(t.TempDir(), "values.yaml")
+	if err := os.WriteFile(src, []byte("foo: bar"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	dst := filepath.Join(t.TempDir(), "cache")
+
+	g := &FileGetter{Logger: helmexec.NewLogger(io.Discard, "debug")}
+	if err := g.Get("", "file://"+filepath.ToSlash(src), dst); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	bs, err := os.ReadFile(filepath.Join(dst, "values.yaml"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(bs) != "foo: bar" {
+		t.Errorf("unexpected content: %s", string(bs))
+	}
+
+	err = g.Get("", "file://example.com/values.yaml", dst)
+	want := "unsupported file URL file://example.com/values.yaml: expected file:///absolute/path/to/file"
+	if err == nil || err.Error() != want {
+		t.Errorf("unexpected error: want %q, got %v", want, err)
+	}
+}
+
+func TestParseAzureBlobURL(t *testing.T) {
+	testcases := []struct {
+		url                     string
+		account, container, key string
+		err                     string
+	}{
+		{url: "az://helmfile/values/values.yaml", account: "helmfile", container: "values", key: "values.yaml"},
+		{url: "az://helmfile/values/subdir/values.yaml", account: "helmfile", container: "values", key: "subdir/values.yaml"},
+		{url: "az://helmfile/values.yaml", err: "invalid Azure Blob URL az://helmfile/values.yaml: expected az://<account>/<container>/<blob>"},
+		{url: "s3://helmfile/values/values.yaml", err: "invalid URL scheme (expected 'az')"},
+	}
+
+	for _, tt := range testcases {
+		t.Run(tt.url, func(t *testing.T) {
+			account, container, key, err := ParseAzureBlobURL(tt.url)
+
+			var errMsg string
+			if err != nil {
+				errMsg = err.Error()
+			}
+			if diff := cmp.Diff(tt.err, errMsg); diff != "" {
+				t.Fatalf("unexpected error: %s", diff)
+			}
+
+			if account != tt.account || container != tt.container || key != tt.key {
+				t.Errorf("unexpected result: want %s %s %s, got %s %s %s", tt.account, tt.container, tt.key, account, container, key)
+			}
+		})
+	}
+}
@@ -0,0 +1,102 @@
+package remote
+
+import (
+	"fmt"
+	neturl "net/url"
+	"strings"
+)
+
+// ParseMirrors parses a comma-separated list of `host=mirror` pairs, like the value of HELMFILE_REMOTE_MIRRORS.
+// The mirror is either a host with an optional path prefix like `artifactory.example.com/github`,
+// which keeps the scheme of the source, or a URL like `https://artifactory.example.com/github` or `file:///srv/mirrors/github`.
+func ParseMirrors(v string) (map[string]string, error) {
+	mirrors := map[string]string{}
+
+	for _, pair := range strings.Split(v, ",") {
+		pair = strings.TrimSpace(pair)
+		if pair == "" {
+			continue
+		}
+
+		host, mirror, found := strings.Cut(pair, "=")
+		host, mirror = strings.TrimSpace(host), strings.TrimSpace(mirror)
+		if !found || host == "" || mirror == "" {
+			return nil, fmt.Errorf("invalid mirror %q: expected <host>=<mirror>", pair)
+		}
+
+		if _, err := parseMirror(mirror); err != nil {
+			return nil, fmt.Errorf("invalid mirror %q: %v", pair, err)
+		}
+
+		mirrors[strings.ToLower(host)] = mirror
+	}
+
+	return mirrors, nil
+}
+
+func parseMirror(mirror string) (*neturl.URL, error) {
+	if !strings.Contains(mirror, "://") {
+		mirror = "//" + mirror
+	}
+
+	u, err := neturl.Parse(mirror)
+	if err != nil {
+		return nil, err
+	}
+
+	if u.Host == "" && u.Scheme != "file" {
+		return nil, fmt.Errorf("missing host")
+	}
+
+	return u, nil
+}
+
+// mirror rewrites the go-getter source to fetch it from the mirror of its host, if any.
+// The forced getter, e.g. `git::`, the path and the query of the source are kept.
+func (r *Remote) mirror(src string) string {
+	if len(r.Mirrors) == 0 {
+		return src
+	}
+
+	forced, rest, found := strings.Cut(src, "::")
+	if !found {
+		forced, rest = "", src
+	}
+
+	u, err := neturl.Parse(rest)
+	if err != nil {
+		return src
+	}
+
+	mirror, ok := r.Mirrors[strings.ToLower(u.Host)]
+	if !ok {
+		mirror, ok = r.Mirrors[strings.ToLower(u.Hostname())]
+	}
+	if !ok {
+		return src
+	}
+
+	m, err := parseMirror(mirror)
+	if err != nil {
+		r.Logger.Warnf("ignoring invalid mirror %q for %s: %v", mirror, u.Host, err)
+		return src
+	}
+
+	if m.Scheme != "" && m.Scheme != u.Scheme {
+		// The credentials of the source are meaningless to a mirror with another protocol
+		u.Scheme = m.Scheme
+		u.User = nil
+	}
+	u.Host = m.Host
+	u.Path = strings.TrimSuffix(m.Path, "/") + u.Path
+	u.RawPath = ""
+
+	mirrored := u.String()
+	if forced != "" {
+		mirrored = forced + "::" + mirrored
+	}
+
+	r.Logger.Debugf("remote> fetching %s from the mirror %s", redactSource(src), redactSource(mirrored))
+
+	return mirrored
+}
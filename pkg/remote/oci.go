@@ -0,0 +1,284 @@
+package remote
+
+import (
+	"crypto/sha256"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	neturl "net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"go.uber.org/zap"
+)
+
+const (
+	ociManifestMediaType = "application/vnd.oci.image.manifest.v1+json"
+	ociTitleAnnotation   = "org.opencontainers.image.title"
+)
+
+var authParamPattern = regexp.MustCompile(`(\w+)="([^"]*)"`)
+
+// OCIGetter fetches plain files pushed to an OCI registry, e.g. with `oras push`,
+// with URLs like `oci://registry/repository:tag/file` or `oci://registry/repository@sha256:.../file`.
+// The file is the layer of the artifact with the `org.opencontainers.image.title` annotation set to the file name.
+// Charts pushed to OCI registries are pulled by helm instead.
+type OCIGetter struct {
+	Logger *zap.SugaredLogger
+
+	// Client is the HTTP client used to access the registry. http.DefaultClient is used if nil
+	Client *http.Client
+}
+
+type ociManifest struct {
+	Layers []ociDescriptor `json:"layers"`
+}
+
+type ociDescriptor struct {
+	MediaType   string            `json:"mediaType"`
+	Digest      string            `json:"digest"`
+	Size        int64             `json:"size"`
+	Annotations map[string]string `json:"annotations,omitempty"`
+}
+
+func (g *OCIGetter) Get(wd, src, dst string) error {
+	u, err := neturl.Parse(src)
+	if err != nil {
+		return err
+	}
+
+	repo, ref, file, err := ParseOCIURL(src)
+	if err != nil {
+		return err
+	}
+
+	registry := &ociRegistry{
+		client: g.Client,
+		base:   "https://" + u.Host,
+		user:   u.User,
+		repo:   repo,
+		logger: g.Logger,
+	}
+	if registry.client == nil {
+		registry.client = http.DefaultClient
+	}
+
+	g.Logger.Debugf("Fetching manifest %s:%s from %s", repo, ref, u.Host)
+
+	var manifest ociManifest
+	if err := registry.getJSON(fmt.Sprintf("/v2/%s/manifests/%s", repo, ref), ociManifestMediaType, &manifest); err != nil {
+		return err
+	}
+
+	var (
+		layer  *ociDescriptor
+		titles []string
+	)
+	for i := range manifest.Layers {
+		title := manifest.Layers[i].Annotations[ociTitleAnnotation]
+		if title == file {
+			layer = &manifest.Layers[i]
+			break
+		}
+		titles = append(titles, title)
+	}
+	if layer == nil {
+		return fmt.Errorf("%s not found in %s: the artifact contains %s", file, src, strings.Join(titles, ", "))
+	}
+
+	resp, err := registry.get(fmt.Sprintf("/v2/%s/blobs/%s", repo, layer.Digest), "")
+	if err != nil {
+		return err
+	}
+	defer func() {
+		_ = resp.Body.Close()
+	}()
+
+	bs, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+
+	if digest := fmt.Sprintf("sha256:%x", sha256.Sum256(bs)); digest != layer.Digest {
+		return fmt.Errorf("digest mismatch for %s: expected %s, got %s", src, layer.Digest, digest)
+	}
+
+	if err := os.MkdirAll(dst, os.FileMode(0700)); err != nil {
+		return err
+	}
+
+	return os.WriteFile(filepath.Join(dst, file), bs, 0644)
+}
+
+// ParseOCIURL returns the repository, the tag or digest, and the file name of an `oci://registry/repository:tag/file` URL.
+// The tag defaults to `latest`.
+func ParseOCIURL(ociURL string) (string, string, string, error) {
+	u, err := neturl.Parse(ociURL)
+	if err != nil {
+		return "", "", "", fmt.Errorf("failed to parse OCI URL: %w", err)
+	}
+
+	if u.Scheme != "oci" {
+		return "", "", "", fmt.Errorf("invalid URL scheme (expected 'oci')")
+	}
+
+	p := strings.TrimPrefix(u.Path, "/")
+	repo, file := path.Dir(p), path.Base(p)
+	if u.Host == "" || repo == "." || file == "." {
+		return "", "", "", fmt.Errorf("invalid OCI URL %s: expected oci://<registry>/<repository>:<tag>/<file>", ociURL)
+	}
+
+	ref := "latest"
+	if i := strings.LastIndex(repo, "@"); i >= 0 {
+		repo, ref = repo[:i], repo[i+1:]
+	} else if i := strings.LastIndex(repo, ":"); i > strings.LastIndex(repo, "/") {
+		repo, ref = repo[:i], repo[i+1:]
+	}
+
+	return repo, ref, file, nil
+}
+
+// ociRegistry is a minimal client of the OCI distribution API supporting anonymous and basic auth, and bearer tokens
+type ociRegistry struct {
+	client *http.Client
+	base   string
+	user   *neturl.Userinfo
+	repo   string
+	token  string
+	logger *zap.SugaredLogger
+}
+
+func (r *ociRegistry) getJSON(p, accept string, v any) error {
+	resp, err := r.get(p, accept)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		_ = resp.Body.Close()
+	}()
+
+	return json.NewDecoder(resp.Body).Decode(v)
+}
+
+func (r *ociRegistry) get(p, accept string) (*http.Response, error) {
+	resp, err := r.do(p, accept)
+	if err != nil {
+		return nil, err
+	}
+
+	if resp.StatusCode == http.StatusUnauthorized && r.token == "" {
+		challenge := resp.Header.Get("WWW-Authenticate")
+		_ = resp.Body.Close()
+
+		if err := r.authenticate(challenge); err != nil {
+			return nil, err
+		}
+
+		resp, err = r.do(p, accept)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		_ = resp.Body.Close()
+		return nil, fmt.Errorf("GET %s%s: unexpected status %s", r.base, p, resp.Status)
+	}
+
+	return resp, nil
+}
+
+func (r *ociRegistry) do(p, accept string) (*http.Response, error) {
+	req, err := http.NewRequest(http.MethodGet, r.base+p, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	if accept != "" {
+		req.Header.Set("Accept", accept)
+	}
+
+	switch {
+	case r.token != "":
+		req.Header.Set("Authorization", "Bearer "+r.token)
+	case r.user != nil:
+		password, _ := r.user.Password()
+		req.SetBasicAuth(r.user.Username(), password)
+	}
+
+	return r.client.Do(req)
+}
+
+// authenticate gets a token from the auth server of the registry as described in the `WWW-Authenticate` header
+func (r *ociRegistry) authenticate(challenge string) error {
+	scheme, params, _ := strings.Cut(challenge, " ")
+	if !strings.EqualFold(scheme, "Bearer") {
+		return fmt.Errorf("unauthorized to access %s: unsupported authentication scheme %q", r.base, scheme)
+	}
+
+	values := map[string]string{}
+	for _, m := range authParamPattern.FindAllStringSubmatch(params, -1) {
+		values[m[1]] = m[2]
+	}
+
+	realm, err := neturl.Parse(values["realm"])
+	if err != nil || values["realm"] == "" {
+		return fmt.Errorf("unauthorized to access %s: invalid realm %q", r.base, values["realm"])
+	}
+
+	q := realm.Query()
+	if s := values["service"]; s != "" {
+		q.Set("service", s)
+	}
+	scope := values["scope"]
+	if scope == "" {
+		scope = fmt.Sprintf("repository:%s:pull", r.repo)
+	}
+	q.Set("scope", scope)
+	realm.RawQuery = q.Encode()
+
+	req, err := http.NewRequest(http.MethodGet, realm.String(), nil)
+	if err != nil {
+		return err
+	}
+	if r.user != nil {
+		password, _ := r.user.Password()
+		req.SetBasicAuth(r.user.Username(), password)
+	}
+
+	r.logger.Debugf("Requesting a token from %s", realm.Host)
+
+	resp, err := r.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		_ = resp.Body.Close()
+	}()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("requesting a token for %s: unexpected status %s", r.base, resp.Status)
+	}
+
+	var token struct {
+		Token       string `json:"token"`
+		AccessToken string `json:"access_token"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&token); err != nil {
+		return err
+	}
+
+	r.token = token.Token
+	if r.token == "" {
+		r.token = token.AccessToken
+	}
+	if r.token == "" {
+		return fmt.Errorf("requesting a token for %s: no token in the response", r.base)
+	}
+
+	return nil
+}
@@ -0,0 +1,127 @@
+package remote
+
+import (
+	"crypto/sha256"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+
+	"github.com/helmfile/helmfile/pkg/helmexec"
+)
+
+func TestOCIGetter(t *testing.T) {
+	values := []byte("foo: bar\n")
+	digest := fmt.Sprintf("sha256:%x", sha256.Sum256(values))
+
+	manifest, err := json.Marshal(ociManifest{
+		Layers: []ociDescriptor{
+			{MediaType: "text/plain", Digest: "sha256:0000", Annotations: map[string]string{ociTitleAnnotation: "README.md"}},
+			{MediaType: "application/yaml", Digest: digest, Size: int64(len(values)), Annotations: map[string]string{ociTitleAnnotation: "values.yaml"}},
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var server *httptest.Server
+	server = httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/token" {
+			if got := r.URL.Query().Get("scope"); got != "repository:helmfile/values:pull" {
+				t.Errorf("unexpected scope: %s", got)
+			}
+			_, _ = w.Write([]byte(`{"token":"secret"}`))
+			return
+		}
+
+		if r.Header.Get("Authorization") != "Bearer secret" {
+			w.Header().Set("WWW-Authenticate", fmt.Sprintf(`Bearer realm="%s/token",service="registry",scope="repository:helmfile/values:pull"`, server.URL))
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+
+		switch r.URL.Path {
+		case "/v2/helmfile/values/manifests/1.0.0":
+			if r.Header.Get("Accept") != ociManifestMediaType {
+				t.Errorf("unexpected accept header: %s", r.Header.Get("Accept"))
+			}
+			_, _ = w.Write(manifest)
+		case "/v2/helmfile/values/blobs/" + digest:
+			_, _ = w.Write(values)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+
+	host := strings.TrimPrefix(server.URL, "https://")
+
+	g := &OCIGetter{
+		Logger: helmexec.NewLogger(io.Discard, "debug"),
+		Client: server.Client(),
+	}
+
+	dst := t.TempDir()
+
+	if err := g.Get("", fmt.Sprintf("oci://%s/helmfile/values:1.0.0/values.yaml", host), dst); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	bs, err := os.ReadFile(filepath.Join(dst, "values.yaml"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(bs) != string(values) {
+		t.Errorf("unexpected content: %s", string(bs))
+	}
+
+	err = g.Get("", fmt.Sprintf("oci://%s/helmfile/values:1.0.0/missing.yaml", host), dst)
+	want := fmt.Sprintf("missing.yaml not found in oci://%s/helmfile/values:1.0.0/missing.yaml: the artifact contains README.md, values.yaml", host)
+	if err == nil || err.Error() != want {
+		t.Errorf("unexpected error: want %q, got %v", want, err)
+	}
+
+	err = g.Get("", fmt.Sprintf("oci://%s/helmfile/values:2.0.0/values.yaml", host), dst)
+	if err == nil || !strings.Contains(err.Error(), "404 Not Found") {
+		t.Errorf("unexpected error: want 404 Not Found, got %v", err)
+	}
+}
+
+func TestParseOCIURL(t *testing.T) {
+	testcases := []struct {
+		url             string
+		repo, ref, file string
+		err             string
+	}{
+		{url: "oci://ghcr.io/helmfile/values:1.0.0/values.yaml", repo: "helmfile/values", ref: "1.0.0", file: "values.yaml"},
+		{url: "oci://ghcr.io/helmfile/values/values.yaml", repo: "helmfile/values", ref: "latest", file: "values.yaml"},
+		{url: "oci://localhost:5000/values@sha256:abc/values.yaml", repo: "values", ref: "sha256:abc", file: "values.yaml"},
+		{url: "oci://ghcr.io/values.yaml", err: "invalid OCI URL oci://ghcr.io/values.yaml: expected oci://<registry>/<repository>:<tag>/<file>"},
+		{url: "https://ghcr.io/helmfile/values/values.yaml", err: "invalid URL scheme (expected 'oci')"},
+	}
+
+	for _, tt := range testcases {
+		t.Run(tt.url, func(t *testing.T) {
+			repo, ref, file, err := ParseOCIURL(tt.url)
+
+			var errMsg string
+			if err != nil {
+				errMsg = err.Error()
+			}
+			if diff := cmp.Diff(tt.err, errMsg); diff != "" {
+				t.Fatalf("unexpected error: %s", diff)
+			}
+
+			if repo != tt.repo || ref != tt.ref || file != tt.file {
+				t.Errorf("unexpected result: want %s %s %s, got %s %s %s", tt.repo, tt.ref, tt.file, repo, ref, file)
+			}
+		})
+	}
+}
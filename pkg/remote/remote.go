@@ -9,9 +9,10 @@ import (
 	"os"
 	"path"
 	"path/filepath"
-	"slices"
 	"strconv"
 	"strings"
+	"sync"
+	"time"
 
 	"github.com/aws/aws-sdk-go/aws"
 	"github.com/aws/aws-sdk-go/aws/session"
@@ -25,10 +26,7 @@ import (
 	"github.com/helmfile/helmfile/pkg/filesystem"
 )
 
-var (
-	protocols               = []string{"s3", "http", "https"}
-	disableInsecureFeatures bool
-)
+var disableInsecureFeatures bool
 
 func init() {
 	disableInsecureFeatures, _ = strconv.ParseBool(os.Getenv(envvar.DisableInsecureFeatures))
@@ -53,12 +51,35 @@ type Remote struct {
 	// Home is the directory in which remote downloads files. If empty, user cache directory is used
 	Home string
 
-	// Getter is the underlying implementation of getter used for fetching remote files
+	// Getter is the underlying implementation of getter used for fetching remote directories,
+	// like git repositories with `git::https://github.com/org/repo.git@path/to/file`
 	Getter Getter
 
-	S3Getter Getter
+	// Getters are the getters used for fetching remote files like `s3://bucket/path/to/file`, keyed by URL scheme.
+	// NewRemote populates it with the getters registered with RegisterGetter.
+	Getters map[string]Getter
+
+	// Mirrors maps hosts to the mirrors remote fetches from instead, e.g. `github.com` to `artifactory.example.com/github`.
+	// See ParseMirrors for the format of the mirrors.
+	Mirrors map[string]string
+
+	// CacheTTL is how long a cached remote file or directory is used before it is fetched again.
+	// Zero means that the cache never expires. Entries fetched from tags and commits never expire.
+	CacheTTL time.Duration
+
+	// Refresh makes remote fetch every mutable source once again, ignoring the cache
+	Refresh bool
+
+	// Offline makes remote fail instead of fetching anything that isn't in the cache.
+	// Cached entries are used regardless of their age.
+	Offline bool
 
-	HttpGetter Getter
+	// err is returned by every fetch when the remote is misconfigured
+	err error
+
+	mu sync.Mutex
+	// fetched records the cache entries fetched by this remote so that refreshing fetches each of them only once
+	fetched map[string]bool
 
 	// Filesystem abstraction
 	// Inject any implementation of your choice, like an im-memory impl for testing, os.ReadFile for the real-world use.
@@ -178,13 +199,17 @@ func ParseNormalProtocol(path string) (string, error) {
 	}
 	protocol := strings.ToLower(parts[0])
 
-	if slices.Contains(protocols, protocol) {
+	if isRegisteredScheme(protocol) {
 		return protocol, nil
 	}
 	return "", fmt.Errorf("failed to parse URL %s", path)
 }
 
 func (r *Remote) Fetch(path string, cacheDirOpt ...string) (string, error) {
+	if r.err != nil {
+		return "", r.err
+	}
+
 	u, err := Parse(path)
 	if err != nil {
 		return "", err
@@ -210,6 +235,17 @@ func (r *Remote) Fetch(path string, cacheDirOpt ...string) (string, error) {
 
 	query := u.RawQuery
 
+	// The checksum is verified by helmfile, so it must not be sent to the remote
+	getterQuery, checksum, err := splitChecksum(query)
+	if err != nil {
+		return "", fmt.Errorf("parsing checksum of %s: %v", redactSource(path), err)
+	}
+
+	getterPath := path
+	if checksum != "" {
+		getterPath = withQuery(path, getterQuery)
+	}
+
 	var cacheKey string
 	replacer := strings.NewReplacer(":", "", "//", "_", "/", "_", ".", "_")
 	dirKey := replacer.Replace(srcDir)
@@ -257,6 +293,44 @@ func (r *Remote) Fetch(path string, cacheDirOpt ...string) (string, error) {
 		}
 	}
 
+	isDir := u.Getter != "normal"
+
+	entryPath := cacheDirPath
+	if !isDir {
+		entryPath = filepath.Join(cacheDirPath, file)
+	}
+
+	// Entries pinned to a checksum can't change, so they never expire
+	immutable := isImmutableRef(u) || checksum != ""
+
+	if cached && !r.Offline && r.isStale(entryPath, immutable) {
+		r.Logger.Debugf("remote> cache of %s is stale", entryPath)
+
+		if err := r.evict(entryPath, isDir); err != nil {
+			return "", fmt.Errorf("removing stale cache %s: %v", entryPath, err)
+		}
+		cached = false
+	}
+
+	if cached && checksum != "" {
+		if err := r.verify(path, entryPath, isDir, checksum); err != nil {
+			if r.Offline {
+				return "", err
+			}
+
+			r.Logger.Warnf("%v. fetching it again", err)
+
+			if err := r.evict(entryPath, isDir); err != nil {
+				return "", fmt.Errorf("removing tampered cache %s: %v", entryPath, err)
+			}
+			cached = false
+		}
+	}
+
+	if !cached && r.Offline {
+		return "", fmt.Errorf("%s is not in the cache, and fetching it is not allowed in offline mode", redactSource(path))
+	}
+
 	if !cached {
 		var getterSrc string
 		if u.User != "" {
@@ -265,40 +339,96 @@ func (r *Remote) Fetch(path string, cacheDirOpt ...string) (string, error) {
 			getterSrc = fmt.Sprintf("%s://%s%s", u.Scheme, u.Host, u.Dir)
 		}
 
-		if len(query) > 0 {
-			getterSrc = strings.Join([]string{getterSrc, query}, "?")
+		if len(getterQuery) > 0 {
+			getterSrc = strings.Join([]string{getterSrc, getterQuery}, "?")
+		}
+
+		if u.Getter != "" && u.Getter != "normal" {
+			getterSrc = u.Getter + "::" + getterSrc
 		}
 
+		getterSrc = r.mirror(getterSrc)
+		getterPath = r.mirror(getterPath)
+
 		r.Logger.Debugf("remote> downloading %s to %s", getterSrc, getterDst)
 
-		switch {
-		case u.Getter == "normal" && u.Scheme == "s3":
-			err := r.S3Getter.Get(r.Home, path, cacheDirPath)
-			if err != nil {
-				return "", multierr.Append(err, err)
-			}
-		case u.Getter == "normal" && (u.Scheme == "https" || u.Scheme == "http"):
-			err := r.HttpGetter.Get(r.Home, path, cacheDirPath)
-			if err != nil {
-				return "", multierr.Append(err, err)
-			}
-		default:
-			if u.Getter != "" {
-				getterSrc = u.Getter + "::" + getterSrc
-			}
+		g, err := r.getterFor(u, getterPath)
+		if err != nil {
+			return "", err
+		}
 
-			if err := r.Getter.Get(r.Home, getterSrc, cacheDirPath); err != nil {
+		if isDir {
+			if err := g.Get(r.Home, getterSrc, cacheDirPath); err != nil {
 				rmerr := os.RemoveAll(cacheDirPath)
 				if rmerr != nil {
 					return "", multierr.Append(err, rmerr)
 				}
 				return "", err
 			}
+		} else if err := g.Get(r.Home, getterPath, cacheDirPath); err != nil {
+			return "", err
+		}
+
+		r.markFetched(entryPath)
+
+		digest, err := r.digest(entryPath, isDir)
+		switch {
+		case err != nil && checksum != "":
+			return "", fmt.Errorf("computing checksum of %s: %v", entryPath, err)
+		case err != nil:
+			r.Logger.Debugf("remote> unable to compute checksum of %s: %v", entryPath, err)
+		case checksum != "" && digest != checksum:
+			if err := r.evict(entryPath, isDir); err != nil {
+				r.Logger.Warnf("unable to remove %s: %v", entryPath, err)
+			}
+			return "", &ChecksumMismatchError{Source: redactSource(path), Expected: checksum, Actual: digest}
+		}
+
+		entry := CacheEntry{
+			Source:    redactSource(path),
+			Path:      entryPath,
+			FetchedAt: now(),
+			Digest:    digest,
+			Immutable: immutable,
+		}
+
+		if g, ok := g.(revisionGetter); ok {
+			src := getterSrc
+			if !isDir {
+				src = getterPath
+			}
+			rev, err := g.Revision(src, cacheDirPath)
+			if err != nil {
+				r.Logger.Debugf("remote> %v", err)
+			}
+			entry.Revision = rev
+		}
+
+		if err := r.writeCacheEntry(entry); err != nil {
+			r.Logger.Warnf("unable to write cache metadata of %s: %v", entryPath, err)
 		}
 	}
 	return filepath.Join(cacheDirPath, file), nil
 }
 
+// getterFor returns the getter for the source, which is chosen by the scheme of the URL to fetch for remote files,
+// as the URL may point to a mirror with another scheme than the source
+func (r *Remote) getterFor(u *Source, getterPath string) (Getter, error) {
+	if u.Getter != "normal" {
+		return r.Getter, nil
+	}
+
+	scheme, _, _ := strings.Cut(getterPath, "://")
+	scheme = strings.ToLower(scheme)
+
+	g, ok := r.Getters[scheme]
+	if !ok || g == nil {
+		return nil, fmt.Errorf("no getter registered for the %q scheme of %s", scheme, redactSource(getterPath))
+	}
+
+	return g, nil
+}
+
 type Getter interface {
 	Get(wd, src, dst string) error
 }
@@ -313,6 +443,10 @@ type S3Getter struct {
 
 type HttpGetter struct {
 	Logger *zap.SugaredLogger
+
+	mu sync.Mutex
+	// etags are the ETags of the responses of the fetched files, keyed by their local paths
+	etags map[string]string
 }
 
 func (g *GoGetter) Get(wd, src, dst string) error {
@@ -445,9 +579,13 @@ func (g *HttpGetter) Get(wd, src, dst string) error {
 		}
 	}(localFile)
 
-	_, err = localFile.ReadFrom(resp.Body)
+	if _, err := localFile.ReadFrom(resp.Body); err != nil {
+		return err
+	}
 
-	return err
+	g.setETag(targetFilePath, resp.Header.Get("ETag"))
+
+	return nil
 }
 
 func (g *S3Getter) S3FileExists(path string) (string, error) {
@@ -536,12 +674,20 @@ func NewRemote(logger *zap.SugaredLogger, homeDir string, fs *filesystem.FileSys
 		panic("Remote sources are disabled due to 'DISABLE_INSECURE_FEATURES'")
 	}
 	remote := &Remote{
-		Logger:     logger,
-		Home:       homeDir,
-		Getter:     &GoGetter{Logger: logger},
-		S3Getter:   &S3Getter{Logger: logger},
-		HttpGetter: &HttpGetter{Logger: logger},
-		fs:         fs,
+		Logger:  logger,
+		Home:    homeDir,
+		Getter:  &GoGetter{Logger: logger},
+		Getters: newGetters(logger),
+		fs:      fs,
+	}
+
+	if v := os.Getenv(envvar.RemoteMirrors); v != "" {
+		mirrors, err := ParseMirrors(v)
+		if err != nil {
+			// Fetching from the original hosts may be forbidden, so every fetch fails until the mirrors are fixed
+			remote.err = fmt.Errorf("invalid %s: %v", envvar.RemoteMirrors, err)
+		}
+		remote.Mirrors = mirrors
 	}
 
 	if remote.Home == "" {
@@ -259,12 +259,11 @@ func TestRemote_S3(t *testing.T) {
 				get: get,
 			}
 			remote := &Remote{
-				Logger:     helmexec.NewLogger(io.Discard, "debug"),
-				Home:       CacheDir(),
-				Getter:     getter,
-				S3Getter:   getter,
-				HttpGetter: getter,
-				fs:         testfs.ToFileSystem(),
+				Logger:  helmexec.NewLogger(io.Discard, "debug"),
+				Home:    CacheDir(),
+				Getter:  getter,
+				Getters: map[string]Getter{"s3": getter},
+				fs:      testfs.ToFileSystem(),
 			}
 
 			url := "s3://helm-s3-values-example/subdir/values.yaml"
@@ -18,6 +18,11 @@ var (
 	// It's false by default in Helmfile v0.x and true by default for Helmfile v1.x.
 	GoccyGoYaml bool
 
+	// StrictFields is set to true in order to let Helmfile fail on the fields of a rendered helmfile that
+	// aren't defined in its JSON schema, like a misspelled field of a release.
+	// It's false by default in Helmfile v0.x, where such fields are warned about, and true by default for Helmfile v1.x.
+	StrictFields bool
+
 	// We set this via ldflags at build-time so that we can use the
 	// value specified at the build time as the runtime default.
 	v1Mode string
@@ -29,7 +34,7 @@ func Info() string {
 		yamlLib = "goccy/go-yaml"
 	}
 
-	return fmt.Sprintf("V1 mode = %v\nYAML library = %v", V1Mode, yamlLib)
+	return fmt.Sprintf("V1 mode = %v\nYAML library = %v\nStrict fields = %v", V1Mode, yamlLib, StrictFields)
 }
 
 func init() {
@@ -54,4 +59,14 @@ func init() {
 	default:
 		GoccyGoYaml = V1Mode
 	}
+
+	// You can turn the strict fields on or off at runtime via an envvar:
+	switch os.Getenv(envvar.StrictFields) {
+	case "true":
+		StrictFields = true
+	case "false":
+		StrictFields = false
+	default:
+		StrictFields = V1Mode
+	}
 }
@@ -0,0 +1,145 @@
+package state
+
+import (
+	"path/filepath"
+	"strings"
+
+	"github.com/helmfile/helmfile/pkg/remote"
+)
+
+// ChangedFiles is the set of the absolute paths to the files changed since a git ref
+type ChangedFiles map[string]struct{}
+
+// NewChangedFiles returns the ChangedFiles made of the absolute paths to the changed files
+func NewChangedFiles(paths []string) ChangedFiles {
+	files := ChangedFiles{}
+	for _, p := range paths {
+		files[filepath.Clean(p)] = struct{}{}
+	}
+	return files
+}
+
+// changedIn returns the first changed file that is either the path or within the path
+func (c ChangedFiles) changedIn(path string) (string, bool) {
+	if _, ok := c[path]; ok {
+		return path, true
+	}
+
+	prefix := path + string(filepath.Separator)
+	for f := range c {
+		if strings.HasPrefix(f, prefix) {
+			return f, true
+		}
+	}
+
+	return "", false
+}
+
+// ReleaseInputs returns the absolute paths to the local files the release is rendered from:
+// the helmfile that defines it, its values and secrets files, and its local chart.
+// Glob patterns in values and secrets files are expanded, while paths that no longer exist are kept as they are,
+// so that deleting a file counts as changing it. Remote files are not included.
+func (st *HelmState) ReleaseInputs(r *ReleaseSpec) ([]string, error) {
+	storage := st.storage()
+
+	inputs := []string{storage.normalizePath(st.FilePath)}
+
+	for _, files := range [][]any{r.Values, r.Secrets} {
+		for _, v := range files {
+			path, ok := v.(string)
+			if !ok || remote.IsRemote(path) {
+				continue
+			}
+
+			path = r.ValuesPathPrefix + path
+
+			matches, err := storage.ExpandPaths(path)
+			if err != nil {
+				return nil, err
+			}
+
+			inputs = append(inputs, storage.normalizePath(path))
+			inputs = append(inputs, matches...)
+		}
+	}
+
+	chart := r.Chart
+	if r.Directory != "" {
+		chart = r.Directory
+	}
+	if chart != "" && isLocalChart(chart) {
+		inputs = append(inputs, normalizeChart(st.basePath, chart))
+	}
+
+	for i, in := range inputs {
+		abs, err := st.fs.Abs(in)
+		if err != nil {
+			return nil, err
+		}
+		// The changed files are listed under the real path of the git repository
+		if real, err := st.fs.EvalSymlinks(abs); err == nil {
+			abs = real
+		}
+		inputs[i] = abs
+	}
+
+	return inputs, nil
+}
+
+// markUnchangedReleases marks the releases none of whose inputs changed as filtered,
+// unless they need a changed release directly or transitively
+func (st *HelmState) markUnchangedReleases(releases []Release) error {
+	changed := map[string]bool{}
+
+	for _, r := range releases {
+		inputs, err := st.ReleaseInputs(&r.ReleaseSpec)
+		if err != nil {
+			return err
+		}
+
+		id := ReleaseToID(&r.ReleaseSpec)
+
+		for _, in := range inputs {
+			if f, ok := st.ChangedFiles.changedIn(in); ok {
+				st.logger.Debugf("release %q is changed as %s changed", id, f)
+				changed[id] = true
+				break
+			}
+		}
+	}
+
+	var dependsOnChanged func(r ReleaseSpec, visited map[string]bool) bool
+	dependsOnChanged = func(r ReleaseSpec, visited map[string]bool) bool {
+		id := ReleaseToID(&r)
+		if changed[id] {
+			return true
+		}
+		if visited[id] {
+			return false
+		}
+		visited[id] = true
+
+		for _, n := range r.Needs {
+			for _, need := range releases {
+				if ReleaseToID(&need.ReleaseSpec) == n && dependsOnChanged(need.ReleaseSpec, visited) {
+					return true
+				}
+			}
+		}
+
+		return false
+	}
+
+	for i, r := range releases {
+		id := ReleaseToID(&r.ReleaseSpec)
+		switch {
+		case changed[id]:
+		case dependsOnChanged(r.ReleaseSpec, map[string]bool{}):
+			st.logger.Debugf("release %q is changed as it needs a changed release", id)
+		default:
+			releases[i].Filtered = true
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,120 @@
+package state
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+
+	"github.com/helmfile/helmfile/pkg/testhelper"
+)
+
+func TestSelectReleases_ChangedFiles(t *testing.T) {
+	releases := []ReleaseSpec{
+		{
+			Name:      "db",
+			Chart:     "stable/postgresql",
+			Namespace: "default",
+			Secrets:   []any{"secrets/db.yaml"},
+		},
+		{
+			Name:      "api",
+			Chart:     "stable/api",
+			Namespace: "default",
+			Values:    []any{"values/api.yaml"},
+			Needs:     []string{"default/db"},
+		},
+		{
+			Name:      "web",
+			Chart:     "stable/nginx",
+			Namespace: "default",
+			Values:    []any{"values/web-*.yaml", map[string]any{"replicas": 2}},
+			Needs:     []string{"default/api"},
+		},
+		{
+			Name:      "worker",
+			Chart:     "./charts/worker",
+			Namespace: "default",
+		},
+	}
+
+	cases := []struct {
+		name      string
+		changed   []string
+		selectors []string
+		want      []string
+	}{
+		{
+			name:    "globbed values file",
+			changed: []string{"/path/to/values/web-2.yaml"},
+			want:    []string{"web"},
+		},
+		{
+			name:    "secrets file of a needed release",
+			changed: []string{"/path/to/secrets/db.yaml"},
+			want:    []string{"db", "api", "web"},
+		},
+		{
+			name:    "deleted values file",
+			changed: []string{"/path/to/values/api.yaml"},
+			want:    []string{"api", "web"},
+		},
+		{
+			name:    "file within a local chart",
+			changed: []string{"/path/to/charts/worker/templates/deployment.yaml"},
+			want:    []string{"worker"},
+		},
+		{
+			name:    "helmfile",
+			changed: []string{"/path/to/helmfile.yaml"},
+			want:    []string{"db", "api", "web", "worker"},
+		},
+		{
+			name:    "unrelated file",
+			changed: []string{"/path/to/README.md", "/path/to/charts/worker-v2/Chart.yaml"},
+		},
+		{
+			name:      "changed releases matching selectors",
+			changed:   []string{"/path/to/secrets/db.yaml"},
+			selectors: []string{"name!=web"},
+			want:      []string{"db", "api"},
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			st := &HelmState{
+				basePath: ".",
+				FilePath: "helmfile.yaml",
+				ReleaseSetSpec: ReleaseSetSpec{
+					Releases:     releases,
+					Selectors:    c.selectors,
+					ChangedFiles: NewChangedFiles(c.changed),
+				},
+				logger:         logger,
+				RenderedValues: map[string]any{},
+			}
+			st = injectFs(st, testhelper.NewTestFs(map[string]string{
+				"/path/to/helmfile.yaml":               "",
+				"/path/to/secrets/db.yaml":             "",
+				"/path/to/values/web-1.yaml":           "",
+				"/path/to/values/web-2.yaml":           "",
+				"/path/to/charts/worker/Chart.yaml":    "",
+				"/path/to/charts/worker-v2/Chart.yaml": "",
+			}))
+
+			selected, err := st.GetSelectedReleases(false)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			var got []string
+			for _, r := range selected {
+				got = append(got, r.Name)
+			}
+
+			if d := cmp.Diff(c.want, got); d != "" {
+				t.Errorf("unexpected releases: want (-), got (+): %s", d)
+			}
+		})
+	}
+}
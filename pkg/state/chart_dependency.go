@@ -1,13 +1,20 @@
 package state
 
 import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"crypto/sha256"
 	"fmt"
+	"io"
 	"os"
 	"path/filepath"
+	"slices"
 	"sort"
 	"strings"
 
 	"github.com/Masterminds/semver/v3"
+	"github.com/aryann/difflib"
 	"go.uber.org/zap"
 
 	"github.com/helmfile/helmfile/pkg/app/version"
@@ -40,6 +47,9 @@ type ResolvedChartDependency struct {
 	// Version is the version number of the dependent chart.
 	// In the context of helmfile this can be omitted. When omitted, it is considered `*` which results helm/helmfile fetching the latest version.
 	Version string `yaml:"version"`
+	// Digest is the sha256 digest of the chart archive, like `sha256:<hex>`, verified when Helmfile downloads the chart.
+	// It is empty in the lock files written by older versions of Helmfile.
+	Digest string `yaml:"digest,omitempty"`
 }
 
 type UnresolvedDependencies struct {
@@ -132,6 +142,60 @@ func (d *ResolvedDependencies) Get(chart, versionConstraint string) (string, err
 	return "", fmt.Errorf("no resolved dependency found for \"%s\", running \"helmfile deps\" may resolve the issue", chart)
 }
 
+// digest returns the digest of the locked chart archive, or an empty string when it's not locked or has no digest
+func (d *ResolvedDependencies) digest(chart, repository, version string) string {
+	if d == nil {
+		return ""
+	}
+
+	for _, dep := range d.deps[chart] {
+		if dep.Repository == repository && dep.Version == version {
+			return dep.Digest
+		}
+	}
+
+	return ""
+}
+
+// dependencyOf returns the name of the chart of the release and the URL of its repository, when the chart is managed in the lock file.
+// That is, the chart is either in one of the repositories of the helmfile, or in an OCI registry like `oci://registry/path/chart`.
+func dependencyOf(r *ReleaseSpec, repos map[string]RepositorySpec) (string, string, bool) {
+	if strings.HasPrefix(r.Chart, "oci://") {
+		i := strings.LastIndex(r.Chart, "/")
+		return r.Chart[i+1:], r.Chart[:i], true
+	}
+
+	repo, chart, ok := resolveRemoteChart(r.Chart)
+	if !ok {
+		return "", "", false
+	}
+
+	repoSpec, ok := repos[repo]
+	// Skip this chart from dependency management, as there's no matching `repository` in the helmfile state,
+	// which may imply that this is a local chart within a directory, like `charts/myapp`
+	if !ok {
+		return "", "", false
+	}
+
+	url := repoSpec.URL
+
+	if repoSpec.OCI {
+		url = fmt.Sprintf("oci://%s", url)
+	}
+
+	return chart, url, true
+}
+
+func repositoriesByName(st *HelmState) map[string]RepositorySpec {
+	repos := map[string]RepositorySpec{}
+
+	for _, r := range st.Repositories {
+		repos[r.Name] = r
+	}
+
+	return repos
+}
+
 func (st *HelmState) mergeLockedDependencies() (*HelmState, error) {
 	filename, unresolved, err := getUnresolvedDependenciess(st)
 	if err != nil {
@@ -160,23 +224,19 @@ func resolveDependencies(st *HelmState, depMan *chartDependencyManager, unresolv
 		return st, nil
 	}
 
-	repoToURL := map[string]string{}
-
-	for _, r := range st.Repositories {
-		repoToURL[r.Name] = r.URL
-	}
+	repos := repositoriesByName(st)
 
 	updated := *st
-	for i, r := range updated.Releases {
-		repo, chart, ok := resolveRemoteChart(r.Chart)
+	for i := range updated.Releases {
+		r := &updated.Releases[i]
+
+		chart, _, ok := dependencyOf(r, repos)
 		if !ok {
 			continue
 		}
 
-		_, ok = repoToURL[repo]
-		// Skip this chart from dependency management, as there's no matching `repository` in the helmfile state,
-		// which may imply that this is a local chart within a directory, like `charts/myapp`
-		if !ok {
+		// The lock files written by older versions of Helmfile don't have the charts in OCI registries referenced by URL
+		if _, locked := resolved.deps[chart]; !locked && strings.HasPrefix(r.Chart, "oci://") {
 			continue
 		}
 
@@ -185,13 +245,47 @@ func resolveDependencies(st *HelmState, depMan *chartDependencyManager, unresolv
 			return nil, err
 		}
 
-		updated.Releases[i].Version = ver
+		r.Version = ver
 	}
 
+	updated.lockedDeps = resolved
+
 	return &updated, nil
 }
 
-func (st *HelmState) updateDependenciesInTempDir(shell helmexec.DependencyUpdater, tempDir func(string, string) (string, error)) (*HelmState, error) {
+// The update policies limit how far `helmfile deps` moves the locked version of the chart of a release
+const (
+	// UpdatePolicyNone keeps the locked version
+	UpdatePolicyNone = "none"
+	// UpdatePolicyPatch allows updating to newer patch versions of the locked version
+	UpdatePolicyPatch = "patch"
+	// UpdatePolicyMinor allows updating to newer minor and patch versions of the locked version
+	UpdatePolicyMinor = "minor"
+	// UpdatePolicyMajor allows updating to any version satisfying the version of the release. This is the default
+	UpdatePolicyMajor = "major"
+)
+
+var updatePolicies = []string{UpdatePolicyPatch, UpdatePolicyMinor, UpdatePolicyMajor, UpdatePolicyNone}
+
+// UpdateDepsOpts is the options for updating the lock file
+type UpdateDepsOpts struct {
+	// UpdatePolicy is the update policy of the releases that don't set their own updatePolicy
+	UpdatePolicy string
+	// DryRun prints the changes to the lock file instead of writing it
+	DryRun bool
+	// AllowDigestChanges records the new digests of the charts re-published with the locked versions instead of failing
+	AllowDigestChanges bool
+}
+
+type UpdateDepsOpt interface{ Apply(*UpdateDepsOpts) }
+
+func (o *UpdateDepsOpts) Apply(opts *UpdateDepsOpts) {
+	*opts = *o
+}
+
+// updateDependenciesInTempDir updates the lock file of the helmfile state.
+// When selected is not nil, only the charts of the selected releases are updated and the others keep their locked versions.
+func (st *HelmState) updateDependenciesInTempDir(shell helmexec.DependencyUpdater, tempDir func(string, string) (string, error), selected []ReleaseSpec, opts UpdateDepsOpts) (*HelmState, error) {
 	filename, unresolved, err := getUnresolvedDependenciess(st)
 	if err != nil {
 		return nil, err
@@ -202,6 +296,30 @@ func (st *HelmState) updateDependenciesInTempDir(shell helmexec.DependencyUpdate
 		return st, nil
 	}
 
+	depMan := NewChartDependencyManager(filename, st.logger, st.LockFile)
+	depMan.dryRun = opts.DryRun
+	depMan.allowDigestChanges = opts.AllowDigestChanges
+
+	locked, _, err := depMan.Resolve(unresolved)
+	if err != nil {
+		return nil, err
+	}
+
+	var selectedIDs map[string]bool
+	if selected != nil {
+		selectedIDs = map[string]bool{}
+		for i := range selected {
+			selectedIDs[ReleaseToID(&selected[i])] = true
+		}
+	}
+
+	_, unresolved, err = getDependencies(st, func(r *ReleaseSpec, chart string) (string, error) {
+		return updateConstraint(r, chart, locked, selectedIDs == nil || selectedIDs[ReleaseToID(r)], opts.UpdatePolicy)
+	})
+	if err != nil {
+		return nil, err
+	}
+
 	d, err := tempDir("", "")
 	if err != nil {
 		return nil, fmt.Errorf("unable to create dir: %v", err)
@@ -210,42 +328,95 @@ func (st *HelmState) updateDependenciesInTempDir(shell helmexec.DependencyUpdate
 		_ = os.RemoveAll(d)
 	}()
 
-	return updateDependencies(st, shell, unresolved, filename, d)
+	return updateDependencies(st, shell, depMan, unresolved, d)
 }
 
-func getUnresolvedDependenciess(st *HelmState) (string, *UnresolvedDependencies, error) {
-	repoToURL := map[string]RepositorySpec{}
+// updateConstraint returns the version constraint to update the chart of the release with.
+// It limits the update from the locked version according to the update policy of the release, or defaultPolicy when the release has none.
+// Releases that are not selected keep their locked versions.
+func updateConstraint(r *ReleaseSpec, chart string, locked *ResolvedDependencies, selected bool, defaultPolicy string) (string, error) {
+	policy := r.UpdatePolicy
+	if policy == "" {
+		policy = defaultPolicy
+	}
 
-	for _, r := range st.Repositories {
-		repoToURL[r.Name] = r
+	if policy != "" && !slices.Contains(updatePolicies, policy) {
+		return "", fmt.Errorf("release %q: unsupported updatePolicy %q: expected one of %s", r.Name, policy, strings.Join(updatePolicies, ", "))
 	}
 
+	if !selected {
+		policy = UpdatePolicyNone
+	}
+
+	if policy == "" || policy == UpdatePolicyMajor || locked == nil {
+		return r.Version, nil
+	}
+
+	current, err := locked.Get(chart, r.Version)
+	if err != nil {
+		// The chart isn't locked yet, or the locked version no longer satisfies the version of the release
+		return r.Version, nil
+	}
+
+	v, err := semver.NewVersion(current)
+	if err != nil {
+		return "", err
+	}
+
+	var limit string
+	switch policy {
+	case UpdatePolicyNone:
+		limit = current
+	case UpdatePolicyPatch:
+		limit = fmt.Sprintf(">=%s, <%d.%d.0", current, v.Major(), v.Minor()+1)
+	case UpdatePolicyMinor:
+		limit = fmt.Sprintf(">=%s, <%d.0.0", current, v.Major()+1)
+	}
+
+	if r.Version == "" {
+		return limit, nil
+	}
+
+	// Commas take precedence over ||, so that the limit is added to each of the alternatives of the version
+	var constraints []string
+	for _, c := range strings.Split(r.Version, "||") {
+		constraints = append(constraints, strings.TrimSpace(c)+", "+limit)
+	}
+
+	return strings.Join(constraints, " || "), nil
+}
+
+func getUnresolvedDependenciess(st *HelmState) (string, *UnresolvedDependencies, error) {
+	return getDependencies(st, func(r *ReleaseSpec, _ string) (string, error) {
+		return r.Version, nil
+	})
+}
+
+// getDependencies returns the name of the lock file without the extension, and the remote charts of the releases to be installed.
+// versionConstraint returns the version constraint of the chart of the release, given the name of the chart without the repository.
+func getDependencies(st *HelmState, versionConstraint func(r *ReleaseSpec, chart string) (string, error)) (string, *UnresolvedDependencies, error) {
+	repos := repositoriesByName(st)
+
 	unresolved := &UnresolvedDependencies{deps: map[string][]unresolvedChartDependency{}}
 
-	for _, r := range st.Releases {
-		if !r.Desired() {
-			continue
-		}
+	for i := range st.Releases {
+		r := &st.Releases[i]
 
-		repo, chart, ok := resolveRemoteChart(r.Chart)
-		if !ok {
+		if !r.Desired() {
 			continue
 		}
 
-		repoSpec, ok := repoToURL[repo]
-		// Skip this chart from dependency management, as there's no matching `repository` in the helmfile state,
-		// which may imply that this is a local chart within a directory, like `charts/myapp`
+		chart, url, ok := dependencyOf(r, repos)
 		if !ok {
 			continue
 		}
 
-		url := repoSpec.URL
-
-		if repoSpec.OCI {
-			url = fmt.Sprintf("oci://%s", url)
+		constraint, err := versionConstraint(r, chart)
+		if err != nil {
+			return "", nil, err
 		}
 
-		if err := unresolved.Add(chart, url, r.Version); err != nil {
+		if err := unresolved.Add(chart, url, constraint); err != nil {
 			return "", nil, err
 		}
 	}
@@ -258,14 +429,17 @@ func getUnresolvedDependenciess(st *HelmState) (string, *UnresolvedDependencies,
 	return filename, unresolved, nil
 }
 
-func updateDependencies(st *HelmState, shell helmexec.DependencyUpdater, unresolved *UnresolvedDependencies, filename, wd string) (*HelmState, error) {
-	depMan := NewChartDependencyManager(filename, st.logger, st.LockFile)
-
+func updateDependencies(st *HelmState, shell helmexec.DependencyUpdater, depMan *chartDependencyManager, unresolved *UnresolvedDependencies, wd string) (*HelmState, error) {
 	_, err := depMan.Update(shell, wd, unresolved)
 	if err != nil {
 		return nil, fmt.Errorf("unable to update %d deps: %v", len(unresolved.deps), err)
 	}
 
+	// The lock file is left as is on dry-run, so there's nothing to resolve from
+	if depMan.dryRun {
+		return st, nil
+	}
+
 	return resolveDependencies(st, depMan, unresolved)
 }
 
@@ -276,6 +450,12 @@ type chartDependencyManager struct {
 
 	logger *zap.SugaredLogger
 
+	// dryRun prints the changes to the lock file instead of writing it
+	dryRun bool
+
+	// allowDigestChanges records the new digests of the charts re-published with the locked versions instead of failing
+	allowDigestChanges bool
+
 	readFile  func(string) ([]byte, error)
 	writeFile func(string, []byte, os.FileMode) error
 }
@@ -349,6 +529,17 @@ func (m *chartDependencyManager) doUpdate(chartLockFile string, unresolved *Unre
 		return nil, err
 	}
 
+	originalReqs := &ChartLockedRequirements{}
+	if originalLockFileContent != nil {
+		if err := yaml.Unmarshal(originalLockFileContent, originalReqs); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := m.addDigests(lockedReqs, originalReqs, filepath.Join(wd, "charts")); err != nil {
+		return nil, err
+	}
+
 	sort.Slice(lockedReqs.ResolvedDependencies, func(i, j int) bool {
 		return lockedReqs.ResolvedDependencies[i].ChartName < lockedReqs.ResolvedDependencies[j].ChartName
 	})
@@ -361,6 +552,11 @@ func (m *chartDependencyManager) doUpdate(chartLockFile string, unresolved *Unre
 		return nil, err
 	}
 
+	if m.dryRun {
+		fmt.Print(lockFileDiff(lockFilePath, originalLockFileContent, updatedLockFileContent))
+		return nil, nil
+	}
+
 	// Commit the lock file if and only if everything looks ok
 	if err := m.writeBytes(lockFilePath, updatedLockFileContent); err != nil {
 		return nil, err
@@ -370,6 +566,41 @@ func (m *chartDependencyManager) doUpdate(chartLockFile string, unresolved *Unre
 	return resolved, err
 }
 
+// addDigests records the digests of the chart archives downloaded by `helm dependency update` into chartsDir.
+// It fails on the charts whose digests differ from the original lock file, as they have been re-published with the same versions,
+// unless allowDigestChanges is set.
+func (m *chartDependencyManager) addDigests(lockedReqs, originalReqs *ChartLockedRequirements, chartsDir string) error {
+	for i := range lockedReqs.ResolvedDependencies {
+		d := &lockedReqs.ResolvedDependencies[i]
+
+		archive := filepath.Join(chartsDir, fmt.Sprintf("%s-%s.tgz", d.ChartName, d.Version))
+
+		digest, err := chartArchiveDigest(archive)
+		if os.IsNotExist(err) {
+			m.logger.Debugf("skipped recording the digest of chart %s %s: %s not found", d.ChartName, d.Version, archive)
+			continue
+		} else if err != nil {
+			return err
+		}
+
+		d.Digest = digest
+
+		for _, o := range originalReqs.ResolvedDependencies {
+			if o.ChartName != d.ChartName || o.Repository != d.Repository || o.Version != d.Version || o.Digest == "" || o.Digest == d.Digest {
+				continue
+			}
+
+			if !m.allowDigestChanges {
+				return fmt.Errorf("the digest of chart %s %s from %s changed from %s to %s, which means the chart has been re-published with the same version. Run with --allow-digest-changes to record the new digest", d.ChartName, d.Version, d.Repository, o.Digest, d.Digest)
+			}
+
+			m.logger.Warnf("WARNING: the digest of chart %s %s from %s changed from %s to %s, which means the chart has been re-published with the same version", d.ChartName, d.Version, d.Repository, o.Digest, d.Digest)
+		}
+	}
+
+	return nil
+}
+
 func (m *chartDependencyManager) Resolve(unresolved *UnresolvedDependencies) (*ResolvedDependencies, bool, error) {
 	updatedLockFileContent, err := m.readBytes(m.lockFileName())
 	if err != nil {
@@ -431,3 +662,190 @@ func (m *chartDependencyManager) writeBytes(filename string, data []byte) error
 	m.logger.Debugf("writeBytes: wrote to %s:\n%s", filename, data)
 	return nil
 }
+
+// lockFileDiff returns the changes to the lock file line by line, or a note when there's none
+func lockFileDiff(filename string, before, after []byte) string {
+	if bytes.Equal(before, after) {
+		return fmt.Sprintf("%s is up to date\n", filename)
+	}
+
+	records := difflib.Diff(strings.Split(string(before), "\n"), strings.Split(string(after), "\n"))
+
+	var b strings.Builder
+
+	fmt.Fprintf(&b, "--- %s\n+++ %s\n", filename, filename)
+	for _, r := range records {
+		if r.Payload == "" && r.Delta == difflib.Common {
+			continue
+		}
+		switch r.Delta {
+		case difflib.LeftOnly:
+			b.WriteString("- ")
+		case difflib.RightOnly:
+			b.WriteString("+ ")
+		default:
+			b.WriteString("  ")
+		}
+		b.WriteString(r.Payload)
+		b.WriteString("\n")
+	}
+
+	return b.String()
+}
+
+// chartArchiveDigest returns the sha256 digest of the chart archive, like `sha256:<hex>`
+func chartArchiveDigest(path string) (string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer func() {
+		_ = f.Close()
+	}()
+
+	h := sha256.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return "", err
+	}
+
+	return fmt.Sprintf("sha256:%x", h.Sum(nil)), nil
+}
+
+// verifiedChartArchive is the name of the chart archive kept in the directory the chart is extracted into by fetchVerifiedChart,
+// so that the chart can be verified against the locked digest again when the directory is reused.
+const verifiedChartArchive = ".helmfile-chart.tgz"
+
+// fetchVerifiedChart downloads the chart archive with `helm fetch`, fails when its digest doesn't match the locked digest,
+// and extracts it into dir, the same as `helm fetch --untar --untardir dir`.
+func fetchVerifiedChart(helm helmexec.Interface, chart, digest, dir string, flags ...string) error {
+	archiveDir, err := os.MkdirTemp("", "helmfile-chart-")
+	if err != nil {
+		return err
+	}
+	defer func() {
+		_ = os.RemoveAll(archiveDir)
+	}()
+
+	if err := helm.Fetch(chart, append(flags, "--destination", archiveDir)...); err != nil {
+		return err
+	}
+
+	archives, err := filepath.Glob(filepath.Join(archiveDir, "*.tgz"))
+	if err != nil {
+		return err
+	}
+	if len(archives) != 1 {
+		return fmt.Errorf("expected a chart archive of %s to be downloaded, but found %d", chart, len(archives))
+	}
+
+	actual, err := chartArchiveDigest(archives[0])
+	if err != nil {
+		return err
+	}
+
+	if actual != digest {
+		return fmt.Errorf("digest mismatch for chart %s: the lock file has %s but the downloaded chart has %s. The chart may have been re-published with the same version", chart, digest, actual)
+	}
+
+	if err := extractChartArchive(archives[0], dir); err != nil {
+		return err
+	}
+
+	return copyFile(archives[0], filepath.Join(dir, verifiedChartArchive))
+}
+
+// verifyCachedChart verifies the chart already fetched into dir by fetchVerifiedChart against the locked digest.
+// The chart is extracted again from the verified archive, so that the files modified after the chart was fetched are never used.
+func verifyCachedChart(chart, digest, dir string) error {
+	archive := filepath.Join(dir, verifiedChartArchive)
+
+	actual, err := chartArchiveDigest(archive)
+	if os.IsNotExist(err) {
+		return fmt.Errorf("chart %s in %s can't be verified against the digest %s in the lock file. Remove the directory to download the chart again", chart, dir, digest)
+	} else if err != nil {
+		return err
+	}
+
+	if actual != digest {
+		return fmt.Errorf("digest mismatch for chart %s: the lock file has %s but the chart in %s has %s", chart, digest, dir, actual)
+	}
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return err
+	}
+
+	for _, e := range entries {
+		if e.Name() == verifiedChartArchive {
+			continue
+		}
+		if err := os.RemoveAll(filepath.Join(dir, e.Name())); err != nil {
+			return err
+		}
+	}
+
+	return extractChartArchive(archive, dir)
+}
+
+// extractChartArchive extracts the files of the chart archive into dir. As the archive has the chart in a directory named after the chart,
+// the chart is extracted into dir/<chart>, the same as `helm fetch --untar --untardir dir`.
+func extractChartArchive(archive, dir string) error {
+	f, err := os.Open(archive)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		_ = f.Close()
+	}()
+
+	gr, err := gzip.NewReader(f)
+	if err != nil {
+		return fmt.Errorf("reading chart archive %s: %v", archive, err)
+	}
+
+	tr := tar.NewReader(gr)
+
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("reading chart archive %s: %v", archive, err)
+		}
+
+		// Chart archives only have regular files, and their directories are implied by the paths of the files
+		if hdr.Typeflag != tar.TypeReg {
+			continue
+		}
+
+		name := filepath.Clean(filepath.FromSlash(hdr.Name))
+		if filepath.IsAbs(name) || name == ".." || strings.HasPrefix(name, ".."+string(filepath.Separator)) {
+			return fmt.Errorf("chart archive %s has a file outside the chart: %s", archive, hdr.Name)
+		}
+
+		path := filepath.Join(dir, name)
+
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			return err
+		}
+
+		if err := writeArchivedFile(path, tr); err != nil {
+			return err
+		}
+	}
+}
+
+func writeArchivedFile(path string, r io.Reader) error {
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0644)
+	if err != nil {
+		return err
+	}
+
+	if _, err := io.Copy(f, r); err != nil {
+		_ = f.Close()
+		return err
+	}
+
+	return f.Close()
+}
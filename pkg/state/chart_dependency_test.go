@@ -1,9 +1,20 @@
 package state
 
 import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"crypto/sha256"
+	"fmt"
+	"os"
+	"path/filepath"
 	"testing"
 
 	"github.com/stretchr/testify/require"
+
+	"github.com/helmfile/helmfile/pkg/exectest"
+	"github.com/helmfile/helmfile/pkg/filesystem"
+	"github.com/helmfile/helmfile/pkg/helmexec"
 )
 
 func TestGetUnresolvedDependenciess(t *testing.T) {
@@ -64,3 +75,265 @@ func TestGetUnresolvedDependenciess(t *testing.T) {
 		})
 	}
 }
+
+func TestUpdateConstraint(t *testing.T) {
+	locked := &ResolvedDependencies{deps: map[string][]ResolvedChartDependency{
+		"redis": {{ChartName: "redis", Repository: "https://charts.bitnami.com/bitnami", Version: "17.3.1"}},
+	}}
+
+	tests := []struct {
+		name          string
+		release       ReleaseSpec
+		selected      bool
+		defaultPolicy string
+		want          string
+		wantErr       string
+	}{
+		{name: "no policy", release: ReleaseSpec{Version: "~17.3"}, selected: true, want: "~17.3"},
+		{name: "major", release: ReleaseSpec{Version: ">=17.0.0", UpdatePolicy: "major"}, selected: true, want: ">=17.0.0"},
+		{name: "patch", release: ReleaseSpec{UpdatePolicy: "patch"}, selected: true, want: ">=17.3.1, <17.4.0"},
+		{name: "minor", release: ReleaseSpec{Version: ">=17.0.0", UpdatePolicy: "minor"}, selected: true, want: ">=17.0.0, >=17.3.1, <18.0.0"},
+		{name: "none", release: ReleaseSpec{Version: "~17.3", UpdatePolicy: "none"}, selected: true, want: "~17.3, 17.3.1"},
+		{name: "default policy", release: ReleaseSpec{Version: "^17.0.0 || ^18.0.0"}, selected: true, defaultPolicy: "patch", want: "^17.0.0, >=17.3.1, <17.4.0 || ^18.0.0, >=17.3.1, <17.4.0"},
+		{name: "release policy over default", release: ReleaseSpec{UpdatePolicy: "major"}, selected: true, defaultPolicy: "none", want: ""},
+		{name: "not selected", release: ReleaseSpec{Version: ">=17.0.0"}, selected: false, want: ">=17.0.0, 17.3.1"},
+		{name: "not locked", release: ReleaseSpec{Version: "~16.0", UpdatePolicy: "patch"}, selected: true, want: "~16.0"},
+		{name: "unsupported", release: ReleaseSpec{Name: "cache", UpdatePolicy: "latest"}, selected: true, wantErr: `release "cache": unsupported updatePolicy "latest": expected one of patch, minor, major, none`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := updateConstraint(&tt.release, "redis", locked, tt.selected, tt.defaultPolicy)
+			if tt.wantErr != "" {
+				require.EqualError(t, err, tt.wantErr)
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestLockFileDiff(t *testing.T) {
+	before := "dependencies:\n- name: redis\n  version: 17.3.1\n"
+	after := "dependencies:\n- name: redis\n  version: 17.3.2\n"
+
+	require.Equal(t, `--- helmfile.lock
++++ helmfile.lock
+  dependencies:
+  - name: redis
+-   version: 17.3.1
++   version: 17.3.2
+`, lockFileDiff("helmfile.lock", []byte(before), []byte(after)))
+
+	require.Equal(t, "helmfile.lock is up to date\n", lockFileDiff("helmfile.lock", []byte(before), []byte(before)))
+}
+
+// chartArchive returns a chart archive as packaged by `helm package`
+func chartArchive(t *testing.T, name, version string) []byte {
+	t.Helper()
+
+	var buf bytes.Buffer
+
+	gw := gzip.NewWriter(&buf)
+	tw := tar.NewWriter(gw)
+
+	content := []byte(fmt.Sprintf("apiVersion: v2\nname: %s\nversion: %s\n", name, version))
+	require.NoError(t, tw.WriteHeader(&tar.Header{Name: name + "/Chart.yaml", Mode: 0644, Size: int64(len(content))}))
+	_, err := tw.Write(content)
+	require.NoError(t, err)
+
+	require.NoError(t, tw.Close())
+	require.NoError(t, gw.Close())
+
+	return buf.Bytes()
+}
+
+type fetchArchiveHelm struct {
+	*exectest.Helm
+
+	archive []byte
+	fetched []string
+}
+
+func (h *fetchArchiveHelm) Fetch(chart string, flags ...string) error {
+	h.fetched = append(h.fetched, fmt.Sprintf("%s %v", chart, flags[:len(flags)-2]))
+
+	return os.WriteFile(filepath.Join(flags[len(flags)-1], "chart.tgz"), h.archive, 0644)
+}
+
+func TestFetchVerifiedChart(t *testing.T) {
+	archive := chartArchive(t, "redis", "17.3.1")
+	digest := fmt.Sprintf("sha256:%x", sha256.Sum256(archive))
+
+	helm := &fetchArchiveHelm{Helm: &exectest.Helm{Helm3: true}, archive: archive}
+
+	dir := t.TempDir()
+	require.NoError(t, fetchVerifiedChart(helm, "bitnami/redis", digest, dir, "--version", "17.3.1"))
+	require.Equal(t, []string{"bitnami/redis [--version 17.3.1]"}, helm.fetched)
+	require.FileExists(t, filepath.Join(dir, "redis", "Chart.yaml"))
+
+	dir = t.TempDir()
+	err := fetchVerifiedChart(helm, "bitnami/redis", "sha256:0000", dir, "--version", "17.3.1")
+	require.EqualError(t, err, fmt.Sprintf("digest mismatch for chart bitnami/redis: the lock file has sha256:0000 but the downloaded chart has %s. The chart may have been re-published with the same version", digest))
+	require.NoDirExists(t, filepath.Join(dir, "redis"))
+}
+
+func TestChartDependencyManager_AddDigests(t *testing.T) {
+	chartsDir := t.TempDir()
+
+	archive := chartArchive(t, "redis", "17.3.1")
+	require.NoError(t, os.WriteFile(filepath.Join(chartsDir, "redis-17.3.1.tgz"), archive, 0644))
+	digest := fmt.Sprintf("sha256:%x", sha256.Sum256(archive))
+
+	var logs bytes.Buffer
+	m := NewChartDependencyManager("helmfile", helmexec.NewLogger(&logs, "warn"), "")
+
+	locked := &ChartLockedRequirements{ResolvedDependencies: []ResolvedChartDependency{
+		{ChartName: "redis", Repository: "https://charts.bitnami.com/bitnami", Version: "17.3.1"},
+		{ChartName: "nginx", Repository: "https://charts.bitnami.com/bitnami", Version: "13.2.0"},
+	}}
+	original := &ChartLockedRequirements{ResolvedDependencies: []ResolvedChartDependency{
+		{ChartName: "redis", Repository: "https://charts.bitnami.com/bitnami", Version: "17.3.1", Digest: "sha256:0000"},
+	}}
+
+	err := m.addDigests(locked, original, chartsDir)
+	require.EqualError(t, err, "the digest of chart redis 17.3.1 from https://charts.bitnami.com/bitnami changed from sha256:0000 to "+digest+", which means the chart has been re-published with the same version. Run with --allow-digest-changes to record the new digest")
+
+	// The new digest is recorded only when explicitly allowed
+	m.allowDigestChanges = true
+	require.NoError(t, m.addDigests(locked, original, chartsDir))
+
+	require.Equal(t, digest, locked.ResolvedDependencies[0].Digest)
+	require.Empty(t, locked.ResolvedDependencies[1].Digest)
+	require.Contains(t, logs.String(), "the digest of chart redis 17.3.1 from https://charts.bitnami.com/bitnami changed from sha256:0000 to "+digest)
+}
+
+func TestResolveDependencies_OCI(t *testing.T) {
+	st := &HelmState{
+		ReleaseSetSpec: ReleaseSetSpec{
+			Releases: []ReleaseSpec{
+				{Name: "app", Chart: "oci://registry.example.com/charts/app", Version: "~1.0"},
+				{Name: "other", Chart: "oci://registry.example.com/charts/other", Version: "2.0.0"},
+			},
+		},
+		logger: helmexec.NewLogger(&bytes.Buffer{}, "warn"),
+	}
+
+	_, unresolved, err := getUnresolvedDependenciess(st)
+	require.NoError(t, err)
+	require.Equal(t, []unresolvedChartDependency{{ChartName: "app", Repository: "oci://registry.example.com/charts", VersionConstraint: "~1.0"}}, unresolved.deps["app"])
+
+	m := NewChartDependencyManager("helmfile", st.logger, "")
+	m.readFile = func(string) ([]byte, error) {
+		return []byte(`dependencies:
+- name: app
+  repository: oci://registry.example.com/charts
+  version: 1.0.3
+  digest: sha256:1234
+`), nil
+	}
+
+	resolved, err := resolveDependencies(st, m, unresolved)
+	require.NoError(t, err)
+
+	// The chart missing in the lock file written by an older version of Helmfile is left as is
+	require.Equal(t, "1.0.3", resolved.Releases[0].Version)
+	require.Equal(t, "2.0.0", resolved.Releases[1].Version)
+	require.Equal(t, "sha256:1234", resolved.lockedDigest(&resolved.Releases[0]))
+	require.Empty(t, resolved.lockedDigest(&resolved.Releases[1]))
+}
+
+func TestPrepareCharts_LockedDigest(t *testing.T) {
+	archive := chartArchive(t, "redis", "17.3.1")
+	digest := fmt.Sprintf("sha256:%x", sha256.Sum256(archive))
+
+	newState := func(digest string) *HelmState {
+		return &HelmState{
+			basePath:       t.TempDir(),
+			fs:             filesystem.DefaultFileSystem(),
+			logger:         helmexec.NewLogger(&bytes.Buffer{}, "warn"),
+			RenderedValues: map[string]any{},
+			ReleaseSetSpec: ReleaseSetSpec{
+				Repositories: []RepositorySpec{{Name: "bitnami", URL: "https://charts.bitnami.com/bitnami"}},
+				Releases: []ReleaseSpec{
+					{Name: "redis", Chart: "bitnami/redis", Version: "17.3.1"},
+				},
+			},
+			lockedDeps: &ResolvedDependencies{deps: map[string][]ResolvedChartDependency{
+				"redis": {{ChartName: "redis", Repository: "https://charts.bitnami.com/bitnami", Version: "17.3.1", Digest: digest}},
+			}},
+		}
+	}
+
+	for _, cmd := range []string{"sync", "apply"} {
+		t.Run(cmd, func(t *testing.T) {
+			helm := &fetchArchiveHelm{Helm: &exectest.Helm{Helm3: true}, archive: archive}
+
+			// The verified chart is handed over to helm instead of its name
+			charts, errs := newState(digest).PrepareCharts(helm, t.TempDir(), 1, cmd, ChartPrepareOptions{SkipResolve: true, SkipRepos: true})
+			require.Empty(t, errs)
+			require.Equal(t, []string{"bitnami/redis [--version 17.3.1]"}, helm.fetched)
+
+			chart := charts[PrepareChartKey{Name: "redis"}]
+			require.NotEqual(t, "bitnami/redis", chart)
+			require.FileExists(t, filepath.Join(chart, "Chart.yaml"))
+
+			_, errs = newState("sha256:0000").PrepareCharts(helm, t.TempDir(), 1, cmd, ChartPrepareOptions{SkipResolve: true, SkipRepos: true})
+			require.Len(t, errs, 1)
+			require.EqualError(t, errs[0], fmt.Sprintf(`release "redis": digest mismatch for chart bitnami/redis: the lock file has sha256:0000 but the downloaded chart has %s. The chart may have been re-published with the same version`, digest))
+		})
+	}
+}
+
+func TestPrepareCharts_CachedLockedDigest(t *testing.T) {
+	archive := chartArchive(t, "redis", "17.3.1")
+	digest := fmt.Sprintf("sha256:%x", sha256.Sum256(archive))
+
+	newState := func(digest string) *HelmState {
+		return &HelmState{
+			basePath:       t.TempDir(),
+			fs:             filesystem.DefaultFileSystem(),
+			logger:         helmexec.NewLogger(&bytes.Buffer{}, "warn"),
+			RenderedValues: map[string]any{},
+			ReleaseSetSpec: ReleaseSetSpec{
+				Repositories: []RepositorySpec{{Name: "bitnami", URL: "https://charts.bitnami.com/bitnami"}},
+				Releases: []ReleaseSpec{
+					{Name: "redis", Chart: "bitnami/redis", Version: "17.3.1"},
+				},
+			},
+			lockedDeps: &ResolvedDependencies{deps: map[string][]ResolvedChartDependency{
+				"redis": {{ChartName: "redis", Repository: "https://charts.bitnami.com/bitnami", Version: "17.3.1", Digest: digest}},
+			}},
+		}
+	}
+
+	opts := ChartPrepareOptions{SkipResolve: true, SkipRepos: true, ForceDownload: true}
+
+	helm := &fetchArchiveHelm{Helm: &exectest.Helm{Helm3: true}, archive: archive}
+	dir := t.TempDir()
+
+	charts, errs := newState(digest).PrepareCharts(helm, dir, 1, "template", opts)
+	require.Empty(t, errs)
+	chart := charts[PrepareChartKey{Name: "redis"}]
+	require.NoError(t, os.WriteFile(filepath.Join(chart, "Chart.yaml"), []byte("name: modified\n"), 0644))
+
+	// The cached chart is verified and extracted again from its archive instead of being downloaded again
+	charts, errs = newState(digest).PrepareCharts(helm, dir, 1, "template", opts)
+	require.Empty(t, errs)
+	require.Len(t, helm.fetched, 1)
+	chartYaml, err := os.ReadFile(filepath.Join(charts[PrepareChartKey{Name: "redis"}], "Chart.yaml"))
+	require.NoError(t, err)
+	require.Equal(t, "apiVersion: v2\nname: redis\nversion: 17.3.1\n", string(chartYaml))
+
+	_, errs = newState("sha256:0000").PrepareCharts(helm, dir, 1, "template", opts)
+	require.Len(t, errs, 1)
+	require.ErrorContains(t, errs[0], fmt.Sprintf("digest mismatch for chart bitnami/redis: the lock file has sha256:0000 but the chart in %s has %s", filepath.Dir(chart), digest))
+
+	// The chart put into the directory without its archive can't be verified
+	require.NoError(t, os.Remove(filepath.Join(filepath.Dir(chart), verifiedChartArchive)))
+	_, errs = newState(digest).PrepareCharts(helm, dir, 1, "template", opts)
+	require.Len(t, errs, 1)
+	require.ErrorContains(t, errs[0], "can't be verified against the digest")
+	require.Len(t, helm.fetched, 1)
+}
@@ -10,6 +10,7 @@ import (
 	"go.uber.org/zap"
 
 	"github.com/helmfile/helmfile/pkg/environment"
+	"github.com/helmfile/helmfile/pkg/event"
 	"github.com/helmfile/helmfile/pkg/filesystem"
 	"github.com/helmfile/helmfile/pkg/helmexec"
 	"github.com/helmfile/helmfile/pkg/maputil"
@@ -123,6 +124,8 @@ func (c *StateCreator) Parse(content []byte, baseDir, file string) (*HelmState,
 		state.DeprecatedReleases = []ReleaseSpec{}
 	}
 
+	state.setNeedsPositions(content, file)
+
 	// TODO: Remove this function once Helmfile v0.x
 	if state.DeprecatedContext != "" && state.HelmDefaults.KubeContext == "" {
 		state.HelmDefaults.KubeContext = state.DeprecatedContext
@@ -144,6 +147,8 @@ func (c *StateCreator) Parse(content []byte, baseDir, file string) (*HelmState,
 
 	state.logger = c.logger
 	state.valsRuntime = c.valsRuntime
+	state.asyncHooks = &event.AsyncHooks{}
+	state.remote = c.remote
 
 	return &state, nil
 }
@@ -255,9 +260,21 @@ func (c *StateCreator) loadEnvValues(st *HelmState, name string, failOnMissingEn
 
 				envSecretFiles = append(envSecretFiles, resolved...)
 			}
-			if err = c.scatterGatherEnvSecretFiles(st, envSecretFiles, envVals); err != nil {
+
+			secrets := map[string]any{}
+			if err = c.scatterGatherEnvSecretFiles(st, envSecretFiles, secrets); err != nil {
 				return nil, err
 			}
+
+			if envVals == nil {
+				envVals = map[string]any{}
+			}
+			if err := mergo.Merge(&envVals, secrets, mergo.WithOverride); err != nil {
+				return nil, err
+			}
+
+			st.envSecrets = secrets
+			st.envSecretFiles = envSecretFiles
 		}
 	} else if ctxEnv == nil && overrode == nil && name != DefaultEnv && failOnMissingEnv {
 		return nil, &UndefinedEnvError{Env: name}
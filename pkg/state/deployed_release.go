@@ -0,0 +1,162 @@
+package state
+
+import (
+	"strings"
+
+	"github.com/helmfile/helmfile/pkg/helmexec"
+)
+
+// ReleaseStatusUninstalled is the status of a release uninstalled with its history kept
+const ReleaseStatusUninstalled = "uninstalled"
+
+// DeployedRelease is a release as listed by `helm list`
+type DeployedRelease struct {
+	Name      string
+	Namespace string
+	Revision  string
+	Updated   string
+	Status    string
+	// Chart is the name and the version of the deployed chart, like nginx-1.2.3
+	Chart      string
+	AppVersion string
+}
+
+// DeployedReleases are the releases deployed to a kube context
+type DeployedReleases struct {
+	// All is the releases in all the namespaces
+	All []DeployedRelease
+	// Default is the releases in the namespace helm uses for the releases without a namespace,
+	// which is the namespace of the kube context. It is nil unless listed
+	Default []DeployedRelease
+}
+
+// ListDeployedReleases lists the releases in all the namespaces of the kube context, whatever their status.
+// The releases in the default namespace are listed as well when withDefault is true.
+// The current kube context is used when kubeContext is empty.
+func ListDeployedReleases(helm helmexec.Interface, kubeContext string, withDefault bool) (*DeployedReleases, error) {
+	var flags []string
+	if kubeContext != "" {
+		flags = append(flags, "--kube-context", kubeContext)
+	}
+
+	all, err := listDeployedReleases(helm, append(flags, "--all-namespaces")...)
+	if err != nil {
+		return nil, err
+	}
+
+	deployed := &DeployedReleases{All: all}
+
+	if withDefault {
+		// Like helmfile does for the releases without a namespace, helm is run without --namespace,
+		// so that it resolves the namespace from the kube context
+		deployed.Default, err = listDeployedReleases(helm, flags...)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return deployed, nil
+}
+
+func listDeployedReleases(helm helmexec.Interface, flags ...string) ([]DeployedRelease, error) {
+	out, err := helm.List(helmexec.HelmContext{}, "", append(flags, "--all", "--max", "0")...)
+	if err != nil {
+		return nil, err
+	}
+
+	return parseHelmList(out), nil
+}
+
+// parseHelmList parses the table printed by `helm list`, without the header as it is removed by helmexec
+func parseHelmList(out string) []DeployedRelease {
+	var releases []DeployedRelease
+
+	for _, line := range strings.Split(out, "\n") {
+		// The columns are separated by tabs, while the updated time contains spaces
+		cols := strings.Split(line, "\t")
+		if len(cols) < 6 {
+			continue
+		}
+
+		for i := range cols {
+			cols[i] = strings.TrimSpace(cols[i])
+		}
+
+		r := DeployedRelease{
+			Name:      cols[0],
+			Namespace: cols[1],
+			Revision:  cols[2],
+			Updated:   cols[3],
+			Status:    cols[4],
+			Chart:     cols[5],
+		}
+		if len(cols) > 6 {
+			r.AppVersion = cols[6]
+		}
+
+		releases = append(releases, r)
+	}
+
+	return releases
+}
+
+// FindDeployedRelease returns the deployed release of the release among the releases deployed to its kube context.
+// A release without a namespace matches only the deployed release of the same name in the default namespace.
+func FindDeployedRelease(release *ReleaseSpec, deployed *DeployedReleases) (*DeployedRelease, bool) {
+	releases := deployed.All
+	if release.Namespace == "" {
+		releases = deployed.Default
+	}
+
+	for i := range releases {
+		d := &releases[i]
+		if d.Name == release.Name && (release.Namespace == "" || d.Namespace == release.Namespace) {
+			return d, true
+		}
+	}
+
+	return nil, false
+}
+
+// Installed returns true unless the release is uninstalled with its history kept
+func (d *DeployedRelease) Installed() bool {
+	return d != nil && d.Status != ReleaseStatusUninstalled
+}
+
+// ChartVersion returns the version of the deployed chart, given the name of the chart or the chart of the release
+// like stable/nginx
+func (d *DeployedRelease) ChartVersion(chart string) string {
+	if v, ok := strings.CutPrefix(d.Chart, chartNameWithoutRepository(chart)+"-"); ok {
+		return v
+	}
+
+	// The chart may be named differently than the chart of the release, as in the case of a local chart.
+	// Chart names can contain dashes, while versions start with a digit
+	for i := 0; i+1 < len(d.Chart); i++ {
+		if d.Chart[i] == '-' && d.Chart[i+1] >= '0' && d.Chart[i+1] <= '9' {
+			return d.Chart[i+1:]
+		}
+	}
+
+	return ""
+}
+
+// OutOfDate returns true when the deployed release differs from the desired release:
+// the release is desired but not installed, installed but not desired, or deployed with a chart version
+// that doesn't satisfy the desired version.
+func OutOfDate(release *ReleaseSpec, deployed *DeployedRelease) bool {
+	if release.Desired() != deployed.Installed() {
+		return true
+	}
+
+	if !deployed.Installed() {
+		return false
+	}
+
+	return !versionMatches(release.Version, deployed.ChartVersion(release.Chart))
+}
+
+func chartNameWithoutRepository(chart string) string {
+	chartSplit := strings.Split(chart, "/")
+	return chartSplit[len(chartSplit)-1]
+}
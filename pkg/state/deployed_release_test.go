@@ -0,0 +1,132 @@
+package state
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestParseHelmList(t *testing.T) {
+	out := "nginx  \tweb        \t3       \t2024-01-02 15:04:05.123456 +0000 UTC\tdeployed\tnginx-15.1.0     \t1.25.3     \n" +
+		"db     \tbackend    \t1       \t2024-01-01 10:00:00.5 +0000 UTC     \tfailed  \tpostgresql-12.1.0\t16.1.0     \n"
+
+	want := []DeployedRelease{
+		{Name: "nginx", Namespace: "web", Revision: "3", Updated: "2024-01-02 15:04:05.123456 +0000 UTC", Status: "deployed", Chart: "nginx-15.1.0", AppVersion: "1.25.3"},
+		{Name: "db", Namespace: "backend", Revision: "1", Updated: "2024-01-01 10:00:00.5 +0000 UTC", Status: "failed", Chart: "postgresql-12.1.0", AppVersion: "16.1.0"},
+	}
+
+	if d := cmp.Diff(want, parseHelmList(out)); d != "" {
+		t.Errorf("unexpected releases: want (-), got (+): %s", d)
+	}
+}
+
+func TestDeployedRelease_ChartVersion(t *testing.T) {
+	cases := []struct {
+		chart    string
+		deployed string
+		want     string
+	}{
+		{chart: "bitnami/nginx", deployed: "nginx-15.1.0", want: "15.1.0"},
+		{chart: "cert-manager", deployed: "cert-manager-v1.13.2", want: "v1.13.2"},
+		{chart: "./charts/my-app", deployed: "app-0.1.0-rc.1", want: "0.1.0-rc.1"},
+		{chart: "./charts/my-app", deployed: "app", want: ""},
+	}
+
+	for _, c := range cases {
+		d := &DeployedRelease{Chart: c.deployed}
+		if got := d.ChartVersion(c.chart); got != c.want {
+			t.Errorf("unexpected version of %s deployed from %s: want %q, got %q", c.deployed, c.chart, c.want, got)
+		}
+	}
+}
+
+func TestOutOfDate(t *testing.T) {
+	installed := false
+
+	cases := []struct {
+		name     string
+		release  ReleaseSpec
+		deployed *DeployedRelease
+		want     bool
+	}{
+		{
+			name:     "deployed with the desired version",
+			release:  ReleaseSpec{Name: "nginx", Chart: "bitnami/nginx", Version: "15.1.0"},
+			deployed: &DeployedRelease{Name: "nginx", Status: "deployed", Chart: "nginx-15.1.0"},
+		},
+		{
+			name:     "deployed with a version satisfying the constraint",
+			release:  ReleaseSpec{Name: "nginx", Chart: "bitnami/nginx", Version: "~15.1"},
+			deployed: &DeployedRelease{Name: "nginx", Status: "deployed", Chart: "nginx-15.1.3"},
+		},
+		{
+			name:     "deployed with another version",
+			release:  ReleaseSpec{Name: "nginx", Chart: "bitnami/nginx", Version: "15.2.0"},
+			deployed: &DeployedRelease{Name: "nginx", Status: "deployed", Chart: "nginx-15.1.0"},
+			want:     true,
+		},
+		{
+			name:    "not deployed",
+			release: ReleaseSpec{Name: "nginx", Chart: "bitnami/nginx"},
+			want:    true,
+		},
+		{
+			name:     "uninstalled",
+			release:  ReleaseSpec{Name: "nginx", Chart: "bitnami/nginx"},
+			deployed: &DeployedRelease{Name: "nginx", Status: ReleaseStatusUninstalled, Chart: "nginx-15.1.0"},
+			want:     true,
+		},
+		{
+			name:     "deployed but not desired",
+			release:  ReleaseSpec{Name: "nginx", Chart: "bitnami/nginx", Installed: &installed},
+			deployed: &DeployedRelease{Name: "nginx", Status: "deployed", Chart: "nginx-15.1.0"},
+			want:     true,
+		},
+		{
+			name:    "neither deployed nor desired",
+			release: ReleaseSpec{Name: "nginx", Chart: "bitnami/nginx", Installed: &installed},
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			if got := OutOfDate(&c.release, c.deployed); got != c.want {
+				t.Errorf("unexpected out of date: want %t, got %t", c.want, got)
+			}
+		})
+	}
+}
+
+func TestFindDeployedRelease(t *testing.T) {
+	deployed := &DeployedReleases{
+		All: []DeployedRelease{
+			{Name: "app", Namespace: "staging"},
+			{Name: "app", Namespace: "prod"},
+			{Name: "other", Namespace: "staging"},
+		},
+		Default: []DeployedRelease{
+			{Name: "app", Namespace: "prod"},
+		},
+	}
+
+	if d, ok := FindDeployedRelease(&ReleaseSpec{Name: "app", Namespace: "prod"}, deployed); !ok || d.Namespace != "prod" {
+		t.Errorf("unexpected deployed release: %v", d)
+	}
+
+	if d, ok := FindDeployedRelease(&ReleaseSpec{Name: "app", Namespace: "staging"}, deployed); !ok || d.Namespace != "staging" {
+		t.Errorf("unexpected deployed release: %v", d)
+	}
+
+	// The release without a namespace is installed into the default namespace
+	if d, ok := FindDeployedRelease(&ReleaseSpec{Name: "app"}, deployed); !ok || d.Namespace != "prod" {
+		t.Errorf("unexpected deployed release: %v", d)
+	}
+
+	if _, ok := FindDeployedRelease(&ReleaseSpec{Name: "other"}, deployed); ok {
+		t.Error("unexpected deployed release outside the default namespace")
+	}
+
+	if _, ok := FindDeployedRelease(&ReleaseSpec{Name: "app", Namespace: "dev"}, deployed); ok {
+		t.Error("unexpected deployed release in namespace dev")
+	}
+}
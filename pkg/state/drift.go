@@ -0,0 +1,324 @@
+package state
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/Masterminds/semver/v3"
+	"helm.sh/helm/v3/pkg/strvals"
+
+	"github.com/helmfile/helmfile/pkg/helmexec"
+	"github.com/helmfile/helmfile/pkg/maputil"
+	"github.com/helmfile/helmfile/pkg/yaml"
+)
+
+const (
+	// DriftMissing means the release is desired but not installed
+	DriftMissing = "missing"
+	// DriftUnwanted means the release is installed although it has `installed: false`
+	DriftUnwanted = "unwanted"
+	// DriftVersion means the deployed chart version doesn't match the desired version
+	DriftVersion = "version"
+	// DriftValues means the deployed values differ from the rendered values
+	DriftValues = "values"
+)
+
+// ReleaseDrift is the difference between the desired state and the live state of a release
+type ReleaseDrift struct {
+	// ID is the release ID as returned by ReleaseToID
+	ID        string `json:"id"`
+	Desired   bool   `json:"desired"`
+	Installed bool   `json:"installed"`
+	// DesiredVersion is the chart version or the version constraint in the helmfile state
+	DesiredVersion  string `json:"desiredVersion,omitempty"`
+	DeployedVersion string `json:"deployedVersion,omitempty"`
+	// ChangedValues are the paths to the values that differ between the deployed and the rendered values
+	ChangedValues []string `json:"changedValues,omitempty"`
+	// Drifts are the categories of the drift. It is empty when the release hasn't drifted
+	Drifts []string `json:"drifts"`
+}
+
+// DetectDrifts compares the live state of each release with its desired state, without running helm-diff.
+// The result is in the same order as releases.
+func (st *HelmState) DetectDrifts(helm helmexec.Interface, releases []ReleaseSpec, concurrency int) ([]ReleaseDrift, []error) {
+	var mu sync.Mutex
+
+	drifts := map[string]*ReleaseDrift{}
+
+	errs := st.iterateOnReleases(helm, concurrency, releases, func(release ReleaseSpec, workerIndex int) error {
+		st.ApplyOverrides(&release)
+
+		drift, err := st.detectDrift(helm, &release, workerIndex)
+		if err != nil {
+			return err
+		}
+
+		mu.Lock()
+		drifts[drift.ID] = drift
+		mu.Unlock()
+
+		return nil
+	})
+
+	var result []ReleaseDrift
+	for i := range releases {
+		if d, ok := drifts[ReleaseToID(&releases[i])]; ok {
+			result = append(result, *d)
+		}
+	}
+
+	return result, errs
+}
+
+func (st *HelmState) detectDrift(helm helmexec.Interface, release *ReleaseSpec, workerIndex int) (*ReleaseDrift, error) {
+	context := st.createHelmContext(release, workerIndex)
+
+	out, err := st.listReleases(context, helm, release)
+	if err != nil {
+		return nil, err
+	}
+
+	installed := releaseListed(out, release.Name)
+
+	drift := &ReleaseDrift{
+		ID:             ReleaseToID(release),
+		Desired:        release.Desired(),
+		Installed:      installed,
+		DesiredVersion: release.Version,
+		Drifts:         []string{},
+	}
+
+	switch {
+	case drift.Desired && !installed:
+		drift.Drifts = append(drift.Drifts, DriftMissing)
+		return drift, nil
+	case !drift.Desired && installed:
+		drift.Drifts = append(drift.Drifts, DriftUnwanted)
+		return drift, nil
+	case !installed:
+		return drift, nil
+	}
+
+	// Unlike getDeployedVersion, this never falls back to the version of the desired chart, which would hide the version drift
+	version, _ := parseDeployedVersion(out, filepath.Base(release.Chart))
+	switch {
+	case version == "":
+		st.logger.Warnf("unable to get the deployed chart version of release %q: no chart named %q in the output of helm list", drift.ID, filepath.Base(release.Chart))
+	default:
+		drift.DeployedVersion = version
+		if !versionMatches(release.Version, version) {
+			drift.Drifts = append(drift.Drifts, DriftVersion)
+		}
+	}
+
+	changed, err := st.changedValues(context, helm, release, workerIndex)
+	if err != nil {
+		return nil, err
+	}
+	if len(changed) > 0 {
+		drift.ChangedValues = changed
+		drift.Drifts = append(drift.Drifts, DriftValues)
+	}
+
+	return drift, nil
+}
+
+// releaseListed returns true when the output of helm-list has a row for the release.
+// Unlike isReleaseInstalled, it never takes the header of the output for a release, so that a release missing
+// in the cluster is never reported as installed.
+func releaseListed(out, name string) bool {
+	for _, line := range strings.Split(out, "\n") {
+		if fields := strings.Fields(line); len(fields) > 0 && fields[0] == name {
+			return true
+		}
+	}
+
+	return false
+}
+
+// versionMatches returns true when the deployed version satisfies the desired version, which can be either a version
+// or a version constraint. Any version matches when no version is desired.
+func versionMatches(desired, deployed string) bool {
+	if desired == "" {
+		return true
+	}
+
+	c, err := semver.NewConstraint(desired)
+	if err != nil {
+		return desired == deployed
+	}
+
+	v, err := semver.NewVersion(deployed)
+	if err != nil {
+		return desired == deployed
+	}
+
+	return c.Check(v)
+}
+
+// changedValues returns the paths to the values that differ between the deployed release and the rendered values files and set values.
+func (st *HelmState) changedValues(context helmexec.HelmContext, helm helmexec.Interface, release *ReleaseSpec, workerIndex int) ([]string, error) {
+	desired, err := st.renderedValues(helm, release, workerIndex)
+	if err != nil {
+		return nil, err
+	}
+
+	flags := st.appendConnectionFlags(nil, release)
+	if release.Namespace != "" {
+		flags = append(flags, "--namespace", release.Namespace)
+	}
+
+	out, err := helm.GetValues(context, release.Name, flags...)
+	if err != nil {
+		return nil, err
+	}
+
+	var deployed map[string]any
+	if err := yaml.Unmarshal([]byte(out), &deployed); err != nil {
+		return nil, fmt.Errorf("parsing values of the release %q: %v", release.Name, err)
+	}
+
+	d, err := normalizeValues(deployed)
+	if err != nil {
+		return nil, err
+	}
+
+	r, err := normalizeValues(desired)
+	if err != nil {
+		return nil, err
+	}
+
+	return diffValuePaths("", d, r), nil
+}
+
+// renderedValues merges the values files and the set values of the release in the same way Helm does.
+func (st *HelmState) renderedValues(helm helmexec.Interface, release *ReleaseSpec, workerIndex int) (map[string]any, error) {
+	files, err := st.generateValuesFiles(helm, release, workerIndex)
+	if err != nil {
+		return nil, err
+	}
+	defer st.removeFiles(files)
+
+	values := map[string]any{}
+
+	for _, f := range files {
+		bs, err := os.ReadFile(f)
+		if err != nil {
+			return nil, err
+		}
+
+		var m map[string]any
+		if err := yaml.Unmarshal(bs, &m); err != nil {
+			return nil, fmt.Errorf("parsing values file %s: %v", f, err)
+		}
+
+		m, err = maputil.CastKeysToStrings(m)
+		if err != nil {
+			return nil, err
+		}
+
+		values = maputil.MergeMaps(values, m)
+	}
+
+	setFlags, err := st.setFlags(release.SetValues)
+	if err != nil {
+		return nil, err
+	}
+
+	readFile := func(rs []rune) (any, error) {
+		bs, err := os.ReadFile(string(rs))
+		return string(bs), err
+	}
+
+	for i := 0; i+1 < len(setFlags); i += 2 {
+		switch setFlags[i] {
+		case "--set":
+			err = strvals.ParseInto(setFlags[i+1], values)
+		case "--set-file":
+			err = strvals.ParseIntoFile(setFlags[i+1], values, readFile)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("parsing set values: %v", err)
+		}
+	}
+
+	return values, nil
+}
+
+// normalizeValues converts the values to the form they would have after being decoded from JSON,
+// so that e.g. an int from a values file and the same number from `helm get values` are equal.
+func normalizeValues(values map[string]any) (map[string]any, error) {
+	if values == nil {
+		return map[string]any{}, nil
+	}
+
+	m, err := maputil.CastKeysToStrings(values)
+	if err != nil {
+		return nil, err
+	}
+
+	bs, err := json.Marshal(m)
+	if err != nil {
+		return nil, err
+	}
+
+	var normalized map[string]any
+	if err := json.Unmarshal(bs, &normalized); err != nil {
+		return nil, err
+	}
+
+	return normalized, nil
+}
+
+func diffValuePaths(prefix string, a, b map[string]any) []string {
+	keys := map[string]bool{}
+	for k := range a {
+		keys[k] = true
+	}
+	for k := range b {
+		keys[k] = true
+	}
+
+	var sorted []string
+	for k := range keys {
+		sorted = append(sorted, k)
+	}
+	sort.Strings(sorted)
+
+	var paths []string
+
+	for _, k := range sorted {
+		path := k
+		if prefix != "" {
+			path = prefix + "." + k
+		}
+
+		am, aIsMap := a[k].(map[string]any)
+		bm, bIsMap := b[k].(map[string]any)
+		if aIsMap && bIsMap {
+			paths = append(paths, diffValuePaths(path, am, bm)...)
+			continue
+		}
+
+		if _, ok := a[k]; !ok {
+			paths = append(paths, path)
+			continue
+		}
+		if _, ok := b[k]; !ok {
+			paths = append(paths, path)
+			continue
+		}
+
+		if !reflect.DeepEqual(a[k], b[k]) {
+			paths = append(paths, path)
+		}
+	}
+
+	return paths
+}
@@ -0,0 +1,69 @@
+package state
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestVersionMatches(t *testing.T) {
+	tests := []struct {
+		desired  string
+		deployed string
+		want     bool
+	}{
+		{desired: "", deployed: "1.0.0", want: true},
+		{desired: "1.0.0", deployed: "1.0.0", want: true},
+		{desired: "1.0.0", deployed: "1.0.1", want: false},
+		{desired: "~1.0.0", deployed: "1.0.1", want: true},
+		{desired: ">=2.0.0", deployed: "1.0.1", want: false},
+		{desired: "v1", deployed: "v1", want: true},
+		{desired: "foo", deployed: "foo", want: true},
+		{desired: "foo", deployed: "bar", want: false},
+	}
+
+	for _, tt := range tests {
+		require.Equal(t, tt.want, versionMatches(tt.desired, tt.deployed), "desired %q, deployed %q", tt.desired, tt.deployed)
+	}
+}
+
+func TestDiffValuePaths(t *testing.T) {
+	deployed := map[string]any{
+		"replicas": float64(1),
+		"image": map[string]any{
+			"repository": "nginx",
+			"tag":        "v1",
+		},
+		"extra": true,
+	}
+	rendered := map[string]any{
+		"replicas": float64(1),
+		"image": map[string]any{
+			"repository": "nginx",
+			"tag":        "v2",
+		},
+		"ingress": map[string]any{
+			"enabled": true,
+		},
+	}
+
+	require.Equal(t, []string{"extra", "image.tag", "ingress"}, diffValuePaths("", deployed, rendered))
+	require.Empty(t, diffValuePaths("", deployed, deployed))
+}
+
+func TestNormalizeValues(t *testing.T) {
+	values, err := normalizeValues(map[string]any{
+		"replicas": 2,
+		"nested": map[any]any{
+			"port": int64(8080),
+		},
+	})
+	require.NoError(t, err)
+
+	require.Equal(t, map[string]any{
+		"replicas": float64(2),
+		"nested": map[string]any{
+			"port": float64(8080),
+		},
+	}, values)
+}
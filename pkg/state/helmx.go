@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"strings"
 
 	"github.com/helmfile/chartify"
 
@@ -109,13 +110,21 @@ func (st *HelmState) goGetterChart(chart, dir, cacheDir string, force bool) (str
 		chart = dir
 	}
 
+	// Charts in OCI registries are pulled by helm. Remote only fetches plain files from them.
+	if strings.HasPrefix(chart, "oci://") {
+		return chart, nil
+	}
+
 	_, err := remote.Parse(chart)
 	if err != nil {
 		if force {
 			return "", fmt.Errorf("Parsing url from dir failed due to error %q.\nContinuing the process assuming this is a regular Helm chart or a local dir.", err.Error())
 		}
 	} else {
-		r := remote.NewRemote(st.logger, "", st.fs)
+		r := st.remote
+		if r == nil {
+			r = remote.NewRemote(st.logger, "", st.fs)
+		}
 
 		fetchedDir, err := r.Fetch(chart, cacheDir)
 		if err != nil {
@@ -0,0 +1,414 @@
+package state
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/goccy/go-yaml/ast"
+	"github.com/goccy/go-yaml/parser"
+)
+
+// maxReportedNeedsCycles is the maximum number of cycles reported when the needs of the releases contain cycles,
+// as the number of cycles can grow exponentially with the number of releases
+const maxReportedNeedsCycles = 20
+
+// SourcePosition is the position of an entry in a helmfile
+type SourcePosition struct {
+	File string
+	// Line is the 1-based line number of the entry in the rendered helmfile.
+	// It is the line number in the helmfile itself unless template expressions add or remove lines before the entry.
+	Line int
+}
+
+func (p SourcePosition) String() string {
+	return fmt.Sprintf("%s:%d", p.File, p.Line)
+}
+
+// NeedPosition returns the position of the i-th entry of the needs of the release, if known
+func (r ReleaseSpec) NeedPosition(i int) (SourcePosition, bool) {
+	// The positions are unknown for the needs of releases that were not parsed from a helmfile, or that were
+	// merged from release templates whose positions are unknown
+	if len(r.NeedsPositions) != len(r.Needs) || i >= len(r.NeedsPositions) {
+		return SourcePosition{}, false
+	}
+
+	return r.NeedsPositions[i], true
+}
+
+// setNeedsPositions records the positions of the needs of the releases and the release templates
+// parsed from the content of the helmfile
+func (st *HelmState) setNeedsPositions(content []byte, file string) {
+	releases, templates := needsPositions(content, file)
+
+	if len(releases) == len(st.Releases) {
+		for i := range st.Releases {
+			if len(releases[i]) == len(st.Releases[i].Needs) {
+				st.Releases[i].NeedsPositions = releases[i]
+			}
+		}
+	}
+
+	for name, positions := range templates {
+		t, ok := st.Templates[name]
+		if ok && len(positions) == len(t.Needs) {
+			t.NeedsPositions = positions
+			st.Templates[name] = t
+		}
+	}
+}
+
+// ShiftNeedsPositions adds lines to the line numbers of the needs parsed from the file.
+// It is used for helmfiles made of multiple parts separated by `---`, where each part is parsed separately.
+func (st *HelmState) ShiftNeedsPositions(file string, lines int) {
+	shift := func(positions []SourcePosition) {
+		for i := range positions {
+			if positions[i].File == file {
+				positions[i].Line += lines
+			}
+		}
+	}
+
+	for i := range st.Releases {
+		shift(st.Releases[i].NeedsPositions)
+	}
+
+	for _, t := range st.Templates {
+		shift(t.NeedsPositions)
+	}
+}
+
+// needsPositions returns the positions of the needs of the releases in the order they are defined in the documents
+// of the content, and the positions of the needs of the release templates.
+// It returns nothing when the content can't be parsed, in which case the decoder reports the error.
+func needsPositions(content []byte, file string) ([][]SourcePosition, map[string][]SourcePosition) {
+	f, err := parser.ParseBytes(content, 0)
+	if err != nil {
+		return nil, nil
+	}
+
+	var releases [][]SourcePosition
+
+	templates := map[string][]SourcePosition{}
+
+	for _, doc := range f.Docs {
+		for _, kv := range mappingValues(doc.Body) {
+			switch kv.Key.GetToken().Value {
+			case "releases", "charts":
+				seq, ok := unwrapNode(kv.Value).(*ast.SequenceNode)
+				if !ok {
+					continue
+				}
+
+				for _, release := range seq.Values {
+					releases = append(releases, needsPositionsOf(release, file))
+				}
+			case "templates":
+				for _, t := range mappingValues(kv.Value) {
+					name := t.Key.GetToken().Value
+					// The template defined first wins when documents define the same template
+					if _, ok := templates[name]; !ok {
+						templates[name] = needsPositionsOf(t.Value, file)
+					}
+				}
+			}
+		}
+	}
+
+	return releases, templates
+}
+
+func needsPositionsOf(release ast.Node, file string) []SourcePosition {
+	for _, kv := range mappingValues(release) {
+		if kv.Key.GetToken().Value != "needs" {
+			continue
+		}
+
+		seq, ok := unwrapNode(kv.Value).(*ast.SequenceNode)
+		if !ok {
+			return nil
+		}
+
+		positions := make([]SourcePosition, 0, len(seq.Values))
+		for _, n := range seq.Values {
+			positions = append(positions, SourcePosition{File: file, Line: n.GetToken().Position.Line})
+		}
+
+		return positions
+	}
+
+	return nil
+}
+
+func mappingValues(node ast.Node) []*ast.MappingValueNode {
+	switch n := unwrapNode(node).(type) {
+	case *ast.MappingNode:
+		return n.Values
+	case *ast.MappingValueNode:
+		return []*ast.MappingValueNode{n}
+	}
+
+	return nil
+}
+
+func unwrapNode(node ast.Node) ast.Node {
+	for {
+		switch n := node.(type) {
+		case *ast.AnchorNode:
+			node = n.Value
+		case *ast.TagNode:
+			node = n.Value
+		default:
+			return node
+		}
+	}
+}
+
+// checkNeedsCycles returns an error describing every cycle in the needs of the releases, with the positions of the needs
+// that form them.
+// Needs of undefined releases are ignored, as they are reported by the DAG.
+func checkNeedsCycles(releases []Release) error {
+	type edge struct {
+		from, to string
+	}
+
+	var ids []string
+
+	deps := map[string][]string{}
+	positions := map[edge]SourcePosition{}
+
+	for _, r := range releases {
+		id := ReleaseToID(&r.ReleaseSpec)
+		if _, ok := deps[id]; !ok {
+			ids = append(ids, id)
+			deps[id] = nil
+		}
+	}
+
+	for _, r := range releases {
+		id := ReleaseToID(&r.ReleaseSpec)
+
+		for i, n := range r.Needs {
+			if _, ok := deps[n]; !ok {
+				continue
+			}
+
+			e := edge{from: id, to: n}
+			if _, ok := positions[e]; ok {
+				continue
+			}
+
+			p, _ := r.NeedPosition(i)
+			positions[e] = p
+			deps[id] = append(deps[id], n)
+		}
+	}
+
+	cycles, truncated := findCycles(ids, deps)
+	if len(cycles) == 0 {
+		return nil
+	}
+
+	var b strings.Builder
+
+	if len(cycles) == 1 {
+		b.WriteString(`found a cycle in the "needs" of the releases:`)
+	} else {
+		fmt.Fprintf(&b, `found %d cycles in the "needs" of the releases:`, len(cycles))
+	}
+
+	for i, cycle := range cycles {
+		fmt.Fprintf(&b, "\n\ncycle %d: %s", i+1, strings.Join(cycle, " -> "))
+
+		for j := 0; j < len(cycle)-1; j++ {
+			fmt.Fprintf(&b, "\n  %q needs %q", cycle[j], cycle[j+1])
+			if p := positions[edge{from: cycle[j], to: cycle[j+1]}]; p.File != "" {
+				fmt.Fprintf(&b, " at %s", p)
+			}
+		}
+	}
+
+	if truncated {
+		fmt.Fprintf(&b, "\n\nonly the first %d cycles are shown", maxReportedNeedsCycles)
+	}
+
+	return fmt.Errorf("%s", b.String())
+}
+
+// findCycles returns the elementary cycles of the graph, each starting and ending with its lowest node in the order of ids.
+// It stops after finding maxReportedNeedsCycles cycles, in which case truncated is true.
+func findCycles(ids []string, deps map[string][]string) (cycles [][]string, truncated bool) {
+	sorted := append([]string{}, ids...)
+	sort.Strings(sorted)
+
+	index := map[string]int{}
+	for i, id := range sorted {
+		index[id] = i
+	}
+
+	components := stronglyConnectedComponents(sorted, deps)
+
+	var (
+		start  string
+		path   []string
+		onPath = map[string]bool{}
+		visit  func(id string)
+	)
+
+	visit = func(id string) {
+		path = append(path, id)
+		onPath[id] = true
+
+		for _, d := range deps[id] {
+			if truncated {
+				break
+			}
+
+			// Only the cycles whose lowest node is the start are searched, so that each cycle is found once
+			if components[d] != components[start] || index[d] < index[start] {
+				continue
+			}
+
+			if d == start {
+				if len(cycles) == maxReportedNeedsCycles {
+					truncated = true
+					break
+				}
+				cycles = append(cycles, append(append([]string{}, path...), start))
+			} else if !onPath[d] {
+				visit(d)
+			}
+		}
+
+		onPath[id] = false
+		path = path[:len(path)-1]
+	}
+
+	for _, id := range sorted {
+		if truncated {
+			break
+		}
+
+		start = id
+		visit(id)
+	}
+
+	return cycles, truncated
+}
+
+// stronglyConnectedComponents returns the index of the strongly connected component of each node, computed with
+// Tarjan's algorithm
+func stronglyConnectedComponents(ids []string, deps map[string][]string) map[string]int {
+	var (
+		counter    int
+		stack      []string
+		onStack    = map[string]bool{}
+		indices    = map[string]int{}
+		lowlinks   = map[string]int{}
+		components = map[string]int{}
+		strongly   func(id string)
+	)
+
+	strongly = func(id string) {
+		indices[id] = counter
+		lowlinks[id] = counter
+		counter++
+
+		stack = append(stack, id)
+		onStack[id] = true
+
+		for _, d := range deps[id] {
+			if _, visited := indices[d]; !visited {
+				strongly(d)
+				lowlinks[id] = min(lowlinks[id], lowlinks[d])
+			} else if onStack[d] {
+				lowlinks[id] = min(lowlinks[id], indices[d])
+			}
+		}
+
+		if lowlinks[id] == indices[id] {
+			c := len(components)
+			for {
+				n := stack[len(stack)-1]
+				stack = stack[:len(stack)-1]
+				onStack[n] = false
+				components[n] = c
+				if n == id {
+					break
+				}
+			}
+		}
+	}
+
+	for _, id := range ids {
+		if _, visited := indices[id]; !visited {
+			strongly(id)
+		}
+	}
+
+	return components
+}
+
+// DisabledNeeds returns a warning for each need of the releases that points to a release of the state that won't be
+// installed, because of `installed: false` or its condition.
+// Releases that won't be installed themselves are skipped.
+func (st *HelmState) DisabledNeeds(releases []ReleaseSpec) ([]string, error) {
+	values := st.Values()
+
+	byID := map[string]ReleaseSpec{}
+	for _, r := range st.Releases {
+		byID[ReleaseToID(&r)] = r
+	}
+
+	var warnings []string
+
+	for _, r := range releases {
+		enabled, err := ConditionEnabled(r, values)
+		if err != nil {
+			return nil, err
+		}
+		if !enabled || !r.Desired() {
+			continue
+		}
+
+		for i, n := range r.Needs {
+			need, ok := byID[n]
+			if !ok {
+				continue
+			}
+
+			w, err := DisabledNeedWarning(r, i, need, values)
+			if err != nil {
+				return nil, err
+			}
+			if w != "" {
+				warnings = append(warnings, w)
+			}
+		}
+	}
+
+	return warnings, nil
+}
+
+// DisabledNeedWarning returns a warning when the release needed by the i-th entry of the needs of the release won't be
+// installed, or an empty string otherwise.
+// values are the values of the state the needed release is defined in, used to evaluate its condition.
+func DisabledNeedWarning(r ReleaseSpec, i int, need ReleaseSpec, values map[string]any) (string, error) {
+	var reason string
+	if !need.Desired() {
+		reason = "is not installed as it has installed: false"
+	} else if enabled, err := ConditionEnabled(need, values); err != nil {
+		return "", err
+	} else if !enabled {
+		reason = fmt.Sprintf("is disabled by its condition %q", need.Condition)
+	} else {
+		return "", nil
+	}
+
+	var at string
+	if p, ok := r.NeedPosition(i); ok {
+		at = fmt.Sprintf(" at %s", p)
+	}
+
+	return fmt.Sprintf("release %q needs %q%s, which %s", ReleaseToID(&r), r.Needs[i], at, reason), nil
+}
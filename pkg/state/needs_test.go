@@ -0,0 +1,133 @@
+package state
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestReadFromYaml_NeedsPositions(t *testing.T) {
+	yamlContent := []byte(`templates:
+  default:
+    needs:
+    - infra/vault
+releases:
+- name: db
+  namespace: default
+  chart: mychart
+- name: app
+  namespace: default
+  chart: mychart
+  needs:
+  - db
+  - infra/vault
+---
+releases:
+- name: worker
+  namespace: default
+  chart: mychart
+  needs: [db, app]
+`)
+
+	state, err := createFromYaml(yamlContent, "helmfile.yaml", DefaultEnv, logger)
+	require.NoError(t, err)
+
+	require.Len(t, state.Releases, 3)
+	require.Empty(t, state.Releases[0].NeedsPositions)
+	require.Equal(t, []SourcePosition{{File: "helmfile.yaml", Line: 13}, {File: "helmfile.yaml", Line: 14}}, state.Releases[1].NeedsPositions)
+	require.Equal(t, []SourcePosition{{File: "helmfile.yaml", Line: 20}, {File: "helmfile.yaml", Line: 20}}, state.Releases[2].NeedsPositions)
+	require.Equal(t, []SourcePosition{{File: "helmfile.yaml", Line: 4}}, state.Templates["default"].NeedsPositions)
+
+	p, ok := state.Releases[1].NeedPosition(1)
+	require.True(t, ok)
+	require.Equal(t, "helmfile.yaml:14", p.String())
+
+	state.ShiftNeedsPositions("helmfile.yaml", 10)
+	require.Equal(t, 23, state.Releases[1].NeedsPositions[0].Line)
+
+	_, ok = ReleaseSpec{Needs: []string{"db"}}.NeedPosition(0)
+	require.False(t, ok)
+}
+
+func TestGroupReleasesByDependency_Cycles(t *testing.T) {
+	at := func(line int) SourcePosition {
+		return SourcePosition{File: "helmfile.yaml", Line: line}
+	}
+
+	releases := []Release{
+		{ReleaseSpec: ReleaseSpec{Name: "a", Namespace: "default", Needs: []string{"default/b"}, NeedsPositions: []SourcePosition{at(5)}}},
+		{ReleaseSpec: ReleaseSpec{Name: "b", Namespace: "default", Needs: []string{"default/c", "default/a"}, NeedsPositions: []SourcePosition{at(9), at(10)}}},
+		{ReleaseSpec: ReleaseSpec{Name: "c", Namespace: "default", Needs: []string{"default/a"}}},
+		{ReleaseSpec: ReleaseSpec{Name: "d", Namespace: "default", Needs: []string{"default/d", "default/a"}, NeedsPositions: []SourcePosition{at(20), at(21)}}},
+		{ReleaseSpec: ReleaseSpec{Name: "e", Namespace: "default", Needs: []string{"default/undefined"}}},
+	}
+
+	_, err := GroupReleasesByDependency(releases, PlanOptions{})
+	require.EqualError(t, err, `found 3 cycles in the "needs" of the releases:
+
+cycle 1: default/a -> default/b -> default/c -> default/a
+  "default/a" needs "default/b" at helmfile.yaml:5
+  "default/b" needs "default/c" at helmfile.yaml:9
+  "default/c" needs "default/a"
+
+cycle 2: default/a -> default/b -> default/a
+  "default/a" needs "default/b" at helmfile.yaml:5
+  "default/b" needs "default/a" at helmfile.yaml:10
+
+cycle 3: default/d -> default/d
+  "default/d" needs "default/d" at helmfile.yaml:20`)
+}
+
+func TestFindCycles_Truncated(t *testing.T) {
+	// Every pair of nodes in a complete graph of 5 nodes forms a cycle, and so do larger subsets
+	ids := []string{"a", "b", "c", "d", "e"}
+	deps := map[string][]string{}
+	for _, from := range ids {
+		for _, to := range ids {
+			if from != to {
+				deps[from] = append(deps[from], to)
+			}
+		}
+	}
+
+	cycles, truncated := findCycles(ids, deps)
+	require.True(t, truncated)
+	require.Len(t, cycles, maxReportedNeedsCycles)
+	require.Equal(t, []string{"a", "b", "a"}, cycles[0])
+
+	cycles, truncated = findCycles([]string{"a", "b"}, map[string][]string{"b": {"a"}})
+	require.False(t, truncated)
+	require.Empty(t, cycles)
+}
+
+func TestHelmState_DisabledNeeds(t *testing.T) {
+	no := false
+
+	st := &HelmState{
+		RenderedValues: map[string]any{
+			"db":    map[string]any{"enabled": false},
+			"cache": map[string]any{"enabled": true},
+		},
+	}
+	st.Releases = []ReleaseSpec{
+		{Name: "db", Namespace: "default", Condition: "db.enabled"},
+		{Name: "cache", Namespace: "default", Condition: "cache.enabled"},
+		{Name: "queue", Namespace: "default", Installed: &no},
+		{
+			Name:           "app",
+			Namespace:      "default",
+			Needs:          []string{"default/db", "default/cache", "default/queue"},
+			NeedsPositions: []SourcePosition{{File: "helmfile.yaml", Line: 12}, {File: "helmfile.yaml", Line: 13}, {File: "helmfile.yaml", Line: 14}},
+		},
+		{Name: "old", Namespace: "default", Installed: &no, Needs: []string{"default/queue"}},
+		{Name: "worker", Namespace: "default", Needs: []string{"default/queue"}},
+	}
+
+	warnings, err := st.DisabledNeeds(st.Releases)
+	require.NoError(t, err)
+	require.Equal(t, []string{
+		`release "default/app" needs "default/db" at helmfile.yaml:12, which is disabled by its condition "db.enabled"`,
+		`release "default/app" needs "default/queue" at helmfile.yaml:14, which is not installed as it has installed: false`,
+		`release "default/worker" needs "default/queue", which is not installed as it has installed: false`,
+	}, warnings)
+}
@@ -214,9 +214,21 @@ func (r ReleaseSpec) Clone() (*ReleaseSpec, error) {
 		return nil, fmt.Errorf("failed cloning release \"%s\": %v", r.Name, err)
 	}
 
+	// The positions are not serialized
+	deserialized.NeedsPositions = append([]SourcePosition(nil), r.NeedsPositions...)
+
 	return &deserialized, nil
 }
 
 func (r ReleaseSpec) Desired() bool {
 	return r.Installed == nil || *r.Installed
 }
+
+// ConcurrencyWeight returns the number of slots of the concurrency budget the release takes up.
+// Every release takes up at least one slot.
+func (r ReleaseSpec) ConcurrencyWeight() int {
+	if r.Weight < 1 {
+		return 1
+	}
+	return r.Weight
+}
@@ -17,6 +17,27 @@ type ReleaseFilter interface {
 type LabelFilter struct {
 	positiveLabels [][]string
 	negativeLabels [][]string
+	// requirements are the set-based, existence and regex requirements such as tier in (a,b), !tier and name=~^api-
+	requirements []labelRequirement
+}
+
+const (
+	labelOperatorIn           = "in"
+	labelOperatorNotIn        = "notin"
+	labelOperatorExists       = "exists"
+	labelOperatorDoesNotExist = "!"
+	labelOperatorMatches      = "=~"
+	labelOperatorDoesNotMatch = "!~"
+)
+
+// labelRequirement is a requirement on a label other than k=v and k!=v
+type labelRequirement struct {
+	key      string
+	operator string
+	// values are the values of the set for the in and notin operators
+	values []string
+	// regexp is the regular expression for the =~ and !~ operators
+	regexp *regexp.Regexp
 }
 
 // Match will match a release that has the same labels as the filter
@@ -25,7 +46,7 @@ func (l LabelFilter) Match(r ReleaseSpec) bool {
 		for _, element := range l.positiveLabels {
 			k := element[0]
 			v := element[1]
-			if rVal, ok := r.Labels[k]; !ok {
+			if rVal, ok := labelValue(r, k); !ok {
 				return false
 			} else if rVal != v {
 				return false
@@ -37,35 +58,156 @@ func (l LabelFilter) Match(r ReleaseSpec) bool {
 		for _, element := range l.negativeLabels {
 			k := element[0]
 			v := element[1]
-			if rVal, ok := r.Labels[k]; !ok {
+			if rVal, ok := labelValue(r, k); !ok {
 
 			} else if rVal == v {
 				return false
 			}
 		}
 	}
+
+	for _, req := range l.requirements {
+		if !req.match(r) {
+			return false
+		}
+	}
+
 	return true
 }
 
-// ParseLabels takes a label in the form foo=bar,baz!=bat and returns a LabelFilter that will match the labels
+func (req labelRequirement) match(r ReleaseSpec) bool {
+	v, ok := labelValue(r, req.key)
+
+	switch req.operator {
+	case labelOperatorIn:
+		return ok && contains(req.values, v)
+	case labelOperatorNotIn:
+		return !ok || !contains(req.values, v)
+	case labelOperatorExists:
+		return ok
+	case labelOperatorDoesNotExist:
+		return !ok
+	case labelOperatorMatches:
+		return ok && req.regexp.MatchString(v)
+	case labelOperatorDoesNotMatch:
+		return !ok || !req.regexp.MatchString(v)
+	}
+
+	return false
+}
+
+func contains(values []string, v string) bool {
+	for _, value := range values {
+		if value == v {
+			return true
+		}
+	}
+	return false
+}
+
+// labelValue returns the value of the label of the release.
+// The name, the namespace, the chart and the kube context of the release are available as labels,
+// and take precedence over the labels of the release with the same keys.
+// The namespace, the chart and the kube context exist only when they are set.
+func labelValue(r ReleaseSpec, k string) (string, bool) {
+	switch k {
+	case "name":
+		return r.Name, true
+	case "namespace":
+		return r.Namespace, r.Namespace != ""
+	case "chart":
+		// Strip off just the last portion for the name stable/newrelic would give newrelic
+		chartSplit := strings.Split(r.Chart, "/")
+		return chartSplit[len(chartSplit)-1], r.Chart != ""
+	case "kubeContext":
+		return r.KubeContext, r.KubeContext != ""
+	}
+
+	v, ok := r.Labels[k]
+
+	return v, ok
+}
+
+const labelChars = `[a-zA-Z0-9_\.\/\+-]+`
+
+var (
+	reMissmatch    = regexp.MustCompile(`^` + labelChars + `!=` + labelChars + `$`)
+	reMatch        = regexp.MustCompile(`^` + labelChars + `=` + labelChars + `$`)
+	reRegexp       = regexp.MustCompile(`^(` + labelChars + `)\s*(=~|!~)\s*(.+)$`)
+	reSet          = regexp.MustCompile(`^(` + labelChars + `)\s+(in|notin)\s*\((.*)\)$`)
+	reDoesNotExist = regexp.MustCompile(`^!\s*(` + labelChars + `)$`)
+	reExists       = regexp.MustCompile(`^` + labelChars + `$`)
+	reValue        = regexp.MustCompile(`^` + labelChars + `$`)
+)
+
+// ParseLabels takes a label in the form foo=bar,baz!=bat and returns a LabelFilter that will match the labels.
+// Besides k=v and k!=v, it accepts the set-based requirements k in (v1,v2) and k notin (v1,v2),
+// the existence requirements k and !k, and the regular expression requirements k=~regex and k!~regex.
 func ParseLabels(l string) (LabelFilter, error) {
 	lf := LabelFilter{}
 	lf.positiveLabels = [][]string{}
 	lf.negativeLabels = [][]string{}
 	var err error
-	labels := strings.Split(l, ",")
-	reMissmatch := regexp.MustCompile(`^[a-zA-Z0-9_\.\/\+-]+!=[a-zA-Z0-9_\.\/\+-]+$`)
-	reMatch := regexp.MustCompile(`^[a-zA-Z0-9_\.\/\+-]+=[a-zA-Z0-9_\.\/\+-]+$`)
+	labels := splitLabels(l)
 	for _, label := range labels {
+		label = strings.TrimSpace(label)
 		if match := reMissmatch.MatchString(label); match { // k!=v case
 			kv := strings.Split(label, "!=")
 			lf.negativeLabels = append(lf.negativeLabels, kv)
 		} else if match := reMatch.MatchString(label); match { // k=v case
 			kv := strings.Split(label, "=")
 			lf.positiveLabels = append(lf.positiveLabels, kv)
+		} else if m := reRegexp.FindStringSubmatch(label); m != nil { // k=~regex and k!~regex cases
+			re, err := regexp.Compile(m[3])
+			if err != nil {
+				return lf, fmt.Errorf("malformed label: %s. Invalid regular expression: %v", label, err)
+			}
+			lf.requirements = append(lf.requirements, labelRequirement{key: m[1], operator: m[2], regexp: re})
+		} else if m := reSet.FindStringSubmatch(label); m != nil { // k in (v1,v2) and k notin (v1,v2) cases
+			var values []string
+			for _, v := range strings.Split(m[3], ",") {
+				v = strings.TrimSpace(v)
+				if !reValue.MatchString(v) {
+					return lf, fmt.Errorf("malformed label: %s. Expected values in form (v1,v2)", label)
+				}
+				values = append(values, v)
+			}
+			lf.requirements = append(lf.requirements, labelRequirement{key: m[1], operator: m[2], values: values})
+		} else if m := reDoesNotExist.FindStringSubmatch(label); m != nil { // !k case
+			lf.requirements = append(lf.requirements, labelRequirement{key: m[1], operator: labelOperatorDoesNotExist})
+		} else if match := reExists.MatchString(label); match { // k case
+			lf.requirements = append(lf.requirements, labelRequirement{key: label, operator: labelOperatorExists})
 		} else { // malformed case
-			return lf, fmt.Errorf("malformed label: %s. Expected label in form k=v or k!=v", label)
+			return lf, fmt.Errorf("malformed label: %s. Expected label in form k=v, k!=v, k=~regex, k!~regex, k in (v1,v2), k notin (v1,v2), k or !k", label)
 		}
 	}
 	return lf, err
 }
+
+// splitLabels splits the labels separated by commas, except for the commas within parentheses, brackets and braces,
+// like the ones in tier in (a,b) and name=~^api-[a-z]{2,3}$
+func splitLabels(l string) []string {
+	var (
+		labels []string
+		depth  int
+		start  int
+	)
+
+	for i, c := range l {
+		switch c {
+		case '(', '[', '{':
+			depth++
+		case ')', ']', '}':
+			if depth > 0 {
+				depth--
+			}
+		case ',':
+			if depth == 0 {
+				labels = append(labels, l[start:i])
+				start = i + 1
+			}
+		}
+	}
+
+	return append(labels, l[start:])
+}
@@ -0,0 +1,99 @@
+package state
+
+import (
+	"testing"
+)
+
+func TestLabelFilter_Match(t *testing.T) {
+	api := ReleaseSpec{Name: "api-users", Namespace: "backend", Chart: "charts/api", KubeContext: "prod", Labels: map[string]string{"tier": "backend"}}
+	web := ReleaseSpec{Name: "web", Chart: "stable/nginx", Labels: map[string]string{"tier": "frontend", "canary": "true"}}
+	worker := ReleaseSpec{Name: "worker", Namespace: "backend", Chart: "charts/worker"}
+
+	cases := []struct {
+		selector string
+		want     []string
+	}{
+		{"tier in (backend, frontend)", []string{"api-users", "web"}},
+		{"tier notin (frontend)", []string{"api-users", "worker"}},
+		{"tier", []string{"api-users", "web"}},
+		{"!tier", []string{"worker"}},
+		{"tier,!canary", []string{"api-users"}},
+		{"name=~^api-", []string{"api-users"}},
+		{"name!~^api-", []string{"web", "worker"}},
+		{"chart=~^(api|nginx)$", []string{"api-users", "web"}},
+		{"namespace=backend,chart!=api", []string{"worker"}},
+		{"kubeContext=prod", []string{"api-users"}},
+		{"kubeContext", []string{"api-users"}},
+		{"kubeContext!=prod", []string{"web", "worker"}},
+		{"namespace notin (backend)", []string{"web"}},
+	}
+
+	for _, c := range cases {
+		t.Run(c.selector, func(t *testing.T) {
+			f, err := ParseLabels(c.selector)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			var got []string
+			for _, r := range []ReleaseSpec{api, web, worker} {
+				if f.Match(r) {
+					got = append(got, r.Name)
+				}
+			}
+
+			if len(got) != len(c.want) {
+				t.Fatalf("unexpected matches: want %v, got %v", c.want, got)
+			}
+			for i := range got {
+				if got[i] != c.want[i] {
+					t.Fatalf("unexpected matches: want %v, got %v", c.want, got)
+				}
+			}
+		})
+	}
+}
+
+func TestMarkExcludedReleases_PseudoLabels(t *testing.T) {
+	releases := []ReleaseSpec{
+		{Name: "api", Namespace: "backend", Chart: "charts/api"},
+		{Name: "web", Chart: "stable/nginx", Labels: map[string]string{"name": "frontend"}},
+	}
+
+	cases := []struct {
+		selector string
+		want     []string
+	}{
+		{"!namespace", []string{"web"}},
+		{"namespace", []string{"api"}},
+		// The name of the release takes precedence over its label
+		{"name=frontend", nil},
+		{"name=web", []string{"web"}},
+		{"chart=nginx", []string{"web"}},
+	}
+
+	for _, c := range cases {
+		t.Run(c.selector, func(t *testing.T) {
+			rs, err := markExcludedReleases(releases, []string{c.selector}, nil, nil)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			var got []string
+			for _, r := range rs {
+				if !r.Filtered {
+					got = append(got, r.Name)
+				}
+			}
+
+			if len(got) != len(c.want) {
+				t.Fatalf("unexpected matches: want %v, got %v", c.want, got)
+			}
+			for i := range got {
+				if got[i] != c.want[i] {
+					t.Fatalf("unexpected matches: want %v, got %v", c.want, got)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,156 @@
+package state
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/helmfile/helmfile/pkg/helmexec"
+	"github.com/helmfile/helmfile/pkg/yaml"
+)
+
+// ReleaseFingerprint captures the desired and the deployed state of a release at a point in time.
+// Two fingerprints of the same release differ when either the helmfile state or the cluster has changed in between.
+type ReleaseFingerprint struct {
+	// ID is the release ID as returned by ReleaseToID
+	ID string `json:"id"`
+	// Chart is the chart of the release
+	Chart string `json:"chart"`
+	// Version is the resolved chart version
+	Version string `json:"version,omitempty"`
+	// ValuesHash is the hash of the rendered values files and set values
+	ValuesHash string `json:"valuesHash"`
+	// Revision is the revision of the deployed release. It is empty when the release is not installed
+	Revision string `json:"revision,omitempty"`
+}
+
+// Diff returns a human-readable description of every difference between the two fingerprints.
+func (f ReleaseFingerprint) Diff(other ReleaseFingerprint) []string {
+	var diffs []string
+
+	if f.Chart != other.Chart {
+		diffs = append(diffs, fmt.Sprintf("chart changed from %q to %q", f.Chart, other.Chart))
+	}
+	if f.Version != other.Version {
+		diffs = append(diffs, fmt.Sprintf("chart version changed from %q to %q", f.Version, other.Version))
+	}
+	if f.ValuesHash != other.ValuesHash {
+		diffs = append(diffs, "values changed")
+	}
+	if f.Revision != other.Revision {
+		diffs = append(diffs, fmt.Sprintf("deployed revision changed from %q to %q", f.Revision, other.Revision))
+	}
+
+	return diffs
+}
+
+// FingerprintRelease renders the values of the release and looks up its deployed revision to build a ReleaseFingerprint.
+func (st *HelmState) FingerprintRelease(helm helmexec.Interface, release *ReleaseSpec) (*ReleaseFingerprint, error) {
+	files, err := st.generateValuesFiles(helm, release, 0)
+	if err != nil {
+		return nil, err
+	}
+	defer st.removeFiles(files)
+
+	values := make([]string, 0, len(files))
+	for _, f := range files {
+		bs, err := os.ReadFile(f)
+		if err != nil {
+			return nil, err
+		}
+		values = append(values, string(bs))
+	}
+
+	valuesHash, err := HashObject([]any{values, release.SetValues})
+	if err != nil {
+		return nil, err
+	}
+
+	version, err := st.resolvedChartVersion(helm, release)
+	if err != nil {
+		return nil, err
+	}
+
+	revision, err := st.getDeployedRevision(st.createHelmContext(release, 0), helm, release)
+	if err != nil {
+		return nil, err
+	}
+
+	return &ReleaseFingerprint{
+		ID:         ReleaseToID(release),
+		Chart:      release.Chart,
+		Version:    version,
+		ValuesHash: valuesHash,
+		Revision:   revision,
+	}, nil
+}
+
+// resolvedChartVersion returns the version of the chart the release is going to be installed with.
+// It is read from the Chart.yaml of local and downloaded charts, and resolved by `helm show chart` when the version
+// of the remote chart is a constraint or unset, as the newest version satisfying it changes as new charts are published.
+func (st *HelmState) resolvedChartVersion(helm helmexec.Interface, release *ReleaseSpec) (string, error) {
+	chart := release.ChartPathOrName()
+
+	if st.fs.DirectoryExistsAt(chart) {
+		bs, err := st.fs.ReadFile(filepath.Join(chart, "Chart.yaml"))
+		if err != nil {
+			return "", err
+		}
+
+		var metadata struct {
+			Version string `yaml:"version"`
+		}
+		if err := yaml.Unmarshal(bs, &metadata); err != nil {
+			return "", fmt.Errorf("parsing Chart.yaml of %s: %v", chart, err)
+		}
+
+		return metadata.Version, nil
+	}
+
+	if isExactVersion(release.Version) {
+		return release.Version, nil
+	}
+
+	metadata, err := helm.ShowChart(chart, st.chartVersionFlags(release)...)
+	if err != nil {
+		return "", fmt.Errorf("resolving the version of chart %q: %v", chart, err)
+	}
+
+	return metadata.Version, nil
+}
+
+// DeployedRevision returns the revision of the deployed release, or an empty string when the release is not installed.
+func (st *HelmState) DeployedRevision(helm helmexec.Interface, release *ReleaseSpec) (string, error) {
+	return st.getDeployedRevision(st.createHelmContext(release, 0), helm, release)
+}
+
+func (st *HelmState) getDeployedRevision(context helmexec.HelmContext, helm helmexec.Interface, release *ReleaseSpec) (string, error) {
+	out, err := st.listReleases(context, helm, release)
+	if err != nil {
+		return "", err
+	}
+
+	return parseRevision(out, release.Name), nil
+}
+
+// parseRevision extracts the revision of the named release from the tab-separated `helm list` output.
+// The revision is the first integer column after the release name, which works regardless of the column layout
+// and whether the header line has been stripped or not.
+func parseRevision(out, name string) string {
+	for _, line := range strings.Split(out, "\n") {
+		cols := strings.Split(line, "\t")
+		if strings.TrimSpace(cols[0]) != name {
+			continue
+		}
+		for _, c := range cols[1:] {
+			c = strings.TrimSpace(c)
+			if _, err := strconv.Atoi(c); err == nil {
+				return c
+			}
+		}
+	}
+
+	return ""
+}
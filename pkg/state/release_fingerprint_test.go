@@ -0,0 +1,74 @@
+package state
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestParseRevision(t *testing.T) {
+	testcases := []struct {
+		subject string
+		out     string
+		name    string
+		want    string
+	}{
+		{
+			subject: "helm 3 output without header",
+			out:     "foo\tdefault  \t12      \t2019-11-01 08:40:07.000 +0000 UTC\tdeployed\traw-3.1.0\t3.1.0\n",
+			name:    "foo",
+			want:    "12",
+		},
+		{
+			subject: "output with header",
+			out: `NAME	REVISION	UPDATED                 	STATUS  	CHART        	APP VERSION	NAMESPACE
+foo 	4       	Fri Nov  1 08:40:07 2019	DEPLOYED	raw-3.1.0	3.1.0      	default
+`,
+			name: "foo",
+			want: "4",
+		},
+		{
+			subject: "other release",
+			out:     "foobar\tdefault\t3\t2019-11-01 08:40:07.000 +0000 UTC\tdeployed\traw-3.1.0\t3.1.0\n",
+			name:    "foo",
+			want:    "",
+		},
+		{
+			subject: "not installed",
+			out:     "",
+			name:    "foo",
+			want:    "",
+		},
+	}
+
+	for _, tc := range testcases {
+		t.Run(tc.subject, func(t *testing.T) {
+			got := parseRevision(tc.out, tc.name)
+			if d := cmp.Diff(tc.want, got); d != "" {
+				t.Fatalf("unexpected result: want (-), got (+):\n%s", d)
+			}
+		})
+	}
+}
+
+func TestReleaseFingerprintDiff(t *testing.T) {
+	planned := ReleaseFingerprint{ID: "default/foo", Chart: "incubator/raw", Version: "1.0.0", ValuesHash: "abc", Revision: "1"}
+
+	current := planned
+	if d := planned.Diff(current); len(d) != 0 {
+		t.Fatalf("unexpected diff: %v", d)
+	}
+
+	current.Version = "1.0.1"
+	current.ValuesHash = "def"
+	current.Revision = "2"
+
+	want := []string{
+		`chart version changed from "1.0.0" to "1.0.1"`,
+		"values changed",
+		`deployed revision changed from "1" to "2"`,
+	}
+	if d := cmp.Diff(want, planned.Diff(current)); d != "" {
+		t.Fatalf("unexpected result: want (-), got (+):\n%s", d)
+	}
+}
@@ -0,0 +1,109 @@
+package state
+
+import (
+	"errors"
+)
+
+const (
+	ReleaseOutcomeUpgraded   = "upgraded"
+	ReleaseOutcomeDeleted    = "deleted"
+	ReleaseOutcomeFailed     = "failed"
+	ReleaseOutcomeRolledBack = "rolledback"
+	ReleaseOutcomeUnchanged  = "unchanged"
+	ReleaseOutcomeSkipped    = "skipped"
+)
+
+// ReleaseReport is the machine-readable result of processing a release
+type ReleaseReport struct {
+	// ID is the release ID as returned by ReleaseToID
+	ID    string `json:"id"`
+	Chart string `json:"chart"`
+	// RequestedVersion is the chart version specified in the helmfile state
+	RequestedVersion string `json:"requestedVersion,omitempty"`
+	// InstalledVersion is the chart version installed by this run
+	InstalledVersion string `json:"installedVersion,omitempty"`
+	// Duration is the time taken to process the release in seconds
+	Duration float64 `json:"duration"`
+	// Outcome is one of upgraded, deleted, failed, rolledback, unchanged and skipped
+	Outcome string `json:"outcome"`
+	// Error is the error message of the failed release
+	Error string `json:"error,omitempty"`
+	// ExitCode is the code of the ReleaseError of the failed release
+	ExitCode int `json:"exitCode,omitempty"`
+}
+
+// Report returns a ReleaseReport for each of the releases.
+// unchanged are the releases that are not going to be changed, and errs are the errors returned while processing the releases.
+// Any other release that is not affected is reported as skipped.
+func (ar *AffectedReleases) Report(releases []ReleaseSpec, unchanged map[string]ReleaseSpec, errs []error) []ReleaseReport {
+	outcomes := map[string]string{}
+	affected := map[string]*ReleaseSpec{}
+
+	// Later outcomes take precedence, so that e.g. a release upgraded then rolled back is reported as rolled back
+	for _, l := range []struct {
+		outcome  string
+		releases []*ReleaseSpec
+	}{
+		{ReleaseOutcomeUpgraded, ar.Upgraded},
+		{ReleaseOutcomeDeleted, ar.Deleted},
+		{ReleaseOutcomeFailed, ar.Failed},
+		{ReleaseOutcomeRolledBack, ar.RolledBack},
+	} {
+		for _, r := range l.releases {
+			id := ReleaseToID(r)
+			outcomes[id] = l.outcome
+			if _, ok := affected[id]; !ok {
+				affected[id] = r
+			}
+		}
+	}
+
+	reports := make([]ReleaseReport, 0, len(releases))
+
+	for i := range releases {
+		release := releases[i]
+		id := ReleaseToID(&release)
+
+		report := ReleaseReport{
+			ID:               id,
+			Chart:            release.Chart,
+			RequestedVersion: release.Version,
+		}
+
+		if r, ok := affected[id]; ok {
+			report.Outcome = outcomes[id]
+			report.InstalledVersion = r.installedVersion
+			report.Duration = r.duration.Seconds()
+		} else if _, ok := unchanged[id]; ok {
+			report.Outcome = ReleaseOutcomeUnchanged
+		} else {
+			report.Outcome = ReleaseOutcomeSkipped
+		}
+
+		if code, err := releaseErr(&release, errs); err != nil {
+			if report.Outcome != ReleaseOutcomeRolledBack {
+				report.Outcome = ReleaseOutcomeFailed
+			}
+			report.Error = err.Error()
+			report.ExitCode = code
+		}
+
+		reports = append(reports, report)
+	}
+
+	return reports
+}
+
+// releaseErr returns the exit code and the ReleaseError of the release, if any
+func releaseErr(release *ReleaseSpec, errs []error) (int, error) {
+	id := ReleaseToID(release)
+
+	for _, err := range errs {
+		var re *ReleaseError
+		if errors.As(err, &re) && re.ReleaseSpec != nil && ReleaseToID(re.ReleaseSpec) == id {
+			return re.Code, re
+		}
+	}
+
+	return 0, nil
+}
@@ -0,0 +1,87 @@
+package state
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+
+	"github.com/helmfile/helmfile/pkg/exectest"
+)
+
+func TestAffectedReleasesReport(t *testing.T) {
+	upgraded := &ReleaseSpec{Name: "upgraded", Chart: "charts/a", Version: "1.0.0", installedVersion: "1.0.0", duration: 2 * time.Second}
+	failed := &ReleaseSpec{Name: "failed", Chart: "charts/b"}
+	deleted := &ReleaseSpec{Name: "deleted", Chart: "charts/c"}
+
+	ar := AffectedReleases{
+		Upgraded: []*ReleaseSpec{upgraded},
+		Deleted:  []*ReleaseSpec{deleted},
+		Failed:   []*ReleaseSpec{failed},
+	}
+
+	releases := []ReleaseSpec{
+		*upgraded,
+		*failed,
+		*deleted,
+		{Name: "unchanged", Chart: "charts/d"},
+		{Name: "skipped", Chart: "charts/e"},
+	}
+
+	errs := []error{
+		NewReleaseError(failed, errors.New("failed processing release failed: boom"), ReleaseErrorCodeFailure),
+	}
+
+	got := ar.Report(releases, map[string]ReleaseSpec{"unchanged": {Name: "unchanged"}}, errs)
+
+	want := []ReleaseReport{
+		{ID: "upgraded", Chart: "charts/a", RequestedVersion: "1.0.0", InstalledVersion: "1.0.0", Duration: 2, Outcome: ReleaseOutcomeUpgraded},
+		{ID: "failed", Chart: "charts/b", Outcome: ReleaseOutcomeFailed, Error: "failed processing release failed: boom", ExitCode: 1},
+		{ID: "deleted", Chart: "charts/c", Outcome: ReleaseOutcomeDeleted},
+		{ID: "unchanged", Chart: "charts/d", Outcome: ReleaseOutcomeUnchanged},
+		{ID: "skipped", Chart: "charts/e", Outcome: ReleaseOutcomeSkipped},
+	}
+
+	if d := cmp.Diff(want, got); d != "" {
+		t.Fatalf("unexpected result: want (-), got (+):\n%s", d)
+	}
+}
+
+func TestAffectedReleasesReport_DeleteFailure(t *testing.T) {
+	st := &HelmState{
+		ReleaseSetSpec: ReleaseSetSpec{
+			Releases: []ReleaseSpec{
+				{Name: "frontend", Chart: "charts/frontend"},
+				{Name: "backend-error", Chart: "charts/backend"},
+			},
+		},
+		logger:         logger,
+		RenderedValues: map[string]any{},
+	}
+
+	var affected AffectedReleases
+
+	errs := st.DeleteReleases(&affected, &exectest.Helm{Helm3: true}, 1, true, "")
+	if len(errs) != 1 {
+		t.Fatalf("unexpected errors: %v", errs)
+	}
+
+	if got, want := errs[0].Error(), `release "backend-error" failed: error`; got != want {
+		t.Fatalf("unexpected error: want %q, got %q", want, got)
+	}
+
+	got := affected.Report(st.Releases, nil, errs)
+	for i := range got {
+		got[i].Duration = 0
+	}
+
+	want := []ReleaseReport{
+		{ID: "frontend", Chart: "charts/frontend", Outcome: ReleaseOutcomeDeleted},
+		{ID: "backend-error", Chart: "charts/backend", Outcome: ReleaseOutcomeFailed, Error: "error", ExitCode: 1},
+	}
+
+	if d := cmp.Diff(want, got); d != "" {
+		t.Fatalf("unexpected result: want (-), got (+):\n%s", d)
+	}
+}
@@ -0,0 +1,372 @@
+package state
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+
+	"github.com/goccy/go-yaml/ast"
+	"github.com/goccy/go-yaml/parser"
+)
+
+// JSONSchemaDraft is the version of the JSON schema of helmfile.yaml
+const JSONSchemaDraft = "http://json-schema.org/draft-07/schema#"
+
+// JSONSchema is a subset of JSON Schema that is enough to describe helmfile.yaml
+type JSONSchema struct {
+	Schema      string `json:"$schema,omitempty"`
+	Ref         string `json:"$ref,omitempty"`
+	Title       string `json:"title,omitempty"`
+	Description string `json:"description,omitempty"`
+	Type        string `json:"type,omitempty"`
+
+	Properties map[string]*JSONSchema `json:"properties,omitempty"`
+	// AdditionalProperties is either false, when the object has no other properties than Properties,
+	// or the schema of the values of a map
+	AdditionalProperties any           `json:"additionalProperties,omitempty"`
+	Items                *JSONSchema   `json:"items,omitempty"`
+	OneOf                []*JSONSchema `json:"oneOf,omitempty"`
+
+	Definitions map[string]*JSONSchema `json:"definitions,omitempty"`
+}
+
+// HelmfileJSONSchema returns the JSON schema of a helmfile.yaml document after its template is rendered.
+// It is generated from the yaml tags of ReleaseSetSpec and the types of its fields, so that it never misses a field.
+// Objects don't allow properties that aren't defined by their types, as the strict decoder rejects them.
+func HelmfileJSONSchema() *JSONSchema {
+	g := &schemaGenerator{definitions: map[string]*JSONSchema{}}
+
+	s := g.objectSchema(reflect.TypeOf(ReleaseSetSpec{}))
+	s.Schema = JSONSchemaDraft
+	s.Title = "helmfile.yaml"
+	s.Description = "The state file of helmfile, after its template is rendered"
+	s.Definitions = g.definitions
+
+	return s
+}
+
+// MarshalHelmfileJSONSchema returns the indented JSON of HelmfileJSONSchema
+func MarshalHelmfileJSONSchema() ([]byte, error) {
+	return json.MarshalIndent(HelmfileJSONSchema(), "", "  ")
+}
+
+type schemaGenerator struct {
+	definitions map[string]*JSONSchema
+}
+
+// schemaOf returns the schema of the values of the type.
+// Named structs are added to the definitions and referenced, so that recursive and shared types are defined once.
+func (g *schemaGenerator) schemaOf(t reflect.Type) *JSONSchema {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+
+	switch t {
+	case reflect.TypeOf(SubHelmfileSpec{}):
+		// A sub-helmfile is either its path, or its path with options. See SubHelmfileSpec.UnmarshalYAML
+		return g.definition("SubHelmfileSpec", func() *JSONSchema {
+			return &JSONSchema{
+				OneOf: []*JSONSchema{
+					{Type: "string"},
+					{
+						Type: "object",
+						Properties: map[string]*JSONSchema{
+							"path":               {Type: "string"},
+							"selectors":          {Type: "array", Items: &JSONSchema{Type: "string"}},
+							"selectorsInherited": {Type: "boolean"},
+							"values":             {Type: "array", Items: &JSONSchema{}},
+						},
+						AdditionalProperties: false,
+					},
+				},
+			}
+		})
+	case reflect.TypeOf(Inherits{}):
+		// A single inherit is deprecated but still accepted. See Inherits.UnmarshalYAML
+		inherit := g.schemaOf(reflect.TypeOf(Inherit{}))
+		return &JSONSchema{
+			OneOf: []*JSONSchema{
+				{Type: "array", Items: inherit},
+				inherit,
+			},
+		}
+	}
+
+	switch t.Kind() {
+	case reflect.String:
+		return &JSONSchema{Type: "string"}
+	case reflect.Bool:
+		return &JSONSchema{Type: "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return &JSONSchema{Type: "integer"}
+	case reflect.Float32, reflect.Float64:
+		return &JSONSchema{Type: "number"}
+	case reflect.Slice, reflect.Array:
+		return &JSONSchema{Type: "array", Items: g.schemaOf(t.Elem())}
+	case reflect.Map:
+		return &JSONSchema{Type: "object", AdditionalProperties: g.schemaOf(t.Elem())}
+	case reflect.Struct:
+		return g.definition(t.Name(), func() *JSONSchema {
+			return g.objectSchema(t)
+		})
+	}
+
+	// Fields of type any accept anything, like the values of releases
+	return &JSONSchema{}
+}
+
+// definition adds the schema returned by f to the definitions unless it is already defined, and returns a reference to it
+func (g *schemaGenerator) definition(name string, f func() *JSONSchema) *JSONSchema {
+	if _, ok := g.definitions[name]; !ok {
+		// Reserve the name before generating the schema in case the type refers to itself
+		g.definitions[name] = nil
+		g.definitions[name] = f()
+	}
+
+	return &JSONSchema{Ref: "#/definitions/" + name}
+}
+
+// objectSchema returns the schema of the struct, with the fields of its inline structs
+func (g *schemaGenerator) objectSchema(t reflect.Type) *JSONSchema {
+	s := &JSONSchema{
+		Type:                 "object",
+		Properties:           map[string]*JSONSchema{},
+		AdditionalProperties: false,
+	}
+
+	g.addProperties(s, t)
+
+	return s
+}
+
+func (g *schemaGenerator) addProperties(s *JSONSchema, t reflect.Type) {
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		if !f.IsExported() {
+			continue
+		}
+
+		name, opts, _ := strings.Cut(f.Tag.Get("yaml"), ",")
+		if name == "-" {
+			continue
+		}
+
+		if strings.Contains(opts, "inline") {
+			g.addProperties(s, f.Type)
+			continue
+		}
+
+		if name == "" {
+			// The yaml decoders default to the lowercased field name
+			name = strings.ToLower(f.Name)
+		}
+
+		s.Properties[name] = g.schemaOf(f.Type)
+	}
+}
+
+// UnknownField is a field of a helmfile that isn't defined in its JSON schema, like a misspelled field of a release
+type UnknownField struct {
+	Position SourcePosition
+	// Path is the path to the object that contains the field, like releases[0], or empty for the top-level fields
+	Path string
+	Name string
+	// Suggestion is the defined field that the name is likely a typo of, if any
+	Suggestion string
+}
+
+func (f UnknownField) String() string {
+	var b strings.Builder
+
+	fmt.Fprintf(&b, "%s: unknown field %q", f.Position, f.Name)
+	if f.Path != "" {
+		fmt.Fprintf(&b, " in %s", f.Path)
+	}
+	if f.Suggestion != "" {
+		fmt.Fprintf(&b, ", did you mean %q?", f.Suggestion)
+	}
+
+	return b.String()
+}
+
+// UnknownFieldsError is returned when a helmfile has fields that aren't defined in its JSON schema
+type UnknownFieldsError struct {
+	Fields []UnknownField
+}
+
+func (e *UnknownFieldsError) Error() string {
+	if len(e.Fields) == 1 {
+		return e.Fields[0].String()
+	}
+
+	lines := []string{fmt.Sprintf("found %d unknown fields:", len(e.Fields))}
+	for _, f := range e.Fields {
+		lines = append(lines, "  "+f.String())
+	}
+
+	return strings.Join(lines, "\n")
+}
+
+// FindUnknownFields returns the fields of the rendered helmfile content that aren't defined in the JSON schema
+// of helmfile.yaml, in the order they appear.
+// lineOffset is added to the line numbers, for helmfiles made of multiple parts separated by `---`.
+// It returns nothing when the content can't be parsed, in which case the decoder reports the error.
+func FindUnknownFields(content []byte, file string, lineOffset int) []UnknownField {
+	f, err := parser.ParseBytes(content, 0)
+	if err != nil {
+		return nil
+	}
+
+	v := &unknownFieldsVisitor{
+		schema:     HelmfileJSONSchema(),
+		file:       file,
+		lineOffset: lineOffset,
+	}
+
+	for _, doc := range f.Docs {
+		v.visit(doc.Body, v.schema, "")
+	}
+
+	return v.fields
+}
+
+type unknownFieldsVisitor struct {
+	schema     *JSONSchema
+	file       string
+	lineOffset int
+
+	fields []UnknownField
+}
+
+func (v *unknownFieldsVisitor) visit(node ast.Node, s *JSONSchema, path string) {
+	node = unwrapNode(node)
+	if node == nil {
+		return
+	}
+
+	s = v.resolve(s)
+
+	if len(s.OneOf) > 0 {
+		// The alternatives are told apart by the kind of the node
+		for _, alt := range s.OneOf {
+			if alt := v.resolve(alt); schemaMatchesNode(alt, node) {
+				v.visit(node, alt, path)
+				return
+			}
+		}
+		return
+	}
+
+	switch s.Type {
+	case "array":
+		seq, ok := node.(*ast.SequenceNode)
+		if !ok || s.Items == nil {
+			return
+		}
+		for i, n := range seq.Values {
+			v.visit(n, s.Items, fmt.Sprintf("%s[%d]", path, i))
+		}
+	case "object":
+		for _, kv := range mappingValues(node) {
+			if _, ok := kv.Key.(*ast.MergeKeyNode); ok {
+				// The merged fields are defined by the anchor, which is checked where it is defined
+				continue
+			}
+
+			name := kv.Key.GetToken().Value
+
+			fieldPath := name
+			if path != "" {
+				fieldPath = path + "." + name
+			}
+
+			if fs, ok := s.Properties[name]; ok {
+				v.visit(kv.Value, fs, fieldPath)
+				continue
+			}
+
+			switch ap := s.AdditionalProperties.(type) {
+			case *JSONSchema:
+				v.visit(kv.Value, ap, fieldPath)
+			case bool:
+				if !ap {
+					v.fields = append(v.fields, UnknownField{
+						Position:   SourcePosition{File: v.file, Line: kv.Key.GetToken().Position.Line + v.lineOffset},
+						Path:       path,
+						Name:       name,
+						Suggestion: suggestField(name, s.Properties),
+					})
+				}
+			}
+		}
+	}
+}
+
+// resolve returns the schema referenced by s, or s itself if it isn't a reference
+func (v *unknownFieldsVisitor) resolve(s *JSONSchema) *JSONSchema {
+	for s.Ref != "" {
+		s = v.schema.Definitions[strings.TrimPrefix(s.Ref, "#/definitions/")]
+	}
+	return s
+}
+
+func schemaMatchesNode(s *JSONSchema, node ast.Node) bool {
+	switch node.(type) {
+	case *ast.MappingNode, *ast.MappingValueNode:
+		return s.Type == "object"
+	case *ast.SequenceNode:
+		return s.Type == "array"
+	}
+	return s.Type != "object" && s.Type != "array"
+}
+
+// suggestField returns the property that the name is most likely a typo of, or an empty string if none is close enough
+func suggestField(name string, properties map[string]*JSONSchema) string {
+	candidates := make([]string, 0, len(properties))
+	for p := range properties {
+		candidates = append(candidates, p)
+	}
+	sort.Strings(candidates)
+
+	var suggestion string
+
+	best := len(name)/3 + 1
+	for _, c := range candidates {
+		if strings.EqualFold(c, name) {
+			return c
+		}
+		if d := editDistance(strings.ToLower(name), strings.ToLower(c)); d < best {
+			best = d
+			suggestion = c
+		}
+	}
+
+	return suggestion
+}
+
+// editDistance returns the Levenshtein distance between a and b
+func editDistance(a, b string) int {
+	prev := make([]int, len(b)+1)
+	cur := make([]int, len(b)+1)
+
+	for j := range prev {
+		prev[j] = j
+	}
+
+	for i := 1; i <= len(a); i++ {
+		cur[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			cur[j] = min(prev[j]+1, cur[j-1]+1, prev[j-1]+cost)
+		}
+		prev, cur = cur, prev
+	}
+
+	return prev[len(b)]
+}
@@ -0,0 +1,165 @@
+package state
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestHelmfileJSONSchema(t *testing.T) {
+	s := HelmfileJSONSchema()
+
+	if s.Schema != JSONSchemaDraft {
+		t.Errorf("unexpected $schema: %q", s.Schema)
+	}
+
+	if d := cmp.Diff(&JSONSchema{Type: "array", Items: &JSONSchema{Ref: "#/definitions/ReleaseSpec"}}, s.Properties["releases"]); d != "" {
+		t.Errorf("unexpected schema of releases: want (-), got (+): %s", d)
+	}
+
+	release := s.Definitions["ReleaseSpec"]
+	if release == nil {
+		t.Fatal("ReleaseSpec is not defined")
+	}
+
+	if release.AdditionalProperties != false {
+		t.Errorf("unexpected additionalProperties of ReleaseSpec: %v", release.AdditionalProperties)
+	}
+
+	for _, p := range []string{"name", "chart", "needs", "valuesTemplate", "hooks", "inherit"} {
+		if _, ok := release.Properties[p]; !ok {
+			t.Errorf("property %s of ReleaseSpec is not defined", p)
+		}
+	}
+
+	for _, p := range []string{"NeedsPositions", "needspositions", "-"} {
+		if _, ok := release.Properties[p]; ok {
+			t.Errorf("unexpected property %s of ReleaseSpec", p)
+		}
+	}
+
+	for _, def := range []string{"HelmSpec", "RepositorySpec", "EnvironmentSpec", "Hook", "HTTPHook", "SubHelmfileSpec"} {
+		if s.Definitions[def] == nil {
+			t.Errorf("%s is not defined", def)
+		}
+	}
+
+	b, err := MarshalHelmfileJSONSchema()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	var decoded map[string]any
+	if err := json.Unmarshal(b, &decoded); err != nil {
+		t.Fatalf("unexpected error decoding the schema: %v", err)
+	}
+}
+
+func TestFindUnknownFields(t *testing.T) {
+	content := `
+helmDefaults: &defaults
+  wait: true
+  waitForjobs: true
+environments:
+  default:
+    values:
+    - foo: bar
+      anything: goes
+helmfiles:
+- path: sub/helmfile.yaml
+  selector:
+  - name=app
+- sub/other.yaml
+templates:
+  default: &default
+    namespace: default
+    needz:
+    - db
+releases:
+- name: db
+  chart: stable/postgresql
+  <<: *default
+  values:
+  - nested:
+      needz: values are free-form
+- name: app
+  chart: stable/app
+  valuesTemplates:
+  - values.yaml.gotmpl
+  hooks:
+  - events: ["presync"]
+    showLogs: true
+    http:
+      url: https://example.com
+      header:
+        X-Foo: bar
+  inherit:
+    template: default
+    exept: [needs]
+- name: web
+  chart: stable/web
+  inherit:
+  - template: default
+repositorie:
+- name: stable
+`
+
+	want := []UnknownField{
+		{Position: SourcePosition{File: "helmfile.yaml", Line: 14}, Path: "helmDefaults", Name: "waitForjobs", Suggestion: "waitForJobs"},
+		{Position: SourcePosition{File: "helmfile.yaml", Line: 22}, Path: "helmfiles[0]", Name: "selector", Suggestion: "selectors"},
+		{Position: SourcePosition{File: "helmfile.yaml", Line: 28}, Path: "templates.default", Name: "needz", Suggestion: "needs"},
+		{Position: SourcePosition{File: "helmfile.yaml", Line: 39}, Path: "releases[1]", Name: "valuesTemplates", Suggestion: "valuesTemplate"},
+		{Position: SourcePosition{File: "helmfile.yaml", Line: 43}, Path: "releases[1].hooks[0]", Name: "showLogs", Suggestion: "showlogs"},
+		{Position: SourcePosition{File: "helmfile.yaml", Line: 46}, Path: "releases[1].hooks[0].http", Name: "header", Suggestion: "headers"},
+		{Position: SourcePosition{File: "helmfile.yaml", Line: 50}, Path: "releases[1].inherit", Name: "exept", Suggestion: "except"},
+		{Position: SourcePosition{File: "helmfile.yaml", Line: 55}, Name: "repositorie", Suggestion: "repositories"},
+	}
+
+	got := FindUnknownFields([]byte(content), "helmfile.yaml", 10)
+
+	if d := cmp.Diff(want, got); d != "" {
+		t.Errorf("unexpected unknown fields: want (-), got (+): %s", d)
+	}
+}
+
+func TestFindUnknownFields_Valid(t *testing.T) {
+	content := `
+repositories:
+- name: stable
+  url: https://charts.helm.sh/stable
+releases:
+- name: app
+  chart: stable/app
+  needs:
+  - db
+  set:
+  - name: image.tag
+    value: v1
+  labels:
+    tier: frontend
+`
+
+	if got := FindUnknownFields([]byte(content), "helmfile.yaml", 0); len(got) > 0 {
+		t.Errorf("unexpected unknown fields: %v", got)
+	}
+
+	if got := FindUnknownFields([]byte("releases: ["), "helmfile.yaml", 0); len(got) > 0 {
+		t.Errorf("unexpected unknown fields in invalid YAML: %v", got)
+	}
+}
+
+func TestUnknownFieldsError(t *testing.T) {
+	err := &UnknownFieldsError{Fields: []UnknownField{
+		{Position: SourcePosition{File: "helmfile.yaml", Line: 4}, Path: "releases[0]", Name: "needz", Suggestion: "needs"},
+		{Position: SourcePosition{File: "helmfile.yaml", Line: 9}, Name: "foo"},
+	}}
+
+	want := `found 2 unknown fields:
+  helmfile.yaml:4: unknown field "needz" in releases[0], did you mean "needs"?
+  helmfile.yaml:9: unknown field "foo"`
+
+	if d := cmp.Diff(want, err.Error()); d != "" {
+		t.Errorf("unexpected error: want (-), got (+): %s", d)
+	}
+}
@@ -22,6 +22,7 @@ import (
 	"github.com/helmfile/vals"
 	"github.com/imdario/mergo"
 	"github.com/tatsushid/go-prettytable"
+	"go.uber.org/multierr"
 	"go.uber.org/zap"
 	"helm.sh/helm/v3/pkg/cli"
 
@@ -67,6 +68,8 @@ type ReleaseSetSpec struct {
 	CommonLabels        map[string]string `yaml:"commonLabels,omitempty"`
 	Releases            []ReleaseSpec     `yaml:"releases,omitempty"`
 	Selectors           []string          `yaml:"-"`
+	// ChangedFiles are the files changed since the ref given to --changed-since. Releases are selected regardless of the changes when nil
+	ChangedFiles ChangedFiles `yaml:"-"`
 
 	// Capabilities.APIVersions
 	ApiVersions []string `yaml:"apiVersions,omitempty"`
@@ -123,6 +126,20 @@ type HelmState struct {
 
 	valsRuntime vals.Evaluator
 
+	// asyncHooks tracks the async hooks started by the releases and the helmfile, until they are joined at cleanup
+	asyncHooks *event.AsyncHooks
+
+	// remote fetches remote values files and charts. A new one is created on each fetch when nil
+	remote *remote.Remote
+
+	// lockedDeps is the content of the lock file, set by ResolveDeps when the lock file exists
+	lockedDeps *ResolvedDependencies
+
+	// envSecretFiles is the paths to the environment secrets files, and envSecrets is the values decrypted from them.
+	// They are kept so that the decrypted values are never vendored in plain text
+	envSecretFiles []string
+	envSecrets     map[string]any
+
 	// RenderedValues is the helmfile-wide values that is `.Values`
 	// which is accessible from within the whole helmfile go template.
 	// Note that this is usually computed by DesiredStateLoader from ReleaseSetSpec.Env
@@ -230,6 +247,9 @@ type ReleaseSpec struct {
 	Directory string `yaml:"directory,omitempty"`
 	// Version is the semver version or version constraint for the chart
 	Version string `yaml:"version,omitempty"`
+	// UpdatePolicy limits how far `helmfile deps` updates the locked version of the chart: patch, minor, major or none.
+	// It defaults to major, which updates to the newest version satisfying Version
+	UpdatePolicy string `yaml:"updatePolicy,omitempty"`
 	// Verify enables signature verification on fetched chart.
 	// Beware some (or many?) chart repositories and charts don't seem to support it.
 	Verify  *bool  `yaml:"verify,omitempty"`
@@ -282,6 +302,12 @@ type ReleaseSpec struct {
 	MissingFileHandler *string `yaml:"missingFileHandler,omitempty"`
 	// Needs is the [TILLER_NS/][NS/]NAME representations of releases that this release depends on.
 	Needs []string `yaml:"needs,omitempty"`
+	// NeedsPositions are the positions of the entries of Needs in the helmfile, used in error messages
+	NeedsPositions []SourcePosition `yaml:"-"`
+	// Priority orders the releases that can be processed at the same time. Releases with higher priorities are started first (default 0)
+	Priority int `yaml:"priority,omitempty"`
+	// Weight is the number of slots of the concurrency budget the release takes up while being processed (default 1)
+	Weight int `yaml:"weight,omitempty"`
 
 	// Hooks is a list of extension points paired with operations, that are executed in specific points of the lifecycle of releases defined in helmfile
 	Hooks []event.Hook `yaml:"hooks,omitempty"`
@@ -408,9 +434,23 @@ type SetValue struct {
 
 // AffectedReleases hold the list of released that where updated, deleted, or in error
 type AffectedReleases struct {
-	Upgraded []*ReleaseSpec
-	Deleted  []*ReleaseSpec
-	Failed   []*ReleaseSpec
+	Upgraded   []*ReleaseSpec
+	Deleted    []*ReleaseSpec
+	Failed     []*ReleaseSpec
+	RolledBack []*ReleaseSpec
+
+	mu sync.Mutex
+}
+
+// Merge appends the releases affected in other to ar. It is safe for concurrent use.
+func (ar *AffectedReleases) Merge(other *AffectedReleases) {
+	ar.mu.Lock()
+	defer ar.mu.Unlock()
+
+	ar.Upgraded = append(ar.Upgraded, other.Upgraded...)
+	ar.Deleted = append(ar.Deleted, other.Deleted...)
+	ar.Failed = append(ar.Failed, other.Failed...)
+	ar.RolledBack = append(ar.RolledBack, other.RolledBack...)
 }
 
 // DefaultEnv is the default environment to use for helm commands
@@ -505,6 +545,18 @@ type RepoUpdater interface {
 func (st *HelmState) SyncRepos(helm RepoUpdater, shouldSkip map[string]bool) ([]string, error) {
 	var updated []string
 
+	if st.offline() {
+		var repos []string
+		for _, repo := range st.Repositories {
+			if !shouldSkip[repo.Name] {
+				repos = append(repos, repo.Name)
+			}
+		}
+		if len(repos) > 0 {
+			return nil, fmt.Errorf("adding repositories %s is not allowed in offline mode. Use --skip-deps to skip it", strings.Join(repos, ", "))
+		}
+	}
+
 	for _, repo := range st.Repositories {
 		if shouldSkip[repo.Name] {
 			continue
@@ -993,11 +1045,8 @@ func (st *HelmState) getDeployedVersion(context helmexec.HelmContext, helm helme
 	//retrieve the version
 	if out, err := st.listReleases(context, helm, release); err == nil {
 		chartName := filepath.Base(release.Chart)
-		//the regexp without escapes : .*\s.*\s.*\s.*\schartName-(.*?)\s
-		pat := regexp.MustCompile(".*\\s.*\\s.*\\s.*\\s" + chartName + "-(.*?)\\s")
-		versions := pat.FindStringSubmatch(out)
-		if len(versions) > 0 {
-			return versions[1], nil
+		if version, ok := parseDeployedVersion(out, chartName); ok {
+			return version, nil
 		} else {
 			chartMetadata, err := helm.ShowChart(release.Chart)
 			if err != nil {
@@ -1010,6 +1059,18 @@ func (st *HelmState) getDeployedVersion(context helmexec.HelmContext, helm helme
 	}
 }
 
+// parseDeployedVersion returns the chart version of the release in the output of `helm list`
+func parseDeployedVersion(out, chartName string) (string, bool) {
+	//the regexp without escapes : .*\s.*\s.*\s.*\schartName-(.*?)\s
+	pat := regexp.MustCompile(".*\\s.*\\s.*\\s.*\\s" + chartName + "-(.*?)\\s")
+	versions := pat.FindStringSubmatch(out)
+	if len(versions) == 0 {
+		return "", false
+	}
+
+	return versions[1], true
+}
+
 func releasesNeedCharts(releases []ReleaseSpec) []ReleaseSpec {
 	var result []ReleaseSpec
 
@@ -1090,7 +1151,7 @@ type PrepareChartKey struct {
 func (st *HelmState) PrepareCharts(helm helmexec.Interface, dir string, concurrency int, helmfileCommand string, opts ChartPrepareOptions) (map[PrepareChartKey]string, []error) {
 	var selected []ReleaseSpec
 
-	if len(st.Selectors) > 0 {
+	if st.HasReleaseFilters() {
 		var err error
 
 		// This and releasesNeedCharts ensures that we run operations like helm-dep-build and prepare-hook calls only on
@@ -1158,7 +1219,7 @@ func (st *HelmState) PrepareCharts(helm helmexec.Interface, dir string, concurre
 				chartFetchedByGoGetter := chartPath != chartName
 
 				if !chartFetchedByGoGetter {
-					ociChartPath, err := st.getOCIChart(release, dir, helm)
+					ociChartPath, err := st.getOCIChart(release, dir, helm, st.lockedDigest(release))
 					if err != nil {
 						results <- &chartPrepareResult{err: fmt.Errorf("release %q: %w", release.Name, err)}
 
@@ -1189,9 +1250,10 @@ func (st *HelmState) PrepareCharts(helm helmexec.Interface, dir string, concurre
 				skipDepsGlobal := opts.SkipDeps
 				skipDepsRelease := release.SkipDeps != nil && *release.SkipDeps
 				skipDepsDefault := release.SkipDeps == nil && st.HelmDefaults.SkipDeps
-				skipDeps := (!isLocal && !chartFetchedByGoGetter) || skipDepsGlobal || skipDepsRelease || skipDepsDefault
+				// `helm dep build` may download the dependencies of the chart
+				skipDeps := (!isLocal && !chartFetchedByGoGetter) || skipDepsGlobal || skipDepsRelease || skipDepsDefault || st.offline()
 
-				if chartification != nil && helmfileCommand != "pull" {
+				if chartification != nil && helmfileCommand != "pull" && helmfileCommand != "vendor" {
 					c := chartify.New(
 						chartify.HelmBin(st.DefaultHelmBinary),
 						chartify.KustomizeBin(st.DefaultKustomizeBinary),
@@ -1261,7 +1323,11 @@ func (st *HelmState) PrepareCharts(helm helmexec.Interface, dir string, concurre
 					chartPath = normalizedChart
 
 					buildDeps = !skipDeps
-				} else if !opts.ForceDownload {
+				} else if !opts.ForceDownload && st.lockedDigest(release) == "" {
+					if st.offline() && !st.fs.FileExistsAt(normalizedChart) {
+						results <- &chartPrepareResult{err: fmt.Errorf("release %q: chart %q must be downloaded, which is not allowed in offline mode", release.Name, chartName)}
+						return
+					}
 					// At this point, we are sure that either:
 					// 1. It is a local chart and we can use it in later process (helm upgrade/template/lint/etc)
 					//    without any modification, or
@@ -1274,6 +1340,9 @@ func (st *HelmState) PrepareCharts(helm helmexec.Interface, dir string, concurre
 					//    For helm 2, we `helm fetch` with the version flags and call `helm template`
 					//    WITHOUT the version flags.
 				} else {
+					// The chart is downloaded by Helmfile either when requested, or when the lock file has its digest.
+					// In the latter case, the chart is never handed over to helm by its name, so that helm
+					// never installs a chart re-published with the same version without verifying it.
 					chartPath, err = generateChartPath(chartName, dir, release, opts.OutputDirTemplate)
 					if err != nil {
 						results <- &chartPrepareResult{err: err}
@@ -1282,10 +1351,27 @@ func (st *HelmState) PrepareCharts(helm helmexec.Interface, dir string, concurre
 
 					// only fetch chart if it is not already fetched
 					if _, err := os.Stat(chartPath); os.IsNotExist(err) {
+						if st.offline() {
+							results <- &chartPrepareResult{err: fmt.Errorf("release %q: downloading chart %q is not allowed in offline mode", release.Name, chartName)}
+							return
+						}
+
 						fetchFlags := st.chartVersionFlags(release)
-						fetchFlags = append(fetchFlags, "--untar", "--untardir", chartPath)
-						if err := helm.Fetch(chartName, fetchFlags...); err != nil {
-							results <- &chartPrepareResult{err: err}
+						if digest := st.lockedDigest(release); digest != "" {
+							if err := fetchVerifiedChart(helm, chartName, digest, chartPath, fetchFlags...); err != nil {
+								results <- &chartPrepareResult{err: fmt.Errorf("release %q: %w", release.Name, err)}
+								return
+							}
+						} else {
+							fetchFlags = append(fetchFlags, "--untar", "--untardir", chartPath)
+							if err := helm.Fetch(chartName, fetchFlags...); err != nil {
+								results <- &chartPrepareResult{err: err}
+								return
+							}
+						}
+					} else if digest := st.lockedDigest(release); digest != "" {
+						if err := verifyCachedChart(chartName, digest, chartPath); err != nil {
+							results <- &chartPrepareResult{err: fmt.Errorf("release %q: %w", release.Name, err)}
 							return
 						}
 					} else {
@@ -2065,17 +2151,17 @@ func (st *HelmState) DeleteReleases(affectedReleases *AffectedReleases, helm hel
 		if _, err := st.triggerReleaseEvent("preuninstall", nil, &release, "delete"); err != nil {
 			affectedReleases.Failed = append(affectedReleases.Failed, &release)
 
-			return err
+			return NewReleaseError(&release, err, ReleaseErrorCodeFailure)
 		}
 
 		if err := helm.DeleteRelease(context, release.Name, flags...); err != nil {
 			affectedReleases.Failed = append(affectedReleases.Failed, &release)
-			return err
+			return NewReleaseError(&release, err, ReleaseErrorCodeFailure)
 		}
 
 		if _, err := st.triggerReleaseEvent("postuninstall", nil, &release, "delete"); err != nil {
 			affectedReleases.Failed = append(affectedReleases.Failed, &release)
-			return err
+			return NewReleaseError(&release, err, ReleaseErrorCodeFailure)
 		}
 		release.duration = time.Since(start)
 
@@ -2084,6 +2170,54 @@ func (st *HelmState) DeleteReleases(affectedReleases *AffectedReleases, helm hel
 	})
 }
 
+// RollbackReleases wrapper for executing helm rollback on the releases.
+// Each release is rolled back to the revision, or to its previous revision when revision is 0.
+func (st *HelmState) RollbackReleases(affectedReleases *AffectedReleases, helm helmexec.Interface, concurrency int, revision int) []error {
+	var m sync.Mutex
+
+	return st.scatterGatherReleases(helm, concurrency, func(release ReleaseSpec, workerIndex int) error {
+		st.ApplyOverrides(&release)
+
+		flags := make([]string, 0)
+		flags = st.appendConnectionFlags(flags, &release)
+		if release.Namespace != "" {
+			flags = append(flags, "--namespace", release.Namespace)
+		}
+
+		context := st.createHelmContext(&release, workerIndex)
+
+		start := time.Now()
+		err := st.rollbackRelease(context, helm, &release, revision, flags)
+		release.duration = time.Since(start)
+
+		m.Lock()
+		if err != nil {
+			affectedReleases.Failed = append(affectedReleases.Failed, &release)
+		} else {
+			affectedReleases.RolledBack = append(affectedReleases.RolledBack, &release)
+		}
+		m.Unlock()
+
+		return err
+	})
+}
+
+func (st *HelmState) rollbackRelease(context helmexec.HelmContext, helm helmexec.Interface, release *ReleaseSpec, revision int, flags []string) error {
+	if _, err := st.triggerReleaseEvent("prerollback", nil, release, "rollback"); err != nil {
+		return err
+	}
+
+	if err := helm.RollbackRelease(context, release.Name, revision, flags...); err != nil {
+		return err
+	}
+
+	if _, err := st.triggerReleaseEvent("postrollback", nil, release, "rollback"); err != nil {
+		return err
+	}
+
+	return nil
+}
+
 type TestOpts struct {
 	Logs bool
 }
@@ -2149,14 +2283,27 @@ func (st *HelmState) GetReleasesWithOverrides() []ReleaseSpec {
 
 func (st *HelmState) SelectReleases(includeTransitiveNeeds bool) ([]Release, error) {
 	values := st.Values()
-	rs, err := markExcludedReleases(st.Releases, st.Selectors, st.CommonLabels, values, includeTransitiveNeeds)
+	rs, err := markExcludedReleases(st.Releases, st.Selectors, st.CommonLabels, values)
 	if err != nil {
 		return nil, err
 	}
+	if st.ChangedFiles != nil {
+		if err := st.markUnchangedReleases(rs); err != nil {
+			return nil, err
+		}
+	}
+	if includeTransitiveNeeds {
+		unmarkNeedsAndTransitives(rs, st.Releases)
+	}
 	return rs, nil
 }
 
-func markExcludedReleases(releases []ReleaseSpec, selectors []string, commonLabels map[string]string, values map[string]any, includeTransitiveNeeds bool) ([]Release, error) {
+// HasReleaseFilters returns true if only some of the releases may be selected, by the selectors or by --changed-since
+func (st *HelmState) HasReleaseFilters() bool {
+	return len(st.Selectors) > 0 || st.ChangedFiles != nil
+}
+
+func markExcludedReleases(releases []ReleaseSpec, selectors []string, commonLabels map[string]string, values map[string]any) ([]Release, error) {
 	var filteredReleases []Release
 	filters := []ReleaseFilter{}
 	for _, label := range selectors {
@@ -2201,9 +2348,6 @@ func markExcludedReleases(releases []ReleaseSpec, selectors []string, commonLabe
 		}
 		filteredReleases = append(filteredReleases, res)
 	}
-	if includeTransitiveNeeds {
-		unmarkNeedsAndTransitives(filteredReleases, releases)
-	}
 	return filteredReleases, nil
 }
 
@@ -2311,11 +2455,24 @@ func (st *HelmState) triggerGlobalReleaseEvent(evt string, evtErr error, helmfil
 		Env:           st.Env,
 		Logger:        st.logger,
 		Fs:            st.fs,
+		Async:         st.asyncHooks,
 	}
 	data := map[string]any{
 		"HelmfileCommand": helmfileCmd,
 	}
-	return bus.Trigger(evt, evtErr, data)
+
+	// Join the async hooks of the releases that haven't been cleaned up, so that they don't outlive the helmfile
+	var asyncErr error
+	if evt == "cleanup" {
+		asyncErr = st.asyncHooks.WaitAll()
+	}
+
+	executed, err := bus.Trigger(evt, evtErr, data)
+	if asyncErr != nil {
+		return false, multierr.Append(asyncErr, err)
+	}
+
+	return executed, err
 }
 
 func (st *HelmState) triggerPrepareEvent(r *ReleaseSpec, helmfileCommand string) (bool, error) {
@@ -2348,6 +2505,8 @@ func (st *HelmState) triggerReleaseEvent(evt string, evtErr error, r *ReleaseSpe
 		Env:           st.Env,
 		Logger:        st.logger,
 		Fs:            st.fs,
+		Async:         st.asyncHooks,
+		AsyncGroup:    ReleaseToID(r),
 	}
 	vals := st.Values()
 	data := map[string]any{
@@ -2364,11 +2523,17 @@ func (st *HelmState) ResolveDeps() (*HelmState, error) {
 	return st.mergeLockedDependencies()
 }
 
-// UpdateDeps wrapper for updating dependencies on the releases
-func (st *HelmState) UpdateDeps(helm helmexec.Interface, includeTransitiveNeeds bool) []error {
+// UpdateDeps wrapper for updating dependencies on the releases.
+// When releases are selected, only the selected releases are updated in the lock file.
+func (st *HelmState) UpdateDeps(helm helmexec.Interface, includeTransitiveNeeds bool, opt ...UpdateDepsOpt) []error {
+	opts := &UpdateDepsOpts{}
+	for _, o := range opt {
+		o.Apply(opts)
+	}
+
 	var selected []ReleaseSpec
 
-	if len(st.Selectors) > 0 {
+	if st.HasReleaseFilters() {
 		var err error
 
 		// This and releasesNeedCharts ensures that we run operations like helm-dep-build and prepare-hook calls only on
@@ -2387,6 +2552,10 @@ func (st *HelmState) UpdateDeps(helm helmexec.Interface, includeTransitiveNeeds
 
 	for _, release := range releases {
 		if st.fs.DirectoryExistsAt(release.ChartPathOrName()) {
+			if opts.DryRun {
+				st.logger.Infof("dry-run: skipped updating dependencies for local chart %s", release.ChartPathOrName())
+				continue
+			}
 			if err := helm.UpdateDeps(release.ChartPathOrName()); err != nil {
 				errs = append(errs, err)
 			}
@@ -2400,7 +2569,14 @@ func (st *HelmState) UpdateDeps(helm helmexec.Interface, includeTransitiveNeeds
 		if tempDir == nil {
 			tempDir = os.MkdirTemp
 		}
-		_, err := st.updateDependenciesInTempDir(helm, tempDir)
+
+		// The releases that are not selected keep their locked versions
+		var selectedReleases []ReleaseSpec
+		if st.HasReleaseFilters() {
+			selectedReleases = append([]ReleaseSpec{}, selected...)
+		}
+
+		_, err := st.updateDependenciesInTempDir(helm, tempDir, selectedReleases, *opts)
 		if err != nil {
 			errs = append(errs, fmt.Errorf("unable to update deps: %v", err))
 		}
@@ -2471,16 +2647,51 @@ func (st *HelmState) appendKeyringFlags(flags []string, release *ReleaseSpec) []
 
 func (st *HelmState) kubeConnectionFlags(release *ReleaseSpec) []string {
 	flags := []string{}
-	if release.KubeContext != "" {
-		flags = append(flags, "--kube-context", release.KubeContext)
-	} else if st.Environments[st.Env.Name].KubeContext != "" {
-		flags = append(flags, "--kube-context", st.Environments[st.Env.Name].KubeContext)
-	} else if st.HelmDefaults.KubeContext != "" {
-		flags = append(flags, "--kube-context", st.HelmDefaults.KubeContext)
+	if kubeContext := st.KubeContext(release); kubeContext != "" {
+		flags = append(flags, "--kube-context", kubeContext)
 	}
 	return flags
 }
 
+// KubeContext returns the kube context the release is deployed to, which is empty for the current kube context
+func (st *HelmState) KubeContext(release *ReleaseSpec) string {
+	switch {
+	case release.KubeContext != "":
+		return release.KubeContext
+	case st.Environments[st.Env.Name].KubeContext != "":
+		return st.Environments[st.Env.Name].KubeContext
+	}
+	return st.HelmDefaults.KubeContext
+}
+
+// ChartRepository returns the name and the URL of the repository the chart of the release is fetched from.
+// The URL is empty when the repository isn't defined in the helmfile. Charts referenced by their URL,
+// like OCI and go-getter charts, have no repository name and their own URL.
+// It returns false for local charts.
+func (st *HelmState) ChartRepository(release *ReleaseSpec) (string, string, bool) {
+	chart := release.Chart
+	if chart == "" {
+		chart = release.Directory
+	}
+
+	if strings.Contains(chart, "://") {
+		return "", chart, true
+	}
+
+	repo, _, ok := resolveRemoteChart(chart)
+	if !ok {
+		return "", "", false
+	}
+
+	for _, r := range st.Repositories {
+		if r.Name == repo {
+			return repo, r.URL, true
+		}
+	}
+
+	return repo, "", true
+}
+
 func (st *HelmState) appendChartDownloadTLSFlags(flags []string, release *ReleaseSpec) []string {
 	switch {
 	case release.InsecureSkipTLSVerify:
@@ -2773,12 +2984,19 @@ func (st *HelmState) RenderReleaseValuesFileToBytes(release *ReleaseSpec, path s
 	return rawBytes, nil
 }
 
+// offline returns true when helmfile must not access the network to fetch anything.
+// The remote is shared by all the states and knows whether helmfile runs in offline mode.
+func (st *HelmState) offline() bool {
+	return st.remote != nil && st.remote.Offline
+}
+
 func (st *HelmState) storage() *Storage {
 	return &Storage{
 		FilePath: st.FilePath,
 		basePath: st.basePath,
 		logger:   st.logger,
 		fs:       st.fs,
+		remote:   st.remote,
 	}
 }
 
@@ -3134,7 +3352,7 @@ func renderValsSecrets(e vals.Evaluator, input ...string) ([]string, error) {
 	return output, nil
 }
 
-// DisplayAffectedReleases logs the upgraded, deleted and in error releases
+// DisplayAffectedReleases logs the upgraded, deleted, in error and rolled back releases
 func (ar *AffectedReleases) DisplayAffectedReleases(logger *zap.SugaredLogger) {
 	if ar.Upgraded != nil && len(ar.Upgraded) > 0 {
 		logger.Info("\nUPDATED RELEASES:")
@@ -3182,6 +3400,20 @@ func (ar *AffectedReleases) DisplayAffectedReleases(logger *zap.SugaredLogger) {
 		}
 		logger.Info(tbl.String())
 	}
+	if len(ar.RolledBack) > 0 {
+		logger.Info("\nROLLED BACK RELEASES:")
+		tbl, _ := prettytable.NewTable(prettytable.Column{Header: "NAME"},
+			prettytable.Column{Header: "DURATION", AlignRight: true},
+		)
+		tbl.Separator = "   "
+		for _, release := range ar.RolledBack {
+			err := tbl.AddRow(release.Name, release.duration.Round(time.Second))
+			if err != nil {
+				logger.Warn("Could not add row, %v", err)
+			}
+		}
+		logger.Info(tbl.String())
+	}
 }
 
 func escape(value string) string {
@@ -3467,7 +3699,8 @@ func (st *HelmState) Reverse() {
 	}
 }
 
-func (st *HelmState) getOCIChart(release *ReleaseSpec, tempDir string, helm helmexec.Interface) (*string, error) {
+// getOCIChart pulls the chart of the release from the OCI registry, and verifies its digest when the lock file has one
+func (st *HelmState) getOCIChart(release *ReleaseSpec, tempDir string, helm helmexec.Interface, digest string) (*string, error) {
 	qualifiedChartName, chartName, chartVersion, err := st.getOCIQualifiedChartName(release, helm)
 	if err != nil {
 		return nil, err
@@ -3495,6 +3728,13 @@ func (st *HelmState) getOCIChart(release *ReleaseSpec, tempDir string, helm helm
 
 	if st.fs.DirectoryExistsAt(chartPath) {
 		st.logger.Debugf("chart already exists at %s", chartPath)
+		if digest != "" {
+			if err := verifyCachedChart(qualifiedChartName, digest, chartPath); err != nil {
+				return nil, err
+			}
+		}
+	} else if st.offline() {
+		return nil, fmt.Errorf("pulling chart %q is not allowed in offline mode", qualifiedChartName)
 	} else {
 		flags := []string{}
 		repo, _ := st.GetRepositoryAndNameFromChartName(release.Chart)
@@ -3516,14 +3756,22 @@ func (st *HelmState) getOCIChart(release *ReleaseSpec, tempDir string, helm helm
 			}
 		}
 
-		err := helm.ChartPull(qualifiedChartName, chartPath, flags...)
-		if err != nil {
-			return nil, err
-		}
+		if digest != "" {
+			chartURL := "oci://" + strings.TrimSuffix(qualifiedChartName, ":"+chartVersion)
+			flags = append([]string{"--version", chartVersion}, flags...)
+			if err := fetchVerifiedChart(helm, chartURL, digest, chartPath, flags...); err != nil {
+				return nil, err
+			}
+		} else {
+			err := helm.ChartPull(qualifiedChartName, chartPath, flags...)
+			if err != nil {
+				return nil, err
+			}
 
-		err = helm.ChartExport(qualifiedChartName, chartPath)
-		if err != nil {
-			return nil, err
+			err = helm.ChartExport(qualifiedChartName, chartPath)
+			if err != nil {
+				return nil, err
+			}
 		}
 	}
 
@@ -3537,6 +3785,16 @@ func (st *HelmState) getOCIChart(release *ReleaseSpec, tempDir string, helm helm
 	return &chartPath, nil
 }
 
+// lockedDigest returns the digest of the chart archive of the release recorded in the lock file, or an empty string when there's none
+func (st *HelmState) lockedDigest(release *ReleaseSpec) string {
+	chart, url, ok := dependencyOf(release, repositoriesByName(st))
+	if !ok {
+		return ""
+	}
+
+	return st.lockedDeps.digest(chart, url, release.Version)
+}
+
 func (st *HelmState) getOCIQualifiedChartName(release *ReleaseSpec, helm helmexec.Interface) (qualifiedChartName, chartName, chartVersion string, err error) {
 	chartVersion = "latest"
 	if release.Version != "" {
@@ -76,7 +76,7 @@ func (st *HelmState) iterateOnReleases(helm helmexec.Interface, concurrency int,
 			for range inputs {
 				r := <-results
 				if r.err != nil {
-					errs = append(errs, fmt.Errorf("release \"%s\" failed: %v", r.release.Name, r.err))
+					errs = append(errs, fmt.Errorf("release \"%s\" failed: %w", r.release.Name, r.err))
 				}
 			}
 		},
@@ -96,6 +96,9 @@ type PlanOptions struct {
 	IncludeTransitiveNeeds bool
 	SkipNeeds              bool
 	SelectedReleases       []ReleaseSpec
+
+	// Concurrency is the maximum number of releases processed at once while traversing the DAG. 0 is unlimited
+	Concurrency int
 }
 
 func (st *HelmState) PlanReleases(opts PlanOptions) ([][]Release, error) {
@@ -132,6 +135,7 @@ func SortedReleaseGroups(releases []Release, opts PlanOptions) ([][]Release, err
 func GroupReleasesByDependency(releases []Release, opts PlanOptions) ([][]Release, error) {
 	idToReleases := map[string][]Release{}
 	idToIndex := map[string]int{}
+	idToPriority := map[string]int{}
 
 	d := dag.New()
 	for i, r := range releases {
@@ -139,6 +143,9 @@ func GroupReleasesByDependency(releases []Release, opts PlanOptions) ([][]Releas
 
 		idToReleases[id] = append(idToReleases[id], r)
 		idToIndex[id] = i
+		if p, ok := idToPriority[id]; !ok || r.Priority > p {
+			idToPriority[id] = r.Priority
+		}
 
 		var needs []string
 		for i := 0; i < len(r.Needs); i++ {
@@ -161,6 +168,10 @@ func GroupReleasesByDependency(releases []Release, opts PlanOptions) ([][]Releas
 		selectedReleaseIDs = append(selectedReleaseIDs, id)
 	}
 
+	if err := checkNeedsCycles(releases); err != nil {
+		return nil, err
+	}
+
 	plan, err := d.Plan(dag.SortOptions{
 		Only:                selectedReleaseIDs,
 		WithDependencies:    opts.IncludeNeeds,
@@ -241,7 +252,13 @@ func GroupReleasesByDependency(releases []Release, opts PlanOptions) ([][]Releas
 		// Make the helmfile behavior deterministic for reproducibility and ease of testing
 		// We try to keep the order of definitions to keep backward-compatibility
 		// See https://github.com/roboll/helmfile/issues/988
+		// Releases with higher priorities come first, so that they are started first
 		sort.Slice(idsInGroup, func(i, j int) bool {
+			pi := idToPriority[idsInGroup[i]]
+			pj := idToPriority[idsInGroup[j]]
+			if pi != pj {
+				return pi > pj
+			}
 			ii := idToIndex[idsInGroup[i]]
 			ij := idToIndex[idsInGroup[j]]
 			return ii < ij
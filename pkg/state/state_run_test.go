@@ -0,0 +1,40 @@
+package state
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestGroupReleasesByDependency_Priority(t *testing.T) {
+	releases := []Release{
+		{ReleaseSpec: ReleaseSpec{Name: "web", Namespace: "default"}},
+		{ReleaseSpec: ReleaseSpec{Name: "worker", Namespace: "default", Priority: -1}},
+		{ReleaseSpec: ReleaseSpec{Name: "database", Namespace: "default", Priority: 10}},
+		{ReleaseSpec: ReleaseSpec{Name: "cache", Namespace: "default", Priority: 10}},
+		{ReleaseSpec: ReleaseSpec{Name: "app", Namespace: "default", Needs: []string{"default/database"}}},
+	}
+
+	groups, err := GroupReleasesByDependency(releases, PlanOptions{})
+	require.NoError(t, err)
+
+	var names [][]string
+	for _, g := range groups {
+		var ns []string
+		for _, r := range g {
+			ns = append(ns, r.Name)
+		}
+		names = append(names, ns)
+	}
+
+	require.Equal(t, [][]string{
+		{"database", "cache", "web", "worker"},
+		{"app"},
+	}, names)
+}
+
+func TestReleaseSpec_ConcurrencyWeight(t *testing.T) {
+	require.Equal(t, 1, ReleaseSpec{}.ConcurrencyWeight())
+	require.Equal(t, 1, ReleaseSpec{Weight: -1}.ConcurrencyWeight())
+	require.Equal(t, 3, ReleaseSpec{Weight: 3}.ConcurrencyWeight())
+}
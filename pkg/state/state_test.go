@@ -37,7 +37,12 @@ func TestLabelParsing(t *testing.T) {
 		{"foo=bar", LabelFilter{positiveLabels: [][]string{{"foo", "bar"}}, negativeLabels: [][]string{}}, false},
 		{"foo!=bar", LabelFilter{positiveLabels: [][]string{}, negativeLabels: [][]string{{"foo", "bar"}}}, false},
 		{"foo!=bar,baz=bat", LabelFilter{positiveLabels: [][]string{{"baz", "bat"}}, negativeLabels: [][]string{{"foo", "bar"}}}, false},
-		{"foo", LabelFilter{positiveLabels: [][]string{}, negativeLabels: [][]string{}}, true},
+		{"foo", LabelFilter{positiveLabels: [][]string{}, negativeLabels: [][]string{}, requirements: []labelRequirement{{key: "foo", operator: labelOperatorExists}}}, false},
+		{"tier in (a, b),!canary,foo=bar", LabelFilter{positiveLabels: [][]string{{"foo", "bar"}}, negativeLabels: [][]string{}, requirements: []labelRequirement{{key: "tier", operator: labelOperatorIn, values: []string{"a", "b"}}, {key: "canary", operator: labelOperatorDoesNotExist}}}, false},
+		{"tier notin (c)", LabelFilter{positiveLabels: [][]string{}, negativeLabels: [][]string{}, requirements: []labelRequirement{{key: "tier", operator: labelOperatorNotIn, values: []string{"c"}}}}, false},
+		{"tier in (a,", LabelFilter{positiveLabels: [][]string{}, negativeLabels: [][]string{}}, true},
+		{"tier in (a,b c)", LabelFilter{positiveLabels: [][]string{}, negativeLabels: [][]string{}}, true},
+		{"name=~^api-[", LabelFilter{positiveLabels: [][]string{}, negativeLabels: [][]string{}}, true},
 		{"foo!=bar=baz", LabelFilter{positiveLabels: [][]string{}, negativeLabels: [][]string{}}, true},
 		{"=bar", LabelFilter{positiveLabels: [][]string{}, negativeLabels: [][]string{}}, true},
 	}
@@ -2167,6 +2172,100 @@ generated: 2019-05-16T15:42:45.50486+09:00
 	}
 }
 
+func TestHelmState_UpdateDeps_SelectedReleases(t *testing.T) {
+	lockFile := filepath.Join(t.TempDir(), "helmfile.lock")
+	originalLock := `version: ""
+dependencies:
+- name: nginx
+  repository: https://charts.bitnami.com/bitnami
+  version: 13.2.0
+- name: redis
+  repository: https://charts.bitnami.com/bitnami
+  version: 17.3.1
+digest: ""
+generated: "0001-01-01T00:00:00Z"
+`
+
+	for _, dryRun := range []bool{false, true} {
+		t.Run(fmt.Sprintf("dry-run=%t", dryRun), func(t *testing.T) {
+			require.NoError(t, os.WriteFile(lockFile, []byte(originalLock), 0644))
+
+			helm := &exectest.Helm{
+				UpdateDepsCallbacks: map[string]func(string) error{},
+				Helm3:               true,
+			}
+
+			var chartYaml string
+			tempDir := func(dir, prefix string) (string, error) {
+				generatedDir, err := os.MkdirTemp(dir, prefix)
+				if err != nil {
+					return "", err
+				}
+				helm.UpdateDepsCallbacks[generatedDir] = func(chart string) error {
+					content, err := os.ReadFile(filepath.Join(generatedDir, "Chart.yaml"))
+					if err != nil {
+						return err
+					}
+					chartYaml = string(content)
+
+					return os.WriteFile(filepath.Join(generatedDir, "Chart.lock"), []byte(`dependencies:
+- name: redis
+  repository: https://charts.bitnami.com/bitnami
+  version: 17.3.1
+- name: nginx
+  repository: https://charts.bitnami.com/bitnami
+  version: 13.2.4
+digest: ""
+generated: "0001-01-01T00:00:00Z"
+`), 0644)
+				}
+				return generatedDir, nil
+			}
+
+			state := &HelmState{
+				basePath: "/src",
+				FilePath: "/src/helmfile.yaml",
+				ReleaseSetSpec: ReleaseSetSpec{
+					LockFile:  lockFile,
+					Selectors: []string{"name=nginx"},
+					Releases: []ReleaseSpec{
+						{Name: "nginx", Chart: "bitnami/nginx", Version: "~13.2", UpdatePolicy: UpdatePolicyPatch},
+						{Name: "redis", Chart: "bitnami/redis", Version: "^17.0.0"},
+					},
+					Repositories: []RepositorySpec{
+						{Name: "bitnami", URL: "https://charts.bitnami.com/bitnami"},
+					},
+				},
+				RenderedValues: map[string]any{},
+				tempDir:        tempDir,
+				logger:         helmexec.NewLogger(io.Discard, "debug"),
+			}
+			state = injectFs(state, testhelper.NewTestFs(map[string]string{}))
+
+			out, err := testutil.CaptureStdout(func() {
+				errs := state.UpdateDeps(helm, false, &UpdateDepsOpts{DryRun: dryRun})
+				require.Empty(t, errs)
+			})
+			require.NoError(t, err)
+
+			// Only the selected release is updated, and only to a newer patch version as of its update policy
+			require.Contains(t, chartYaml, "~13.2, >=13.2.0, <13.3.0")
+			require.Contains(t, chartYaml, "^17.0.0, 17.3.1")
+
+			lock, err := os.ReadFile(lockFile)
+			require.NoError(t, err)
+
+			if dryRun {
+				require.Equal(t, originalLock, string(lock))
+				require.Contains(t, out, "-   version: 13.2.0\n+   version: 13.2.4\n")
+			} else {
+				require.Contains(t, string(lock), "version: 13.2.4")
+				require.Empty(t, out)
+			}
+		})
+	}
+}
+
 func TestHelmState_ResolveDeps_NoLockFile(t *testing.T) {
 	logger := helmexec.NewLogger(io.Discard, "debug")
 	state := &HelmState{
@@ -21,6 +21,7 @@ type Storage struct {
 
 	basePath string
 	fs       *filesystem.FileSystem
+	remote   *remote.Remote
 }
 
 func NewStorage(forFile string, logger *zap.SugaredLogger, fs *filesystem.FileSystem) *Storage {
@@ -58,7 +59,10 @@ func (st *Storage) resolveFile(missingFileHandler *string, tpe, path string, opt
 	}
 
 	if remote.IsRemote(path) {
-		r := remote.NewRemote(st.logger, "", st.fs)
+		r := st.remote
+		if r == nil {
+			r = remote.NewRemote(st.logger, "", st.fs)
+		}
 
 		fetchedFilePath, err := r.Fetch(path, "values")
 		if err != nil {
@@ -0,0 +1,354 @@
+package state
+
+import (
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/helmfile/helmfile/pkg/yaml"
+)
+
+// Vendor copies the charts, values files and secrets files of the releases into dir/name,
+// and returns the state rewritten to refer to the copies instead of the original, possibly remote, sources.
+// The rewritten state is meant to be written to dir, so that the paths in it are relative to dir.
+//
+// Repositories, sub-helmfiles, bases and templates are dropped from the rewritten state,
+// as they are already resolved while loading the state. The environment values are written to a file
+// so that hooks and values templates can still refer to them.
+//
+// Decrypted secrets are never written. The environment secrets files are copied as they are, and
+// the values files of the releases are copied as their sources, to be rendered on deployment.
+func (st *HelmState) Vendor(dir, name string) (*HelmState, error) {
+	stateDir := filepath.Join(dir, name)
+
+	vendored := &HelmState{
+		basePath: st.basePath,
+		FilePath: name + ".yaml",
+		ReleaseSetSpec: ReleaseSetSpec{
+			DefaultHelmBinary:      st.DefaultHelmBinary,
+			DefaultKustomizeBinary: st.DefaultKustomizeBinary,
+			HelmDefaults:           st.HelmDefaults,
+			OverrideKubeContext:    st.OverrideKubeContext,
+			OverrideNamespace:      st.OverrideNamespace,
+			CommonLabels:           st.CommonLabels,
+			ApiVersions:            st.ApiVersions,
+			KubeVersion:            st.KubeVersion,
+			Hooks:                  st.Hooks,
+		},
+	}
+
+	envValues, err := st.Env.GetMergedValues()
+	if err != nil {
+		return nil, err
+	}
+
+	envFile, err := writeVendoredYAML(stateDir, "environment.yaml", withoutSecrets(envValues, st.envSecrets))
+	if err != nil {
+		return nil, err
+	}
+
+	var envSecrets []string
+	for i, p := range st.envSecretFiles {
+		dst := filepath.Join(stateDir, "secrets", fmt.Sprintf("environment-%d-%s", i, filepath.Base(p)))
+		if err := copyFile(p, dst); err != nil {
+			return nil, err
+		}
+		envSecrets = append(envSecrets, relativeTo(dir, dst))
+	}
+
+	vendored.Environments = map[string]EnvironmentSpec{
+		st.Env.Name: {
+			Values:      []any{relativeTo(dir, envFile)},
+			Secrets:     envSecrets,
+			KubeContext: st.Env.KubeContext,
+		},
+	}
+
+	secrets := secretStrings(st.envSecrets, nil)
+
+	for i := range st.Releases {
+		r := st.Releases[i]
+
+		prefix := fmt.Sprintf("%02d-%s", i, r.Name)
+
+		chart, err := st.vendorChart(&r, dir, filepath.Join(stateDir, "charts", prefix))
+		if err != nil {
+			return nil, fmt.Errorf("vendoring the chart of release %q: %v", r.Name, err)
+		}
+
+		var valuesFiles []any
+		for _, v := range r.Values {
+			path, ok := v.(string)
+			if !ok {
+				if containsSecrets(v, secrets) {
+					return nil, fmt.Errorf("vendoring the values of release %q: inline values containing environment secrets cannot be vendored", r.Name)
+				}
+
+				f, err := writeVendoredYAML(filepath.Join(stateDir, "values"), fmt.Sprintf("%s-%d.yaml", prefix, len(valuesFiles)), v)
+				if err != nil {
+					return nil, err
+				}
+				valuesFiles = append(valuesFiles, relativeTo(dir, f))
+				continue
+			}
+
+			paths, skip, err := st.storage().resolveFile(r.MissingFileHandler, "values", r.ValuesPathPrefix+path, st.MissingFileHandlerConfig.resolveFileOptions()...)
+			if err != nil {
+				return nil, fmt.Errorf("vendoring the values of release %q: %v", r.Name, err)
+			}
+			if skip {
+				continue
+			}
+
+			// Values files are copied as their sources and never rendered, as rendering them may write the secrets
+			// they refer to, like `fetchSecretValue` and `ref+` values, in plain text
+			for _, p := range paths {
+				dst := filepath.Join(stateDir, "values", fmt.Sprintf("%s-%d-%s", prefix, len(valuesFiles), filepath.Base(p)))
+				if err := copyFile(p, dst); err != nil {
+					return nil, err
+				}
+				valuesFiles = append(valuesFiles, relativeTo(dir, dst))
+			}
+		}
+
+		for _, v := range r.SetValues {
+			if containsSecrets([]any{v.Value, v.Values}, secrets) {
+				return nil, fmt.Errorf("vendoring the values of release %q: set value %q containing environment secrets cannot be vendored", r.Name, v.Name)
+			}
+		}
+
+		var secretsFiles []any
+		for _, s := range r.Secrets {
+			path, ok := s.(string)
+			if !ok {
+				secretsFiles = append(secretsFiles, s)
+				continue
+			}
+
+			paths, skip, err := st.storage().resolveFile(r.MissingFileHandler, "secrets", r.ValuesPathPrefix+path, st.MissingFileHandlerConfig.resolveFileOptions()...)
+			if err != nil {
+				return nil, fmt.Errorf("vendoring the secrets of release %q: %v", r.Name, err)
+			}
+			if skip {
+				continue
+			}
+
+			// Secrets are copied as they are, so that they are never written in plain text
+			for _, p := range paths {
+				dst := filepath.Join(stateDir, "secrets", fmt.Sprintf("%s-%d-%s", prefix, len(secretsFiles), filepath.Base(p)))
+				if err := copyFile(p, dst); err != nil {
+					return nil, err
+				}
+				secretsFiles = append(secretsFiles, relativeTo(dir, dst))
+			}
+		}
+
+		// Prefixed so that it is never mistaken for a chart in a repository, like `000-helmfile/charts/00-app`
+		r.Chart = "./" + relativeTo(dir, chart)
+		r.ChartPath = ""
+		r.Directory = ""
+		r.ForceGoGetter = false
+		r.Values = valuesFiles
+		r.Secrets = secretsFiles
+		r.ValuesPathPrefix = ""
+		r.ValuesTemplate = nil
+		r.Inherit = nil
+
+		vendored.Releases = append(vendored.Releases, r)
+	}
+
+	return vendored, nil
+}
+
+// vendorChart copies the chart of the release to dst, unless it was already downloaded into the vendor directory.
+// It returns the path to the vendored chart.
+func (st *HelmState) vendorChart(release *ReleaseSpec, dir, dst string) (string, error) {
+	chart := normalizeChart(st.basePath, release.ChartPathOrName())
+
+	switch {
+	case strings.HasPrefix(absPath(chart), absPath(dir)+string(filepath.Separator)):
+		// Downloaded into the vendor directory by PrepareCharts
+		return chart, nil
+	case st.fs.DirectoryExistsAt(chart):
+		return dst, copyDir(chart, dst)
+	case st.fs.FileExistsAt(chart):
+		dst = filepath.Join(dst, filepath.Base(chart))
+		return dst, copyFile(chart, dst)
+	}
+
+	return "", fmt.Errorf("chart %q is neither downloaded nor local", release.Chart)
+}
+
+// withoutSecrets returns the values without the keys that are set by the decrypted secrets.
+func withoutSecrets(values, secrets map[string]any) map[string]any {
+	result := map[string]any{}
+
+	for k, v := range values {
+		s, ok := secrets[k]
+		if !ok {
+			result[k] = v
+			continue
+		}
+
+		vm, vok := v.(map[string]any)
+		sm, sok := s.(map[string]any)
+		if vok && sok {
+			result[k] = withoutSecrets(vm, sm)
+		}
+	}
+
+	return result
+}
+
+// secretStrings appends the non-empty strings in the decrypted secrets to acc.
+func secretStrings(v any, acc []string) []string {
+	switch t := v.(type) {
+	case map[string]any:
+		for _, e := range t {
+			acc = secretStrings(e, acc)
+		}
+	case map[any]any:
+		for _, e := range t {
+			acc = secretStrings(e, acc)
+		}
+	case []any:
+		for _, e := range t {
+			acc = secretStrings(e, acc)
+		}
+	case string:
+		if t != "" {
+			acc = append(acc, t)
+		}
+	}
+
+	return acc
+}
+
+// containsSecrets returns true when any string in v contains any of the secrets.
+func containsSecrets(v any, secrets []string) bool {
+	switch t := v.(type) {
+	case map[string]any:
+		for _, e := range t {
+			if containsSecrets(e, secrets) {
+				return true
+			}
+		}
+	case map[any]any:
+		for _, e := range t {
+			if containsSecrets(e, secrets) {
+				return true
+			}
+		}
+	case []any:
+		for _, e := range t {
+			if containsSecrets(e, secrets) {
+				return true
+			}
+		}
+	case []string:
+		for _, e := range t {
+			if containsSecrets(e, secrets) {
+				return true
+			}
+		}
+	case string:
+		for _, s := range secrets {
+			if strings.Contains(t, s) {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+func writeVendoredYAML(dir, name string, v any) (string, error) {
+	bs, err := yaml.Marshal(v)
+	if err != nil {
+		return "", err
+	}
+
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return "", err
+	}
+
+	path := filepath.Join(dir, name)
+
+	return path, os.WriteFile(path, bs, 0644)
+}
+
+func relativeTo(dir, path string) string {
+	rel, err := filepath.Rel(absPath(dir), absPath(path))
+	if err != nil {
+		return path
+	}
+	return filepath.ToSlash(rel)
+}
+
+func absPath(path string) string {
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return path
+	}
+	return abs
+}
+
+func copyDir(src, dst string) error {
+	return filepath.WalkDir(src, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+
+		rel, err := filepath.Rel(src, path)
+		if err != nil {
+			return err
+		}
+
+		if d.IsDir() {
+			if d.Name() == ".git" {
+				return filepath.SkipDir
+			}
+			return os.MkdirAll(filepath.Join(dst, rel), 0755)
+		}
+
+		if !d.Type().IsRegular() {
+			return nil
+		}
+
+		return copyFile(path, filepath.Join(dst, rel))
+	})
+}
+
+func copyFile(src, dst string) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		_ = in.Close()
+	}()
+
+	info, err := in.Stat()
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(dst), 0755); err != nil {
+		return err
+	}
+
+	out, err := os.OpenFile(dst, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, info.Mode().Perm())
+	if err != nil {
+		return err
+	}
+
+	if _, err := io.Copy(out, in); err != nil {
+		_ = out.Close()
+		return err
+	}
+
+	return out.Close()
+}
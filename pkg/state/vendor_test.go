@@ -0,0 +1,290 @@
+package state
+
+import (
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+
+	"github.com/helmfile/helmfile/pkg/environment"
+	"github.com/helmfile/helmfile/pkg/filesystem"
+	"github.com/helmfile/helmfile/pkg/helmexec"
+	"github.com/helmfile/helmfile/pkg/yaml"
+)
+
+func TestHelmState_Vendor(t *testing.T) {
+	base := t.TempDir()
+	dir := t.TempDir()
+
+	files := map[string]string{
+		"charts/app/Chart.yaml":            "name: app\nversion: 0.1.0\n",
+		"charts/app/templates/cm.yaml":     "kind: ConfigMap\n",
+		"charts/app/.git/HEAD":             "ref: refs/heads/main\n",
+		"values/app.yaml":                  "replicas: 2\n",
+		"secrets/app.yaml":                 "password: ENC[AES256_GCM,data:abc]\n",
+		"charts/packaged/packaged-1.0.tgz": "tgz",
+	}
+	for p, content := range files {
+		if err := os.MkdirAll(filepath.Dir(filepath.Join(base, p)), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(filepath.Join(base, p), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// A chart downloaded into the vendor directory by PrepareCharts
+	downloaded := filepath.Join(dir, "000-helmfile", "charts", "stable", "nginx", "latest", "nginx")
+	if err := os.MkdirAll(downloaded, 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	env := environment.New("prod")
+	env.Values = map[string]any{"domain": "example.com"}
+
+	st := &HelmState{
+		basePath:       base,
+		FilePath:       "helmfile.yaml",
+		logger:         helmexec.NewLogger(io.Discard, "warn"),
+		fs:             filesystem.DefaultFileSystem(),
+		RenderedValues: map[string]any{},
+		ReleaseSetSpec: ReleaseSetSpec{
+			Env:          *env,
+			Repositories: []RepositorySpec{{Name: "stable", URL: "https://charts.helm.sh/stable"}},
+			Releases: []ReleaseSpec{
+				{
+					Name:    "app",
+					Chart:   "./charts/app",
+					Values:  []any{"values/app.yaml", map[string]any{"inline": true}},
+					Secrets: []any{"secrets/app.yaml"},
+				},
+				{
+					Name:      "nginx",
+					Chart:     "stable/nginx",
+					ChartPath: downloaded,
+				},
+				{
+					Name:  "packaged",
+					Chart: "./charts/packaged/packaged-1.0.tgz",
+				},
+			},
+		},
+	}
+
+	vendored, err := st.Vendor(dir, "000-helmfile")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if vendored.FilePath != "000-helmfile.yaml" {
+		t.Errorf("unexpected file path: %s", vendored.FilePath)
+	}
+
+	if len(vendored.Repositories) != 0 {
+		t.Errorf("repositories must be dropped: %v", vendored.Repositories)
+	}
+
+	wantEnv := map[string]EnvironmentSpec{
+		"prod": {Values: []any{"000-helmfile/environment.yaml"}},
+	}
+	if diff := cmp.Diff(wantEnv, vendored.Environments); diff != "" {
+		t.Errorf("unexpected environments: %s", diff)
+	}
+
+	type release struct {
+		Chart   string
+		Values  []any
+		Secrets []any
+	}
+
+	var got []release
+	for _, r := range vendored.Releases {
+		got = append(got, release{Chart: r.Chart, Values: r.Values, Secrets: r.Secrets})
+	}
+
+	want := []release{
+		{
+			Chart:   "./000-helmfile/charts/00-app",
+			Values:  []any{"000-helmfile/values/00-app-0-app.yaml", "000-helmfile/values/00-app-1.yaml"},
+			Secrets: []any{"000-helmfile/secrets/00-app-0-app.yaml"},
+		},
+		{
+			Chart: "./000-helmfile/charts/stable/nginx/latest/nginx",
+		},
+		{
+			Chart: "./000-helmfile/charts/02-packaged/packaged-1.0.tgz",
+		},
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("unexpected releases: %s", diff)
+	}
+
+	for p, content := range map[string]string{
+		"000-helmfile/environment.yaml":                    "domain: example.com\n",
+		"000-helmfile/charts/00-app/Chart.yaml":            files["charts/app/Chart.yaml"],
+		"000-helmfile/charts/00-app/templates/cm.yaml":     files["charts/app/templates/cm.yaml"],
+		"000-helmfile/values/00-app-0-app.yaml":            files["values/app.yaml"],
+		"000-helmfile/values/00-app-1.yaml":                "inline: true\n",
+		"000-helmfile/secrets/00-app-0-app.yaml":           files["secrets/app.yaml"],
+		"000-helmfile/charts/02-packaged/packaged-1.0.tgz": "tgz",
+	} {
+		bs, err := os.ReadFile(filepath.Join(dir, p))
+		if err != nil {
+			t.Errorf("unexpected error: %v", err)
+			continue
+		}
+		if string(bs) != content {
+			t.Errorf("unexpected content of %s: want %q, got %q", p, content, string(bs))
+		}
+	}
+
+	if _, err := os.Stat(filepath.Join(dir, "000-helmfile", "charts", "00-app", ".git")); !os.IsNotExist(err) {
+		t.Errorf("the .git directory must not be vendored")
+	}
+
+	// The rewritten state must be loadable as a helmfile
+	out, err := vendored.ToYaml()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	var spec ReleaseSetSpec
+	if err := yaml.Unmarshal([]byte(out), &spec); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(spec.Releases) != len(vendored.Releases) {
+		t.Errorf("unexpected number of releases: want %d, got %d", len(vendored.Releases), len(spec.Releases))
+	}
+}
+
+func TestHelmState_Vendor_RemoteChart(t *testing.T) {
+	st := &HelmState{
+		basePath: t.TempDir(),
+		FilePath: "helmfile.yaml",
+		logger:   helmexec.NewLogger(io.Discard, "warn"),
+		fs:       filesystem.DefaultFileSystem(),
+		ReleaseSetSpec: ReleaseSetSpec{
+			Env: *environment.New("default"),
+			Releases: []ReleaseSpec{
+				{Name: "nginx", Chart: "stable/nginx"},
+			},
+		},
+	}
+
+	_, err := st.Vendor(t.TempDir(), "000-helmfile")
+
+	want := `vendoring the chart of release "nginx": chart "stable/nginx" is neither downloaded nor local`
+	if err == nil || err.Error() != want {
+		t.Errorf("unexpected error: want %q, got %v", want, err)
+	}
+}
+
+func TestHelmState_Vendor_EnvironmentSecrets(t *testing.T) {
+	base := t.TempDir()
+	dir := t.TempDir()
+
+	files := map[string]string{
+		"secrets/env.yaml":      "db:\n  password: ENC[AES256_GCM,data:abc]\n",
+		"values/app.yaml":       "replicas: 2\n",
+		"values/db.yaml.gotmpl": "password: {{ .Values.db.password | b64enc }}\n",
+		"charts/app/Chart.yaml": "name: app\nversion: 0.1.0\n",
+	}
+	for p, content := range files {
+		if err := os.MkdirAll(filepath.Dir(filepath.Join(base, p)), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(filepath.Join(base, p), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	env := environment.New("prod")
+	env.Values = map[string]any{
+		"db": map[string]any{"host": "db.example.com", "password": "s3cr3t"},
+	}
+
+	st := &HelmState{
+		basePath:       base,
+		FilePath:       "helmfile.yaml",
+		logger:         helmexec.NewLogger(io.Discard, "warn"),
+		fs:             filesystem.DefaultFileSystem(),
+		RenderedValues: map[string]any{},
+		envSecretFiles: []string{filepath.Join(base, "secrets/env.yaml")},
+		envSecrets:     map[string]any{"db": map[string]any{"password": "s3cr3t"}},
+		ReleaseSetSpec: ReleaseSetSpec{
+			Env: *env,
+			Releases: []ReleaseSpec{
+				{
+					Name:   "app",
+					Chart:  "./charts/app",
+					Values: []any{"values/app.yaml", "values/db.yaml.gotmpl"},
+				},
+			},
+		},
+	}
+
+	vendored, err := st.Vendor(dir, "000-helmfile")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	wantEnv := map[string]EnvironmentSpec{
+		"prod": {
+			Values:  []any{"000-helmfile/environment.yaml"},
+			Secrets: []string{"000-helmfile/secrets/environment-0-env.yaml"},
+		},
+	}
+	if diff := cmp.Diff(wantEnv, vendored.Environments); diff != "" {
+		t.Errorf("unexpected environments: %s", diff)
+	}
+
+	// The values files are copied as their sources, so that neither the secret nor its base64 encoding is written
+	wantValues := []any{"000-helmfile/values/00-app-0-app.yaml", "000-helmfile/values/00-app-1-db.yaml.gotmpl"}
+	if diff := cmp.Diff(wantValues, vendored.Releases[0].Values); diff != "" {
+		t.Errorf("unexpected values: %s", diff)
+	}
+
+	for p, content := range map[string]string{
+		"000-helmfile/environment.yaml":               "db:\n  host: db.example.com\n",
+		"000-helmfile/secrets/environment-0-env.yaml": files["secrets/env.yaml"],
+		"000-helmfile/values/00-app-0-app.yaml":       files["values/app.yaml"],
+		"000-helmfile/values/00-app-1-db.yaml.gotmpl": files["values/db.yaml.gotmpl"],
+	} {
+		bs, err := os.ReadFile(filepath.Join(dir, p))
+		if err != nil {
+			t.Errorf("unexpected error: %v", err)
+			continue
+		}
+		if string(bs) != content {
+			t.Errorf("unexpected content of %s: want %q, got %q", p, content, string(bs))
+		}
+	}
+
+	err = filepath.WalkDir(dir, func(path string, d os.DirEntry, err error) error {
+		if err != nil || d.IsDir() {
+			return err
+		}
+		bs, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		if strings.Contains(string(bs), "s3cr3t") || strings.Contains(string(bs), "czNjcjN0") {
+			t.Errorf("the decrypted secret must not be written to %s", path)
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	st.Releases[0].Values = []any{map[string]any{"password": "s3cr3t"}}
+
+	_, err = st.Vendor(t.TempDir(), "000-helmfile")
+
+	want := `vendoring the values of release "app": inline values containing environment secrets cannot be vendored`
+	if err == nil || err.Error() != want {
+		t.Errorf("unexpected error: want %q, got %v", want, err)
+	}
+}
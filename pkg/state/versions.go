@@ -0,0 +1,210 @@
+package state
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/Masterminds/semver/v3"
+
+	"github.com/helmfile/helmfile/pkg/helmexec"
+)
+
+const (
+	// VersionIssueUnpinned means the release doesn't set the version of its chart
+	VersionIssueUnpinned = "unpinned"
+	// VersionIssueRange means the version of the chart is a constraint rather than an exact version
+	VersionIssueRange = "range"
+	// VersionIssueDevel means the release allows development versions of its chart with `devel: true`
+	VersionIssueDevel = "devel"
+	// VersionIssueLockMismatch means no entry of the lock file satisfies the version of the chart
+	VersionIssueLockMismatch = "lock-mismatch"
+)
+
+// ChartVersionCheck is the result of checking the chart version of a release
+type ChartVersionCheck struct {
+	// ID is the release ID as returned by ReleaseToID
+	ID    string `json:"id"`
+	Chart string `json:"chart"`
+	// Version is the chart version or the version constraint in the helmfile state
+	Version string `json:"version,omitempty"`
+	// Locked is the chart version resolved from the lock file
+	Locked string `json:"locked,omitempty"`
+	// Newest is the newest chart version available in the repository
+	Newest string `json:"newest,omitempty"`
+	// NewestPatch, NewestMinor and NewestMajor are the newest chart versions available in the repository
+	// that are patch, minor and major upgrades of the pinned version, or of the locked one when the version is a range
+	NewestPatch string `json:"newestPatch,omitempty"`
+	NewestMinor string `json:"newestMinor,omitempty"`
+	NewestMajor string `json:"newestMajor,omitempty"`
+	// Issues are the categories of the issues found. It is empty when the version of the chart is pinned
+	Issues []string `json:"issues"`
+}
+
+// CheckChartVersions checks that the releases of remote charts pin the versions of their charts and match the lock file,
+// and looks up the newer versions of the charts available in the repositories. Releases with `installed: false` are skipped.
+// The charts of OCI registries are checked but not looked up, as `helm search repo` doesn't support them.
+// The result is in the same order as releases.
+func (st *HelmState) CheckChartVersions(helm helmexec.Interface, releases []ReleaseSpec) ([]ChartVersionCheck, error) {
+	locked, lockFileExists, err := st.lockedDependencies()
+	if err != nil {
+		return nil, err
+	}
+
+	repos := repositoriesByName(st)
+
+	available := map[string][]string{}
+
+	var checks []ChartVersionCheck
+
+	for i := range releases {
+		release := &releases[i]
+
+		if !release.Desired() {
+			continue
+		}
+
+		_, url, ok := st.ChartRepository(release)
+		// Skip local charts, which may look like `charts/myapp` but have no matching `repository` in the helmfile state
+		if !ok || url == "" {
+			continue
+		}
+
+		check := ChartVersionCheck{
+			ID:      ReleaseToID(release),
+			Chart:   release.Chart,
+			Version: release.Version,
+			Issues:  []string{},
+		}
+
+		devel := st.isDevelopment(release)
+
+		pinned := release.Version != ""
+		switch {
+		case !pinned:
+			check.Issues = append(check.Issues, VersionIssueUnpinned)
+		case !isExactVersion(release.Version):
+			check.Issues = append(check.Issues, VersionIssueRange)
+			pinned = false
+		}
+
+		if devel {
+			check.Issues = append(check.Issues, VersionIssueDevel)
+		}
+
+		chart, depURL, managed := dependencyOf(release, repos)
+
+		if managed && lockFileExists {
+			ver, err := locked.Get(chart, release.Version)
+			if err != nil {
+				check.Issues = append(check.Issues, VersionIssueLockMismatch)
+			} else {
+				check.Locked = ver
+			}
+		}
+
+		if managed && !strings.HasPrefix(depURL, "oci://") {
+			key := fmt.Sprintf("%s:%t", release.Chart, devel)
+
+			versions, ok := available[key]
+			if !ok {
+				var flags []string
+				if devel {
+					flags = append(flags, "--devel")
+				}
+
+				versions, err = helm.SearchChartVersions(release.Chart, flags...)
+				if err != nil {
+					return nil, fmt.Errorf("searching versions of chart %q for release %q: %v", release.Chart, check.ID, err)
+				}
+
+				available[key] = versions
+			}
+
+			current := check.Locked
+			if pinned {
+				current = release.Version
+			}
+
+			check.Newest, check.NewestPatch, check.NewestMinor, check.NewestMajor = newestVersions(current, versions, devel)
+		}
+
+		checks = append(checks, check)
+	}
+
+	return checks, nil
+}
+
+// lockedDependencies reads the chart versions of the lock file. It returns false when the lock file doesn't exist.
+func (st *HelmState) lockedDependencies() (*ResolvedDependencies, bool, error) {
+	filename, unresolved, err := getUnresolvedDependenciess(st)
+	if err != nil {
+		return nil, false, err
+	}
+
+	depMan := NewChartDependencyManager(filename, st.logger, st.LockFile)
+
+	if st.fs.ReadFile != nil {
+		depMan.readFile = st.fs.ReadFile
+	}
+
+	return depMan.Resolve(unresolved)
+}
+
+func isExactVersion(v string) bool {
+	_, err := semver.StrictNewVersion(strings.TrimPrefix(v, "v"))
+	return err == nil
+}
+
+// newestVersions returns the newest of the available versions, and the newest patch, minor and major upgrades of the current version.
+// Only the newest version is returned when the current version is unknown. Pre-releases are ignored unless devel is true.
+func newestVersions(current string, available []string, devel bool) (newest, patch, minor, major string) {
+	var cur *semver.Version
+	if current != "" {
+		cur, _ = semver.NewVersion(current)
+	}
+
+	var newestV, patchV, minorV, majorV *semver.Version
+
+	newer := func(v, than *semver.Version) bool {
+		return than == nil || v.GreaterThan(than)
+	}
+
+	for _, a := range available {
+		v, err := semver.NewVersion(a)
+		if err != nil || (v.Prerelease() != "" && !devel) {
+			continue
+		}
+
+		if newer(v, newestV) {
+			newestV = v
+		}
+
+		if cur == nil || !v.GreaterThan(cur) {
+			continue
+		}
+
+		switch {
+		case v.Major() > cur.Major():
+			if newer(v, majorV) {
+				majorV = v
+			}
+		case v.Minor() > cur.Minor():
+			if newer(v, minorV) {
+				minorV = v
+			}
+		default:
+			if newer(v, patchV) {
+				patchV = v
+			}
+		}
+	}
+
+	original := func(v *semver.Version) string {
+		if v == nil {
+			return ""
+		}
+		return v.Original()
+	}
+
+	return original(newestV), original(patchV), original(minorV), original(majorV)
+}
@@ -0,0 +1,104 @@
+package state
+
+import (
+	"fmt"
+	"io"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/helmfile/helmfile/pkg/exectest"
+	"github.com/helmfile/helmfile/pkg/filesystem"
+	"github.com/helmfile/helmfile/pkg/helmexec"
+)
+
+func TestNewestVersions(t *testing.T) {
+	available := []string{"13.0.0-rc.1", "13.0.0", "12.2.0", "12.1.3", "12.1.2", "12.1.0", "11.9.9", "foo"}
+
+	tests := []struct {
+		current                     string
+		devel                       bool
+		newest, patch, minor, major string
+	}{
+		{current: "12.1.0", newest: "13.0.0", patch: "12.1.3", minor: "12.2.0", major: "13.0.0"},
+		{current: "v12.1.3", newest: "13.0.0", minor: "12.2.0", major: "13.0.0"},
+		{current: "13.0.0", newest: "13.0.0"},
+		{current: "", newest: "13.0.0"},
+		{current: "12.2.0", devel: true, newest: "13.0.0", major: "13.0.0"},
+		{current: "13.0.0-beta.1", devel: true, newest: "13.0.0", patch: "13.0.0"},
+	}
+
+	for _, tt := range tests {
+		newest, patch, minor, major := newestVersions(tt.current, available, tt.devel)
+		require.Equal(t, []string{tt.newest, tt.patch, tt.minor, tt.major}, []string{newest, patch, minor, major}, "current %q", tt.current)
+	}
+}
+
+func TestHelmState_CheckChartVersions(t *testing.T) {
+	devel := true
+	notInstalled := false
+
+	st := &HelmState{
+		FilePath: "/src/helmfile.yaml",
+		ReleaseSetSpec: ReleaseSetSpec{
+			Repositories: []RepositorySpec{
+				{Name: "bitnami", URL: "https://charts.bitnami.com/bitnami"},
+				{Name: "registry", URL: "registry.example.com/charts", OCI: true},
+			},
+			Releases: []ReleaseSpec{
+				{Name: "db", Namespace: "data", Chart: "bitnami/postgresql", Version: "12.1.0"},
+				{Name: "cache", Namespace: "data", Chart: "bitnami/redis", Version: "~17.3"},
+				{Name: "queue", Namespace: "data", Chart: "bitnami/rabbitmq", Devel: &devel},
+				{Name: "search", Namespace: "data", Chart: "bitnami/elasticsearch", Version: "19.5.0"},
+				{Name: "app", Namespace: "web", Chart: "registry/app", Version: "1.0.0"},
+				{Name: "local", Namespace: "web", Chart: "charts/local"},
+				{Name: "removed", Namespace: "web", Chart: "bitnami/nginx", Installed: &notInstalled},
+			},
+		},
+		logger: helmexec.NewLogger(io.Discard, "debug"),
+		fs: &filesystem.FileSystem{
+			ReadFile: func(f string) ([]byte, error) {
+				if f != "helmfile.lock" {
+					return nil, fmt.Errorf("stub: unexpected file: %s", f)
+				}
+				return []byte(`dependencies:
+- name: postgresql
+  repository: https://charts.bitnami.com/bitnami
+  version: 12.1.0
+- name: redis
+  repository: https://charts.bitnami.com/bitnami
+  version: 17.3.1
+- name: rabbitmq
+  repository: https://charts.bitnami.com/bitnami
+  version: 11.0.0
+- name: elasticsearch
+  repository: https://charts.bitnami.com/bitnami
+  version: 19.4.0
+- name: app
+  repository: oci://registry.example.com/charts
+  version: 1.0.0
+`), nil
+			},
+		},
+	}
+
+	helm := &exectest.Helm{
+		ChartVersions: map[string][]string{
+			"bitnami/postgresql":    {"13.0.0", "12.1.2", "12.1.0"},
+			"bitnami/redis":         {"17.4.0", "17.3.2", "17.3.1"},
+			"bitnami/rabbitmq":      {"11.1.0-rc.0", "11.0.0"},
+			"bitnami/elasticsearch": {"19.5.0"},
+		},
+	}
+
+	checks, err := st.CheckChartVersions(helm, st.Releases)
+	require.NoError(t, err)
+
+	require.Equal(t, []ChartVersionCheck{
+		{ID: "data/db", Chart: "bitnami/postgresql", Version: "12.1.0", Locked: "12.1.0", Newest: "13.0.0", NewestPatch: "12.1.2", NewestMajor: "13.0.0", Issues: []string{}},
+		{ID: "data/cache", Chart: "bitnami/redis", Version: "~17.3", Locked: "17.3.1", Newest: "17.4.0", NewestPatch: "17.3.2", NewestMinor: "17.4.0", Issues: []string{VersionIssueRange}},
+		{ID: "data/queue", Chart: "bitnami/rabbitmq", Locked: "11.0.0", Newest: "11.1.0-rc.0", NewestMinor: "11.1.0-rc.0", Issues: []string{VersionIssueUnpinned, VersionIssueDevel}},
+		{ID: "data/search", Chart: "bitnami/elasticsearch", Version: "19.5.0", Newest: "19.5.0", Issues: []string{VersionIssueLockMismatch}},
+		{ID: "web/app", Chart: "registry/app", Version: "1.0.0", Locked: "1.0.0", Issues: []string{}},
+	}, checks)
+}
@@ -0,0 +1,68 @@
+package testhelper
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+)
+
+// TestGetter is a fake remote getter that writes fixtures into the destination instead of accessing the network.
+// It can be registered for any scheme, or used as the go-getter of a remote.
+type TestGetter struct {
+	// Fixtures maps the sources, as passed to the getter, to the files to write into the destination directory,
+	// keyed by their paths relative to it
+	Fixtures map[string]map[string]string
+
+	// Fs is written into when set. The files are written to the disk otherwise.
+	Fs *TestFs
+
+	mu      sync.Mutex
+	fetched []string
+}
+
+func NewTestGetter(fixtures map[string]map[string]string) *TestGetter {
+	return &TestGetter{
+		Fixtures: fixtures,
+	}
+}
+
+func (g *TestGetter) Get(wd, src, dst string) error {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	files, ok := g.Fixtures[src]
+	if !ok {
+		return fmt.Errorf("%s not found", src)
+	}
+
+	g.fetched = append(g.fetched, src)
+
+	for rel, content := range files {
+		path := filepath.Join(dst, rel)
+
+		if g.Fs != nil {
+			if err := g.Fs.WriteFile(path, []byte(content), 0644); err != nil {
+				return err
+			}
+			continue
+		}
+
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			return err
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Fetched returns the sources fetched so far, in order
+func (g *TestGetter) Fetched() []string {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	return append([]string(nil), g.fetched...)
+}
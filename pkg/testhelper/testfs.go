@@ -46,6 +46,7 @@ func (f *TestFs) ToFileSystem() *ffs.FileSystem {
 		FileExists:        f.FileExists,
 		DirectoryExistsAt: f.DirectoryExistsAt,
 		ReadFile:          f.ReadFile,
+		WriteFile:         f.WriteFile,
 		Glob:              f.Glob,
 		Getwd:             f.Getwd,
 		Chdir:             f.Chdir,
@@ -99,6 +100,19 @@ func (f *TestFs) ReadFile(filename string) ([]byte, error) {
 	return []byte(str), nil
 }
 
+func (f *TestFs) WriteFile(filename string, data []byte, _ os.FileMode) error {
+	if !strings.HasPrefix(filename, "/") {
+		filename = filepath.ToSlash(filepath.Join(f.Cwd, filename))
+	}
+
+	f.files[filename] = string(data)
+	for d := filepath.ToSlash(filepath.Dir(filename)); !f.dirs[d]; d = filepath.ToSlash(filepath.Dir(d)) {
+		f.dirs[d] = true
+	}
+
+	return nil
+}
+
 func (f *TestFs) SuccessfulReads() []string {
 	return f.successfulReads
 }
@@ -105,12 +105,21 @@ func (helm *noCallHelmExec) DeleteRelease(context helmexec.HelmContext, name str
 	helm.doPanic()
 	return nil
 }
+func (helm *noCallHelmExec) RollbackRelease(context helmexec.HelmContext, name string, revision int, flags ...string) error {
+	helm.doPanic()
+	return nil
+}
 
 func (helm *noCallHelmExec) List(context helmexec.HelmContext, filter string, flags ...string) (string, error) {
 	helm.doPanic()
 	return "", nil
 }
 
+func (helm *noCallHelmExec) GetValues(context helmexec.HelmContext, name string, flags ...string) (string, error) {
+	helm.doPanic()
+	return "", nil
+}
+
 func (helm *noCallHelmExec) DecryptSecret(context helmexec.HelmContext, name string, flags ...string) (string, error) {
 	helm.doPanic()
 	return "", nil
@@ -142,7 +151,12 @@ func (helm *noCallHelmExec) IsVersionAtLeast(versionStr string) bool {
 	return false
 }
 
-func (helm *noCallHelmExec) ShowChart(chartPath string) (chart.Metadata, error) {
+func (helm *noCallHelmExec) ShowChart(chartPath string, flags ...string) (chart.Metadata, error) {
 	helm.doPanic()
 	return chart.Metadata{}, nil
 }
+
+func (helm *noCallHelmExec) SearchChartVersions(chart string, flags ...string) ([]string, error) {
+	helm.doPanic()
+	return nil, nil
+}